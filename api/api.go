@@ -0,0 +1,9 @@
+// Описание HTTP API сервиса в формате OpenAPI и gRPC API в формате protobuf
+package api
+
+import _ "embed"
+
+//go:generate protoc -I proto --go_out=proto --go_opt=paths=source_relative --go-grpc_out=proto --go-grpc_opt=paths=source_relative social/v1/social.proto
+
+//go:embed openapi.yaml
+var Spec []byte
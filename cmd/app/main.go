@@ -2,24 +2,111 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/Lucky112/social/config"
 	"github.com/Lucky112/social/internal/app"
 )
 
+const usage = `Usage:
+  social [-config <file>] [-set path=value ...]
+  social config print [-redacted] [-config <file>] [-set path=value ...]
+  social config fields
+
+Settings are taken from defaults, then the config file, then SOCIAL_* environment variables, then -set flags.
+Settings marked "hot" in "config fields" are reloaded on SIGHUP or when the config file changes,
+the others require a restart.
+`
+
 func main() {
-	configFilePath := flag.String("config", "", "Path to the config file (JSON or YAML)")
-	flag.Parse()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfigCommand(os.Args[2:])
+		return
+	}
+
+	flags := flag.NewFlagSet("social", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	sources := sourcesFlags(flags)
+
+	_ = flags.Parse(os.Args[1:])
 
-	if *configFilePath == "" {
-		log.Fatalf("No config file provided. Use -config <file_path> to specify the config file.")
+	config, err := config.LoadFrom(*sources)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
 	}
 
-	config, err := config.Load(*configFilePath)
+	app.Run(config, *sources)
+}
+
+func runConfigCommand(args []string) {
+	if len(args) == 1 && args[0] == "fields" {
+		printFields()
+		return
+	}
+
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	sources := sourcesFlags(flags)
+	redacted := flags.Bool("redacted", false, "Hide secrets such as passwords and keys")
+
+	_ = flags.Parse(args[1:])
+
+	cfg, err := config.LoadFrom(*sources)
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
-	app.Run(config)
+	err = config.Print(os.Stdout, cfg, *redacted)
+	if err != nil {
+		log.Fatalf("Error printing config: %v", err)
+	}
+}
+
+// Список настроек с переменными окружения и отметкой о том, применяются ли изменения без перезапуска
+func printFields() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tENVIRONMENT\tRELOAD")
+
+	for _, f := range config.Fields() {
+		reload := "restart"
+		if f.Hot {
+			reload = "hot"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Path, f.Env, reload)
+	}
+
+	_ = w.Flush()
+}
+
+// Флаги источников конфигурации, общие для запуска приложения и вывода конфигурации
+func sourcesFlags(flags *flag.FlagSet) *config.Sources {
+	sources := &config.Sources{
+		Env: os.Environ(),
+	}
+
+	flags.StringVar(&sources.File, "config", "", "Path to the config file (JSON or YAML)")
+	flags.Var((*overrides)(&sources.Overrides), "set", "Setting in path=value form, e.g. db_config.host=localhost. May be repeated")
+
+	return sources
+}
+
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
 }
@@ -1,82 +1,258 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/go-playground/validator/v10"
-	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
+	// Уровень журнала: debug, info, warn или error
+	LogLevel string `json:"log_level" yaml:"log_level" validate:"omitempty,oneof=debug info warn error" reload:"hot"`
+
 	DBConfig     *DBConfig     `json:"db_config"     yaml:"db_config"     validate:"required"`
 	ServerConfig *ServerConfig `json:"server_config" yaml:"server_config" validate:"required"`
+	AuthConfig   *AuthConfig   `json:"auth_config"   yaml:"auth_config"   validate:"required"`
+	CacheConfig  *CacheConfig  `json:"cache_config"  yaml:"cache_config"`
+	EventsConfig *EventsConfig `json:"events_config" yaml:"events_config" validate:"required"`
+	// Отправка событий по подпискам пользователей (/webhooks)
+	WebhooksConfig *WebhooksConfig `json:"webhooks_config" yaml:"webhooks_config" validate:"required"`
+	// Загрузка фотографий анкет
+	PhotosConfig *PhotosConfig `json:"photos_config" yaml:"photos_config" validate:"required"`
 }
 
 type DBConfig struct {
 	Host     string `json:"host"     yaml:"host"     validate:"required,hostname|ip"`
 	Port     uint16 `json:"port"     yaml:"port"     validate:"required,min=1,max=65535"`
 	User     string `json:"user"     yaml:"user"     validate:"required"`
-	Password string `json:"password" yaml:"password" validate:"required"`
+	Password string `json:"password" yaml:"password" validate:"required" secret:"true"`
 	Database string `json:"database" yaml:"database" validate:"required"`
+
+	// Режим TLS: disable, allow, prefer, require, verify-ca или verify-full
+	SSLMode string `json:"ssl_mode" yaml:"ssl_mode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	// Время ожидания установки соединения в секундах
+	ConnectTimeoutSeconds int `json:"connect_timeout_seconds" yaml:"connect_timeout_seconds" validate:"omitempty,min=1"`
+	// Наибольшая длительность запроса в миллисекундах, после которой сервер его прерывает. На миграции не действует
+	StatementTimeoutMs int `json:"statement_timeout_ms" yaml:"statement_timeout_ms" validate:"omitempty,min=1"`
+	// Имя приложения в pg_stat_activity
+	ApplicationName string `json:"application_name" yaml:"application_name"`
+	// Схемы для таблиц без указания схемы, например "scl,public"
+	SearchPath string `json:"search_path" yaml:"search_path"`
+
+	// Параметры пула соединений. Незаданные (нулевые) параметры берутся по умолчанию pgxpool
+	MaxConns               int32 `json:"max_conns"                  yaml:"max_conns"                  validate:"omitempty,min=1"`
+	MinConns               int32 `json:"min_conns"                  yaml:"min_conns"                  validate:"omitempty,min=1"`
+	MaxConnLifetimeSeconds int   `json:"max_conn_lifetime_seconds"  yaml:"max_conn_lifetime_seconds"  validate:"omitempty,min=1"`
+	MaxConnIdleTimeSeconds int   `json:"max_conn_idle_time_seconds" yaml:"max_conn_idle_time_seconds" validate:"omitempty,min=1"`
+
+	// Число повторных попыток подключения при запуске, пока база не начнет принимать соединения,
+	// и задержка перед первой попыткой в миллисекундах. Задержка удваивается с каждой попыткой
+	ConnectRetries   int `json:"connect_retries"    yaml:"connect_retries"    validate:"min=0"`
+	ConnectBackoffMs int `json:"connect_backoff_ms" yaml:"connect_backoff_ms" validate:"min=0"`
 }
 
 type ServerConfig struct {
 	Port   uint16 `json:"port" yaml:"port" validate:"required,min=1,max=65535"`
-	JWTKey string `json:"jwt_key" yaml:"jwt_key" validate:"required"`
+	JWTKey string `json:"jwt_key" yaml:"jwt_key" validate:"required" secret:"true" reload:"hot"`
+	// Прежние ключи подписи JWT: выпущенные с ними токены принимаются до истечения срока действия
+	JWTPreviousKeys []string `json:"jwt_previous_keys" yaml:"jwt_previous_keys" validate:"dive,required" secret:"true" reload:"hot"`
+
+	OpenAPIValidation *OpenAPIValidationConfig `json:"openapi_validation" yaml:"openapi_validation"`
+
+	// Дата отключения маршрутов без префикса /api/v1 в формате YYYY-MM-DD,
+	// передается клиентам в заголовке Sunset
+	LegacySunset string `json:"legacy_sunset" yaml:"legacy_sunset" validate:"omitempty,datetime=2006-01-02"`
+
+	// Отдавать счетчики процесса, в том числе попаданий в кэш анкет, в формате expvar по адресу /debug/vars
+	ExposeMetrics bool `json:"expose_metrics" yaml:"expose_metrics"`
+
+	// gRPC API на отдельном порту. Если не задан, gRPC-сервер не запускается
+	GRPC *GRPCConfig `json:"grpc" yaml:"grpc"`
+
+	// Ограничения частоты запросов к маршрутам HTTP API. Маршруты без ограничений не проверяются
+	RateLimits []*RateLimitConfig `json:"rate_limits" yaml:"rate_limits" validate:"dive" reload:"hot"`
+
+	// Запросы к API из браузера со страниц других сайтов. Если не задан, такие запросы запрещены
+	CORS *CORSConfig `json:"cors" yaml:"cors"`
+
+	// Повтор POST-запросов с заголовком Idempotency-Key
+	Idempotency *IdempotencyConfig `json:"idempotency" yaml:"idempotency" validate:"required"`
+
+	// Наибольший размер тела запроса в байтах. Должен вмещать загружаемые фотографии
+	BodyLimitBytes int `json:"body_limit_bytes" yaml:"body_limit_bytes" validate:"required,min=1"`
+}
+
+type IdempotencyConfig struct {
+	// Сколько секунд хранить ответ на запрос для повторов с тем же ключом
+	TTLSeconds int `json:"ttl_seconds" yaml:"ttl_seconds" validate:"required,min=1"`
+	// Сколько секунд ключ занят выполняющимся запросом. Если экземпляр приложения упал,
+	// не выполнив запрос, ключ освобождается по истечении этого времени
+	LockTimeoutSeconds int `json:"lock_timeout_seconds" yaml:"lock_timeout_seconds" validate:"required,min=1"`
+}
+
+type CORSConfig struct {
+	// Разрешенные источники вида https://social.example.com или "*" для любых
+	AllowOrigins []string `json:"allow_origins" yaml:"allow_origins" validate:"dive,required" reload:"hot"`
+}
+
+type GRPCConfig struct {
+	Port uint16 `json:"port" yaml:"port" validate:"required,min=1,max=65535"`
+	// Регистрировать сервис reflection, нужный, например, grpcurl
+	Reflection bool `json:"reflection" yaml:"reflection"`
+}
+
+// Ограничение частоты запросов к маршруту по алгоритму token bucket.
+// Запросы считаются отдельно для каждого пользователя, а запросы без валидного токена - для каждого IP-адреса
+type RateLimitConfig struct {
+	// Метод и путь маршрута без префикса версии, например "GET /profiles/search".
+	// Путь может содержать параметры: "GET /profiles/:id"
+	Route string `json:"route" yaml:"route" validate:"required"`
+	// Число запросов, которое можно сделать подряд
+	Burst int `json:"burst" yaml:"burst" validate:"required,min=1"`
+	// Число запросов в минуту, на которое восстанавливается запас
+	PerMinute int `json:"per_minute" yaml:"per_minute" validate:"required,min=1"`
+}
+
+// Проверка запросов и ответов по описанию API из api/openapi.yaml.
+// По умолчанию выключена
+type OpenAPIValidationConfig struct {
+	// Отклонять запросы, не соответствующие описанию, с ответом 400
+	Requests bool `json:"requests" yaml:"requests"`
+	// Записывать в журнал ответы, не соответствующие описанию
+	Responses bool `json:"responses" yaml:"responses"`
+}
+
+// Кэш анкет в памяти процесса. Если не задан, анкеты всегда читаются из базы
+type CacheConfig struct {
+	// Наибольшее число анкет в кэше
+	ProfilesSize int `json:"profiles_size" yaml:"profiles_size" validate:"required,min=1"`
+	// Время жизни анкеты в кэше в секундах
+	ProfilesTTLSeconds int `json:"profiles_ttl_seconds" yaml:"profiles_ttl_seconds" validate:"required,min=1"`
+}
+
+// Публикация доменных событий из outbox
+type EventsConfig struct {
+	// Интервал опроса outbox в миллисекундах
+	PollIntervalMs int `json:"poll_interval_ms" yaml:"poll_interval_ms" validate:"required,min=1"`
+	// Наибольшее число событий, публикуемых в одной транзакции
+	BatchSize int `json:"batch_size" yaml:"batch_size" validate:"required,min=1"`
+	// Число попыток публикации, после которого событие переносится в scl.outbox_dead_letter
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts" validate:"required,min=1"`
+	// Задержка перед первой повторной попыткой в миллисекундах. Удваивается с каждой попыткой,
+	// но не превышает max_retry_backoff_ms
+	RetryBackoffMs    int `json:"retry_backoff_ms"     yaml:"retry_backoff_ms"     validate:"required,min=1"`
+	MaxRetryBackoffMs int `json:"max_retry_backoff_ms" yaml:"max_retry_backoff_ms" validate:"required,gtefield=RetryBackoffMs"`
+
+	// Отправка событий запросами POST. Если не задана, события получают только подписчики внутри процесса
+	HTTP *EventsHTTPConfig `json:"http" yaml:"http"`
+}
+
+type EventsHTTPConfig struct {
+	URL string `json:"url" yaml:"url" validate:"required,url"`
+	// Время ожидания ответа в секундах
+	TimeoutSeconds int `json:"timeout_seconds" yaml:"timeout_seconds" validate:"required,min=1"`
+}
+
+// Отправка доставок по подпискам на события
+type WebhooksConfig struct {
+	// Интервал опроса ожидающих доставок в миллисекундах
+	PollIntervalMs int `json:"poll_interval_ms" yaml:"poll_interval_ms" validate:"required,min=1"`
+	// Наибольшее число доставок, занимаемых за раз. Пачка занимается на (batch_size + 1) * timeout_seconds
+	BatchSize int `json:"batch_size" yaml:"batch_size" validate:"required,min=1"`
+	// Число попыток, после которого доставка получает статус failed
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts" validate:"required,min=1"`
+	// Задержка перед первой повторной попыткой в миллисекундах. Удваивается с каждой попыткой,
+	// но не превышает max_retry_backoff_ms
+	RetryBackoffMs    int `json:"retry_backoff_ms"     yaml:"retry_backoff_ms"     validate:"required,min=1"`
+	MaxRetryBackoffMs int `json:"max_retry_backoff_ms" yaml:"max_retry_backoff_ms" validate:"required,gtefield=RetryBackoffMs"`
+	// Время ожидания ответа получателя в секундах
+	TimeoutSeconds int `json:"timeout_seconds" yaml:"timeout_seconds" validate:"required,min=1"`
+}
+
+// Фотографии анкет: ограничения загружаемых файлов, миниатюры и хранилище файлов
+type PhotosConfig struct {
+	// Наибольший размер загружаемого файла в байтах
+	MaxSizeBytes int64 `json:"max_size_bytes" yaml:"max_size_bytes" validate:"required,min=1"`
+	// Наибольшее число пикселей изображения. Ограничивает память, нужную для декодирования
+	MaxPixels int `json:"max_pixels" yaml:"max_pixels" validate:"required,min=1"`
+	// Качество JPEG исходной фотографии и миниатюр, от 1 до 100
+	JPEGQuality int `json:"jpeg_quality" yaml:"jpeg_quality" validate:"required,min=1,max=100"`
+	// Размеры миниатюр, создаваемых при загрузке. Имя размера используется в адресе фотографии
+	Thumbnails []*ThumbnailConfig `json:"thumbnails" yaml:"thumbnails" validate:"unique=Name,dive"`
+
+	// Хранилище файлов: local или s3
+	Storage string                   `json:"storage" yaml:"storage" validate:"required,oneof=local s3"`
+	Local   *LocalPhotoStorageConfig `json:"local"   yaml:"local"   validate:"required_if=Storage local"`
+	S3      *S3PhotoStorageConfig    `json:"s3"      yaml:"s3"      validate:"required_if=Storage s3"`
+}
+
+type ThumbnailConfig struct {
+	Name string `json:"name" yaml:"name" validate:"required,alphanum,ne=original"`
+	// Наибольшая сторона миниатюры в пикселях. Изображения меньшего размера не увеличиваются
+	MaxSide int `json:"max_side" yaml:"max_side" validate:"required,min=1"`
+}
+
+// Каталог локальной файловой системы. При нескольких экземплярах приложения каталог должен быть общим
+type LocalPhotoStorageConfig struct {
+	Dir string `json:"dir" yaml:"dir" validate:"required"`
+}
+
+// S3 или S3-совместимое хранилище, например MinIO
+type S3PhotoStorageConfig struct {
+	Endpoint        string `json:"endpoint"          yaml:"endpoint"          validate:"required,url"`
+	Region          string `json:"region"            yaml:"region"            validate:"required"`
+	Bucket          string `json:"bucket"            yaml:"bucket"            validate:"required"`
+	AccessKeyId     string `json:"access_key_id"     yaml:"access_key_id"     validate:"required"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key" validate:"required" secret:"true"`
+	// Адресовать бакет в пути, а не в имени хоста. Нужно большинству S3-совместимых хранилищ
+	PathStyle bool `json:"path_style" yaml:"path_style"`
+	// Время ожидания ответа в секундах, по умолчанию 30
+	TimeoutSeconds int `json:"timeout_seconds" yaml:"timeout_seconds" validate:"omitempty,min=1"`
+}
+
+type AuthConfig struct {
+	// Ключ шифрования TOTP-секретов в базе: 32 байта в hex-представлении
+	TOTPKey    string `json:"totp_key"    yaml:"totp_key"    validate:"required,hexadecimal,len=64" secret:"true"`
+	TOTPIssuer string `json:"totp_issuer" yaml:"totp_issuer" validate:"required"`
+
+	PasswordHash *PasswordHashConfig `json:"password_hash" yaml:"password_hash"`
+	Policy       *PolicyConfig       `json:"policy"        yaml:"policy"`
+}
+
+// Параметры argon2id для хэширования паролей. Незаданные параметры берутся по умолчанию.
+// При изменении параметров хэши существующих паролей обновляются при следующем входе
+type PasswordHashConfig struct {
+	// Объем памяти в КиБ
+	Memory      uint32 `json:"memory"      yaml:"memory"      validate:"omitempty,min=8192"`
+	Iterations  uint32 `json:"iterations"  yaml:"iterations"  validate:"omitempty,min=1"`
+	Parallelism uint8  `json:"parallelism" yaml:"parallelism" validate:"omitempty,min=1"`
+}
+
+// Правила для логинов, email и паролей при регистрации и смене пароля.
+// Незаданные (нулевые) параметры берутся по умолчанию
+type PolicyConfig struct {
+	PasswordMinLength    int  `json:"password_min_length"    yaml:"password_min_length"    validate:"omitempty,min=1"`
+	PasswordMaxLength    int  `json:"password_max_length"    yaml:"password_max_length"    validate:"omitempty,min=1,gtefield=PasswordMinLength"`
+	PasswordRequireUpper bool `json:"password_require_upper" yaml:"password_require_upper"`
+	PasswordRequireLower bool `json:"password_require_lower" yaml:"password_require_lower"`
+	PasswordRequireDigit bool `json:"password_require_digit" yaml:"password_require_digit"`
+	PasswordRequireOther bool `json:"password_require_other" yaml:"password_require_other"`
+	// Разрешить пароли из встроенного списка распространенных и утекших паролей
+	AllowCommonPasswords bool `json:"allow_common_passwords" yaml:"allow_common_passwords"`
+
+	LoginMinLength int `json:"login_min_length" yaml:"login_min_length" validate:"omitempty,min=1"`
+	// Не больше размера колонки scl.users.login
+	LoginMaxLength int `json:"login_max_length" yaml:"login_max_length" validate:"omitempty,min=1,max=50"`
+	// Регулярное выражение допустимых символов логина
+	LoginPattern string `json:"login_pattern" yaml:"login_pattern"`
 }
 
+// Загружает конфигурацию из файла и переменных окружения SOCIAL_*
 func Load(filename string) (*Config, error) {
-	var config *Config
-	var err error
-
-	ext := filepath.Ext(filename)
-
-	switch ext {
-	case ".json":
-		config, err = fromFile(filename, json.Unmarshal)
-		if err != nil {
-			return nil, fmt.Errorf("loading from json: %v", err)
-		}
-	case ".yaml", ".yml":
-		config, err = fromFile(filename, yaml.Unmarshal)
-		if err != nil {
-			return nil, fmt.Errorf("loading from yaml: %v", err)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported file type of '%s': .json or .yaml expected", filename)
-	}
-
-	err = validate(config)
-	if err != nil {
-		return nil, fmt.Errorf("invalid config: %v", err)
-	}
-
-	return config, nil
-}
-
-func fromFile(filename string, parser func([]byte, any) error) (*Config, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("opening file: %v", err)
-	}
-	defer file.Close()
-
-	byteValue, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %v", err)
-	}
-
-	var config Config
-	err = parser(byteValue, &config)
-	if err != nil {
-		return nil, fmt.Errorf("parsing file: %v", err)
-	}
-
-	return &config, nil
+	return LoadFrom(Sources{
+		File: filename,
+		Env:  os.Environ(),
+	})
 }
 
 func validate(config *Config) error {
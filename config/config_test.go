@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTOTPKey = "0000000000000000000000000000000000000000000000000000000000000000"
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadFrom(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+db_config:
+  host: postgres
+  user: admin
+  password: file password
+  database: demo
+server_config:
+  port: 16000
+  jwt_key: file key
+auth_config:
+  totp_key: `+testTOTPKey+`
+`)
+
+	t.Run("test defaults are overridden by file", func(t *testing.T) {
+		cfg, err := LoadFrom(Sources{File: file})
+		require.NoError(t, err)
+
+		assert.EqualValues(t, 5432, cfg.DBConfig.Port)
+		assert.EqualValues(t, 16000, cfg.ServerConfig.Port)
+		assert.Equal(t, "social", cfg.AuthConfig.TOTPIssuer)
+		assert.Equal(t, "file password", cfg.DBConfig.Password)
+		assert.Nil(t, cfg.CacheConfig)
+	})
+
+	t.Run("test env overrides file and flags override env", func(t *testing.T) {
+		cfg, err := LoadFrom(Sources{
+			File: file,
+			Env: []string{
+				"SOCIAL_DB_CONFIG_HOST=db.local",
+				"SOCIAL_SERVER_CONFIG_PORT=17000",
+				"SOCIAL_CACHE_CONFIG_PROFILES_SIZE=10",
+				"SOCIAL_CACHE_CONFIG_PROFILES_TTL_SECONDS=60",
+				"HOME=/root",
+			},
+			Overrides: []string{
+				"server_config.port=18000",
+				"server_config.expose_metrics=true",
+				`server_config.rate_limits=[{route: "GET /profiles/search", burst: 1, per_minute: 1}]`,
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "db.local", cfg.DBConfig.Host)
+		assert.EqualValues(t, 18000, cfg.ServerConfig.Port)
+		assert.True(t, cfg.ServerConfig.ExposeMetrics)
+		require.NotNil(t, cfg.CacheConfig)
+		assert.Equal(t, 10, cfg.CacheConfig.ProfilesSize)
+		require.Len(t, cfg.ServerConfig.RateLimits, 1)
+		assert.Equal(t, "GET /profiles/search", cfg.ServerConfig.RateLimits[0].Route)
+	})
+
+	t.Run("test s3 photo storage", func(t *testing.T) {
+		cfg, err := LoadFrom(Sources{
+			File: file,
+			Env: []string{
+				"SOCIAL_PHOTOS_CONFIG_STORAGE=s3",
+				"SOCIAL_PHOTOS_CONFIG_S3_ENDPOINT=http://minio:9000",
+				"SOCIAL_PHOTOS_CONFIG_S3_REGION=us-east-1",
+				"SOCIAL_PHOTOS_CONFIG_S3_BUCKET=photos",
+				"SOCIAL_PHOTOS_CONFIG_S3_ACCESS_KEY_ID=key",
+				"SOCIAL_PHOTOS_CONFIG_S3_SECRET_ACCESS_KEY=secret",
+			},
+		})
+		require.NoError(t, err)
+
+		require.NotNil(t, cfg.PhotosConfig.S3)
+		assert.Equal(t, "photos", cfg.PhotosConfig.S3.Bucket)
+		assert.Len(t, cfg.PhotosConfig.Thumbnails, 3)
+	})
+
+	t.Run("test secrets from files", func(t *testing.T) {
+		password := writeFile(t, "db_password", "secret password\n")
+		jwtKey := writeFile(t, "jwt_key", "secret key")
+
+		withRefs := writeFile(t, "config.json", `{
+			"db_config": {"host": "postgres", "user": "admin", "password_file": "`+password+`", "database": "demo"},
+			"server_config": {"jwt_key": "file key"},
+			"auth_config": {"totp_key": "`+testTOTPKey+`"}
+		}`)
+
+		cfg, err := LoadFrom(Sources{
+			File: withRefs,
+			Env:  []string{"SOCIAL_SERVER_CONFIG_JWT_KEY_FILE=" + jwtKey},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "secret password", cfg.DBConfig.Password)
+		assert.Equal(t, "secret key", cfg.ServerConfig.JWTKey)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		cases := []struct {
+			name string
+			src  Sources
+		}{
+			{"unsupported file type", Sources{File: writeFile(t, "config.toml", "")}},
+			{"unknown env variable", Sources{File: file, Env: []string{"SOCIAL_DB_CONFIG_HOTS=db"}}},
+			{"invalid env value", Sources{File: file, Env: []string{"SOCIAL_DB_CONFIG_PORT=port"}}},
+			{"unknown flag setting", Sources{File: file, Overrides: []string{"db_config.hots=db"}}},
+			{"malformed flag", Sources{File: file, Overrides: []string{"db_config.host"}}},
+			{"missing secret file", Sources{File: file, Overrides: []string{"db_config.password_file=/nonexistent"}}},
+			{"file reference to non-string setting", Sources{File: file, Overrides: []string{"db_config.port_file=/nonexistent"}}},
+			{"missing required settings", Sources{}},
+			{"s3 photo storage without settings", Sources{File: file, Overrides: []string{"photos_config.storage=s3"}}},
+			{"duplicate thumbnail names", Sources{File: file, Overrides: []string{`photos_config.thumbnails=[{name: a, max_side: 1}, {name: a, max_side: 2}]`}}},
+		}
+
+		for _, c := range cases {
+			_, err := LoadFrom(c.src)
+			assert.Error(t, err, c.name)
+		}
+	})
+
+	t.Run("test value and file reference together", func(t *testing.T) {
+		password := writeFile(t, "db_password", "secret")
+		both := writeFile(t, "config.yaml", "db_config:\n  password: a\n  password_file: "+password+"\n")
+
+		_, err := LoadFrom(Sources{File: both})
+		assert.ErrorContains(t, err, "both")
+	})
+}
+
+func TestPrint(t *testing.T) {
+	cfg := defaults()
+	cfg.DBConfig.Password = "secret password"
+	cfg.ServerConfig.JWTKey = "secret key"
+
+	var out bytes.Buffer
+	require.NoError(t, Print(&out, cfg, true))
+
+	assert.NotContains(t, out.String(), "secret")
+	assert.Equal(t, 2, strings.Count(out.String(), redactedValue))
+	// пустые секреты не скрываются, чтобы было видно, что они не заданы
+	assert.Contains(t, out.String(), `totp_key: ""`)
+	// исходная конфигурация не изменяется
+	assert.Equal(t, "secret password", cfg.DBConfig.Password)
+
+	out.Reset()
+	require.NoError(t, Print(&out, cfg, false))
+	assert.Contains(t, out.String(), "secret password")
+}
+
+func TestDiff(t *testing.T) {
+	old := defaults()
+	old.DBConfig.Host = "postgres"
+
+	updated := defaults()
+	updated.DBConfig.Host = "replica"
+	updated.LogLevel = "debug"
+	updated.ServerConfig.CORS = &CORSConfig{AllowOrigins: []string{"*"}}
+
+	changed := Diff(old, updated)
+	require.Len(t, changed, 3)
+
+	assert.Equal(t, "log_level", changed[0].Path)
+	assert.True(t, changed[0].Hot)
+	assert.Equal(t, "db_config.host", changed[1].Path)
+	assert.False(t, changed[1].Hot)
+	assert.Equal(t, "server_config.cors.allow_origins", changed[2].Path)
+	assert.True(t, changed[2].Hot)
+
+	// пустой раздел не отличается от незаданного
+	updated = defaults()
+	updated.ServerConfig.OpenAPIValidation = &OpenAPIValidationConfig{}
+	assert.Empty(t, Diff(defaults(), updated))
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Значение, которым заменяются секреты при выводе конфигурации
+const redactedValue = "[REDACTED]"
+
+// Выводит итоговую конфигурацию в YAML. Если redacted, значения секретов (полей с тегом secret) скрываются
+func Print(w io.Writer, config *Config, redacted bool) error {
+	if redacted {
+		// копия, чтобы не изменять переданную конфигурацию
+		data, err := yaml.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("marshaling config: %v", err)
+		}
+
+		var c Config
+		err = yaml.Unmarshal(data, &c)
+		if err != nil {
+			return fmt.Errorf("copying config: %v", err)
+		}
+
+		redact(reflect.ValueOf(&c).Elem())
+
+		config = &c
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(config)
+	if err != nil {
+		return fmt.Errorf("encoding config: %v", err)
+	}
+
+	return encoder.Close()
+}
+
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			redact(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			fv := v.Field(i)
+
+			if field.Tag.Get("secret") == "true" {
+				redactSecret(fv)
+				continue
+			}
+
+			redact(fv)
+		}
+	}
+}
+
+// Скрывает непустые строки, в том числе в списках
+func redactSecret(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(redactedValue)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redactSecret(v.Index(i))
+		}
+	}
+}
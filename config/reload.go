@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Настройка и то, как применяются ее изменения
+type Field struct {
+	// Путь из yaml-имен через точку, например db_config.host
+	Path string
+	// Имя переменной окружения
+	Env string
+	// Изменение применяется без перезапуска, при перезагрузке конфигурации
+	Hot bool
+}
+
+// Все настройки в порядке объявления. Изменения настроек с тегом reload:"hot" применяются
+// при перезагрузке конфигурации, остальных - только после перезапуска
+func Fields() []Field {
+	var fields []Field
+
+	for _, s := range settings(reflect.TypeOf(Config{})) {
+		fields = append(fields, Field{
+			Path: s.path,
+			Env:  envPrefix + strings.ToUpper(strings.ReplaceAll(s.path, ".", "_")),
+			Hot:  s.field.Tag.Get("reload") == "hot",
+		})
+	}
+
+	return fields
+}
+
+// Настройки, значения которых в old и new различаются
+func Diff(old, new *Config) []Field {
+	var changed []Field
+
+	for _, f := range Fields() {
+		if !reflect.DeepEqual(value(old, f.Path), value(new, f.Path)) {
+			changed = append(changed, f)
+		}
+	}
+
+	return changed
+}
+
+// Значение настройки по пути. Настройки незаданного раздела имеют нулевые значения
+func value(config *Config, path string) any {
+	v := reflect.ValueOf(config)
+
+	for _, segment := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v = reflect.New(v.Type().Elem())
+			}
+			v = v.Elem()
+		}
+
+		field, ok := fieldByName(v.Type(), segment)
+		if !ok {
+			return nil
+		}
+
+		v = v.FieldByIndex(field.Index)
+	}
+
+	return v.Interface()
+}
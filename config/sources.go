@@ -0,0 +1,441 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Префикс переменных окружения с настройками
+const envPrefix = "SOCIAL_"
+
+// Суффикс настройки, значение которой читается из файла, например password_file: /run/secrets/db_password.
+// Так можно задать любую строковую настройку
+const fileSuffix = "_file"
+
+// Источники конфигурации. Каждый следующий источник переопределяет предыдущие:
+// значения по умолчанию, файл, переменные окружения, флаги командной строки
+type Sources struct {
+	// JSON- или YAML-файл. Необязателен, если все настройки заданы другими источниками
+	File string
+	// Переменные окружения в формате os.Environ. Учитываются только переменные с префиксом SOCIAL_:
+	// имя переменной - путь к настройке через подчеркивание в верхнем регистре, например SOCIAL_DB_CONFIG_HOST
+	Env []string
+	// Значения из флагов командной строки в формате путь=значение, например db_config.host=localhost
+	Overrides []string
+}
+
+func LoadFrom(src Sources) (*Config, error) {
+	config := defaults()
+
+	if src.File != "" {
+		err := applyFile(config, src.File)
+		if err != nil {
+			return nil, fmt.Errorf("loading from file: %v", err)
+		}
+	}
+
+	err := applyEnv(config, src.Env)
+	if err != nil {
+		return nil, fmt.Errorf("loading from environment: %v", err)
+	}
+
+	err = applyOverrides(config, src.Overrides)
+	if err != nil {
+		return nil, fmt.Errorf("loading from flags: %v", err)
+	}
+
+	err = validate(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	return config, nil
+}
+
+// Значения по умолчанию. Необязательные разделы (кэш, gRPC и т.п.) по умолчанию выключены
+func defaults() *Config {
+	return &Config{
+		LogLevel: "info",
+		DBConfig: &DBConfig{
+			Port:             5432,
+			ApplicationName:  "social",
+			ConnectRetries:   10,
+			ConnectBackoffMs: 500,
+		},
+		ServerConfig: &ServerConfig{
+			Port: 15000,
+			Idempotency: &IdempotencyConfig{
+				TTLSeconds:         86400,
+				LockTimeoutSeconds: 60,
+			},
+			BodyLimitBytes: 16 << 20,
+		},
+		AuthConfig: &AuthConfig{
+			TOTPIssuer: "social",
+		},
+		EventsConfig: &EventsConfig{
+			PollIntervalMs:    1000,
+			BatchSize:         100,
+			MaxAttempts:       10,
+			RetryBackoffMs:    1000,
+			MaxRetryBackoffMs: 300000,
+		},
+		WebhooksConfig: &WebhooksConfig{
+			PollIntervalMs:    1000,
+			BatchSize:         100,
+			MaxAttempts:       8,
+			RetryBackoffMs:    10000,
+			MaxRetryBackoffMs: 3600000,
+			TimeoutSeconds:    10,
+		},
+		PhotosConfig: &PhotosConfig{
+			MaxSizeBytes: 10 << 20,
+			MaxPixels:    40_000_000,
+			JPEGQuality:  85,
+			Thumbnails: []*ThumbnailConfig{
+				{Name: "small", MaxSide: 64},
+				{Name: "medium", MaxSide: 256},
+				{Name: "large", MaxSide: 1024},
+			},
+			Storage: "local",
+			Local:   &LocalPhotoStorageConfig{Dir: "data/photos"},
+		},
+	}
+}
+
+func applyFile(config *Config, filename string) error {
+	switch ext := filepath.Ext(filename); ext {
+	case ".json", ".yaml", ".yml":
+	default:
+		return fmt.Errorf("unsupported file type of '%s': .json or .yaml expected", filename)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading file: %v", err)
+	}
+
+	// JSON - подмножество YAML, поэтому оба формата разбираются одинаково
+	var root yaml.Node
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		return fmt.Errorf("parsing file: %v", err)
+	}
+
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	err = resolveFileRefs(root.Content[0], reflect.TypeOf(config).Elem(), "")
+	if err != nil {
+		return err
+	}
+
+	// значения из файла накладываются на значения по умолчанию
+	err = root.Decode(config)
+	if err != nil {
+		return fmt.Errorf("parsing file: %v", err)
+	}
+
+	return nil
+}
+
+// Заменяет настройки вида x_file на x со значением из указанного файла
+func resolveFileRefs(node *yaml.Node, t reflect.Type, path string) error {
+	t = structType(t)
+	if node.Kind != yaml.MappingNode || t == nil {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		fieldPath := joinPath(path, key.Value)
+
+		if field, ok := fieldByName(t, key.Value); ok {
+			err := resolveFileRefs(value, field.Type, fieldPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := strings.CutSuffix(key.Value, fileSuffix)
+		if !ok {
+			continue
+		}
+
+		field, ok := fieldByName(t, name)
+		if !ok || field.Type.Kind() != reflect.String {
+			return fmt.Errorf("'%s' does not refer to a string setting", fieldPath)
+		}
+
+		if hasKey(node, name) {
+			return fmt.Errorf("both '%s' and '%s' are set", joinPath(path, name), fieldPath)
+		}
+
+		secret, err := readSecret(value.Value)
+		if err != nil {
+			return fmt.Errorf("reading '%s': %v", fieldPath, err)
+		}
+
+		key.Value = name
+		value.SetString(secret)
+	}
+
+	return nil
+}
+
+func applyEnv(config *Config, env []string) error {
+	names := envNames(reflect.TypeOf(config).Elem())
+
+	for _, kv := range env {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+
+		path, ok := names[name]
+		if !ok {
+			return fmt.Errorf("unknown variable %s", name)
+		}
+
+		err := set(config, path, value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyOverrides(config *Config, overrides []string) error {
+	for _, kv := range overrides {
+		path, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("'%s': path=value expected", kv)
+		}
+
+		err := set(config, path, value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Имена переменных окружения всех настроек: SOCIAL_DB_CONFIG_HOST -> db_config.host.
+// Для строковых настроек есть и переменная с суффиксом _FILE
+func envNames(t reflect.Type) map[string]string {
+	names := make(map[string]string)
+
+	for _, s := range settings(t) {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(s.path, ".", "_"))
+		names[name] = s.path
+
+		if s.kind == reflect.String {
+			names[name+strings.ToUpper(fileSuffix)] = s.path + fileSuffix
+		}
+	}
+
+	return names
+}
+
+// Настройка: путь из yaml-имен через точку
+type setting struct {
+	path  string
+	kind  reflect.Kind
+	field reflect.StructField
+}
+
+// Все настройки типа конфигурации t в порядке объявления полей.
+// Вложенные структуры раскрываются, списки считаются одной настройкой
+func settings(t reflect.Type) []setting {
+	var result []setting
+
+	var walk func(t reflect.Type, path string)
+	walk = func(t reflect.Type, path string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+
+			fieldPath := joinPath(path, name)
+
+			if nested := structType(field.Type); nested != nil {
+				walk(nested, fieldPath)
+				continue
+			}
+
+			result = append(result, setting{path: fieldPath, kind: field.Type.Kind(), field: field})
+		}
+	}
+
+	walk(structType(t), "")
+
+	return result
+}
+
+// Устанавливает настройку по пути, создавая незаданные разделы
+func set(config *Config, path, value string) error {
+	v := reflect.ValueOf(config).Elem()
+	segments := strings.Split(path, ".")
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		field, ok := fieldByName(v.Type(), segment)
+		if !ok {
+			name, isFile := strings.CutSuffix(segment, fileSuffix)
+			if !last || !isFile {
+				return fmt.Errorf("unknown setting '%s'", path)
+			}
+
+			field, ok = fieldByName(v.Type(), name)
+			if !ok || field.Type.Kind() != reflect.String {
+				return fmt.Errorf("'%s' does not refer to a string setting", path)
+			}
+
+			secret, err := readSecret(value)
+			if err != nil {
+				return err
+			}
+
+			v.FieldByIndex(field.Index).SetString(secret)
+			return nil
+		}
+
+		fv := v.FieldByIndex(field.Index)
+
+		if last {
+			return setValue(fv, value)
+		}
+
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown setting '%s'", path)
+		}
+
+		v = fv
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("parsing bool: %v", err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parsing int: %v", err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parsing uint: %v", err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parsing float: %v", err)
+		}
+		v.SetFloat(f)
+	default:
+		// списки и вложенные разделы целиком задаются в YAML, например [{route: "GET /profiles", burst: 1, per_minute: 1}]
+		err := yaml.Unmarshal([]byte(value), v.Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("parsing yaml: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Секреты в файлах часто заканчиваются переводом строки, который не является частью значения
+func readSecret(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file: %v", err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	t = structType(t)
+	if t == nil {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if yamlName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// Тип структуры для структур и указателей на них, иначе nil
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return t
+}
+
+func hasKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -21,6 +21,31 @@ def connect_db():
     )
 
 
+# Интересы новых анкет - части hobbies через запятую, нормализованные так же,
+# как в приложении и в миграции 014_interests
+interests_query = """
+CREATE TEMPORARY TABLE parsed_interests ON COMMIT DROP AS
+SELECT DISTINCT
+    p.id AS profile_id,
+    trim(regexp_replace(lower(normalize(part, NFKC)), '\\s+', ' ', 'g')) AS name
+FROM scl.profiles p
+CROSS JOIN LATERAL unnest(string_to_array(p.hobbies, ',')) AS part
+WHERE NOT EXISTS (SELECT 1 FROM scl.profile_interests pi WHERE pi.profile_id = p.id);
+
+DELETE FROM parsed_interests
+WHERE name = '' OR char_length(name) > 50;
+
+INSERT INTO scl.interests (name)
+SELECT DISTINCT name FROM parsed_interests
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO scl.profile_interests (profile_id, interest_id)
+SELECT pi.profile_id, i.id
+FROM parsed_interests pi
+JOIN scl.interests i ON i.name = pi.name;
+"""
+
+
 def insert_data(data, batch_size=1000):
     conn = connect_db()
     cursor = conn.cursor()
@@ -42,6 +67,8 @@ def insert_data(data, batch_size=1000):
             # Выполняем запрос на вставку батча
             cursor.execute(full_query)
 
+        cursor.execute(interests_query)
+
         conn.commit()
         print(f"Данные успешно вставлены ({len(data)} строк)")
     except Exception as e:
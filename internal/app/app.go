@@ -2,21 +2,97 @@ package app
 
 import (
 	"context"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/events"
 	"github.com/Lucky112/social/internal/service"
 	"github.com/Lucky112/social/internal/transport"
+	"github.com/Lucky112/social/internal/transport/idempotency"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/rpc"
 )
 
-func Run(config *config.Config) {
-	service, err := service.NewService(context.Background(), config.DBConfig)
+// Как часто удалять из базы истекшие ключи идемпотентности
+const idempotencySweepInterval = 10 * time.Minute
+
+// Запускает приложение. Источники конфигурации нужны для ее перезагрузки без перезапуска
+func Run(config *config.Config, sources config.Sources) {
+	logLevel := new(slog.LevelVar)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	err := logLevel.UnmarshalText([]byte(config.LogLevel))
+	if err != nil {
+		panic(err)
+	}
+
+	service, err := service.NewService(context.Background(), config)
+	if err != nil {
+		panic(err)
+	}
+
+	authService, err := service.AuthService()
+	if err != nil {
+		panic(err)
+	}
+
+	jwtKeys := jwt.NewKeys(config.ServerConfig.JWTKey, config.ServerConfig.JWTPreviousKeys...)
+
+	webhooksService := service.WebhooksService()
+	idempotencyStore := service.IdempotencyStore()
+
+	photosService, err := service.PhotosService()
 	if err != nil {
 		panic(err)
 	}
 
-	server := transport.NewServer(config.ServerConfig, service.AuthService(), service.ProfilesService())
+	accountService, err := service.AccountService()
+	if err != nil {
+		panic(err)
+	}
+
+	server, err := transport.NewServer(config.ServerConfig, jwtKeys, authService, service.ProfilesService(), service.AdminService(), accountService, webhooksService, photosService, service.InterestsService(), idempotencyStore)
+	if err != nil {
+		panic(err)
+	}
+
+	bus := events.NewBus()
+	if http := config.EventsConfig.HTTP; http != nil {
+		bus.Subscribe(events.NewHTTPPublisher(http.URL, time.Duration(http.TimeoutSeconds)*time.Second).Publish)
+	}
+	bus.Subscribe(webhooksService.Enqueue)
+	go service.EventsRelay(bus).Run(context.Background())
+	go service.WebhooksWorker().Run(context.Background())
+	go idempotency.RunSweeper(context.Background(), idempotencyStore, idempotencySweepInterval)
+
+	errs := make(chan error, 2)
+
+	go func() {
+		errs <- server.Start()
+	}()
+
+	if config.ServerConfig.GRPC != nil {
+		rpcServer := rpc.NewServer(config.ServerConfig.GRPC, jwtKeys, authService, service.ProfilesService())
+
+		go func() {
+			errs <- rpcServer.Start()
+		}()
+	}
+
+	r := reloader{
+		sources:  sources,
+		started:  config,
+		applied:  config,
+		loaded:   config,
+		logLevel: logLevel,
+		jwtKeys:  jwtKeys,
+		server:   server,
+	}
+	go r.run(context.Background())
 
-	err = server.Start()
+	err = <-errs
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/jwt"
+)
+
+// Как часто проверять, не изменилась ли конфигурация
+const configPollInterval = 5 * time.Second
+
+// Подсистемы, настройки которых можно менять во время работы
+type reloadableServer interface {
+	Reload(cfg *config.ServerConfig) error
+}
+
+// Перезагрузка конфигурации без перезапуска: по сигналу SIGHUP и при изменении файла конфигурации
+// или файлов с секретами. Применяются только изменения настроек, отмеченных в config.Fields как Hot
+type reloader struct {
+	sources config.Sources
+	// Конфигурация, с которой запущено приложение: настройки, требующие перезапуска, действуют из нее
+	started *config.Config
+	// Конфигурация, hot-настройки которой применены последними
+	applied *config.Config
+	// Последняя прочитанная конфигурация, с ней сравнивается следующая
+	loaded *config.Config
+	// Ошибка последнего чтения, чтобы не повторять ее в журнале при каждой проверке
+	lastErr string
+
+	logLevel *slog.LevelVar
+	jwtKeys  *jwt.Keys
+	server   reloadableServer
+}
+
+func (r *reloader) run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			slog.Info("reloading config on SIGHUP")
+			r.reload(true)
+		case <-ticker.C:
+			r.reload(false)
+		}
+	}
+}
+
+// Читает конфигурацию заново и применяет изменения. Без force ничего не делает,
+// если конфигурация не изменилась с прошлого чтения
+func (r *reloader) reload(force bool) {
+	cfg, err := config.LoadFrom(r.sources)
+	if err != nil {
+		if force || err.Error() != r.lastErr {
+			slog.Error("config is not reloaded", "error", err)
+		}
+		r.lastErr = err.Error()
+		return
+	}
+	r.lastErr = ""
+
+	if !force && reflect.DeepEqual(cfg, r.loaded) {
+		return
+	}
+	r.loaded = cfg
+
+	err = r.apply(cfg)
+	if err != nil {
+		slog.Error("config is not reloaded", "error", err)
+	}
+}
+
+// Применяет изменения всех hot-настроек или, если хотя бы одно изменение невалидно, ни одного
+func (r *reloader) apply(cfg *config.Config) error {
+	var hot, restart []string
+	for _, f := range config.Diff(r.started, cfg) {
+		if !f.Hot {
+			restart = append(restart, f.Path)
+		}
+	}
+	for _, f := range config.Diff(r.applied, cfg) {
+		if f.Hot {
+			hot = append(hot, f.Path)
+		}
+	}
+
+	if len(restart) > 0 {
+		slog.Warn("changed settings require restart and are not applied", "settings", strings.Join(restart, ", "))
+	}
+
+	if len(hot) == 0 {
+		slog.Info("no settings to reload")
+		return nil
+	}
+
+	var logLevel slog.Level
+	err := logLevel.UnmarshalText([]byte(cfg.LogLevel))
+	if err != nil {
+		return fmt.Errorf("parsing log level: %v", err)
+	}
+
+	// единственная операция, которая может не удаться, выполняется первой
+	err = r.server.Reload(cfg.ServerConfig)
+	if err != nil {
+		return err
+	}
+
+	r.jwtKeys.Update(cfg.ServerConfig.JWTKey, cfg.ServerConfig.JWTPreviousKeys...)
+	r.logLevel.Set(logLevel)
+
+	r.applied = cfg
+
+	slog.Info("config reloaded", "settings", strings.Join(hot, ", "))
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/jwt"
+)
+
+const testConfig = `
+log_level: info
+db_config:
+  host: postgres
+  user: admin
+  password: password
+  database: demo
+server_config:
+  jwt_key: key
+auth_config:
+  totp_key: 0000000000000000000000000000000000000000000000000000000000000000
+`
+
+type fakeServer struct {
+	reloaded *config.ServerConfig
+	err      error
+}
+
+func (s *fakeServer) Reload(cfg *config.ServerConfig) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.reloaded = cfg
+	return nil
+}
+
+func newTestReloader(t *testing.T) (*reloader, *fakeServer, string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(testConfig), 0o600))
+
+	sources := config.Sources{File: file}
+	cfg, err := config.LoadFrom(sources)
+	require.NoError(t, err)
+
+	server := &fakeServer{}
+
+	return &reloader{
+		sources:  sources,
+		started:  cfg,
+		applied:  cfg,
+		loaded:   cfg,
+		logLevel: new(slog.LevelVar),
+		jwtKeys:  jwt.NewKeys(cfg.ServerConfig.JWTKey),
+		server:   server,
+	}, server, file
+}
+
+func TestReloader(t *testing.T) {
+	t.Run("test hot settings are applied", func(t *testing.T) {
+		r, server, file := newTestReloader(t)
+
+		updated := strings.Replace(testConfig, "log_level: info", "log_level: debug", 1)
+		updated = strings.Replace(updated, "  jwt_key: key\n", "  jwt_key: key\n  rate_limits:\n    - {route: GET /profiles, burst: 1, per_minute: 1}\n", 1)
+		require.NoError(t, os.WriteFile(file, []byte(updated), 0o600))
+
+		r.reload(false)
+
+		require.NotNil(t, server.reloaded)
+		assert.Len(t, server.reloaded.RateLimits, 1)
+		assert.Equal(t, slog.LevelDebug, r.logLevel.Level())
+		assert.Equal(t, "debug", r.applied.LogLevel)
+	})
+
+	t.Run("test unchanged config is not reloaded", func(t *testing.T) {
+		r, server, _ := newTestReloader(t)
+
+		r.reload(false)
+
+		assert.Nil(t, server.reloaded)
+	})
+
+	t.Run("test invalid config is rejected", func(t *testing.T) {
+		r, server, file := newTestReloader(t)
+		applied := r.applied
+
+		require.NoError(t, os.WriteFile(file, []byte("log_level: verbose\n"), 0o600))
+
+		r.reload(false)
+
+		assert.Nil(t, server.reloaded)
+		assert.Same(t, applied, r.applied)
+		assert.NotEmpty(t, r.lastErr)
+	})
+
+	t.Run("test failed reload changes nothing", func(t *testing.T) {
+		r, server, _ := newTestReloader(t)
+		server.err = errors.New("invalid rate limit")
+
+		updated := *r.applied
+		updated.LogLevel = "error"
+
+		err := r.apply(&updated)
+		assert.Error(t, err)
+		assert.Equal(t, slog.LevelInfo, r.logLevel.Level())
+		assert.Equal(t, "info", r.applied.LogLevel)
+	})
+}
@@ -0,0 +1,101 @@
+// Пакет blob хранит файлы (например, фотографии анкет) по ключам вида "photos/1/abc/original.jpg"
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Файла с ключом нет в хранилище
+var ErrNotFound = errors.New("blob not found")
+
+// Хранилище в каталоге локальной файловой системы. Подходит для одного экземпляра приложения
+// или каталога, общего для всех экземпляров
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) LocalStore {
+	return LocalStore{dir}
+}
+
+// Записывает файл через временный файл, чтобы читатели не увидели его частично записанным
+func (s LocalStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("creating directory for '%s': %v", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file for '%s': %v", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing '%s': %v", key, err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("renaming temporary file to '%s': %v", key, err)
+	}
+
+	return nil
+}
+
+func (s LocalStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading '%s': %w", key, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("reading '%s': %v", key, err)
+	}
+
+	return data, nil
+}
+
+// Удаляет файл. Удаление отсутствующего файла не считается ошибкой
+func (s LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("deleting '%s': %v", key, err)
+	}
+
+	return nil
+}
+
+// Путь к файлу. Ключи, выходящие за пределы каталога хранилища, отвергаются
+func (s LocalStore) path(key string) (string, error) {
+	local := filepath.FromSlash(key)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid blob key '%s'", key)
+	}
+
+	return filepath.Join(s.dir, local), nil
+}
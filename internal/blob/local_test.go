@@ -0,0 +1,58 @@
+package blob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+
+	err := store.Put(ctx, "photos/1/abc/original.jpg", []byte("data"), "image/jpeg")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "photos", "1", "abc", "original.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	err = store.Put(ctx, "photos/1/abc/original.jpg", []byte("new data"), "image/jpeg")
+	require.NoError(t, err)
+
+	data, err = store.Get(ctx, "photos/1/abc/original.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new data"), data)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "photos", "1", "abc"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files must not be left behind")
+
+	err = store.Delete(ctx, "photos/1/abc/original.jpg")
+	require.NoError(t, err)
+
+	_, err = store.Get(ctx, "photos/1/abc/original.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Delete(ctx, "photos/1/abc/original.jpg")
+	assert.NoError(t, err, "deleting missing blob")
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"../escape", "photos/../../escape", "/absolute", ""} {
+		err := store.Put(ctx, key, []byte("data"), "image/jpeg")
+		assert.Error(t, err, key)
+
+		_, err = store.Get(ctx, key)
+		assert.Error(t, err, key)
+		assert.NotErrorIs(t, err, ErrNotFound, key)
+	}
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Заголовки запроса с событием
+const (
+	HeaderEventId   = "X-Event-Id"
+	HeaderEventType = "X-Event-Type"
+)
+
+// Тело запроса с событием
+type Message struct {
+	Id            string           `json:"id"`
+	Type          models.EventType `json:"type"`
+	AggregateType string           `json:"aggregate_type"`
+	AggregateId   string           `json:"aggregate_id"`
+	OccurredAt    time.Time        `json:"occurred_at"`
+	Data          json.RawMessage  `json:"data"`
+}
+
+func NewMessage(event *models.Event) Message {
+	return Message{
+		Id:            event.Id,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateId:   event.AggregateId,
+		OccurredAt:    event.OccurredAt,
+		Data:          event.Payload,
+	}
+}
+
+// Публикует события запросами POST на заданный адрес. Событие считается опубликованным,
+// если получатель ответил статусом 2xx
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPublisher(url string, timeout time.Duration) HTTPPublisher {
+	return HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p HTTPPublisher) Publish(ctx context.Context, event *models.Event) error {
+	body, err := json.Marshal(NewMessage(event))
+	if err != nil {
+		return fmt.Errorf("marshalling event: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventId, event.Id)
+	req.Header.Set(HeaderEventType, string(event.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// тело ответа вычитывается, чтобы соединение можно было использовать повторно
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
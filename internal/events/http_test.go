@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestHTTPPublisher(t *testing.T) {
+	status := http.StatusNoContent
+	var received []Message
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "7", r.Header.Get(HeaderEventId))
+		assert.Equal(t, string(models.EventUserRegistered), r.Header.Get(HeaderEventType))
+
+		var msg Message
+		err := json.NewDecoder(r.Body).Decode(&msg)
+		assert.NoError(t, err)
+		received = append(received, msg)
+
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	publisher := NewHTTPPublisher(receiver.URL, time.Second)
+
+	event := &models.Event{
+		Id:            "7",
+		Type:          models.EventUserRegistered,
+		AggregateType: models.AggregateUser,
+		AggregateId:   "1",
+		Payload:       json.RawMessage(`{"user_id":"1"}`),
+		OccurredAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err := publisher.Publish(context.Background(), event)
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, NewMessage(event), received[0])
+
+	t.Run("error status", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+
+		err := publisher.Publish(context.Background(), event)
+		assert.ErrorContains(t, err, "503")
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		err := NewHTTPPublisher("http://127.0.0.1:1", time.Second).Publish(context.Background(), event)
+		assert.Error(t, err)
+	})
+}
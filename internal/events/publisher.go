@@ -0,0 +1,70 @@
+// Пакет events публикует доменные события, записанные в outbox, для других частей приложения и внешних систем
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Публикует событие. Ошибка означает, что событие нужно опубликовать повторно
+type Publisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+// Обработчик события. Событие может быть доставлено повторно, поэтому обработчик должен быть идемпотентным,
+// например пропускать события с уже обработанным Id
+type Handler func(ctx context.Context, event *models.Event) error
+
+// Публикация событий подписчикам внутри процесса
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[models.EventType][]Handler
+	all      []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[models.EventType][]Handler),
+	}
+}
+
+// Подписывает обработчик на события указанных типов, а если типы не указаны - на все события
+func (b *Bus) Subscribe(handler Handler, types ...models.EventType) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(types) == 0 {
+		b.all = append(b.all, handler)
+		return
+	}
+
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// Вызывает все обработчики события, даже если некоторые из них вернули ошибку.
+// Ошибка любого обработчика приводит к повторной публикации события всем обработчикам
+func (b *Bus) Publish(ctx context.Context, event *models.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.all...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		err := h(ctx, event)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("handling event %s: %w", event.Id, errors.Join(errs...))
+	}
+
+	return nil
+}
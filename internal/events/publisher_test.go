@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+
+	var users, all []string
+	bus.Subscribe(func(ctx context.Context, event *models.Event) error {
+		users = append(users, event.Id)
+		return nil
+	}, models.EventUserRegistered)
+	bus.Subscribe(func(ctx context.Context, event *models.Event) error {
+		all = append(all, event.Id)
+		return nil
+	})
+
+	ctx := context.Background()
+
+	err := bus.Publish(ctx, &models.Event{Id: "1", Type: models.EventUserRegistered})
+	require.NoError(t, err)
+
+	err = bus.Publish(ctx, &models.Event{Id: "2", Type: models.EventProfileCreated})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1"}, users)
+	assert.Equal(t, []string{"1", "2"}, all)
+
+	t.Run("handler error", func(t *testing.T) {
+		handlerErr := errors.New("handler failed")
+		bus.Subscribe(func(ctx context.Context, event *models.Event) error {
+			return handlerErr
+		}, models.EventProfileCreated)
+
+		err := bus.Publish(ctx, &models.Event{Id: "3", Type: models.EventProfileCreated})
+		require.ErrorIs(t, err, handlerErr)
+
+		// остальные обработчики все равно вызываются
+		assert.Equal(t, []string{"1", "2", "3"}, all)
+	})
+}
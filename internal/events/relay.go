@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Исходящие события, записанные в outbox
+type Storage interface {
+	// Захватывает блокировку публикации до конца транзакции, чтобы события
+	// публиковал только один экземпляр приложения. Возвращает false, если блокировка занята
+	Lock(ctx context.Context) (bool, error)
+	// Не больше limit событий, готовых к публикации, в порядке записи. События сущности,
+	// у которой есть более раннее событие в ожидании повторной попытки, не возвращаются
+	Pending(ctx context.Context, limit int) ([]*models.Event, error)
+	// Удаляет опубликованное событие
+	Delete(ctx context.Context, id string) error
+	// Запоминает неудачную попытку публикации и откладывает следующую до next
+	Retry(ctx context.Context, id string, next time.Time, lastErr string) error
+	// Переносит событие в таблицу недоставленных
+	DeadLetter(ctx context.Context, id string, lastErr string) error
+}
+
+type TxManager interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type RelayConfig struct {
+	// Интервал опроса outbox, если в прошлый раз опубликованы все готовые события
+	PollInterval time.Duration
+	// Наибольшее число событий, публикуемых в одной транзакции
+	BatchSize int
+	// Число попыток публикации, после которого событие переносится в таблицу недоставленных
+	MaxAttempts int
+	// Задержка перед первой повторной попыткой. Удваивается с каждой попыткой, но не превышает MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Публикует события из outbox. Доставка "хотя бы один раз": событие удаляется из outbox
+// в той же транзакции, в которой опубликовано, поэтому при сбое до фиксации оно будет опубликовано повторно.
+// События одной сущности публикуются в порядке записи: пока событие ждет повторной попытки,
+// следующие события этой сущности не публикуются
+type Relay struct {
+	storage   Storage
+	tx        TxManager
+	publisher Publisher
+	cfg       RelayConfig
+	now       func() time.Time
+}
+
+func NewRelay(storage Storage, tx TxManager, publisher Publisher, cfg RelayConfig) Relay {
+	return Relay{
+		storage:   storage,
+		tx:        tx,
+		publisher: publisher,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Публикует события, пока не отменен контекст
+func (r Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayPending(ctx)
+		if err != nil {
+			slog.Warn("relaying events", "error", err)
+		}
+
+		// полная пачка означает, что в outbox, вероятно, остались готовые события
+		if err == nil && n == r.cfg.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Публикует одну пачку готовых событий. Возвращает число событий, которые пытался опубликовать
+func (r Relay) RelayPending(ctx context.Context) (int, error) {
+	n := 0
+
+	err := r.tx.InTx(ctx, func(ctx context.Context) error {
+		locked, err := r.storage.Lock(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := r.storage.Pending(ctx, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		// сущности, событие которых не удалось опубликовать: их следующие события ждут повторной попытки
+		blocked := make(map[string]bool)
+
+		for _, event := range events {
+			key := event.AggregateType + "/" + event.AggregateId
+			if blocked[key] {
+				continue
+			}
+
+			n++
+
+			done, err := r.publish(ctx, event)
+			if err != nil {
+				return err
+			}
+			if !done {
+				blocked[key] = true
+			}
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// Публикует событие и отмечает результат в outbox. Возвращает false, если событие нужно опубликовать повторно.
+// Ошибка означает, что результат не удалось сохранить
+func (r Relay) publish(ctx context.Context, event *models.Event) (bool, error) {
+	err := r.publisher.Publish(ctx, event)
+	if err == nil {
+		return true, r.storage.Delete(ctx, event.Id)
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= r.cfg.MaxAttempts {
+		slog.Error("event moved to dead letter", "id", event.Id, "type", event.Type, "attempts", attempts, "error", err)
+		return true, r.storage.DeadLetter(ctx, event.Id, err.Error())
+	}
+
+	slog.Warn("publishing event", "id", event.Id, "type", event.Type, "attempts", attempts, "error", err)
+
+	return false, r.storage.Retry(ctx, event.Id, r.now().Add(r.backoff(attempts)), err.Error())
+}
+
+// Задержка перед повторной попыткой после attempts неудачных
+func (r Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.Backoff
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, r.cfg.MaxBackoff)
+}
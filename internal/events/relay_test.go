@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+)
+
+// Публикатор, запоминающий события и отклоняющий события из fail
+type recordingPublisher struct {
+	published []string
+	fail      map[string]bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *models.Event) error {
+	if p.fail[event.Id] {
+		return errors.New("receiver unavailable")
+	}
+
+	p.published = append(p.published, event.Id)
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(cfg RelayConfig) (inmemory.OutboxStorage, *recordingPublisher, Relay) {
+		db := inmemory.NewDB()
+		outbox := inmemory.NewOutboxStorage(db)
+		publisher := &recordingPublisher{fail: make(map[string]bool)}
+
+		return outbox, publisher, NewRelay(outbox, inmemory.NewTxManager(db), publisher, cfg)
+	}
+
+	add := func(t *testing.T, outbox inmemory.OutboxStorage, aggregateId string) {
+		err := outbox.Add(ctx, &models.Event{
+			Type:          models.EventProfileCreated,
+			AggregateType: models.AggregateProfile,
+			AggregateId:   aggregateId,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("publishes in order", func(t *testing.T) {
+		outbox, publisher, relay := setup(RelayConfig{BatchSize: 2, MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour})
+		add(t, outbox, "a")
+		add(t, outbox, "b")
+		add(t, outbox, "a")
+
+		n, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		n, err = relay.RelayPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		assert.Equal(t, []string{"1", "2", "3"}, publisher.published)
+		assert.Empty(t, outbox.Events(ctx))
+	})
+
+	t.Run("failed event blocks its aggregate", func(t *testing.T) {
+		outbox, publisher, relay := setup(RelayConfig{BatchSize: 10, MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour})
+		add(t, outbox, "a")
+		add(t, outbox, "b")
+		add(t, outbox, "a")
+		publisher.fail["1"] = true
+
+		_, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+
+		// второе событие "a" ждет, пока не будет опубликовано первое
+		assert.Equal(t, []string{"2"}, publisher.published)
+
+		pending := outbox.Events(ctx)
+		require.Len(t, pending, 2)
+		assert.Equal(t, "1", pending[0].Id)
+		assert.Equal(t, 1, pending[0].Attempts)
+
+		_, err = relay.RelayPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2"}, publisher.published)
+	})
+
+	t.Run("retries and dead letter", func(t *testing.T) {
+		outbox, publisher, relay := setup(RelayConfig{BatchSize: 10, MaxAttempts: 3})
+		add(t, outbox, "a")
+		add(t, outbox, "a")
+		publisher.fail["1"] = true
+
+		for i := 0; i < 2; i++ {
+			_, err := relay.RelayPending(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, publisher.published)
+		}
+
+		// третья неудачная попытка переносит событие в недоставленные, и следующее событие "a" публикуется
+		_, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+
+		dead := outbox.DeadLetters(ctx)
+		require.Len(t, dead, 1)
+		assert.Equal(t, "1", dead[0].Id)
+		assert.Equal(t, 3, dead[0].Attempts)
+
+		_, err = relay.RelayPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2"}, publisher.published)
+		assert.Empty(t, outbox.Events(ctx))
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		relay := Relay{cfg: RelayConfig{Backoff: time.Second, MaxBackoff: 5 * time.Second}}
+
+		assert.Equal(t, time.Second, relay.backoff(1))
+		assert.Equal(t, 2*time.Second, relay.backoff(2))
+		assert.Equal(t, 4*time.Second, relay.backoff(3))
+		assert.Equal(t, 5*time.Second, relay.backoff(4))
+		assert.Equal(t, 5*time.Second, relay.backoff(20))
+	})
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Действие администратора, фиксируемое в журнале аудита
+type AuditAction string
+
+const (
+	AuditListUsers     AuditAction = "list_users"
+	AuditDisableUser   AuditAction = "disable_user"
+	AuditEnableUser    AuditAction = "enable_user"
+	AuditDeleteProfile AuditAction = "delete_profile"
+	AuditResetPassword AuditAction = "reset_password"
+)
+
+// Запись журнала аудита
+type AuditRecord struct {
+	ActorId   string
+	Action    AuditAction
+	TargetId  string
+	CreatedAt time.Time
+}
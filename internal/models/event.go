@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Тип доменного события
+type EventType string
+
+const (
+	EventUserRegistered EventType = "user.registered"
+	EventProfileCreated EventType = "profile.created"
+	EventProfileUpdated EventType = "profile.updated"
+)
+
+// Сущности, к которым относятся события. События одной сущности публикуются в порядке их записи
+const (
+	AggregateUser    = "user"
+	AggregateProfile = "profile"
+)
+
+// Доменное событие, записываемое в outbox вместе с изменением, которое его вызвало
+type Event struct {
+	// Идентификатор записи в outbox. Подписчики могут использовать его, чтобы не обрабатывать событие повторно
+	Id            string
+	Type          EventType
+	AggregateType string
+	AggregateId   string
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+	// Число неудачных попыток публикации
+	Attempts int
+}
+
+type UserRegisteredPayload struct {
+	UserId string `json:"user_id"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+}
+
+// Что изменилось в анкете в событии ProfileUpdated
+const (
+	ProfileChangePrivacy = "privacy"
+	ProfileChangePhotos  = "photos"
+)
+
+// Данные анкеты в событиях ProfileCreated и ProfileUpdated
+type ProfilePayload struct {
+	ProfileId string `json:"profile_id"`
+	UserId    string `json:"user_id"`
+	Name      string `json:"name"`
+	Surname   string `json:"surname"`
+	// Только в ProfileUpdated
+	Changed []string `json:"changed,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Запрос с заголовком Idempotency-Key: ключ, отпечаток запроса и, если запрос уже выполнен, сохраненный ответ
+type IdempotencyRecord struct {
+	// Пользователь или IP-адрес, к которому относится ключ
+	Scope string
+	Key   string
+	// Хэш метода, пути и тела запроса. Повтор с тем же ключом, но другим отпечатком - ошибка клиента
+	Fingerprint string
+	// Запрос выполнен и его ответ сохранен. Пока запрос выполняется, Response пуст
+	Completed bool
+	Response  StoredResponse
+	ExpiresAt time.Time
+}
+
+// Ответ, отдаваемый повторным запросам с тем же ключом
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
@@ -0,0 +1,17 @@
+package models
+
+// Наибольшая длина интереса в символах. Более длинные части hobbies интересами не считаются
+const MaxInterestLength = 50
+
+// Интерес и число анкет, в которых он указан
+type InterestCount struct {
+	Name     string
+	Profiles int
+}
+
+// Анкета, найденная по общим интересам
+type ProfileMatch struct {
+	Profile *Profile
+	// Общие интересы, по их числу анкеты упорядочиваются
+	Shared []string
+}
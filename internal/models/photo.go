@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Имя варианта фотографии с исходным размером
+const PhotoOriginal = "original"
+
+// Фотография анкеты. Последняя загруженная фотография - аватар анкеты
+type Photo struct {
+	Id        string
+	ProfileId string
+	UserId    string
+	// Исходная фотография и уменьшенные копии
+	Variants  []PhotoVariant
+	CreatedAt time.Time
+}
+
+// Вариант фотографии определенного размера, сохраненный в хранилище файлов
+type PhotoVariant struct {
+	// original или имя размера миниатюры из конфигурации
+	Name        string
+	Key         string
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+}
+
+// Вариант с указанным именем
+func (p *Photo) Variant(name string) (PhotoVariant, bool) {
+	for _, v := range p.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return PhotoVariant{}, false
+}
+
+var PhotoNotFound = errors.New("photo not found")
+var PhotoTooLarge = errors.New("photo is too large")
+var UnsupportedImage = errors.New("unsupported image format")
+var NotProfileOwner = errors.New("profile belongs to another user")
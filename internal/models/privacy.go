@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Кому видно поле анкеты. Значения упорядочены от самой открытой до самой закрытой
+type Visibility string
+
+const (
+	// Всем, в том числе без входа
+	VisibilityPublic Visibility = "public"
+	// Зарегистрированным пользователям
+	VisibilityRegistered Visibility = "registered"
+	// Друзьям владельца
+	VisibilityFriends Visibility = "friends"
+	// Только владельцу
+	VisibilityHidden Visibility = "hidden"
+)
+
+var visibilities = []Visibility{VisibilityPublic, VisibilityRegistered, VisibilityFriends, VisibilityHidden}
+
+// Видимость полей, для которых владелец ее не менял. Совпадает с доступом к анкетам до появления настроек
+const DefaultVisibility = VisibilityRegistered
+
+// Поле анкеты, видимость которого настраивается. Имя анкеты видно всегда
+type ProfileField string
+
+const (
+	FieldSurname   ProfileField = "surname"
+	FieldSex       ProfileField = "sex"
+	FieldBirthdate ProfileField = "birthdate"
+	FieldCity      ProfileField = "city"
+	FieldHobbies   ProfileField = "hobbies"
+	// Аватар и все фотографии анкеты
+	FieldPhotos ProfileField = "photos"
+)
+
+var ProfileFields = []ProfileField{FieldSurname, FieldSex, FieldBirthdate, FieldCity, FieldHobbies, FieldPhotos}
+
+func VisibilityFromString(v string) (Visibility, error) {
+	if !slices.Contains(visibilities, Visibility(v)) {
+		return "", fmt.Errorf("unknown visibility '%s': only %v are available", v, visibilities)
+	}
+
+	return Visibility(v), nil
+}
+
+func ProfileFieldFromString(f string) (ProfileField, error) {
+	if !slices.Contains(ProfileFields, ProfileField(f)) {
+		return "", fmt.Errorf("unknown profile field '%s': only %v are available", f, ProfileFields)
+	}
+
+	return ProfileField(f), nil
+}
+
+// Доступно ли поле с такой видимостью пользователю с доступом access
+func (v Visibility) VisibleTo(access Visibility) bool {
+	return slices.Index(visibilities, v) <= slices.Index(visibilities, access)
+}
+
+// Видимости полей, доступных пользователю с доступом access
+func VisibilitiesTo(access Visibility) []Visibility {
+	return slices.Clone(visibilities[:slices.Index(visibilities, access)+1])
+}
+
+// Настройки видимости полей анкеты
+type Privacy struct {
+	// Поля, видимость которых владелец изменил. Остальные поля видны с DefaultVisibility
+	Fields    map[ProfileField]Visibility
+	UpdatedAt time.Time
+}
+
+// Видимость поля. Для nil-настроек - DefaultVisibility
+func (p *Privacy) Visibility(f ProfileField) Visibility {
+	if p == nil {
+		return DefaultVisibility
+	}
+
+	v, ok := p.Fields[f]
+	if !ok {
+		return DefaultVisibility
+	}
+
+	return v
+}
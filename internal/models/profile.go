@@ -2,13 +2,14 @@ package models
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/Lucky112/social/internal/models/sex"
 )
 
 type Profile struct {
-	id        string
+	Id        string
 	UserId    string
 	Name      string
 	Surname   string
@@ -16,11 +17,28 @@ type Profile struct {
 	Birthdate time.Time
 	Address   string
 	Hobbies   string
+	// Нормализованные интересы (теги). Их видимость совпадает с видимостью hobbies
+	Interests []string
+	// Время последнего изменения анкеты
+	UpdatedAt time.Time
+	// Последняя загруженная фотография, если она есть
+	Avatar *Photo
+	// Настройки видимости полей, если владелец их менял
+	Privacy *Privacy
+	// Поля, скрытые от пользователя, запросившего анкету. Их значения в анкете пустые
+	Hidden []ProfileField
+}
+
+// Скрыто ли поле от пользователя, запросившего анкету
+func (p *Profile) IsHidden(f ProfileField) bool {
+	return slices.Contains(p.Hidden, f)
 }
 
 type SearchParams struct {
 	NamePrefix    string
 	SurnamePrefix string
+	// Интерес, который должен быть у анкеты. Пустой - любые интересы
+	Interest string
 }
 
 var ProfileNotFound = errors.New("profile not found")
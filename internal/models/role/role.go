@@ -0,0 +1,75 @@
+package role
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Role string
+
+const (
+	User  Role = "user"
+	Admin Role = "admin"
+)
+
+// Разрешение на выполнение группы действий в API
+type Permission string
+
+const (
+	ReadProfiles   Permission = "profiles:read"
+	WriteProfiles  Permission = "profiles:write"
+	ManageProfiles Permission = "profiles:manage"
+	ManageUsers    Permission = "users:manage"
+	// Управление своими подписками на события
+	ManageWebhooks Permission = "webhooks:manage"
+	// Управление подписками всех пользователей и подписка на события пользователей
+	ManageAllWebhooks Permission = "webhooks:manage_all"
+)
+
+var permissions = map[Role][]Permission{
+	User: {
+		ReadProfiles,
+		WriteProfiles,
+		ManageWebhooks,
+	},
+	Admin: {
+		ReadProfiles,
+		WriteProfiles,
+		ManageProfiles,
+		ManageUsers,
+		ManageWebhooks,
+		ManageAllWebhooks,
+	},
+}
+
+func (r Role) String() string {
+	return string(r)
+}
+
+// Проверяет, дает ли роль указанное разрешение
+func (r Role) Has(p Permission) bool {
+	return slices.Contains(permissions[r], p)
+}
+
+func FromString(role string) (Role, error) {
+	r := Role(strings.ToLower(role))
+
+	_, ok := permissions[r]
+	if !ok {
+		return "", fmt.Errorf("unknown role '%s': only %v are available", role, []Role{User, Admin})
+	}
+
+	return r, nil
+}
+
+// Проверяет, дает ли хотя бы одна из ролей указанное разрешение
+func AnyHas(roles []Role, p Permission) bool {
+	for _, r := range roles {
+		if r.Has(p) {
+			return true
+		}
+	}
+
+	return false
+}
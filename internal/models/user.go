@@ -1,6 +1,12 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lucky112/social/internal/models/role"
+)
 
 // Структура данных с информацией о пользователе
 type User struct {
@@ -9,8 +15,54 @@ type User struct {
 	Login          string
 	Password       string
 	HashedPassword []byte
+	TOTPSecret     []byte
+	TOTPEnabled    bool
+	Roles          []role.Role
+	Disabled       bool
+	// Поколение MFA-токенов. Токены прошлых поколений недействительны
+	MFAGeneration int
+	// Токены, выпущенные раньше этого момента, считаются отозванными
+	TokensValidAfter time.Time
+}
+
+// Все данные, связанные с пользователем, для выгрузки по его запросу
+type AccountData struct {
+	User *User
+	// Анкеты с настройками видимости и интересами
+	Profiles []*Profile
+	// Все фотографии анкет пользователя
+	Photos   []*Photo
+	Webhooks []*Webhook
+}
+
+// Данные для подключения TOTP-аутентификатора
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+	RecoveryCodes   []string
 }
 
 var UserNotFound = errors.New("user not found")
 var UserAlreadyExists = errors.New("user already exists")
 var UserBadCredentials = errors.New("invalid credentials for user")
+var UserDisabled = errors.New("user is disabled")
+var SessionRevoked = errors.New("session is revoked")
+
+var TOTPAlreadyEnabled = errors.New("totp is already enabled")
+var TOTPNotEnrolled = errors.New("totp is not enrolled")
+var InvalidMFACode = errors.New("invalid mfa code")
+var MFATokenRevoked = errors.New("mfa token is revoked")
+
+// Пользователь с таким же значением уникального поля (email или login) уже существует.
+// Сравнивается с UserAlreadyExists через errors.Is
+type UserConflictError struct {
+	Field string
+}
+
+func (e *UserConflictError) Error() string {
+	return fmt.Sprintf("user with the same %s already exists", e.Field)
+}
+
+func (e *UserConflictError) Unwrap() error {
+	return UserAlreadyExists
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Нарушение правила валидации в конкретном поле
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// Ошибка валидации входных данных со списком нарушений по полям
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// Добавляет нарушение в список
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Возвращает саму ошибку, если есть нарушения, иначе nil
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Lucky112/social/internal/models/role"
+)
+
+// Подписка на события: при каждом подходящем событии на URL отправляется запрос POST,
+// подписанный HMAC-SHA256 с секретом подписки
+type Webhook struct {
+	Id     string
+	UserId string
+	URL    string
+	// Типы событий, на которые оформлена подписка
+	Events []EventType
+	// Ключ подписи запросов. Показывается владельцу только при создании подписки
+	Secret    string
+	Active    bool
+	CreatedAt time.Time
+}
+
+// Подписка на событие указанного типа
+func (w *Webhook) Subscribed(t EventType) bool {
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Доставка события по подписке
+type WebhookDelivery struct {
+	Id        string
+	WebhookId string
+	EventId   string
+	EventType EventType
+	// Доставка, которую вручную повторяет эта доставка
+	RedeliveryOf string
+	// Тело запроса
+	Payload  json.RawMessage
+	Status   DeliveryStatus
+	Attempts int
+	// Статус ответа получателя на последнюю попытку, 0 - если ответа не было
+	ResponseStatus int
+	LastError      string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+}
+
+// Доставка вместе с адресом и секретом подписки, нужными для отправки
+type OutgoingDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// Результат попытки доставки
+type DeliveryAttempt struct {
+	Status         DeliveryStatus
+	ResponseStatus int
+	Error          string
+	// Время следующей попытки для доставок, оставшихся в статусе pending
+	NextAttemptAt time.Time
+	At            time.Time
+}
+
+// Пользователь, выполняющий действие, и его роли
+type Actor struct {
+	UserId string
+	Roles  []role.Role
+}
+
+var WebhookNotFound = errors.New("webhook not found")
+var DeliveryNotFound = errors.New("webhook delivery not found")
@@ -0,0 +1,212 @@
+// Пакет photos проверяет загруженные изображения и готовит из них фотографии анкет:
+// исходный размер и миниатюры в JPEG без метаданных
+package photos
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"slices"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// MIME-тип всех подготовленных вариантов фотографии
+const ContentType = "image/jpeg"
+
+// Форматы, которые принимаются при загрузке. Тип определяется по содержимому, а не по заголовкам запроса
+var acceptedTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// Размер миниатюры: наибольшая сторона в пикселях
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+type Config struct {
+	// Наибольший размер загружаемого файла в байтах
+	MaxBytes int64
+	// Наибольшее число пикселей в изображении. Ограничивает память, нужную для декодирования
+	MaxPixels int
+	Sizes     []Size
+	// Качество JPEG от 1 до 100
+	Quality int
+}
+
+// Подготовленный вариант фотографии
+type Image struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+type Processor struct {
+	cfg Config
+}
+
+func NewProcessor(cfg Config) Processor {
+	return Processor{cfg}
+}
+
+// Проверяет изображение и возвращает исходный вариант и миниатюры. Изображение перекодируется,
+// поэтому метаданные (EXIF, GPS-координаты и т.п.) не сохраняются; поворот из EXIF применяется к пикселям.
+// Для неподдерживаемых форматов возвращает models.UnsupportedImage, для слишком больших - models.PhotoTooLarge
+func (p Processor) Process(data []byte) ([]Image, error) {
+	if int64(len(data)) > p.cfg.MaxBytes {
+		return nil, fmt.Errorf("%d bytes exceed the limit of %d: %w", len(data), p.cfg.MaxBytes, models.PhotoTooLarge)
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(acceptedTypes, contentType) {
+		return nil, fmt.Errorf("content type %s: %w", contentType, models.UnsupportedImage)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image header: %v: %w", err, models.UnsupportedImage)
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("empty image: %w", models.UnsupportedImage)
+	}
+
+	if cfg.Width*cfg.Height > p.cfg.MaxPixels {
+		return nil, fmt.Errorf("%dx%d pixels exceed the limit of %d: %w", cfg.Width, cfg.Height, p.cfg.MaxPixels, models.PhotoTooLarge)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %v: %w", err, models.UnsupportedImage)
+	}
+
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	original := orient(flatten(src), orientation)
+
+	res := make([]Image, 0, len(p.cfg.Sizes)+1)
+
+	img, err := p.encode(models.PhotoOriginal, original)
+	if err != nil {
+		return nil, err
+	}
+	res = append(res, img)
+
+	for _, size := range p.cfg.Sizes {
+		img, err := p.encode(size.Name, scale(original, size.MaxSide))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, img)
+	}
+
+	return res, nil
+}
+
+func (p Processor) encode(name string, img *image.RGBA) (Image, error) {
+	var buf bytes.Buffer
+
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.cfg.Quality})
+	if err != nil {
+		return Image{}, fmt.Errorf("encoding %s: %v", name, err)
+	}
+
+	b := img.Bounds()
+
+	return Image{
+		Name:   name,
+		Data:   buf.Bytes(),
+		Width:  b.Dx(),
+		Height: b.Dy(),
+	}, nil
+}
+
+// Переносит изображение на белый фон: в JPEG нет прозрачности
+func flatten(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+
+	return dst
+}
+
+// Уменьшает изображение так, чтобы наибольшая сторона не превышала maxSide. Изображения меньше не увеличиваются
+func scale(src *image.RGBA, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	if w >= h {
+		h = max(1, h*maxSide/w)
+		w = maxSide
+	} else {
+		w = max(1, w*maxSide/h)
+		h = maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	return dst
+}
+
+// Поворачивает и отражает изображение согласно тегу Orientation из EXIF
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// при ориентациях 5-8 ширина и высота меняются местами
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,213 @@
+package photos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+var testConfig = Config{
+	MaxBytes:  1 << 20,
+	MaxPixels: 1000 * 1000,
+	Sizes: []Size{
+		{Name: "small", MaxSide: 10},
+		{Name: "large", MaxSide: 100},
+	},
+	Quality: 90,
+}
+
+// Изображение w x h, левая половина которого красная, а правая - синяя
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+// JPEG с сегментом EXIF, в котором есть ориентация и произвольные метаданные
+func encodeJPEGWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 55.7558N 37.6173E"...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	res := append([]byte{}, data[:2]...)
+	res = append(res, app1...)
+
+	return append(res, data[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "jpeg", format)
+
+	return img
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func TestProcess(t *testing.T) {
+	p := NewProcessor(testConfig)
+
+	t.Run("test variants", func(t *testing.T) {
+		images, err := p.Process(encodePNG(t, testImage(40, 20)))
+		require.NoError(t, err)
+		require.Len(t, images, 3)
+
+		sizes := make(map[string][2]int)
+		for _, img := range images {
+			decoded := decode(t, img.Data)
+			assert.Equal(t, img.Width, decoded.Bounds().Dx())
+			assert.Equal(t, img.Height, decoded.Bounds().Dy())
+			sizes[img.Name] = [2]int{img.Width, img.Height}
+		}
+
+		assert.Equal(t, map[string][2]int{
+			models.PhotoOriginal: {40, 20},
+			"small":              {10, 5},
+			// изображения не увеличиваются
+			"large": {40, 20},
+		}, sizes)
+	})
+
+	t.Run("test exif is stripped and orientation applied", func(t *testing.T) {
+		data := encodeJPEGWithExif(t, testImage(40, 20), 6)
+		require.Equal(t, 6, jpegOrientation(data))
+
+		images, err := p.Process(data)
+		require.NoError(t, err)
+
+		for _, img := range images {
+			assert.False(t, bytes.Contains(img.Data, []byte("Exif")), img.Name)
+			assert.False(t, bytes.Contains(img.Data, []byte("GPS")), img.Name)
+		}
+
+		original := decode(t, images[0].Data)
+		require.Equal(t, image.Rect(0, 0, 20, 40), original.Bounds())
+
+		// после поворота на 90° по часовой стрелке красная левая половина становится верхней
+		assert.True(t, isRed(original.At(10, 5)))
+		assert.False(t, isRed(original.At(10, 35)))
+	})
+
+	t.Run("test transparent image is put on white", func(t *testing.T) {
+		images, err := p.Process(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 4, 4))))
+		require.NoError(t, err)
+
+		r, g, b, _ := decode(t, images[0].Data).At(1, 1).RGBA()
+		assert.Greater(t, r, uint32(0xF000))
+		assert.Greater(t, g, uint32(0xF000))
+		assert.Greater(t, b, uint32(0xF000))
+	})
+
+	t.Run("test unsupported content", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, gif.Encode(&buf, testImage(4, 4), nil))
+
+		_, err := p.Process(buf.Bytes())
+		assert.ErrorIs(t, err, models.UnsupportedImage)
+
+		_, err = p.Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+		assert.ErrorIs(t, err, models.UnsupportedImage)
+
+		// заголовок PNG без изображения
+		_, err = p.Process(encodePNG(t, testImage(4, 4))[:40])
+		assert.ErrorIs(t, err, models.UnsupportedImage)
+	})
+
+	t.Run("test limits", func(t *testing.T) {
+		data := encodePNG(t, testImage(40, 20))
+
+		small := NewProcessor(Config{MaxBytes: int64(len(data)) - 1, MaxPixels: 1000, Quality: 90})
+		_, err := small.Process(data)
+		assert.ErrorIs(t, err, models.PhotoTooLarge)
+
+		few := NewProcessor(Config{MaxBytes: 1 << 20, MaxPixels: 40*20 - 1, Quality: 90})
+		_, err = few.Process(data)
+		assert.ErrorIs(t, err, models.PhotoTooLarge)
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// пиксель в левом верхнем углу изображения 3x2 после каждой из ориентаций
+	corners := map[int]image.Point{
+		1: {0, 0},
+		2: {2, 0},
+		3: {2, 1},
+		4: {0, 1},
+		5: {0, 0},
+		6: {1, 0},
+		7: {1, 2},
+		8: {0, 2},
+	}
+
+	for orientation, want := range corners {
+		src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+		src.Set(0, 0, color.White)
+
+		dst := orient(src, orientation)
+
+		if orientation >= 5 {
+			assert.Equal(t, image.Rect(0, 0, 2, 3), dst.Bounds(), "orientation %d", orientation)
+		} else {
+			assert.Equal(t, image.Rect(0, 0, 3, 2), dst.Bounds(), "orientation %d", orientation)
+		}
+		assert.Equal(t, color.RGBA{255, 255, 255, 255}, dst.At(want.X, want.Y), "orientation %d", orientation)
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	assert.Equal(t, 1, jpegOrientation(nil))
+	assert.Equal(t, 1, jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}))
+	assert.Equal(t, 8, jpegOrientation(encodeJPEGWithExif(t, testImage(2, 2), 8)))
+	assert.Equal(t, 1, jpegOrientation(encodeJPEGWithExif(t, testImage(2, 2), 42)))
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис управления пользователем своим аккаунтом
+type AccountService struct {
+	users     UsersStorage
+	profiles  ProfilesStorage
+	webhooks  WebhooksStorage
+	photos    PhotosStorage
+	privacy   PrivacyStorage
+	interests InterestsStorage
+	tx        TxManager
+	blobs     BlobStore
+	hasher    PasswordHasher
+	policy    CredentialsPolicy
+}
+
+// Выполняет функцию в рамках одной транзакции. Хранилища, вызванные с контекстом,
+// переданным в функцию, работают внутри этой транзакции, поэтому в одной транзакции
+// можно объединить вызовы нескольких хранилищ и сервисов. Вложенный вызов InTx
+// выполняется в точке сохранения внешней транзакции
+type TxManager interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// blobs - хранилище файлов фотографий, которые удаляются вместе с аккаунтом
+func NewAccountService(users UsersStorage, profiles ProfilesStorage, webhooks WebhooksStorage, photos PhotosStorage, privacy PrivacyStorage, interests InterestsStorage, tx TxManager, blobs BlobStore, hasher PasswordHasher, policy CredentialsPolicy) AccountService {
+	return AccountService{
+		users:     users,
+		profiles:  profiles,
+		webhooks:  webhooks,
+		photos:    photos,
+		privacy:   privacy,
+		interests: interests,
+		tx:        tx,
+		blobs:     blobs,
+		hasher:    hasher,
+		policy:    policy,
+	}
+}
+
+// Меняет пароль пользователя после проверки старого.
+// Все ранее выпущенные токены пользователя отзываются.
+// Если новый пароль нарушает политику, возвращает *models.ValidationError
+func (s AccountService) ChangePassword(ctx context.Context, userId, oldPassword, newPassword string) error {
+	err := s.policy.CheckPassword("new_password", newPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.users.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return err
+		}
+
+		return fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	ok, err := s.hasher.Verify([]byte(oldPassword), user.HashedPassword)
+	if err != nil {
+		return fmt.Errorf("verifying password: %v", err)
+	}
+	if !ok {
+		return models.UserBadCredentials
+	}
+
+	hashedPassword, err := s.hasher.Hash([]byte(newPassword))
+	if err != nil {
+		return fmt.Errorf("hashing password: %v", err)
+	}
+
+	err = s.users.SetPassword(ctx, userId, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("updating password: %v", err)
+	}
+
+	return nil
+}
+
+// Удаляет пользователя вместе с его анкетами, фотографиями и подписками в одной транзакции.
+// Файлы фотографий удаляются из хранилища файлов после фиксации транзакции
+func (s AccountService) DeleteAccount(ctx context.Context, userId string) error {
+	var photos []*models.Photo
+
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		// строки фотографий удаляются каскадно вместе с анкетами, а файлы нужно удалить отдельно
+		photos, err = s.photos.GetByUser(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("getting photos: %v", err)
+		}
+
+		err = s.profiles.DeleteByUser(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("deleting profiles: %v", err)
+		}
+
+		err = s.webhooks.DeleteByUser(ctx, userId)
+		if err != nil {
+			return fmt.Errorf("deleting webhooks: %v", err)
+		}
+
+		err = s.users.Delete(ctx, userId)
+		if err != nil {
+			if errors.Is(err, models.UserNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("deleting user: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteBlobs(ctx, photos)
+
+	return nil
+}
+
+// Удаляет файлы фотографий удаленного аккаунта. Аккаунт уже удален,
+// поэтому ошибки только логируются: оставшиеся файлы недоступны через API
+func (s AccountService) deleteBlobs(ctx context.Context, photos []*models.Photo) {
+	// файлы удаляются, даже если запрос удаления отменен
+	ctx = context.WithoutCancel(ctx)
+
+	for _, p := range photos {
+		for _, v := range p.Variants {
+			err := s.blobs.Delete(ctx, v.Key)
+			if err != nil {
+				slog.Warn("deleting file of deleted account", "key", v.Key, "error", err)
+			}
+		}
+	}
+}
+
+// Собирает все данные, связанные с пользователем
+func (s AccountService) Export(ctx context.Context, userId string) (*models.AccountData, error) {
+	user, err := s.users.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	profiles, err := s.profiles.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("getting profiles of user '%s': %v", userId, err)
+	}
+
+	ids := make([]string, len(profiles))
+	for i, p := range profiles {
+		ids[i] = p.Id
+	}
+
+	privacy, err := s.privacy.Privacy(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("getting privacy of user '%s': %v", userId, err)
+	}
+
+	interests, err := s.interests.Interests(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("getting interests of user '%s': %v", userId, err)
+	}
+
+	for _, p := range profiles {
+		p.Privacy = privacy[p.Id]
+		p.Interests = interests[p.Id]
+	}
+
+	photos, err := s.photos.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("getting photos of user '%s': %v", userId, err)
+	}
+
+	webhooks, err := s.webhooks.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("getting webhooks of user '%s': %v", userId, err)
+	}
+
+	return &models.AccountData{
+		User:     user,
+		Profiles: profiles,
+		Photos:   photos,
+		Webhooks: webhooks,
+	}, nil
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/photos"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+// Транзакция, просто вызывающая функцию
+type fakeTxManager struct{}
+
+func (fakeTxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestAccount(t *testing.T) {
+	users := mocks.NewUsersStorage(t)
+	profiles := mocks.NewProfilesStorage(t)
+	webhooks := mocks.NewWebhooksStorage(t)
+	db := inmemory.NewDB()
+	accountService := NewAccountService(users, profiles, webhooks, inmemory.NewPhotosStorage(db), inmemory.NewPrivacyStorage(db), inmemory.NewInterestsStorage(db), fakeTxManager{}, blob.NewLocalStore(t.TempDir()), testHasher, testPolicy)
+
+	userId := "1"
+	password := "pwd"
+	hashedPwd, err := testHasher.Hash([]byte(password))
+	require.NoError(t, err)
+
+	user := &models.User{
+		Id:             userId,
+		Login:          "login",
+		HashedPassword: hashedPwd,
+	}
+
+	t.Run("test ChangePassword", func(t *testing.T) {
+		var newHash []byte
+
+		users.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		users.On("SetPassword", mock.Anything, userId, mock.Anything).
+			Run(func(args mock.Arguments) { newHash = args.Get(2).([]byte) }).
+			Return(nil).Once()
+
+		err := accountService.ChangePassword(context.Background(), userId, password, "new password 42")
+		require.NoError(t, err)
+		ok, err := testHasher.Verify([]byte("new password 42"), newHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("test ChangePassword with wrong old password", func(t *testing.T) {
+		users.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+
+		err := accountService.ChangePassword(context.Background(), userId, "wrong", "new password 42")
+		assert.ErrorIs(t, err, models.UserBadCredentials)
+	})
+
+	t.Run("test ChangePassword with weak new password", func(t *testing.T) {
+		err := accountService.ChangePassword(context.Background(), userId, password, "short")
+
+		var verr *models.ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, "new_password", verr.Fields[0].Field)
+	})
+
+	t.Run("test DeleteAccount", func(t *testing.T) {
+		profiles.On("DeleteByUser", mock.Anything, userId).Return(nil).Once()
+		webhooks.On("DeleteByUser", mock.Anything, userId).Return(nil).Once()
+		users.On("Delete", mock.Anything, userId).Return(nil).Once()
+
+		err := accountService.DeleteAccount(context.Background(), userId)
+		assert.NoError(t, err)
+	})
+
+	t.Run("test DeleteAccount profiles error", func(t *testing.T) {
+		profiles.On("DeleteByUser", mock.Anything, userId).Return(errors.New("storage error")).Once()
+
+		err := accountService.DeleteAccount(context.Background(), userId)
+		assert.Error(t, err)
+	})
+
+	t.Run("test DeleteAccount unknown user", func(t *testing.T) {
+		profiles.On("DeleteByUser", mock.Anything, "2").Return(nil).Once()
+		webhooks.On("DeleteByUser", mock.Anything, "2").Return(nil).Once()
+		users.On("Delete", mock.Anything, "2").Return(fmt.Errorf("%w", models.UserNotFound)).Once()
+
+		err := accountService.DeleteAccount(context.Background(), "2")
+		assert.ErrorIs(t, err, models.UserNotFound)
+	})
+
+	t.Run("test Export", func(t *testing.T) {
+		userProfiles := []*models.Profile{{Name: "name"}}
+
+		users.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		profiles.On("GetByUser", mock.Anything, userId).Return(userProfiles, nil).Once()
+		webhooks.On("GetByUser", mock.Anything, userId).Return([]*models.Webhook{{Id: "3"}}, nil).Once()
+
+		data, err := accountService.Export(context.Background(), userId)
+		require.NoError(t, err)
+		assert.Equal(t, user, data.User)
+		assert.Equal(t, userProfiles, data.Profiles)
+		assert.Equal(t, []*models.Webhook{{Id: "3"}}, data.Webhooks)
+	})
+}
+
+// Выгрузка и удаление аккаунта с фотографиями, настройками видимости и интересами
+func TestAccountPhotos(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	users := inmemory.NewUsersStorage(db)
+	profiles := inmemory.NewProfilesStorage(db)
+	webhooks := inmemory.NewWebhooksStorage(db)
+	photosStorage := inmemory.NewPhotosStorage(db)
+	privacy := inmemory.NewPrivacyStorage(db)
+	interests := inmemory.NewInterestsStorage(db)
+	outbox := inmemory.NewOutboxStorage(db)
+	tx := inmemory.NewTxManager(db)
+	dir := t.TempDir()
+	blobs := blob.NewLocalStore(dir)
+
+	accountService := NewAccountService(users, profiles, webhooks, photosStorage, privacy, interests, tx, blobs, testHasher, testPolicy)
+	photosService := NewPhotosService(photosStorage, profiles, privacy, nil, outbox, tx, blobs, photos.NewProcessor(testPhotosConfig))
+
+	userId, err := users.Add(ctx, &models.User{Email: "ivan@example.com", Login: "ivan"})
+	require.NoError(t, err)
+	profileId, err := profiles.Add(ctx, &models.Profile{UserId: userId, Name: "Ivan"})
+	require.NoError(t, err)
+	require.NoError(t, interests.AddInterests(ctx, profileId, []string{"chess"}))
+	require.NoError(t, privacy.SetPrivacy(ctx, profileId, map[models.ProfileField]models.Visibility{models.FieldCity: models.VisibilityHidden}))
+	_, err = webhooks.Add(ctx, &models.Webhook{UserId: userId, URL: "https://example.com/hook", Active: true})
+	require.NoError(t, err)
+
+	owner := models.Actor{UserId: userId, Roles: []role.Role{role.User}}
+	photo, err := photosService.Upload(ctx, owner, profileId, testPNG(t))
+	require.NoError(t, err)
+	require.NotEmpty(t, storedFiles(t, dir))
+
+	t.Run("test Export", func(t *testing.T) {
+		data, err := accountService.Export(ctx, userId)
+		require.NoError(t, err)
+
+		require.Len(t, data.Profiles, 1)
+		assert.Equal(t, []string{"chess"}, data.Profiles[0].Interests)
+		assert.Equal(t, models.VisibilityHidden, data.Profiles[0].Privacy.Visibility(models.FieldCity))
+
+		require.Len(t, data.Photos, 1)
+		assert.Equal(t, photo.Id, data.Photos[0].Id)
+		assert.Equal(t, photo.Variants, data.Photos[0].Variants)
+
+		require.Len(t, data.Webhooks, 1)
+		assert.Equal(t, "https://example.com/hook", data.Webhooks[0].URL)
+	})
+
+	t.Run("test DeleteAccount deletes photo files", func(t *testing.T) {
+		err := accountService.DeleteAccount(ctx, userId)
+		require.NoError(t, err)
+
+		assert.Empty(t, storedFiles(t, dir))
+
+		_, err = photosStorage.Get(ctx, photo.Id)
+		assert.ErrorIs(t, err, models.PhotoNotFound)
+	})
+
+	t.Run("test DeleteAccount keeps files on error", func(t *testing.T) {
+		otherId, err := profiles.Add(ctx, &models.Profile{UserId: "100", Name: "Petr"})
+		require.NoError(t, err)
+		_, err = photosService.Upload(ctx, models.Actor{UserId: "100", Roles: []role.Role{role.User}}, otherId, testPNG(t))
+		require.NoError(t, err)
+
+		// пользователя нет, транзакция откатывается вместе с удалением анкеты
+		err = accountService.DeleteAccount(ctx, "100")
+		require.ErrorIs(t, err, models.UserNotFound)
+
+		assert.NotEmpty(t, storedFiles(t, dir))
+
+		_, err = profiles.Get(ctx, otherId)
+		assert.NoError(t, err)
+	})
+}
+
+// Регистрация пользователя и создание его анкеты разными сервисами в одной транзакции
+func TestTxManagerComposition(t *testing.T) {
+	db := inmemory.NewDB()
+	users := inmemory.NewUsersStorage(db)
+	tx := inmemory.NewTxManager(db)
+
+	outbox := inmemory.NewOutboxStorage(db)
+
+	authService, err := NewAuthService(users, outbox, tx, testHasher, testPolicy, testAuthConfig)
+	require.NoError(t, err)
+	profilesService := NewProfilesService(inmemory.NewProfilesStorage(db), inmemory.NewPhotosStorage(db), inmemory.NewPrivacyStorage(db), inmemory.NewInterestsStorage(db), nil, outbox, tx)
+
+	register := func(ctx context.Context, login string, profileErr error) error {
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			userId, err := authService.NewUser(ctx, &models.User{
+				Email:    login + "@example.com",
+				Login:    login,
+				Password: "correct horse battery",
+			})
+			if err != nil {
+				return err
+			}
+
+			if profileErr != nil {
+				return profileErr
+			}
+
+			_, err = profilesService.Add(ctx, &models.Profile{UserId: userId, Name: login})
+			return err
+		})
+	}
+
+	ctx := context.Background()
+
+	err = register(ctx, "first", nil)
+	require.NoError(t, err)
+
+	user, err := users.Get(ctx, "first")
+	require.NoError(t, err)
+
+	profiles, err := profilesService.GetAll(ctx, models.Actor{UserId: user.Id})
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, user.Id, profiles[0].UserId)
+
+	// события записаны в той же транзакции
+	events := outbox.Events(ctx)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventUserRegistered, events[0].Type)
+	assert.Equal(t, models.EventProfileCreated, events[1].Type)
+
+	// ошибка при создании анкеты отменяет и регистрацию
+	err = register(ctx, "second", errors.New("invalid profile"))
+	require.Error(t, err)
+
+	_, err = users.Get(ctx, "second")
+	assert.ErrorIs(t, err, models.UserNotFound)
+	assert.Len(t, outbox.Events(ctx), 2)
+}
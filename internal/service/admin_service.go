@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+const temporaryPasswordBytes = 12
+
+// Сервис административных действий.
+// Каждое действие фиксируется в журнале аудита. Изменение данных и запись
+// в журнал выполняются в одной транзакции: действие без записи не сохраняется
+type AdminService struct {
+	users    UsersStorage
+	profiles ProfilesStorage
+	audit    AuditStorage
+	tx       TxManager
+	hasher   PasswordHasher
+}
+
+// Журнал аудита действий администраторов
+type AuditStorage interface {
+	Add(ctx context.Context, record *models.AuditRecord) error
+}
+
+func NewAdminService(users UsersStorage, profiles ProfilesStorage, audit AuditStorage, tx TxManager, hasher PasswordHasher) AdminService {
+	return AdminService{
+		users:    users,
+		profiles: profiles,
+		audit:    audit,
+		tx:       tx,
+		hasher:   hasher,
+	}
+}
+
+func (s AdminService) ListUsers(ctx context.Context, actorId string) ([]*models.User, error) {
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting all users: %v", err)
+	}
+
+	err = s.record(ctx, actorId, models.AuditListUsers, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s AdminService) SetUserDisabled(ctx context.Context, actorId, userId string, disabled bool) error {
+	return s.tx.InTx(ctx, func(ctx context.Context) error {
+		err := s.users.SetDisabled(ctx, userId, disabled)
+		if err != nil {
+			if errors.Is(err, models.UserNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("updating user '%s': %v", userId, err)
+		}
+
+		action := models.AuditEnableUser
+		if disabled {
+			action = models.AuditDisableUser
+		}
+
+		return s.record(ctx, actorId, action, userId)
+	})
+}
+
+func (s AdminService) DeleteProfile(ctx context.Context, actorId, profileId string) error {
+	return s.tx.InTx(ctx, func(ctx context.Context) error {
+		err := s.profiles.Delete(ctx, profileId)
+		if err != nil {
+			if errors.Is(err, models.ProfileNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("deleting profile '%s': %v", profileId, err)
+		}
+
+		return s.record(ctx, actorId, models.AuditDeleteProfile, profileId)
+	})
+}
+
+// Сбрасывает пароль пользователя на случайный временный и возвращает его
+func (s AdminService) ResetPassword(ctx context.Context, actorId, userId string) (string, error) {
+	raw := make([]byte, temporaryPasswordBytes)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", fmt.Errorf("generating temporary password: %v", err)
+	}
+	password := base64.RawURLEncoding.EncodeToString(raw)
+
+	hashedPassword, err := s.hasher.Hash([]byte(password))
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %v", err)
+	}
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		err := s.users.SetPassword(ctx, userId, hashedPassword)
+		if err != nil {
+			if errors.Is(err, models.UserNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("updating password of '%s': %v", userId, err)
+		}
+
+		return s.record(ctx, actorId, models.AuditResetPassword, userId)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return password, nil
+}
+
+func (s AdminService) record(ctx context.Context, actorId string, action models.AuditAction, targetId string) error {
+	err := s.audit.Add(ctx, &models.AuditRecord{
+		ActorId:  actorId,
+		Action:   action,
+		TargetId: targetId,
+	})
+	if err != nil {
+		return fmt.Errorf("recording '%s' to audit log: %v", action, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestAdmin(t *testing.T) {
+	users := mocks.NewUsersStorage(t)
+	profiles := mocks.NewProfilesStorage(t)
+	audit := mocks.NewAuditStorage(t)
+	adminService := NewAdminService(users, profiles, audit, fakeTxManager{}, testHasher)
+
+	actorId := "1"
+
+	auditRecord := func(action models.AuditAction, target string) any {
+		return mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.ActorId == actorId && r.Action == action && r.TargetId == target
+		})
+	}
+
+	t.Run("test ListUsers", func(t *testing.T) {
+		expected := []*models.User{{Id: "2"}}
+
+		users.On("GetAll", mock.Anything).Return(expected, nil).Once()
+		audit.On("Add", mock.Anything, auditRecord(models.AuditListUsers, "")).Return(nil).Once()
+
+		actual, err := adminService.ListUsers(context.Background(), actorId)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("test SetUserDisabled", func(t *testing.T) {
+		users.On("SetDisabled", mock.Anything, "2", true).Return(nil).Once()
+		audit.On("Add", mock.Anything, auditRecord(models.AuditDisableUser, "2")).Return(nil).Once()
+
+		err := adminService.SetUserDisabled(context.Background(), actorId, "2", true)
+		assert.NoError(t, err)
+	})
+
+	t.Run("test SetUserDisabled unknown user", func(t *testing.T) {
+		users.On("SetDisabled", mock.Anything, "3", false).Return(fmt.Errorf("%w", models.UserNotFound)).Once()
+
+		err := adminService.SetUserDisabled(context.Background(), actorId, "3", false)
+		assert.ErrorIs(t, err, models.UserNotFound)
+	})
+
+	t.Run("test DeleteProfile", func(t *testing.T) {
+		profiles.On("Delete", mock.Anything, "23").Return(nil).Once()
+		audit.On("Add", mock.Anything, auditRecord(models.AuditDeleteProfile, "23")).Return(nil).Once()
+
+		err := adminService.DeleteProfile(context.Background(), actorId, "23")
+		assert.NoError(t, err)
+	})
+
+	t.Run("test DeleteProfile audit error", func(t *testing.T) {
+		profiles.On("Delete", mock.Anything, "24").Return(nil).Once()
+		audit.On("Add", mock.Anything, mock.Anything).Return(errors.New("storage error")).Once()
+
+		err := adminService.DeleteProfile(context.Background(), actorId, "24")
+		assert.Error(t, err)
+	})
+
+	t.Run("test ResetPassword", func(t *testing.T) {
+		var hashed []byte
+
+		users.On("SetPassword", mock.Anything, "2", mock.Anything).
+			Run(func(args mock.Arguments) { hashed = args.Get(2).([]byte) }).
+			Return(nil).Once()
+		audit.On("Add", mock.Anything, auditRecord(models.AuditResetPassword, "2")).Return(nil).Once()
+
+		password, err := adminService.ResetPassword(context.Background(), actorId, "2")
+		require.NoError(t, err)
+		assert.NotEmpty(t, password)
+		ok, err := testHasher.Verify([]byte(password), hashed)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+// Журнал аудита, отказывающий в записи
+type failingAuditStorage struct{}
+
+func (failingAuditStorage) Add(ctx context.Context, record *models.AuditRecord) error {
+	return errors.New("audit log is unavailable")
+}
+
+// Действие без записи в журнал аудита откатывается
+func TestAdminRollback(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	users := inmemory.NewUsersStorage(db)
+	profiles := inmemory.NewProfilesStorage(db)
+	adminService := NewAdminService(users, profiles, failingAuditStorage{}, inmemory.NewTxManager(db), testHasher)
+
+	hashedPwd, err := testHasher.Hash([]byte("pwd"))
+	require.NoError(t, err)
+	userId, err := users.Add(ctx, &models.User{Email: "ivan@example.com", Login: "ivan", HashedPassword: hashedPwd})
+	require.NoError(t, err)
+	profileId, err := profiles.Add(ctx, &models.Profile{UserId: userId, Name: "Ivan"})
+	require.NoError(t, err)
+
+	t.Run("test SetUserDisabled", func(t *testing.T) {
+		err := adminService.SetUserDisabled(ctx, "1", userId, true)
+		require.Error(t, err)
+
+		user, err := users.GetById(ctx, userId)
+		require.NoError(t, err)
+		assert.False(t, user.Disabled)
+	})
+
+	t.Run("test DeleteProfile", func(t *testing.T) {
+		err := adminService.DeleteProfile(ctx, "1", profileId)
+		require.Error(t, err)
+
+		_, err = profiles.Get(ctx, profileId)
+		assert.NoError(t, err)
+	})
+
+	t.Run("test ResetPassword", func(t *testing.T) {
+		_, err := adminService.ResetPassword(ctx, "1", userId)
+		require.Error(t, err)
+
+		user, err := users.GetById(ctx, userId)
+		require.NoError(t, err)
+		assert.Equal(t, hashedPwd, user.HashedPassword)
+	})
+}
@@ -2,84 +2,189 @@ package service
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/Lucky112/social/config"
 	"github.com/Lucky112/social/internal/models"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	storage UsersStorage
+	storage    UsersStorage
+	outbox     OutboxStorage
+	tx         TxManager
+	hasher     PasswordHasher
+	policy     CredentialsPolicy
+	secrets    secretBox
+	totpIssuer string
 }
 
 // Хранилище зарегистрированных пользователей
 type UsersStorage interface {
-	Exists(ctx context.Context, user *models.User) (bool, error)
-	Get(ctx context.Context, userId string) (*models.User, error)
+	// Если email или логин заняты, возвращает *models.UserConflictError
 	Add(ctx context.Context, user *models.User) (string, error)
+	// Ищет пользователя по нормализованному логину или email
+	Get(ctx context.Context, identifier string) (*models.User, error)
+	GetById(ctx context.Context, userId string) (*models.User, error)
+	SetTOTP(ctx context.Context, userId string, secret []byte, recoveryCodes [][]byte) error
+	EnableTOTP(ctx context.Context, userId string) error
+	UseRecoveryCode(ctx context.Context, userId string, code []byte) (bool, error)
+	// Запоминает шаг принятого TOTP-кода, если он больше сохраненного.
+	// Возвращает false, если код этого шага уже использовался
+	UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error)
+	// Учитывает попытку ввода кода по MFA-токену поколения generation и возвращает число попыток
+	// пользователя за окно window, которое начинается с первой попытки. Если поколение устарело
+	// или попыток за окно уже limit, возвращает models.MFATokenRevoked
+	AddMFAAttempt(ctx context.Context, userId string, generation, limit int, window time.Duration) (int, error)
+	// Сбрасывает счетчик попыток ввода кода
+	ResetMFAAttempts(ctx context.Context, userId string) error
+	// Отзывает выданные MFA-токены, начиная новое поколение. Счетчик попыток не сбрасывается
+	RevokeMFATokens(ctx context.Context, userId string) error
+	GetAll(ctx context.Context) ([]*models.User, error)
+	SetDisabled(ctx context.Context, userId string, disabled bool) error
+	SetPassword(ctx context.Context, userId string, hashedPassword []byte) error
+	UpdatePasswordHash(ctx context.Context, userId string, hashedPassword []byte) error
+	Delete(ctx context.Context, userId string) error
 }
 
-func NewAuthService(storage UsersStorage) AuthService {
-	return AuthService{
-		storage: storage,
+func NewAuthService(storage UsersStorage, outbox OutboxStorage, tx TxManager, hasher PasswordHasher, policy CredentialsPolicy, cfg *config.AuthConfig) (AuthService, error) {
+	key, err := hex.DecodeString(cfg.TOTPKey)
+	if err != nil {
+		return AuthService{}, fmt.Errorf("decoding totp key: %v", err)
+	}
+
+	secrets, err := newSecretBox(key)
+	if err != nil {
+		return AuthService{}, fmt.Errorf("creating totp secrets cipher: %v", err)
 	}
+
+	return AuthService{
+		storage:    storage,
+		outbox:     outbox,
+		tx:         tx,
+		hasher:     hasher,
+		policy:     policy,
+		secrets:    secrets,
+		totpIssuer: cfg.TOTPIssuer,
+	}, nil
 }
 
+// Регистрирует нового пользователя и записывает событие UserRegistered.
+// Если данные нарушают политику, возвращает *models.ValidationError,
+// если email или логин заняты - *models.UserConflictError
 func (s AuthService) NewUser(ctx context.Context, user *models.User) (string, error) {
-	hashedPassword, err := hashAndSalt([]byte(user.Password))
+	user.Email = normalizeIdentifier(user.Email)
+	user.Login = normalizeIdentifier(user.Login)
+
+	err := s.policy.CheckUser(user)
 	if err != nil {
-		return "", fmt.Errorf("hashing password: %v", err)
+		return "", err
 	}
-	user.HashedPassword = hashedPassword
 
-	exists, err := s.storage.Exists(ctx, user)
+	hashedPassword, err := s.hasher.Hash([]byte(user.Password))
 	if err != nil {
-		return "", fmt.Errorf("checking user existence: %v", err)
-	}
-	if exists {
-		return "", models.UserAlreadyExists
+		return "", fmt.Errorf("hashing password: %v", err)
 	}
+	user.HashedPassword = hashedPassword
+
+	var id string
 
-	id, err := s.storage.Add(ctx, user)
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		// уникальность email и логина гарантирует база: предварительная проверка
+		// не защищает от одновременных регистраций
+		id, err = s.storage.Add(ctx, user)
+		if err != nil {
+			return fmt.Errorf("creating new user: %w", err)
+		}
+
+		return recordEvent(ctx, s.outbox, models.EventUserRegistered, models.AggregateUser, id, models.UserRegisteredPayload{
+			UserId: id,
+			Login:  user.Login,
+			Email:  user.Email,
+		})
+	})
 	if err != nil {
-		return "", fmt.Errorf("creating new user: %v", err)
+		return "", err
 	}
 
 	return id, nil
 }
 
-func (s AuthService) Login(ctx context.Context, login, password string) (string, error) {
-	user, err := s.storage.Get(ctx, login)
+// Проверяет идентификатор (логин или email) и пароль пользователя.
+// Если хэш пароля получен устаревшим алгоритмом или с устаревшими параметрами, он пересчитывается.
+// Если у пользователя включен TOTP, вызывающая сторона должна дополнительно проверить код через VerifyMFA
+func (s AuthService) Login(ctx context.Context, identifier, password string) (*models.User, error) {
+	identifier = normalizeIdentifier(identifier)
+
+	user, err := s.storage.Get(ctx, identifier)
 	if err != nil {
 		if errors.Is(err, models.UserNotFound) {
-			return "", err
+			return nil, err
 		}
 
-		return "", fmt.Errorf("looking for user '%s': %v", login, err)
+		return nil, fmt.Errorf("looking for user '%s': %v", identifier, err)
 	}
 
-	err = checkHash([]byte(password), user.HashedPassword)
+	ok, err := s.hasher.Verify([]byte(password), user.HashedPassword)
 	if err != nil {
-		return "", models.UserBadCredentials
+		return nil, fmt.Errorf("verifying password of '%s': %v", identifier, err)
+	}
+	if !ok {
+		return nil, models.UserBadCredentials
 	}
 
-	return user.Id, nil
+	if user.Disabled {
+		return nil, models.UserDisabled
+	}
+
+	if s.hasher.NeedsRehash(user.HashedPassword) {
+		// неудачный пересчет хэша не должен мешать входу: попробуем при следующем
+		err = s.rehash(ctx, user, password)
+		if err != nil {
+			slog.Warn("rehashing password", "user", user.Id, "error", err)
+		}
+	}
+
+	return user, nil
 }
 
-func hashAndSalt(password []byte) ([]byte, error) {
-	hash, err := bcrypt.GenerateFromPassword(password, 14)
+func (s AuthService) rehash(ctx context.Context, user *models.User, password string) error {
+	hashedPassword, err := s.hasher.Hash([]byte(password))
 	if err != nil {
-		return nil, fmt.Errorf("hashing password: %v", err)
+		return fmt.Errorf("hashing password: %v", err)
 	}
 
-	return hash, nil
+	err = s.storage.UpdatePasswordHash(ctx, user.Id, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("updating password hash: %v", err)
+	}
+
+	user.HashedPassword = hashedPassword
+	return nil
 }
 
-func checkHash(pwd, hashedPwd []byte) error {
-	err := bcrypt.CompareHashAndPassword(hashedPwd, pwd)
+// Проверяет, что токен, выпущенный в issuedAt, не отозван:
+// пользователь существует, не заблокирован и не менял пароль после выпуска токена
+func (s AuthService) ValidateSession(ctx context.Context, userId string, issuedAt time.Time) error {
+	user, err := s.storage.GetById(ctx, userId)
 	if err != nil {
-		return fmt.Errorf("comparing passwords: %v", err)
+		if errors.Is(err, models.UserNotFound) {
+			return models.SessionRevoked
+		}
+
+		return fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	if user.Disabled {
+		return models.SessionRevoked
+	}
+
+	// время выпуска токена хранится с точностью до секунды
+	if issuedAt.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return models.SessionRevoked
 	}
 
 	return nil
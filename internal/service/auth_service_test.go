@@ -5,25 +5,49 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/Lucky112/social/config"
 	"github.com/Lucky112/social/internal/models"
 	"github.com/Lucky112/social/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// Дешевые параметры argon2id, чтобы не замедлять тесты
+var testHasher = NewPasswordHasher(&config.PasswordHashConfig{
+	Memory:      8192,
+	Iterations:  1,
+	Parallelism: 1,
+})
+
+// Политика по умолчанию
+var testPolicy, _ = NewCredentialsPolicy(nil)
+
+var testAuthConfig = &config.AuthConfig{
+	TOTPKey:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+	TOTPIssuer: "social",
+}
+
 func TestAuth(t *testing.T) {
-	storage := mocks.NewAuthStorage(t)
-	authService := NewAuthService(storage)
+	storage := mocks.NewUsersStorage(t)
+	outbox := mocks.NewOutboxStorage(t)
+	authService, err := NewAuthService(storage, outbox, fakeTxManager{}, testHasher, testPolicy, testAuthConfig)
+	assert.NoError(t, err)
 
 	t.Run("test NewUser", func(t *testing.T) {
-		storage.On("Exists", mock.Anything, mock.Anything).Return(false, nil).Once()
 		storage.On("Add", mock.Anything, mock.Anything).Return("1", nil).Once()
+		outbox.On("Add", mock.Anything, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventUserRegistered && e.AggregateType == models.AggregateUser && e.AggregateId == "1" &&
+				string(e.Payload) == `{"user_id":"1","login":"login","email":"user@example.com"}`
+		})).Return(nil).Once()
 
 		user := &models.User{
-			Email:    "email",
+			Email:    "user@example.com",
 			Login:    "login",
-			Password: "pwd",
+			Password: "correct horse battery",
 		}
 
 		id, err := authService.NewUser(context.Background(), user)
@@ -31,24 +55,45 @@ func TestAuth(t *testing.T) {
 		assert.Equal(t, "1", id)
 	})
 
+	t.Run("test NewUser normalizes identifiers", func(t *testing.T) {
+		storage.On("Add", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "bob@example.com" && u.Login == "bob"
+		})).Return("1", nil).Once()
+		outbox.On("Add", mock.Anything, mock.Anything).Return(nil).Once()
+
+		user := &models.User{
+			Email:    " Bob@Example.COM",
+			Login:    "Ｂｏｂ",
+			Password: "correct horse battery",
+		}
+
+		_, err := authService.NewUser(context.Background(), user)
+		assert.NoError(t, err)
+	})
+
 	t.Run("test Register again", func(t *testing.T) {
-		storage.On("Exists", mock.Anything, mock.Anything).Return(true, nil).Once()
+		storage.On("Add", mock.Anything, mock.Anything).
+			Return("", fmt.Errorf("inserting into db: %w", &models.UserConflictError{Field: "email"})).Once()
 
 		user := &models.User{
-			Email:    "email",
+			Email:    "user@example.com",
 			Login:    "login",
-			Password: "pwd",
+			Password: "correct horse battery",
 		}
 
 		_, err := authService.NewUser(context.Background(), user)
 		assert.ErrorIs(t, err, models.UserAlreadyExists)
+
+		var conflict *models.UserConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "email", conflict.Field)
 	})
 
 	t.Run("test Login successfully", func(t *testing.T) {
 		userId := "2"
 		login := "login"
 		password := "pwd"
-		hashedPwd, _ := hashAndSalt([]byte(password))
+		hashedPwd, _ := testHasher.Hash([]byte(password))
 
 		user := &models.User{
 			Id:             userId,
@@ -59,16 +104,78 @@ func TestAuth(t *testing.T) {
 
 		storage.On("Get", mock.Anything, "login").Return(user, nil).Once()
 
-		id, err := authService.Login(context.Background(), login, password)
+		actual, err := authService.Login(context.Background(), login, password)
+		assert.NoError(t, err)
+		assert.Equal(t, userId, actual.Id)
+	})
+
+	t.Run("test Login by email in any case", func(t *testing.T) {
+		password := "pwd"
+		hashedPwd, _ := testHasher.Hash([]byte(password))
+
+		user := &models.User{
+			Id:             "4",
+			Email:          "bob@example.com",
+			Login:          "bob",
+			HashedPassword: hashedPwd,
+		}
+
+		storage.On("Get", mock.Anything, "bob@example.com").Return(user, nil).Once()
+
+		actual, err := authService.Login(context.Background(), "BOB@example.com", password)
+		assert.NoError(t, err)
+		assert.Equal(t, "4", actual.Id)
+	})
+
+	t.Run("test Login with legacy bcrypt hash", func(t *testing.T) {
+		login := "legacy"
+		password := "pwd"
+		legacyHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		user := &models.User{
+			Id:             "8",
+			Login:          login,
+			HashedPassword: legacyHash,
+		}
+
+		var newHash []byte
+
+		storage.On("Get", mock.Anything, login).Return(user, nil).Once()
+		storage.On("UpdatePasswordHash", mock.Anything, "8", mock.Anything).
+			Run(func(args mock.Arguments) { newHash = args.Get(2).([]byte) }).
+			Return(nil).Once()
+
+		_, err = authService.Login(context.Background(), login, password)
+		assert.NoError(t, err)
+		assert.False(t, testHasher.NeedsRehash(newHash))
+	})
+
+	t.Run("test Login with failed rehash", func(t *testing.T) {
+		login := "legacy"
+		password := "pwd"
+		legacyHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		user := &models.User{
+			Id:             "8",
+			Login:          login,
+			HashedPassword: legacyHash,
+		}
+
+		storage.On("Get", mock.Anything, login).Return(user, nil).Once()
+		storage.On("UpdatePasswordHash", mock.Anything, "8", mock.Anything).Return(errors.New("storage error")).Once()
+
+		actual, err := authService.Login(context.Background(), login, password)
 		assert.NoError(t, err)
-		assert.Equal(t, userId, id)
+		assert.Equal(t, "8", actual.Id)
 	})
 
 	t.Run("test Login with wrong password", func(t *testing.T) {
 		userId := "2"
 		login := "login"
 		password := "pwd"
-		hashedPwd, _ := hashAndSalt([]byte(password))
+		hashedPwd, _ := testHasher.Hash([]byte(password))
 
 		user := &models.User{
 			Id:             userId,
@@ -83,6 +190,24 @@ func TestAuth(t *testing.T) {
 		assert.ErrorIs(t, err, models.UserBadCredentials)
 	})
 
+	t.Run("test Login disabled user", func(t *testing.T) {
+		login := "disabled"
+		password := "pwd"
+		hashedPwd, _ := testHasher.Hash([]byte(password))
+
+		user := &models.User{
+			Id:             "4",
+			Login:          login,
+			HashedPassword: hashedPwd,
+			Disabled:       true,
+		}
+
+		storage.On("Get", mock.Anything, login).Return(user, nil).Once()
+
+		_, err := authService.Login(context.Background(), login, password)
+		assert.ErrorIs(t, err, models.UserDisabled)
+	})
+
 	t.Run("test Login with unknown login", func(t *testing.T) {
 		login := "unknown login"
 
@@ -92,25 +217,79 @@ func TestAuth(t *testing.T) {
 		assert.ErrorIs(t, err, models.UserNotFound)
 	})
 
-	t.Run("test NewUser storage errors", func(t *testing.T) {
+	t.Run("test NewUser policy violations", func(t *testing.T) {
+		user := &models.User{
+			Email:    "not an email",
+			Login:    "lo",
+			Password: "password",
+		}
+
+		_, err := authService.NewUser(context.Background(), user)
+
+		var verr *models.ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Len(t, verr.Fields, 3)
+	})
+
+	t.Run("test NewUser storage error", func(t *testing.T) {
 		user := &models.User{
-			Email:    "email",
+			Email:    "user@example.com",
 			Login:    "login",
-			Password: "pwd",
+			Password: "correct horse battery",
 		}
 
-		storage.On("Exists", mock.Anything, mock.Anything).Return(false, errors.New("storage error")).Once()
+		storage.On("Add", mock.Anything, mock.Anything).Return("", errors.New("storage error")).Once()
 
 		_, err := authService.NewUser(context.Background(), user)
 		assert.Error(t, err)
+		assert.NotErrorIs(t, err, models.UserAlreadyExists)
+	})
+
+	t.Run("test NewUser outbox error", func(t *testing.T) {
+		user := &models.User{
+			Email:    "user@example.com",
+			Login:    "login",
+			Password: "correct horse battery",
+		}
 
-		storage.On("Exists", mock.Anything, mock.Anything).Return(false, nil).Once()
-		storage.On("Add", mock.Anything, mock.Anything).Return("1", errors.New("storage error")).Once()
+		storage.On("Add", mock.Anything, mock.Anything).Return("1", nil).Once()
+		outbox.On("Add", mock.Anything, mock.Anything).Return(errors.New("storage error")).Once()
 
-		_, err = authService.NewUser(context.Background(), user)
+		// без события регистрация не должна состояться: ошибка откатывает транзакцию
+		_, err := authService.NewUser(context.Background(), user)
 		assert.Error(t, err)
 	})
 
+	t.Run("test ValidateSession", func(t *testing.T) {
+		changedAt := time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)
+		user := &models.User{Id: "5", TokensValidAfter: changedAt}
+
+		storage.On("GetById", mock.Anything, "5").Return(user, nil).Times(3)
+
+		err := authService.ValidateSession(context.Background(), "5", changedAt.Add(-time.Minute))
+		assert.ErrorIs(t, err, models.SessionRevoked)
+
+		err = authService.ValidateSession(context.Background(), "5", changedAt.Truncate(time.Second))
+		assert.NoError(t, err)
+
+		err = authService.ValidateSession(context.Background(), "5", changedAt.Add(time.Minute))
+		assert.NoError(t, err)
+	})
+
+	t.Run("test ValidateSession of disabled user", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, "6").Return(&models.User{Id: "6", Disabled: true}, nil).Once()
+
+		err := authService.ValidateSession(context.Background(), "6", time.Now())
+		assert.ErrorIs(t, err, models.SessionRevoked)
+	})
+
+	t.Run("test ValidateSession of deleted user", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, "7").Return(nil, fmt.Errorf("%w", models.UserNotFound)).Once()
+
+		err := authService.ValidateSession(context.Background(), "7", time.Now())
+		assert.ErrorIs(t, err, models.SessionRevoked)
+	})
+
 	t.Run("test Login storage error", func(t *testing.T) {
 		login := "login"
 
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Симметричное шифрование секретов, хранящихся в базе (AES-256-GCM).
+// Зашифрованное значение имеет вид nonce || ciphertext
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+func newSecretBox(key []byte) (secretBox, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return secretBox{}, fmt.Errorf("creating aes cipher: %v", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return secretBox{}, fmt.Errorf("creating gcm: %v", err)
+	}
+
+	return secretBox{aead}, nil
+}
+
+func (b secretBox) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("generating nonce: %v", err)
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (b secretBox) open(sealed []byte) ([]byte, error) {
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("sealed value is too short")
+	}
+
+	plaintext, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %v", err)
+	}
+
+	return plaintext, nil
+}
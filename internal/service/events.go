@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Хранилище исходящих событий (outbox). События записываются в транзакции изменения,
+// которое их вызвало, а публикуются позже, см. events.Relay
+type OutboxStorage interface {
+	Add(ctx context.Context, event *models.Event) error
+}
+
+// Записывает событие в outbox. Должна вызываться внутри TxManager.InTx вместе с изменением
+func recordEvent(ctx context.Context, outbox OutboxStorage, eventType models.EventType, aggregateType, aggregateId string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling %s payload: %v", eventType, err)
+	}
+
+	err = outbox.Add(ctx, &models.Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		Payload:       data,
+		OccurredAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("recording %s event: %v", eventType, err)
+	}
+
+	return nil
+}
+
+// Записывает событие ProfileUpdated об изменении анкеты. Должна вызываться внутри TxManager.InTx
+// вместе с изменением
+func recordProfileUpdated(ctx context.Context, outbox OutboxStorage, profile *models.Profile, changed ...string) error {
+	return recordEvent(ctx, outbox, models.EventProfileUpdated, models.AggregateProfile, profile.Id, models.ProfilePayload{
+		ProfileId: profile.Id,
+		UserId:    profile.UserId,
+		Name:      profile.Name,
+		Surname:   profile.Surname,
+		Changed:   changed,
+	})
+}
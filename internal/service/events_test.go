@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/events"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/photos"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+)
+
+// Публикатор, запоминающий события
+type recordingPublisher struct {
+	published []*models.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *models.Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestProfileUpdatedEvents(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	profiles := inmemory.NewProfilesStorage(db)
+	privacy := inmemory.NewPrivacyStorage(db)
+	outbox := inmemory.NewOutboxStorage(db)
+	tx := inmemory.NewTxManager(db)
+
+	profilesService := NewProfilesService(profiles, nil, privacy, inmemory.NewInterestsStorage(db), nil, outbox, tx)
+	photosService := NewPhotosService(inmemory.NewPhotosStorage(db), profiles, privacy, nil, outbox, tx, blob.NewLocalStore(t.TempDir()), photos.NewProcessor(testPhotosConfig))
+
+	owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+
+	id, err := profilesService.Add(ctx, &models.Profile{UserId: owner.UserId, Name: "Ivan", Surname: "Petrov", Hobbies: "chess"})
+	require.NoError(t, err)
+
+	_, err = profilesService.SetPrivacy(ctx, owner, id, map[models.ProfileField]models.Visibility{models.FieldCity: models.VisibilityHidden})
+	require.NoError(t, err)
+
+	_, err = photosService.Upload(ctx, owner, id, testPNG(t))
+	require.NoError(t, err)
+
+	t.Run("test failed changes record no events", func(t *testing.T) {
+		other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+		_, err := profilesService.SetPrivacy(ctx, other, id, nil)
+		assert.ErrorIs(t, err, models.NotProfileOwner)
+
+		_, err = photosService.Upload(ctx, owner, id, []byte("GIF89a"))
+		assert.ErrorIs(t, err, models.UnsupportedImage)
+
+		assert.Len(t, outbox.Events(ctx), 3)
+	})
+
+	t.Run("test relay publishes updates after creation", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		relay := events.NewRelay(outbox, tx, publisher, events.RelayConfig{BatchSize: 10, MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour})
+
+		n, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		var types []models.EventType
+		var changed [][]string
+
+		for _, e := range publisher.published {
+			assert.Equal(t, models.AggregateProfile, e.AggregateType)
+			assert.Equal(t, id, e.AggregateId)
+
+			var payload models.ProfilePayload
+			require.NoError(t, json.Unmarshal(e.Payload, &payload))
+			assert.Equal(t, id, payload.ProfileId)
+			assert.Equal(t, owner.UserId, payload.UserId)
+
+			types = append(types, e.Type)
+			changed = append(changed, payload.Changed)
+		}
+
+		assert.Equal(t, []models.EventType{models.EventProfileCreated, models.EventProfileUpdated, models.EventProfileUpdated}, types)
+		assert.Equal(t, [][]string{nil, {models.ProfileChangePrivacy}, {models.ProfileChangePhotos}}, changed)
+		assert.Empty(t, outbox.Events(ctx))
+	})
+}
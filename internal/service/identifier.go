@@ -0,0 +1,13 @@
+package service
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Приводит логин или email к канонической форме, в которой они хранятся и сравниваются:
+// Unicode NFKC и нижний регистр. Так "Bob", "BOB" и "Ｂｏｂ" считаются одним идентификатором
+func normalizeIdentifier(identifier string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimSpace(identifier)))
+}
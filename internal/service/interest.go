@@ -0,0 +1,46 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Разделитель интересов в тексте hobbies, например "Чтение, сериалы"
+const hobbiesSeparator = ","
+
+// Приводит интерес к канонической форме, в которой он хранится и сравнивается:
+// Unicode NFKC, нижний регистр и одиночные пробелы между словами.
+// Так же интересы нормализует миграция 014_interests
+func normalizeInterest(interest string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFKC.String(interest))), " ")
+}
+
+// Нормализованные интересы без пустых, слишком длинных и повторяющихся, в исходном порядке
+func normalizeInterests(interests []string) []string {
+	var res []string
+
+	for _, interest := range interests {
+		interest = normalizeInterest(interest)
+		if interest == "" || utf8.RuneCountInString(interest) > models.MaxInterestLength || slices.Contains(res, interest) {
+			continue
+		}
+
+		res = append(res, interest)
+	}
+
+	return res
+}
+
+// Интересы анкеты: указанные явно, а если их нет - части hobbies
+func interestsOf(profile *models.Profile) []string {
+	if len(profile.Interests) > 0 {
+		return normalizeInterests(profile.Interests)
+	}
+
+	return normalizeInterests(strings.Split(profile.Hobbies, hobbiesSeparator))
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис интересов анкет
+type InterestsService struct {
+	storage InterestsStorage
+}
+
+func NewInterestsService(storage InterestsStorage) InterestsService {
+	return InterestsService{
+		storage: storage,
+	}
+}
+
+// Не более limit интересов, начинающихся с prefix, начиная с самых популярных.
+// Учитываются только анкеты, увлечения в которых видны пользователю viewer
+func (s InterestsService) Autocomplete(ctx context.Context, viewer models.Actor, prefix string, limit int) ([]models.InterestCount, error) {
+	prefix = normalizeInterest(prefix)
+
+	interests, err := s.storage.Autocomplete(ctx, prefix, models.VisibilitiesTo(accessOf(viewer, "")), limit)
+	if err != nil {
+		return nil, fmt.Errorf("autocompleting interests by '%s': %v", prefix, err)
+	}
+
+	return interests, nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Lucky112/social/config"
+)
+
+// Параметры argon2id по умолчанию (рекомендации OWASP)
+const (
+	defaultArgon2Memory      = 19 * 1024
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+const argon2Prefix = "$argon2id$"
+
+var bcryptPrefixes = [][]byte{[]byte("$2a$"), []byte("$2b$"), []byte("$2y$")}
+
+// Хэширование и проверка паролей
+type PasswordHasher interface {
+	Hash(password []byte) ([]byte, error)
+	Verify(password, hash []byte) (bool, error)
+	// Сообщает, что хэш получен устаревшим алгоритмом или с устаревшими параметрами
+	NeedsRehash(hash []byte) bool
+}
+
+// Хэширует пароли с помощью argon2id.
+// Также умеет проверять хэши bcrypt, которые использовались раньше
+type argon2Hasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func NewPasswordHasher(cfg *config.PasswordHashConfig) PasswordHasher {
+	h := argon2Hasher{
+		memory:      defaultArgon2Memory,
+		iterations:  defaultArgon2Iterations,
+		parallelism: defaultArgon2Parallelism,
+	}
+
+	if cfg == nil {
+		return h
+	}
+
+	if cfg.Memory != 0 {
+		h.memory = cfg.Memory
+	}
+	if cfg.Iterations != 0 {
+		h.iterations = cfg.Iterations
+	}
+	if cfg.Parallelism != 0 {
+		h.parallelism = cfg.Parallelism
+	}
+
+	return h
+}
+
+// Возвращает хэш в формате PHC: $argon2id$v=19$m=...,t=...,p=...$salt$key
+func (h argon2Hasher) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %v", err)
+	}
+
+	key := argon2.IDKey(password, salt, h.iterations, h.memory, h.parallelism, argon2KeyLength)
+
+	encoded := fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		h.memory,
+		h.iterations,
+		h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(encoded), nil
+}
+
+func (h argon2Hasher) Verify(password, hash []byte) (bool, error) {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword(hash, password)
+		if err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("comparing bcrypt hash: %v", err)
+		}
+
+		return true, nil
+	}
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false, fmt.Errorf("decoding argon2id hash: %v", err)
+	}
+
+	actual := argon2.IDKey(password, salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (h argon2Hasher) NeedsRehash(hash []byte) bool {
+	params, _, _, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+
+	return params != h
+}
+
+func isBcrypt(hash []byte) bool {
+	for _, prefix := range bcryptPrefixes {
+		if bytes.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeArgon2(hash []byte) (argon2Hasher, []byte, []byte, error) {
+	var params argon2Hasher
+
+	encoded := string(hash)
+	if !strings.HasPrefix(encoded, argon2Prefix) {
+		return params, nil, nil, errors.New("unknown hash algorithm")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(encoded, argon2Prefix), "$")
+	if len(parts) != 4 {
+		return params, nil, nil, fmt.Errorf("expected 4 hash parts, got %d", len(parts))
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[0], "v=%d", &version)
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("parsing version: %v", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	_, err = fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("parsing parameters: %v", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("decoding salt: %v", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("decoding key: %v", err)
+	}
+
+	return params, salt, key, nil
+}
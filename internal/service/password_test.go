@@ -0,0 +1,83 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Lucky112/social/config"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	hasher := NewPasswordHasher(&config.PasswordHashConfig{
+		Memory:      8192,
+		Iterations:  1,
+		Parallelism: 1,
+	})
+
+	t.Run("argon2id round trip", func(t *testing.T) {
+		hash, err := hasher.Hash([]byte("pwd"))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=8192,t=1,p=1$"))
+
+		ok, err := hasher.Verify([]byte("pwd"), hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = hasher.Verify([]byte("wrong"), hash)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		assert.False(t, hasher.NeedsRehash(hash))
+	})
+
+	t.Run("long passwords are not truncated", func(t *testing.T) {
+		long := strings.Repeat("a", 100)
+
+		hash, err := hasher.Hash([]byte(long))
+		require.NoError(t, err)
+
+		ok, err := hasher.Verify([]byte(long[:72]), hash)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("legacy bcrypt hash", func(t *testing.T) {
+		hash, err := bcrypt.GenerateFromPassword([]byte("pwd"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		ok, err := hasher.Verify([]byte("pwd"), hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = hasher.Verify([]byte("wrong"), hash)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		assert.True(t, hasher.NeedsRehash(hash))
+	})
+
+	t.Run("changed parameters", func(t *testing.T) {
+		hash, err := hasher.Hash([]byte("pwd"))
+		require.NoError(t, err)
+
+		stronger := NewPasswordHasher(&config.PasswordHashConfig{
+			Memory:      16384,
+			Iterations:  1,
+			Parallelism: 1,
+		})
+
+		ok, err := stronger.Verify([]byte("pwd"), hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, stronger.NeedsRehash(hash))
+	})
+
+	t.Run("malformed hash", func(t *testing.T) {
+		_, err := hasher.Verify([]byte("pwd"), []byte("$argon2id$garbage"))
+		assert.Error(t, err)
+	})
+}
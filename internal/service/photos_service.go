@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/photos"
+)
+
+// Длина случайной части ключей файлов фотографии, чтобы ключи нельзя было подобрать и не было коллизий
+const photoKeyBytes = 16
+
+// Сервис фотографий анкет: проверяет и уменьшает загруженные изображения,
+// сохраняет их в хранилище файлов, а метаданные - в базе
+type PhotosService struct {
+	storage   PhotosStorage
+	profiles  ProfilesStorage
+	privacy   PrivacyStorage
+	cache     ProfilesCache
+	outbox    OutboxStorage
+	tx        TxManager
+	blobs     BlobStore
+	processor photos.Processor
+}
+
+// Метаданные фотографий анкет
+type PhotosStorage interface {
+	AvatarsStorage
+	Add(ctx context.Context, photo *models.Photo) (string, error)
+	Get(ctx context.Context, id string) (*models.Photo, error)
+	GetByUser(ctx context.Context, userId string) ([]*models.Photo, error)
+}
+
+// Аватары анкет - их последние фотографии
+type AvatarsStorage interface {
+	Avatars(ctx context.Context, profileIds []string) (map[string]*models.Photo, error)
+}
+
+// Хранилище файлов. Для отсутствующего файла Get возвращает blob.ErrNotFound
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// cache - кэш анкет ProfilesService, из которого удаляется анкета с новым аватаром. Может быть не задан
+func NewPhotosService(storage PhotosStorage, profiles ProfilesStorage, privacy PrivacyStorage, cache ProfilesCache, outbox OutboxStorage, tx TxManager, blobs BlobStore, processor photos.Processor) PhotosService {
+	return PhotosService{
+		storage:   storage,
+		profiles:  profiles,
+		privacy:   privacy,
+		cache:     cache,
+		outbox:    outbox,
+		tx:        tx,
+		blobs:     blobs,
+		processor: processor,
+	}
+}
+
+// Загружает фотографию анкеты, которая становится ее аватаром. Загружать фотографии может
+// владелец анкеты или администратор. Метаданные вместе с событием ProfileUpdated пишутся после файлов,
+// поэтому фотография без файлов не появится; при ошибке записанные файлы удаляются
+func (s PhotosService) Upload(ctx context.Context, actor models.Actor, profileId string, data []byte) (*models.Photo, error) {
+	profile, err := s.profiles.Get(ctx, profileId)
+	if err != nil {
+		if errors.Is(err, models.ProfileNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("getting profile '%s': %v", profileId, err)
+	}
+
+	if !canManage(actor, profile.UserId) {
+		return nil, fmt.Errorf("uploading photo of profile '%s': %w", profileId, models.NotProfileOwner)
+	}
+
+	images, err := s.processor.Process(data)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, photoKeyBytes)
+	_, err = rand.Read(random)
+	if err != nil {
+		return nil, fmt.Errorf("generating photo key: %v", err)
+	}
+	prefix := fmt.Sprintf("photos/%s/%s/", profileId, hex.EncodeToString(random))
+
+	photo := &models.Photo{
+		ProfileId: profileId,
+		UserId:    profile.UserId,
+	}
+
+	for _, img := range images {
+		v := models.PhotoVariant{
+			Name:        img.Name,
+			Key:         prefix + img.Name + ".jpg",
+			ContentType: photos.ContentType,
+			Width:       img.Width,
+			Height:      img.Height,
+			Size:        int64(len(img.Data)),
+		}
+
+		err := s.blobs.Put(ctx, v.Key, img.Data, v.ContentType)
+		if err != nil {
+			s.deleteBlobs(ctx, photo.Variants)
+			return nil, fmt.Errorf("storing %s: %v", v.Name, err)
+		}
+
+		photo.Variants = append(photo.Variants, v)
+	}
+
+	var id string
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		id, err = s.storage.Add(ctx, photo)
+		if err != nil {
+			if errors.Is(err, models.ProfileNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("adding photo: %v", err)
+		}
+
+		return recordProfileUpdated(ctx, s.outbox, profile, models.ProfileChangePhotos)
+	})
+	if err != nil {
+		s.deleteBlobs(ctx, photo.Variants)
+		return nil, err
+	}
+
+	if s.cache != nil {
+		s.cache.Delete(ctx, profileId)
+	}
+
+	created, err := s.storage.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting created photo '%s': %v", id, err)
+	}
+
+	return created, nil
+}
+
+// Вариант фотографии и его содержимое. Неизвестный вариант, как и отсутствующая фотография
+// или фотография анкеты, в которой фотографии скрыты от viewer, - models.PhotoNotFound
+func (s PhotosService) Open(ctx context.Context, viewer models.Actor, photoId, variant string) (*models.PhotoVariant, []byte, error) {
+	photo, err := s.storage.Get(ctx, photoId)
+	if err != nil {
+		if errors.Is(err, models.PhotoNotFound) {
+			return nil, nil, err
+		}
+
+		return nil, nil, fmt.Errorf("getting photo '%s': %v", photoId, err)
+	}
+
+	privacy, err := s.privacy.Privacy(ctx, []string{photo.ProfileId})
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting privacy of profile '%s': %v", photo.ProfileId, err)
+	}
+
+	if !privacy[photo.ProfileId].Visibility(models.FieldPhotos).VisibleTo(accessOf(viewer, photo.UserId)) {
+		return nil, nil, fmt.Errorf("photos of profile '%s' are hidden: %w", photo.ProfileId, models.PhotoNotFound)
+	}
+
+	v, ok := photo.Variant(variant)
+	if !ok {
+		return nil, nil, fmt.Errorf("photo '%s' has no variant %s: %w", photoId, variant, models.PhotoNotFound)
+	}
+
+	data, err := s.blobs.Get(ctx, v.Key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, nil, fmt.Errorf("file of photo '%s': %v: %w", photoId, err, models.PhotoNotFound)
+		}
+
+		return nil, nil, fmt.Errorf("reading file of photo '%s': %v", photoId, err)
+	}
+
+	return &v, data, nil
+}
+
+// Удаляет файлы фотографии, которая не была сохранена. Ошибки только логируются:
+// исходная ошибка загрузки важнее, а оставшиеся файлы ни на что не влияют
+func (s PhotosService) deleteBlobs(ctx context.Context, variants []models.PhotoVariant) {
+	// файлы удаляются, даже если запрос загрузки отменен
+	ctx = context.WithoutCancel(ctx)
+
+	for _, v := range variants {
+		err := s.blobs.Delete(ctx, v.Key)
+		if err != nil {
+			slog.Warn("deleting file of failed upload", "key", v.Key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/photos"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+)
+
+var testPhotosConfig = photos.Config{
+	MaxBytes:  1 << 20,
+	MaxPixels: 1 << 20,
+	Sizes:     []photos.Size{{Name: "small", MaxSide: 16}},
+	Quality:   80,
+}
+
+// Хранилище файлов, отказывающее в записи варианта с указанным именем
+type failingBlobStore struct {
+	blob.LocalStore
+
+	failOn string
+}
+
+func (s failingBlobStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if filepath.Base(key) == s.failOn+".jpg" {
+		return errors.New("storage is full")
+	}
+
+	return s.LocalStore.Put(ctx, key, data, contentType)
+}
+
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+// Файлы в каталоге хранилища
+func storedFiles(t *testing.T, dir string) []string {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+
+	return files
+}
+
+func TestPhotosService(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	profiles := inmemory.NewProfilesStorage(db)
+	storage := inmemory.NewPhotosStorage(db)
+	privacy := inmemory.NewPrivacyStorage(db)
+	outbox := inmemory.NewOutboxStorage(db)
+	tx := inmemory.NewTxManager(db)
+	dir := t.TempDir()
+	service := NewPhotosService(storage, profiles, privacy, nil, outbox, tx, blob.NewLocalStore(dir), photos.NewProcessor(testPhotosConfig))
+
+	profileId, err := profiles.Add(ctx, &models.Profile{UserId: "1", Name: "Ivan"})
+	require.NoError(t, err)
+
+	owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+
+	t.Run("test Upload stores variants", func(t *testing.T) {
+		photo, err := service.Upload(ctx, owner, profileId, testPNG(t))
+		require.NoError(t, err)
+		assert.Equal(t, profileId, photo.ProfileId)
+		assert.Equal(t, "1", photo.UserId)
+		require.Len(t, photo.Variants, 2)
+
+		small, ok := photo.Variant("small")
+		require.True(t, ok)
+		assert.Equal(t, 16, small.Width)
+		assert.Equal(t, 8, small.Height)
+
+		v, data, err := service.Open(ctx, owner, photo.Id, "small")
+		require.NoError(t, err)
+		assert.Equal(t, small, *v)
+		assert.Equal(t, "image/jpeg", v.ContentType)
+		assert.EqualValues(t, len(data), v.Size)
+
+		avatars, err := storage.Avatars(ctx, []string{profileId})
+		require.NoError(t, err)
+		assert.Equal(t, photo.Id, avatars[profileId].Id)
+	})
+
+	t.Run("test Upload checks owner", func(t *testing.T) {
+		_, err := service.Upload(ctx, models.Actor{UserId: "2", Roles: []role.Role{role.User}}, profileId, testPNG(t))
+		assert.ErrorIs(t, err, models.NotProfileOwner)
+
+		_, err = service.Upload(ctx, models.Actor{UserId: "2", Roles: []role.Role{role.Admin}}, profileId, testPNG(t))
+		assert.NoError(t, err)
+	})
+
+	t.Run("test Upload errors", func(t *testing.T) {
+		_, err := service.Upload(ctx, owner, "100", testPNG(t))
+		assert.ErrorIs(t, err, models.ProfileNotFound)
+
+		_, err = service.Upload(ctx, owner, profileId, []byte("GIF89a"))
+		assert.ErrorIs(t, err, models.UnsupportedImage)
+	})
+
+	t.Run("test Upload removes files on failure", func(t *testing.T) {
+		dir := t.TempDir()
+		failing := NewPhotosService(storage, profiles, privacy, nil, outbox, tx, failingBlobStore{blob.NewLocalStore(dir), "small"}, photos.NewProcessor(testPhotosConfig))
+
+		_, err := failing.Upload(ctx, owner, profileId, testPNG(t))
+		assert.ErrorContains(t, err, "storage is full")
+		assert.Empty(t, storedFiles(t, dir))
+	})
+
+	t.Run("test Open errors", func(t *testing.T) {
+		_, _, err := service.Open(ctx, owner, "100", models.PhotoOriginal)
+		assert.ErrorIs(t, err, models.PhotoNotFound)
+
+		photo, err := service.Upload(ctx, owner, profileId, testPNG(t))
+		require.NoError(t, err)
+
+		_, _, err = service.Open(ctx, owner, photo.Id, "huge")
+		assert.ErrorIs(t, err, models.PhotoNotFound)
+
+		original, _ := photo.Variant(models.PhotoOriginal)
+		require.NoError(t, os.Remove(filepath.Join(dir, filepath.FromSlash(original.Key))))
+
+		_, _, err = service.Open(ctx, owner, photo.Id, models.PhotoOriginal)
+		assert.ErrorIs(t, err, models.PhotoNotFound)
+	})
+
+	t.Run("test Open hides photos of private profiles", func(t *testing.T) {
+		photo, err := service.Upload(ctx, owner, profileId, testPNG(t))
+		require.NoError(t, err)
+
+		other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+		_, _, err = service.Open(ctx, other, photo.Id, "small")
+		require.NoError(t, err)
+
+		err = privacy.SetPrivacy(ctx, profileId, map[models.ProfileField]models.Visibility{models.FieldPhotos: models.VisibilityHidden})
+		require.NoError(t, err)
+
+		_, _, err = service.Open(ctx, other, photo.Id, "small")
+		assert.ErrorIs(t, err, models.PhotoNotFound)
+
+		_, _, err = service.Open(ctx, owner, photo.Id, "small")
+		assert.NoError(t, err)
+	})
+}
+
+func TestProfilesServiceAvatars(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	profiles := inmemory.NewProfilesStorage(db)
+	photosStorage := inmemory.NewPhotosStorage(db)
+	service := NewProfilesService(profiles, photosStorage, nil, nil, nil, nil, fakeTxManager{})
+
+	withPhoto, err := profiles.Add(ctx, &models.Profile{UserId: "1", Name: "Ivan"})
+	require.NoError(t, err)
+	withoutPhoto, err := profiles.Add(ctx, &models.Profile{UserId: "1", Name: "Petr"})
+	require.NoError(t, err)
+
+	_, err = photosStorage.Add(ctx, &models.Photo{ProfileId: withPhoto, UserId: "1"})
+	require.NoError(t, err)
+	latest, err := photosStorage.Add(ctx, &models.Photo{ProfileId: withPhoto, UserId: "1"})
+	require.NoError(t, err)
+
+	viewer := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+	profile, err := service.Get(ctx, viewer, withPhoto)
+	require.NoError(t, err)
+	require.NotNil(t, profile.Avatar)
+	assert.Equal(t, latest, profile.Avatar.Id)
+
+	all, err := service.GetAll(ctx, viewer)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, latest, all[0].Avatar.Id)
+	assert.Equal(t, withoutPhoto, all[1].Id)
+	assert.Nil(t, all[1].Avatar)
+
+	found, err := service.Search(ctx, viewer, &models.SearchParams{NamePrefix: "Pe"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Nil(t, found[0].Avatar)
+}
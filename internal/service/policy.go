@@ -0,0 +1,219 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Параметры политики по умолчанию
+const (
+	defaultPasswordMinLength = 8
+	defaultPasswordMaxLength = 256
+	defaultLoginMinLength    = 3
+	defaultLoginMaxLength    = 50
+	defaultLoginPattern      = `^[a-zA-Z0-9._-]+$`
+
+	// Не больше размера колонки scl.users.email и предела из RFC 5321
+	emailMaxLength = 254
+)
+
+// Коды нарушений политики
+const (
+	violationRequired       = "required"
+	violationTooShort       = "too_short"
+	violationTooLong        = "too_long"
+	violationMissingUpper   = "missing_uppercase"
+	violationMissingLower   = "missing_lowercase"
+	violationMissingDigit   = "missing_digit"
+	violationMissingOther   = "missing_symbol"
+	violationCommonPassword = "common_password"
+	violationInvalidCharset = "invalid_charset"
+	violationInvalidEmail   = "invalid_email"
+)
+
+// Список распространенных и утекших паролей, по одному в строке в нижнем регистре
+//
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = sync.OnceValue(func() map[string]struct{} {
+	res := make(map[string]struct{})
+
+	for _, line := range strings.Split(commonPasswordsFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			res[line] = struct{}{}
+		}
+	}
+
+	return res
+})
+
+// Правила для логинов, email и паролей пользователей
+type CredentialsPolicy struct {
+	passwordMinLength    int
+	passwordMaxLength    int
+	passwordRequireUpper bool
+	passwordRequireLower bool
+	passwordRequireDigit bool
+	passwordRequireOther bool
+	allowCommonPasswords bool
+
+	loginMinLength int
+	loginMaxLength int
+	loginPattern   *regexp.Regexp
+}
+
+func NewCredentialsPolicy(cfg *config.PolicyConfig) (CredentialsPolicy, error) {
+	if cfg == nil {
+		cfg = &config.PolicyConfig{}
+	}
+
+	pattern := defaultLoginPattern
+	if cfg.LoginPattern != "" {
+		pattern = cfg.LoginPattern
+	}
+
+	loginPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		return CredentialsPolicy{}, fmt.Errorf("compiling login pattern: %v", err)
+	}
+
+	return CredentialsPolicy{
+		passwordMinLength:    valueOrDefault(cfg.PasswordMinLength, defaultPasswordMinLength),
+		passwordMaxLength:    valueOrDefault(cfg.PasswordMaxLength, defaultPasswordMaxLength),
+		passwordRequireUpper: cfg.PasswordRequireUpper,
+		passwordRequireLower: cfg.PasswordRequireLower,
+		passwordRequireDigit: cfg.PasswordRequireDigit,
+		passwordRequireOther: cfg.PasswordRequireOther,
+		allowCommonPasswords: cfg.AllowCommonPasswords,
+
+		loginMinLength: valueOrDefault(cfg.LoginMinLength, defaultLoginMinLength),
+		loginMaxLength: valueOrDefault(cfg.LoginMaxLength, defaultLoginMaxLength),
+		loginPattern:   loginPattern,
+	}, nil
+}
+
+// Проверяет данные нового пользователя.
+// Возвращает *models.ValidationError со всеми найденными нарушениями
+func (p CredentialsPolicy) CheckUser(user *models.User) error {
+	verr := &models.ValidationError{}
+
+	p.checkEmail(verr, "email", user.Email)
+	p.checkLogin(verr, "login", user.Login)
+	p.checkPassword(verr, "password", user.Password)
+
+	return verr.OrNil()
+}
+
+// Проверяет новый пароль, передаваемый в поле field
+func (p CredentialsPolicy) CheckPassword(field, password string) error {
+	verr := &models.ValidationError{}
+
+	p.checkPassword(verr, field, password)
+
+	return verr.OrNil()
+}
+
+func (p CredentialsPolicy) checkEmail(verr *models.ValidationError, field, email string) {
+	if email == "" {
+		verr.Add(field, violationRequired, "email is required")
+		return
+	}
+
+	if len(email) > emailMaxLength {
+		verr.Add(field, violationTooLong, fmt.Sprintf("email must be at most %d bytes long", emailMaxLength))
+		return
+	}
+
+	// адрес должен быть именно адресом, без отображаемого имени и комментариев
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		verr.Add(field, violationInvalidEmail, "email must be a valid RFC 5322 address")
+	}
+}
+
+func (p CredentialsPolicy) checkLogin(verr *models.ValidationError, field, login string) {
+	if login == "" {
+		verr.Add(field, violationRequired, "login is required")
+		return
+	}
+
+	length := utf8.RuneCountInString(login)
+	if length < p.loginMinLength {
+		verr.Add(field, violationTooShort, fmt.Sprintf("login must be at least %d characters long", p.loginMinLength))
+	}
+	if length > p.loginMaxLength {
+		verr.Add(field, violationTooLong, fmt.Sprintf("login must be at most %d characters long", p.loginMaxLength))
+	}
+
+	if !p.loginPattern.MatchString(login) {
+		verr.Add(field, violationInvalidCharset, fmt.Sprintf("login must match %s", p.loginPattern))
+	}
+}
+
+func (p CredentialsPolicy) checkPassword(verr *models.ValidationError, field, password string) {
+	if password == "" {
+		verr.Add(field, violationRequired, "password is required")
+		return
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.passwordMinLength {
+		verr.Add(field, violationTooShort, fmt.Sprintf("password must be at least %d characters long", p.passwordMinLength))
+	}
+	if length > p.passwordMaxLength {
+		verr.Add(field, violationTooLong, fmt.Sprintf("password must be at most %d characters long", p.passwordMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasOther bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasOther = true
+		}
+	}
+
+	if p.passwordRequireUpper && !hasUpper {
+		verr.Add(field, violationMissingUpper, "password must contain an uppercase letter")
+	}
+	if p.passwordRequireLower && !hasLower {
+		verr.Add(field, violationMissingLower, "password must contain a lowercase letter")
+	}
+	if p.passwordRequireDigit && !hasDigit {
+		verr.Add(field, violationMissingDigit, "password must contain a digit")
+	}
+	if p.passwordRequireOther && !hasOther {
+		verr.Add(field, violationMissingOther, "password must contain a symbol")
+	}
+
+	if !p.allowCommonPasswords {
+		_, common := commonPasswords()[strings.ToLower(password)]
+		if common {
+			verr.Add(field, violationCommonPassword, "password is too common")
+		}
+	}
+}
+
+func valueOrDefault(value, def int) int {
+	if value == 0 {
+		return def
+	}
+
+	return value
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violationCodes(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var verr *models.ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	codes := make([]string, len(verr.Fields))
+	for i, f := range verr.Fields {
+		codes[i] = f.Field + ":" + f.Code
+	}
+
+	return codes
+}
+
+func TestCredentialsPolicy(t *testing.T) {
+	policy, err := NewCredentialsPolicy(nil)
+	require.NoError(t, err)
+
+	valid := func() *models.User {
+		return &models.User{
+			Email:    "user@example.com",
+			Login:    "user.name_1",
+			Password: "correct horse battery",
+		}
+	}
+
+	t.Run("test valid user", func(t *testing.T) {
+		assert.NoError(t, policy.CheckUser(valid()))
+	})
+
+	t.Run("test emails", func(t *testing.T) {
+		cases := map[string]string{
+			"":                                 "email:required",
+			"plain":                            "email:invalid_email",
+			"a@b@example.com":                  "email:invalid_email",
+			"Bob <bob@example.com>":            "email:invalid_email",
+			strings.Repeat("a", 250) + "@x.io": "email:too_long",
+		}
+
+		for email, code := range cases {
+			user := valid()
+			user.Email = email
+			assert.Equal(t, []string{code}, violationCodes(t, policy.CheckUser(user)), email)
+		}
+
+		user := valid()
+		user.Email = "first.last+tag@sub.example.org"
+		assert.NoError(t, policy.CheckUser(user))
+	})
+
+	t.Run("test logins", func(t *testing.T) {
+		cases := map[string][]string{
+			"":                      {"login:required"},
+			"ab":                    {"login:too_short"},
+			strings.Repeat("a", 51): {"login:too_long"},
+			"bad login":             {"login:invalid_charset"},
+			"логин":                 {"login:invalid_charset"},
+		}
+
+		for login, codes := range cases {
+			user := valid()
+			user.Login = login
+			assert.Equal(t, codes, violationCodes(t, policy.CheckUser(user)), login)
+		}
+	})
+
+	t.Run("test passwords", func(t *testing.T) {
+		assert.Equal(t, []string{"pwd:too_short"}, violationCodes(t, policy.CheckPassword("pwd", "Ab1!")))
+		assert.Equal(t, []string{"pwd:too_long"}, violationCodes(t, policy.CheckPassword("pwd", strings.Repeat("x", 257))))
+		assert.Equal(t, []string{"pwd:common_password"}, violationCodes(t, policy.CheckPassword("pwd", "Password")))
+		assert.Equal(t, []string{"pwd:common_password"}, violationCodes(t, policy.CheckPassword("pwd", "qwerty123")))
+	})
+
+	t.Run("test character classes", func(t *testing.T) {
+		strict, err := NewCredentialsPolicy(&config.PolicyConfig{
+			PasswordMinLength:    10,
+			PasswordRequireUpper: true,
+			PasswordRequireLower: true,
+			PasswordRequireDigit: true,
+			PasswordRequireOther: true,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			[]string{"pwd:missing_uppercase", "pwd:missing_digit", "pwd:missing_symbol"},
+			violationCodes(t, strict.CheckPassword("pwd", "onlylowercase")),
+		)
+		assert.NoError(t, strict.CheckPassword("pwd", "Str0ng!Passw"))
+	})
+
+	t.Run("test common passwords allowed", func(t *testing.T) {
+		lax, err := NewCredentialsPolicy(&config.PolicyConfig{AllowCommonPasswords: true})
+		require.NoError(t, err)
+
+		assert.NoError(t, lax.CheckPassword("pwd", "password"))
+	})
+
+	t.Run("test invalid login pattern", func(t *testing.T) {
+		_, err := NewCredentialsPolicy(&config.PolicyConfig{LoginPattern: "("})
+		assert.Error(t, err)
+	})
+}
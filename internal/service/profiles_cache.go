@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/cache"
+)
+
+// Кэш анкет по id. Реализации должны быть безопасны для одновременного использования.
+// Ошибки кэша не должны мешать чтению из хранилища, поэтому методы их не возвращают
+type ProfilesCache interface {
+	Get(ctx context.Context, id string) (*models.Profile, bool)
+	Set(ctx context.Context, id string, profile *models.Profile)
+	Delete(ctx context.Context, id string)
+	// Удаляет все анкеты, когда неизвестно, какие из них изменились
+	Purge(ctx context.Context)
+}
+
+// Кэш анкет в памяти процесса
+type LRUProfilesCache struct {
+	lru *cache.LRU[string, *models.Profile]
+}
+
+func NewLRUProfilesCache(size int, ttl time.Duration) LRUProfilesCache {
+	return LRUProfilesCache{
+		lru: cache.NewLRU[string, *models.Profile](size, ttl),
+	}
+}
+
+func (c LRUProfilesCache) Get(ctx context.Context, id string) (*models.Profile, bool) {
+	return c.lru.Get(id)
+}
+
+func (c LRUProfilesCache) Set(ctx context.Context, id string, profile *models.Profile) {
+	c.lru.Set(id, profile)
+}
+
+func (c LRUProfilesCache) Delete(ctx context.Context, id string) {
+	c.lru.Delete(id)
+}
+
+func (c LRUProfilesCache) Purge(ctx context.Context) {
+	c.lru.Purge()
+}
+
+func (c LRUProfilesCache) Stats() cache.Stats {
+	return c.lru.Stats()
+}
+
+// Хранилище анкет, сбрасывающее кэш при удалении анкет. Сам кэш заполняет ProfilesService.Get
+// анкетами вместе с аватарами, интересами и настройками видимости, поэтому другие сервисы
+// читают анкеты из хранилища напрямую.
+// Запись, прочитанная конкурентным запросом до фиксации транзакции удаления, живет в кэше не дольше TTL
+type cachedProfilesStorage struct {
+	ProfilesStorage
+
+	cache ProfilesCache
+}
+
+func newCachedProfilesStorage(storage ProfilesStorage, cache ProfilesCache) ProfilesStorage {
+	if cache == nil {
+		return storage
+	}
+
+	return cachedProfilesStorage{
+		ProfilesStorage: storage,
+		cache:           cache,
+	}
+}
+
+func (s cachedProfilesStorage) Delete(ctx context.Context, id string) error {
+	err := s.ProfilesStorage.Delete(ctx, id)
+	s.cache.Delete(ctx, id)
+
+	return err
+}
+
+// id анкет пользователя неизвестны, поэтому кэш сбрасывается целиком: удаление аккаунта - редкая операция
+func (s cachedProfilesStorage) DeleteByUser(ctx context.Context, userId string) error {
+	err := s.ProfilesStorage.DeleteByUser(ctx, userId)
+	s.cache.Purge(ctx)
+
+	return err
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/photos"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestCachedProfilesStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test Delete invalidates the profile", func(t *testing.T) {
+		profiles := mocks.NewProfilesStorage(t)
+		cache := NewLRUProfilesCache(10, time.Minute)
+		storage := newCachedProfilesStorage(profiles, cache)
+
+		profiles.On("Delete", mock.Anything, "1").Return(nil).Once()
+
+		cache.Set(ctx, "1", &models.Profile{Name: "name"})
+
+		err := storage.Delete(ctx, "1")
+		require.NoError(t, err)
+
+		_, ok := cache.Get(ctx, "1")
+		assert.False(t, ok)
+	})
+
+	t.Run("test DeleteByUser purges the cache", func(t *testing.T) {
+		profiles := mocks.NewProfilesStorage(t)
+		cache := NewLRUProfilesCache(10, time.Minute)
+		storage := newCachedProfilesStorage(profiles, cache)
+
+		profiles.On("DeleteByUser", mock.Anything, "2").Return(errors.New("db error")).Once()
+
+		cache.Set(ctx, "1", &models.Profile{Name: "name"})
+
+		err := storage.DeleteByUser(ctx, "2")
+		assert.Error(t, err)
+		assert.Equal(t, 0, cache.Stats().Size)
+	})
+
+	t.Run("test storage without cache", func(t *testing.T) {
+		profiles := mocks.NewProfilesStorage(t)
+		assert.Equal(t, ProfilesStorage(profiles), newCachedProfilesStorage(profiles, nil))
+	})
+}
+
+// Настройки видимости, считающие запросы
+type countingPrivacyStorage struct {
+	PrivacyStorage
+
+	queries atomic.Int64
+}
+
+func (s *countingPrivacyStorage) Privacy(ctx context.Context, profileIds []string) (map[string]*models.Privacy, error) {
+	s.queries.Add(1)
+	return s.PrivacyStorage.Privacy(ctx, profileIds)
+}
+
+func TestProfilesServiceCache(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	profiles := inmemory.NewProfilesStorage(db)
+	photosStorage := inmemory.NewPhotosStorage(db)
+	privacy := &countingPrivacyStorage{PrivacyStorage: inmemory.NewPrivacyStorage(db)}
+	outbox := inmemory.NewOutboxStorage(db)
+	tx := inmemory.NewTxManager(db)
+	cache := NewLRUProfilesCache(10, time.Minute)
+
+	service := NewProfilesService(newCachedProfilesStorage(profiles, cache), photosStorage, privacy, inmemory.NewInterestsStorage(db), cache, outbox, tx)
+	photosService := NewPhotosService(photosStorage, profiles, privacy, cache, outbox, tx, blob.NewLocalStore(t.TempDir()), photos.NewProcessor(testPhotosConfig))
+
+	owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+	other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+	id, err := service.Add(ctx, &models.Profile{UserId: owner.UserId, Name: "Ivan", Address: "Moscow", Hobbies: "chess"})
+	require.NoError(t, err)
+
+	t.Run("test Get caches the profile with details", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			profile, err := service.Get(ctx, other, id)
+			require.NoError(t, err)
+			assert.Equal(t, "Moscow", profile.Address)
+			assert.Equal(t, []string{"chess"}, profile.Interests)
+		}
+
+		// настройки видимости читаются только при первом запросе
+		assert.EqualValues(t, 1, privacy.queries.Load())
+		assert.EqualValues(t, 2, cache.Stats().Hits)
+	})
+
+	t.Run("test Get does not cache errors", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := service.Get(ctx, other, "100")
+			assert.ErrorIs(t, err, models.ProfileNotFound)
+		}
+
+		_, ok := cache.Get(ctx, "100")
+		assert.False(t, ok)
+	})
+
+	t.Run("test SetPrivacy invalidates the profile", func(t *testing.T) {
+		_, err := service.SetPrivacy(ctx, owner, id, map[models.ProfileField]models.Visibility{models.FieldCity: models.VisibilityHidden})
+		require.NoError(t, err)
+
+		profile, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+		assert.Empty(t, profile.Address)
+	})
+
+	t.Run("test Upload invalidates the profile", func(t *testing.T) {
+		_, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+
+		photo, err := photosService.Upload(ctx, owner, id, testPNG(t))
+		require.NoError(t, err)
+
+		profile, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+		require.NotNil(t, profile.Avatar)
+		assert.Equal(t, photo.Id, profile.Avatar.Id)
+	})
+}
@@ -2,12 +2,30 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/pkg/coalesce"
 )
 
+// Одновременные одинаковые запросы Get и Search выполняются в хранилище один раз,
+// а результат отдается всем вызывающим; Get к тому же кэширует анкеты вместе с аватарами,
+// интересами и настройками видимости. Поэтому анкеты из хранилища нельзя изменять:
+// поля, скрытые от пользователя, очищаются в копиях
 type ProfilesService struct {
-	storage ProfilesStorage
+	storage   ProfilesStorage
+	avatars   AvatarsStorage
+	privacy   PrivacyStorage
+	interests InterestsStorage
+	cache     ProfilesCache
+	outbox    OutboxStorage
+	tx        TxManager
+	gets      *coalesce.Group[*models.Profile]
+	searches  *coalesce.Group[[]*models.Profile]
 }
 
 // Хранилище зарегистрированных пользователей
@@ -16,26 +34,381 @@ type ProfilesStorage interface {
 	Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error)
 	Get(ctx context.Context, id string) (*models.Profile, error)
 	Add(ctx context.Context, profile *models.Profile) (string, error)
+	Delete(ctx context.Context, id string) error
+	GetByUser(ctx context.Context, userId string) ([]*models.Profile, error)
+	DeleteByUser(ctx context.Context, userId string) error
 }
 
-func NewProfilesService(storage ProfilesStorage) ProfilesService {
+// Настройки видимости полей анкет
+type PrivacyStorage interface {
+	Privacy(ctx context.Context, profileIds []string) (map[string]*models.Privacy, error)
+	SetPrivacy(ctx context.Context, profileId string, fields map[models.ProfileField]models.Visibility) error
+}
+
+// Интересы анкет в нормализованном виде (см. normalizeInterest)
+type InterestsStorage interface {
+	AddInterests(ctx context.Context, profileId string, names []string) error
+	Interests(ctx context.Context, profileIds []string) (map[string][]string, error)
+	Autocomplete(ctx context.Context, prefix string, visible []models.Visibility, limit int) ([]models.InterestCount, error)
+	Similar(ctx context.Context, profileId string, visible []models.Visibility, limit int) ([]*models.ProfileMatch, error)
+}
+
+// Если avatars не задано, анкеты возвращаются без аватаров.
+// Если privacy не задано, все поля анкет видны с models.DefaultVisibility.
+// Если interests не задано, интересы анкет не сохраняются.
+// Если cache не задано, анкеты не кэшируются
+func NewProfilesService(storage ProfilesStorage, avatars AvatarsStorage, privacy PrivacyStorage, interests InterestsStorage, cache ProfilesCache, outbox OutboxStorage, tx TxManager) ProfilesService {
 	return ProfilesService{
-		storage: storage,
+		storage:   storage,
+		avatars:   avatars,
+		privacy:   privacy,
+		interests: interests,
+		cache:     cache,
+		outbox:    outbox,
+		tx:        tx,
+		gets:      &coalesce.Group[*models.Profile]{},
+		searches:  &coalesce.Group[[]*models.Profile]{},
 	}
 }
 
-func (s ProfilesService) GetAll(ctx context.Context) ([]*models.Profile, error) {
-	return s.storage.GetAll(ctx)
+// Все анкеты. Поля, недоступные пользователю viewer, в анкетах пусты
+func (s ProfilesService) GetAll(ctx context.Context, viewer models.Actor) ([]*models.Profile, error) {
+	profiles, err := s.storage.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.attachDetails(ctx, profiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, p := range profiles {
+		profiles[i] = visibleTo(viewer, p)
+	}
+
+	return profiles, nil
 }
 
-func (s ProfilesService) Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error) {
-	return s.storage.Search(ctx, params)
+// Анкеты по началу имени и фамилии и по интересу. Поиск по фамилии или интересу не находит анкеты,
+// в которых фамилия или увлечения скрыты от viewer
+func (s ProfilesService) Search(ctx context.Context, viewer models.Actor, params *models.SearchParams) ([]*models.Profile, error) {
+	params = &models.SearchParams{
+		NamePrefix:    params.NamePrefix,
+		SurnamePrefix: params.SurnamePrefix,
+		Interest:      normalizeInterest(params.Interest),
+	}
+
+	key := params.NamePrefix + "\x00" + params.SurnamePrefix + "\x00" + params.Interest
+
+	found, _, err := s.searches.Do(ctx, key, func(ctx context.Context) ([]*models.Profile, error) {
+		profiles, err := s.storage.Search(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.attachDetails(ctx, profiles...)
+		if err != nil {
+			return nil, err
+		}
+
+		return profiles, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]*models.Profile, 0, len(found))
+	for _, p := range found {
+		visible := visibleTo(viewer, p)
+		if params.SurnamePrefix != "" && visible.IsHidden(models.FieldSurname) {
+			continue
+		}
+
+		if params.Interest != "" && visible.IsHidden(models.FieldHobbies) {
+			continue
+		}
+
+		profiles = append(profiles, visible)
+	}
+
+	return profiles, nil
 }
 
-func (s ProfilesService) Get(ctx context.Context, id string) (*models.Profile, error) {
-	return s.storage.Get(ctx, id)
+// Анкета по id. Поля, недоступные пользователю viewer, в ней пусты
+func (s ProfilesService) Get(ctx context.Context, viewer models.Actor, id string) (*models.Profile, error) {
+	profile, _, err := s.gets.Do(ctx, id, func(ctx context.Context) (*models.Profile, error) {
+		if s.cache != nil {
+			cached, ok := s.cache.Get(ctx, id)
+			if ok {
+				return cached, nil
+			}
+		}
+
+		profile, err := s.storage.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.attachDetails(ctx, profile)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.cache != nil {
+			s.cache.Set(ctx, id, profile)
+		}
+
+		return profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return visibleTo(viewer, profile), nil
 }
 
+// Создает анкету с интересами и записывает событие ProfileCreated.
+// Если интересы не указаны явно, они берутся из hobbies
 func (s ProfilesService) Add(ctx context.Context, profile *models.Profile) (string, error) {
-	return s.storage.Add(ctx, profile)
+	var id string
+
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		id, err = s.storage.Add(ctx, profile)
+		if err != nil {
+			return err
+		}
+
+		if s.interests != nil {
+			err = s.interests.AddInterests(ctx, id, interestsOf(profile))
+			if err != nil {
+				return fmt.Errorf("adding interests of profile '%s': %v", id, err)
+			}
+		}
+
+		return recordEvent(ctx, s.outbox, models.EventProfileCreated, models.AggregateProfile, id, models.ProfilePayload{
+			ProfileId: id,
+			UserId:    profile.UserId,
+			Name:      profile.Name,
+			Surname:   profile.Surname,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Настройки видимости полей анкеты. Просматривать и менять их может владелец анкеты или администратор
+func (s ProfilesService) Privacy(ctx context.Context, actor models.Actor, profileId string) (*models.Privacy, error) {
+	_, err := s.managedProfile(ctx, actor, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getPrivacy(ctx, profileId)
+}
+
+// Заменяет настройки видимости полей анкеты и записывает событие ProfileUpdated.
+// Поля, которых нет в fields, видны с models.DefaultVisibility
+func (s ProfilesService) SetPrivacy(ctx context.Context, actor models.Actor, profileId string, fields map[models.ProfileField]models.Visibility) (*models.Privacy, error) {
+	profile, err := s.managedProfile(ctx, actor, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		err := s.privacy.SetPrivacy(ctx, profileId, fields)
+		if err != nil {
+			if errors.Is(err, models.ProfileNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("setting privacy of profile '%s': %v", profileId, err)
+		}
+
+		return recordProfileUpdated(ctx, s.outbox, profile, models.ProfileChangePrivacy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		s.cache.Delete(ctx, profileId)
+	}
+
+	return s.getPrivacy(ctx, profileId)
+}
+
+// Анкеты других пользователей с общими с анкетой profileId интересами, начиная с анкет с наибольшим
+// числом общих интересов. Не учитываются анкеты, увлечения в которых скрыты от viewer.
+// Если от viewer скрыты увлечения самой анкеты profileId, похожих анкет нет
+func (s ProfilesService) Similar(ctx context.Context, viewer models.Actor, profileId string, limit int) ([]*models.ProfileMatch, error) {
+	source, err := s.Get(ctx, viewer, profileId)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.interests == nil || source.IsHidden(models.FieldHobbies) {
+		return []*models.ProfileMatch{}, nil
+	}
+
+	matches, err := s.interests.Similar(ctx, profileId, models.VisibilitiesTo(accessOf(viewer, "")), limit)
+	if err != nil {
+		return nil, fmt.Errorf("getting profiles similar to '%s': %v", profileId, err)
+	}
+
+	profiles := make([]*models.Profile, len(matches))
+	for i, m := range matches {
+		profiles[i] = m.Profile
+	}
+
+	err = s.attachDetails(ctx, profiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		m.Profile = visibleTo(viewer, m.Profile)
+	}
+
+	return matches, nil
+}
+
+func (s ProfilesService) getPrivacy(ctx context.Context, profileId string) (*models.Privacy, error) {
+	if s.privacy == nil {
+		return &models.Privacy{}, nil
+	}
+
+	privacy, err := s.privacy.Privacy(ctx, []string{profileId})
+	if err != nil {
+		return nil, fmt.Errorf("getting privacy of profile '%s': %v", profileId, err)
+	}
+
+	p, ok := privacy[profileId]
+	if !ok {
+		return &models.Privacy{}, nil
+	}
+
+	return p, nil
+}
+
+// Анкета, которую может изменять actor: его собственная или любая для администратора
+func (s ProfilesService) managedProfile(ctx context.Context, actor models.Actor, profileId string) (*models.Profile, error) {
+	profile, err := s.storage.Get(ctx, profileId)
+	if err != nil {
+		if errors.Is(err, models.ProfileNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("getting profile '%s': %v", profileId, err)
+	}
+
+	if !canManage(actor, profile.UserId) {
+		return nil, fmt.Errorf("managing profile '%s': %w", profileId, models.NotProfileOwner)
+	}
+
+	return profile, nil
+}
+
+// Заполняет аватары, интересы и настройки видимости анкет. Анкеты должны быть копиями, полученными из хранилища
+func (s ProfilesService) attachDetails(ctx context.Context, profiles ...*models.Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(profiles))
+	for i, p := range profiles {
+		ids[i] = p.Id
+	}
+
+	if s.avatars != nil {
+		avatars, err := s.avatars.Avatars(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("getting avatars: %v", err)
+		}
+
+		for _, p := range profiles {
+			p.Avatar = avatars[p.Id]
+		}
+	}
+
+	if s.privacy != nil {
+		privacy, err := s.privacy.Privacy(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("getting privacy: %v", err)
+		}
+
+		for _, p := range profiles {
+			p.Privacy = privacy[p.Id]
+		}
+	}
+
+	if s.interests != nil {
+		interests, err := s.interests.Interests(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("getting interests: %v", err)
+		}
+
+		for _, p := range profiles {
+			p.Interests = interests[p.Id]
+		}
+	}
+
+	return nil
+}
+
+// Может ли actor изменять анкеты и фотографии пользователя owner
+func canManage(actor models.Actor, owner string) bool {
+	return (actor.UserId != "" && actor.UserId == owner) || role.AnyHas(actor.Roles, role.ManageProfiles)
+}
+
+// Наибольшая видимость полей, доступных пользователю viewer в анкетах пользователя owner.
+// Дружбы между пользователями пока нет, поэтому поля для друзей видны только владельцу
+func accessOf(viewer models.Actor, owner string) models.Visibility {
+	switch {
+	case canManage(viewer, owner):
+		return models.VisibilityHidden
+	case viewer.UserId != "":
+		return models.VisibilityRegistered
+	default:
+		return models.VisibilityPublic
+	}
+}
+
+// Копия анкеты, в которой поля, недоступные пользователю viewer, пусты и перечислены в Hidden
+func visibleTo(viewer models.Actor, p *models.Profile) *models.Profile {
+	access := accessOf(viewer, p.UserId)
+
+	res := *p
+	res.Hidden = nil
+
+	for _, f := range models.ProfileFields {
+		if p.Privacy.Visibility(f).VisibleTo(access) {
+			continue
+		}
+
+		res.Hidden = append(res.Hidden, f)
+
+		switch f {
+		case models.FieldSurname:
+			res.Surname = ""
+		case models.FieldSex:
+			res.Sex = sex.Unknown
+		case models.FieldBirthdate:
+			res.Birthdate = time.Time{}
+		case models.FieldCity:
+			res.Address = ""
+		case models.FieldHobbies:
+			res.Hobbies = ""
+			res.Interests = nil
+		case models.FieldPhotos:
+			res.Avatar = nil
+		}
+	}
+
+	return &res
 }
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+// Хранилище, отвечающее с задержкой, как база под нагрузкой, и считающее запросы
+type slowProfilesStorage struct {
+	ProfilesStorage
+
+	delay   time.Duration
+	queries atomic.Int64
+}
+
+func (s *slowProfilesStorage) Get(ctx context.Context, id string) (*models.Profile, error) {
+	s.queries.Add(1)
+
+	select {
+	case <-time.After(s.delay):
+		return &models.Profile{Name: id}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *slowProfilesStorage) Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error) {
+	s.queries.Add(1)
+
+	select {
+	case <-time.After(s.delay):
+		return []*models.Profile{{Name: params.NamePrefix, Surname: params.SurnamePrefix}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestProfilesServiceAdd(t *testing.T) {
+	storage := mocks.NewProfilesStorage(t)
+	outbox := mocks.NewOutboxStorage(t)
+	service := NewProfilesService(storage, nil, nil, nil, nil, outbox, fakeTxManager{})
+
+	profile := &models.Profile{UserId: "1", Name: "Ivan", Surname: "Ivanov"}
+
+	t.Run("test Add records ProfileCreated", func(t *testing.T) {
+		storage.On("Add", mock.Anything, profile).Return("10", nil).Once()
+		outbox.On("Add", mock.Anything, mock.MatchedBy(func(e *models.Event) bool {
+			return e.Type == models.EventProfileCreated && e.AggregateType == models.AggregateProfile && e.AggregateId == "10" &&
+				string(e.Payload) == `{"profile_id":"10","user_id":"1","name":"Ivan","surname":"Ivanov"}`
+		})).Return(nil).Once()
+
+		id, err := service.Add(context.Background(), profile)
+		require.NoError(t, err)
+		assert.Equal(t, "10", id)
+	})
+
+	t.Run("test Add storage error", func(t *testing.T) {
+		storage.On("Add", mock.Anything, profile).Return("", errors.New("storage error")).Once()
+
+		_, err := service.Add(context.Background(), profile)
+		assert.Error(t, err)
+	})
+}
+
+func TestProfilesServiceCoalescing(t *testing.T) {
+	ctx := context.Background()
+	viewer := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+	t.Run("test concurrent Get queries storage once", func(t *testing.T) {
+		storage := &slowProfilesStorage{delay: 50 * time.Millisecond}
+		service := NewProfilesService(storage, nil, nil, nil, nil, nil, fakeTxManager{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				profile, err := service.Get(ctx, viewer, "1")
+				assert.NoError(t, err)
+				assert.Equal(t, "1", profile.Name)
+			}()
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, 1, storage.queries.Load())
+	})
+
+	t.Run("test Search is keyed by both prefixes", func(t *testing.T) {
+		storage := &slowProfilesStorage{delay: 50 * time.Millisecond}
+		service := NewProfilesService(storage, nil, nil, nil, nil, nil, fakeTxManager{})
+
+		params := []*models.SearchParams{
+			{NamePrefix: "ab", SurnamePrefix: "c"},
+			{NamePrefix: "a", SurnamePrefix: "bc"},
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(p *models.SearchParams) {
+				defer wg.Done()
+
+				profiles, err := service.Search(ctx, viewer, p)
+				assert.NoError(t, err)
+				require.Len(t, profiles, 1)
+				assert.Equal(t, p.NamePrefix, profiles[0].Name)
+				assert.Equal(t, p.SurnamePrefix, profiles[0].Surname)
+			}(params[i%2])
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, 2, storage.queries.Load())
+	})
+
+	t.Run("test cancelled caller gets its own error", func(t *testing.T) {
+		storage := &slowProfilesStorage{delay: time.Second}
+		service := NewProfilesService(storage, nil, nil, nil, nil, nil, fakeTxManager{})
+
+		cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := service.Get(cancelled, viewer, "1")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func TestProfilesServicePrivacy(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	profiles := inmemory.NewProfilesStorage(db)
+	service := NewProfilesService(profiles, nil, inmemory.NewPrivacyStorage(db), nil, nil, inmemory.NewOutboxStorage(db), inmemory.NewTxManager(db))
+
+	owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+	other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+	admin := models.Actor{UserId: "3", Roles: []role.Role{role.Admin}}
+
+	birthdate := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	id, err := profiles.Add(ctx, &models.Profile{
+		UserId:    owner.UserId,
+		Name:      "Ivan",
+		Surname:   "Petrov",
+		Sex:       sex.Male,
+		Birthdate: birthdate,
+		Address:   "Moscow",
+		Hobbies:   "chess",
+	})
+	require.NoError(t, err)
+
+	t.Run("test fields are visible by default", func(t *testing.T) {
+		profile, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+		assert.Empty(t, profile.Hidden)
+		assert.Equal(t, "Petrov", profile.Surname)
+
+		privacy, err := service.Privacy(ctx, owner, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.VisibilityRegistered, privacy.Visibility(models.FieldBirthdate))
+	})
+
+	_, err = service.SetPrivacy(ctx, owner, id, map[models.ProfileField]models.Visibility{
+		models.FieldSurname:   models.VisibilityHidden,
+		models.FieldBirthdate: models.VisibilityFriends,
+		models.FieldCity:      models.VisibilityPublic,
+	})
+	require.NoError(t, err)
+
+	t.Run("test hidden fields are cleared for other users", func(t *testing.T) {
+		profile, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+		assert.Equal(t, []models.ProfileField{models.FieldSurname, models.FieldBirthdate}, profile.Hidden)
+		assert.Empty(t, profile.Surname)
+		assert.True(t, profile.Birthdate.IsZero())
+		assert.Equal(t, "Ivan", profile.Name)
+		assert.Equal(t, "Moscow", profile.Address)
+		assert.Equal(t, sex.Male, profile.Sex)
+
+		all, err := service.GetAll(ctx, other)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Empty(t, all[0].Surname)
+	})
+
+	t.Run("test owner and admin see all fields", func(t *testing.T) {
+		for _, viewer := range []models.Actor{owner, admin} {
+			profile, err := service.Get(ctx, viewer, id)
+			require.NoError(t, err)
+			assert.Empty(t, profile.Hidden)
+			assert.Equal(t, "Petrov", profile.Surname)
+			assert.Equal(t, birthdate, profile.Birthdate)
+		}
+	})
+
+	t.Run("test Search does not match hidden fields", func(t *testing.T) {
+		found, err := service.Search(ctx, other, &models.SearchParams{SurnamePrefix: "Pe"})
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		found, err = service.Search(ctx, other, &models.SearchParams{NamePrefix: "Iv"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Empty(t, found[0].Surname)
+
+		found, err = service.Search(ctx, owner, &models.SearchParams{SurnamePrefix: "Pe"})
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("test privacy is managed by owner", func(t *testing.T) {
+		_, err := service.Privacy(ctx, other, id)
+		assert.ErrorIs(t, err, models.NotProfileOwner)
+
+		_, err = service.SetPrivacy(ctx, other, id, nil)
+		assert.ErrorIs(t, err, models.NotProfileOwner)
+
+		_, err = service.SetPrivacy(ctx, owner, "100", nil)
+		assert.ErrorIs(t, err, models.ProfileNotFound)
+
+		privacy, err := service.SetPrivacy(ctx, admin, id, nil)
+		require.NoError(t, err)
+		assert.Equal(t, models.VisibilityRegistered, privacy.Visibility(models.FieldSurname))
+	})
+}
+
+func TestProfilesServiceInterests(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	privacy := inmemory.NewPrivacyStorage(db)
+	interests := inmemory.NewInterestsStorage(db)
+	service := NewProfilesService(inmemory.NewProfilesStorage(db), nil, privacy, interests, nil, inmemory.NewOutboxStorage(db), fakeTxManager{})
+
+	owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+	other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+	id, err := service.Add(ctx, &models.Profile{UserId: owner.UserId, Name: "Ivan", Hobbies: "Чтение,  Сериалы , чтение,"})
+	require.NoError(t, err)
+
+	otherId, err := service.Add(ctx, &models.Profile{UserId: other.UserId, Name: "Petr", Hobbies: "ignored", Interests: []string{"Сериалы", " ЧТЕНИЕ", "Шахматы"}})
+	require.NoError(t, err)
+
+	thirdId, err := service.Add(ctx, &models.Profile{UserId: "3", Name: "Anna", Hobbies: "сериалы"})
+	require.NoError(t, err)
+
+	t.Run("test interests are normalized on Add", func(t *testing.T) {
+		profile, err := service.Get(ctx, other, id)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"сериалы", "чтение"}, profile.Interests)
+
+		profile, err = service.Get(ctx, owner, otherId)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"сериалы", "чтение", "шахматы"}, profile.Interests)
+	})
+
+	t.Run("test Search by interest", func(t *testing.T) {
+		found, err := service.Search(ctx, other, &models.SearchParams{Interest: " Чтение "})
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+	})
+
+	t.Run("test Similar ranks by shared interests", func(t *testing.T) {
+		matches, err := service.Similar(ctx, owner, id, 10)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		assert.Equal(t, otherId, matches[0].Profile.Id)
+		assert.Equal(t, []string{"сериалы", "чтение"}, matches[0].Shared)
+		assert.Equal(t, thirdId, matches[1].Profile.Id)
+		assert.Equal(t, []string{"сериалы"}, matches[1].Shared)
+
+		matches, err = service.Similar(ctx, owner, id, 1)
+		require.NoError(t, err)
+		assert.Len(t, matches, 1)
+
+		_, err = service.Similar(ctx, owner, "100", 10)
+		assert.ErrorIs(t, err, models.ProfileNotFound)
+	})
+
+	err = privacy.SetPrivacy(ctx, otherId, map[models.ProfileField]models.Visibility{models.FieldHobbies: models.VisibilityHidden})
+	require.NoError(t, err)
+
+	t.Run("test hidden hobbies hide interests", func(t *testing.T) {
+		profile, err := service.Get(ctx, owner, otherId)
+		require.NoError(t, err)
+		assert.Empty(t, profile.Interests)
+
+		found, err := service.Search(ctx, owner, &models.SearchParams{Interest: "чтение"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, id, found[0].Id)
+
+		matches, err := service.Similar(ctx, owner, id, 10)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, thirdId, matches[0].Profile.Id)
+
+		matches, err = service.Similar(ctx, owner, otherId, 10)
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("test Autocomplete counts visible profiles", func(t *testing.T) {
+		interestsService := NewInterestsService(interests)
+
+		found, err := interestsService.Autocomplete(ctx, owner, "С", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []models.InterestCount{{Name: "сериалы", Profiles: 2}}, found)
+
+		found, err = interestsService.Autocomplete(ctx, models.Actor{UserId: "4", Roles: []role.Role{role.Admin}}, "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []models.InterestCount{{Name: "сериалы", Profiles: 3}, {Name: "чтение", Profiles: 2}}, found)
+
+		found, err = interestsService.Autocomplete(ctx, models.Actor{}, "", 10)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+}
+
+// Сравнение числа запросов к хранилищу на вызов Get с объединением запросов и без него:
+//
+//	go test ./internal/service -run xxx -bench ProfilesServiceGet
+func BenchmarkProfilesServiceGet(b *testing.B) {
+	ctx := context.Background()
+	viewer := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+	// небольшой набор популярных анкет, как в docs/loadtest/GET_Profiles.jmx
+	ids := []string{"1", "2", "3", "4"}
+
+	run := func(b *testing.B, get func(storage ProfilesStorage) func(ctx context.Context, id string) (*models.Profile, error)) {
+		storage := &slowProfilesStorage{delay: time.Millisecond}
+		getFn := get(storage)
+
+		var n atomic.Int64
+
+		b.SetParallelism(16)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				i := n.Add(1)
+				_, err := getFn(ctx, ids[int(i)%len(ids)])
+				if err != nil {
+					b.Error(err)
+				}
+			}
+		})
+
+		b.ReportMetric(float64(storage.queries.Load())/float64(b.N), "queries/op")
+	}
+
+	b.Run("direct", func(b *testing.B) {
+		run(b, func(storage ProfilesStorage) func(ctx context.Context, id string) (*models.Profile, error) {
+			return storage.Get
+		})
+	})
+
+	b.Run("coalesced", func(b *testing.B) {
+		run(b, func(storage ProfilesStorage) func(ctx context.Context, id string) (*models.Profile, error) {
+			service := NewProfilesService(storage, nil, nil, nil, nil, nil, fakeTxManager{})
+
+			return func(ctx context.Context, id string) (*models.Profile, error) {
+				return service.Get(ctx, viewer, id)
+			}
+		})
+	})
+}
@@ -2,21 +2,44 @@ package service
 
 import (
 	"context"
+	"expvar"
 	"fmt"
+	"net"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
 
 	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/blob"
+	"github.com/Lucky112/social/internal/events"
+	"github.com/Lucky112/social/internal/photos"
 	pg "github.com/Lucky112/social/internal/storage/postgres"
+	"github.com/Lucky112/social/internal/webhooks"
 	"github.com/Lucky112/social/pkg/postgres"
 )
 
+// Имя переменной expvar со счетчиками кэша анкет
+const profilesCacheVar = "profiles_cache"
+
+// Время ожидания ответа S3, если оно не задано в конфигурации
+const defaultS3Timeout = 30 * time.Second
+
 type Service struct {
-	dbpool postgres.Pool
+	dbpool         postgres.Pool
+	tx             postgres.TxManager
+	authConfig     *config.AuthConfig
+	hasher         PasswordHasher
+	policy         CredentialsPolicy
+	profilesCache  ProfilesCache
+	eventsConfig   *config.EventsConfig
+	webhooksConfig *config.WebhooksConfig
+	photosConfig   *config.PhotosConfig
 }
 
-func NewService(ctx context.Context, config *config.DBConfig) (Service, error) {
-	cfg := toPostgresConfig(config)
+func NewService(ctx context.Context, config *config.Config) (Service, error) {
+	cfg := toPostgresConfig(config.DBConfig)
 
-	err := migrateDB(cfg)
+	err := migrateDB(ctx, cfg)
 	if err != nil {
 		return Service{}, fmt.Errorf("migrating database: %v", err)
 	}
@@ -26,19 +49,161 @@ func NewService(ctx context.Context, config *config.DBConfig) (Service, error) {
 		return Service{}, fmt.Errorf("creating pgx pool: %v", err)
 	}
 
+	policy, err := NewCredentialsPolicy(config.AuthConfig.Policy)
+	if err != nil {
+		return Service{}, fmt.Errorf("creating credentials policy: %v", err)
+	}
+
 	return Service{
-		dbpool: dbpool,
+		dbpool:         dbpool,
+		tx:             postgres.NewTxManager(dbpool),
+		authConfig:     config.AuthConfig,
+		hasher:         NewPasswordHasher(config.AuthConfig.PasswordHash),
+		policy:         policy,
+		profilesCache:  newProfilesCache(config.CacheConfig),
+		eventsConfig:   config.EventsConfig,
+		webhooksConfig: config.WebhooksConfig,
+		photosConfig:   config.PhotosConfig,
 	}, err
 }
 
-func (s Service) AuthService() AuthService {
+func newProfilesCache(cfg *config.CacheConfig) ProfilesCache {
+	if cfg == nil {
+		return nil
+	}
+
+	cache := NewLRUProfilesCache(cfg.ProfilesSize, time.Duration(cfg.ProfilesTTLSeconds)*time.Second)
+
+	if expvar.Get(profilesCacheVar) == nil {
+		expvar.Publish(profilesCacheVar, expvar.Func(func() any {
+			return cache.Stats()
+		}))
+	}
+
+	return cache
+}
+
+func (s Service) profilesStorage(querier pgxscan.Querier) ProfilesStorage {
+	return newCachedProfilesStorage(pg.NewProfilesProvider(querier), s.profilesCache)
+}
+
+func (s Service) AuthService() (AuthService, error) {
 	storage := pg.NewUsersProvider(s.dbpool)
-	return NewAuthService(storage)
+	return NewAuthService(storage, pg.NewOutboxProvider(s.dbpool), s.tx, s.hasher, s.policy, s.authConfig)
+}
+
+func (s Service) AdminService() AdminService {
+	users := pg.NewUsersProvider(s.dbpool)
+	profiles := s.profilesStorage(s.dbpool)
+	audit := pg.NewAuditProvider(s.dbpool)
+	return NewAdminService(users, profiles, audit, s.tx, s.hasher)
+}
+
+func (s Service) AccountService() (AccountService, error) {
+	blobs, err := newBlobStore(s.photosConfig)
+	if err != nil {
+		return AccountService{}, fmt.Errorf("creating %s photo storage: %v", s.photosConfig.Storage, err)
+	}
+
+	users := pg.NewUsersProvider(s.dbpool)
+	profiles := s.profilesStorage(s.dbpool)
+	return NewAccountService(users, profiles, pg.NewWebhooksProvider(s.dbpool), pg.NewPhotosProvider(s.dbpool), pg.NewPrivacyProvider(s.dbpool), pg.NewInterestsProvider(s.dbpool), s.tx, blobs, s.hasher, s.policy), nil
+}
+
+// Менеджер транзакций для атомарного выполнения операций нескольких сервисов,
+// например регистрации пользователя вместе с созданием его анкеты
+func (s Service) TxManager() TxManager {
+	return s.tx
 }
 
 func (s Service) ProfilesService() ProfilesService {
-	storage := pg.NewProfilesProvider(s.dbpool)
-	return NewProfilesService(storage)
+	storage := s.profilesStorage(s.dbpool)
+	return NewProfilesService(storage, pg.NewPhotosProvider(s.dbpool), pg.NewPrivacyProvider(s.dbpool), pg.NewInterestsProvider(s.dbpool), s.profilesCache, pg.NewOutboxProvider(s.dbpool), s.tx)
+}
+
+func (s Service) InterestsService() InterestsService {
+	return NewInterestsService(pg.NewInterestsProvider(s.dbpool))
+}
+
+// Публикация событий из outbox через publisher
+func (s Service) EventsRelay(publisher events.Publisher) events.Relay {
+	cfg := events.RelayConfig{
+		PollInterval: time.Duration(s.eventsConfig.PollIntervalMs) * time.Millisecond,
+		BatchSize:    s.eventsConfig.BatchSize,
+		MaxAttempts:  s.eventsConfig.MaxAttempts,
+		Backoff:      time.Duration(s.eventsConfig.RetryBackoffMs) * time.Millisecond,
+		MaxBackoff:   time.Duration(s.eventsConfig.MaxRetryBackoffMs) * time.Millisecond,
+	}
+
+	return events.NewRelay(pg.NewOutboxProvider(s.dbpool), s.tx, publisher, cfg)
+}
+
+func (s Service) WebhooksService() WebhooksService {
+	return NewWebhooksService(pg.NewWebhooksProvider(s.dbpool), net.DefaultResolver)
+}
+
+// Отправка доставок по подпискам на события
+func (s Service) WebhooksWorker() webhooks.Worker {
+	cfg := webhooks.WorkerConfig{
+		PollInterval: time.Duration(s.webhooksConfig.PollIntervalMs) * time.Millisecond,
+		BatchSize:    s.webhooksConfig.BatchSize,
+		MaxAttempts:  s.webhooksConfig.MaxAttempts,
+		Backoff:      time.Duration(s.webhooksConfig.RetryBackoffMs) * time.Millisecond,
+		MaxBackoff:   time.Duration(s.webhooksConfig.MaxRetryBackoffMs) * time.Millisecond,
+		Timeout:      time.Duration(s.webhooksConfig.TimeoutSeconds) * time.Second,
+	}
+
+	return webhooks.NewWorker(pg.NewWebhooksProvider(s.dbpool), s.tx, cfg)
+}
+
+// Фотографии анкет в хранилище файлов из конфигурации
+func (s Service) PhotosService() (PhotosService, error) {
+	blobs, err := newBlobStore(s.photosConfig)
+	if err != nil {
+		return PhotosService{}, fmt.Errorf("creating %s photo storage: %v", s.photosConfig.Storage, err)
+	}
+
+	return NewPhotosService(pg.NewPhotosProvider(s.dbpool), s.profilesStorage(s.dbpool), pg.NewPrivacyProvider(s.dbpool), s.profilesCache, pg.NewOutboxProvider(s.dbpool), s.tx, blobs, newPhotoProcessor(s.photosConfig)), nil
+}
+
+// Ключи идемпотентности POST-запросов, общие для всех экземпляров приложения
+func (s Service) IdempotencyStore() pg.IdempotencyProvider {
+	return pg.NewIdempotencyProvider(s.dbpool)
+}
+
+func newBlobStore(cfg *config.PhotosConfig) (BlobStore, error) {
+	if cfg.Storage != "s3" {
+		return blob.NewLocalStore(cfg.Local.Dir), nil
+	}
+
+	timeout := defaultS3Timeout
+	if cfg.S3.TimeoutSeconds != 0 {
+		timeout = time.Duration(cfg.S3.TimeoutSeconds) * time.Second
+	}
+
+	return blob.NewS3Store(blob.S3Config{
+		Endpoint:        cfg.S3.Endpoint,
+		Region:          cfg.S3.Region,
+		Bucket:          cfg.S3.Bucket,
+		AccessKeyId:     cfg.S3.AccessKeyId,
+		SecretAccessKey: cfg.S3.SecretAccessKey,
+		PathStyle:       cfg.S3.PathStyle,
+		Timeout:         timeout,
+	})
+}
+
+func newPhotoProcessor(cfg *config.PhotosConfig) photos.Processor {
+	sizes := make([]photos.Size, len(cfg.Thumbnails))
+	for i, t := range cfg.Thumbnails {
+		sizes[i] = photos.Size{Name: t.Name, MaxSide: t.MaxSide}
+	}
+
+	return photos.NewProcessor(photos.Config{
+		MaxBytes:  cfg.MaxSizeBytes,
+		MaxPixels: cfg.MaxPixels,
+		Sizes:     sizes,
+		Quality:   cfg.JPEGQuality,
+	})
 }
 
 func toPostgresConfig(cfg *config.DBConfig) *postgres.Config {
@@ -48,14 +213,34 @@ func toPostgresConfig(cfg *config.DBConfig) *postgres.Config {
 		Database: cfg.Database,
 		Host:     cfg.Host,
 		Port:     cfg.Port,
+
+		SSLMode:          cfg.SSLMode,
+		ConnectTimeout:   time.Duration(cfg.ConnectTimeoutSeconds) * time.Second,
+		StatementTimeout: time.Duration(cfg.StatementTimeoutMs) * time.Millisecond,
+		ApplicationName:  cfg.ApplicationName,
+		SearchPath:       cfg.SearchPath,
+
+		MaxConns:        cfg.MaxConns,
+		MinConns:        cfg.MinConns,
+		MaxConnLifetime: time.Duration(cfg.MaxConnLifetimeSeconds) * time.Second,
+		MaxConnIdleTime: time.Duration(cfg.MaxConnIdleTimeSeconds) * time.Second,
+
+		ConnectRetries: cfg.ConnectRetries,
+		ConnectBackoff: time.Duration(cfg.ConnectBackoffMs) * time.Millisecond,
 	}
 }
 
-func migrateDB(cfg *postgres.Config) error {
+func migrateDB(ctx context.Context, cfg *postgres.Config) error {
 	sqldb, err := postgres.ViaSTD(cfg)
 	if err != nil {
 		return fmt.Errorf("opening db: %v", err)
 	}
+	defer sqldb.Close()
+
+	err = postgres.WaitReady(ctx, sqldb.PingContext, cfg)
+	if err != nil {
+		return err
+	}
 
 	err = pg.ApplyMigrations(sqldb)
 	if err != nil {
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeBytes  = 5
+
+	// Длительность шага TOTP в секундах. Принимаются коды текущего и соседних шагов
+	totpPeriod = 30
+	// Число попыток ввода кода за mfaAttemptsWindow. После последней неудачной попытки токены отзываются,
+	// и до конца окна код не принимается даже по токену нового входа
+	maxMFAAttempts    = 5
+	mfaAttemptsWindow = 15 * time.Minute
+)
+
+// Начинает подключение TOTP: генерирует секрет и коды восстановления.
+// Аутентификатор включается только после подтверждения кодом через ConfirmTOTP
+func (s AuthService) EnrollTOTP(ctx context.Context, userId string) (*models.TOTPEnrollment, error) {
+	user, err := s.storage.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	if user.TOTPEnabled {
+		return nil, models.TOTPAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.totpIssuer,
+		AccountName: user.Login,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generating totp key: %v", err)
+	}
+
+	encryptedSecret, err := s.secrets.seal([]byte(key.Secret()))
+	if err != nil {
+		return nil, fmt.Errorf("encrypting totp secret: %v", err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("generating recovery codes: %v", err)
+	}
+
+	err = s.storage.SetTOTP(ctx, userId, encryptedSecret, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("saving totp secret: %v", err)
+	}
+
+	return &models.TOTPEnrollment{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// Подтверждает подключение TOTP первым кодом из аутентификатора
+func (s AuthService) ConfirmTOTP(ctx context.Context, userId, code string) error {
+	user, err := s.storage.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return err
+		}
+
+		return fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	if user.TOTPEnabled {
+		return models.TOTPAlreadyEnabled
+	}
+
+	ok, err := s.useTOTP(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.InvalidMFACode
+	}
+
+	err = s.storage.EnableTOTP(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("enabling totp: %v", err)
+	}
+
+	return nil
+}
+
+// Проверяет второй фактор при входе: код из аутентификатора или неиспользованный код восстановления.
+// generation - поколение MFA-токена, выданного при проверке пароля. Каждый MFA-токен одноразовый.
+// Попытки считаются по пользователю, а не по токену: после maxMFAAttempts неудачных попыток
+// за mfaAttemptsWindow токены отзываются и до конца окна возвращается models.MFATokenRevoked
+func (s AuthService) VerifyMFA(ctx context.Context, userId string, generation int, code string) (*models.User, error) {
+	user, err := s.storage.GetById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("looking for user '%s': %v", userId, err)
+	}
+
+	if user.Disabled {
+		return nil, models.UserDisabled
+	}
+
+	if !user.TOTPEnabled {
+		return nil, models.TOTPNotEnrolled
+	}
+
+	// попытка учитывается до проверки кода, поэтому параллельные запросы не превышают лимит
+	attempts, err := s.storage.AddMFAAttempt(ctx, userId, generation, maxMFAAttempts, mfaAttemptsWindow)
+	if err != nil {
+		if errors.Is(err, models.MFATokenRevoked) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("counting mfa attempt: %v", err)
+	}
+
+	ok, err := s.checkMFACode(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		err = s.storage.ResetMFAAttempts(ctx, userId)
+		if err != nil {
+			return nil, fmt.Errorf("resetting mfa attempts: %v", err)
+		}
+	}
+
+	if ok || attempts >= maxMFAAttempts {
+		err = s.storage.RevokeMFATokens(ctx, userId)
+		if err != nil {
+			return nil, fmt.Errorf("revoking mfa tokens: %v", err)
+		}
+	}
+
+	switch {
+	case ok:
+		return user, nil
+	case attempts >= maxMFAAttempts:
+		return nil, models.MFATokenRevoked
+	default:
+		return nil, models.InvalidMFACode
+	}
+}
+
+// Проверяет код из аутентификатора, а если он не подошел - код восстановления
+func (s AuthService) checkMFACode(ctx context.Context, user *models.User, code string) (bool, error) {
+	ok, err := s.useTOTP(ctx, user, code)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	used, err := s.storage.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("using recovery code: %v", err)
+	}
+
+	return used, nil
+}
+
+// Проверяет код из аутентификатора и запоминает его шаг.
+// Повторно код того же или более раннего шага не принимается
+func (s AuthService) useTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	step, ok, err := s.validateTOTP(user, code)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	fresh, err := s.storage.UseTOTPStep(ctx, user.Id, step)
+	if err != nil {
+		return false, fmt.Errorf("using totp step: %v", err)
+	}
+
+	return fresh, nil
+}
+
+// Возвращает шаг, которому соответствует код
+func (s AuthService) validateTOTP(user *models.User, code string) (int64, bool, error) {
+	if len(user.TOTPSecret) == 0 {
+		return 0, false, models.TOTPNotEnrolled
+	}
+
+	secret, err := s.secrets.open(user.TOTPSecret)
+	if err != nil {
+		return 0, false, fmt.Errorf("decrypting totp secret: %v", err)
+	}
+
+	opts := hotp.ValidateOpts{
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for _, step := range []int64{current, current - 1, current + 1} {
+		ok, err := hotp.ValidateCustom(code, uint64(step), string(secret), opts)
+		if err != nil {
+			// код неверной длины или из недопустимых символов
+			return 0, false, nil
+		}
+
+		if ok {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([][]byte, recoveryCodesCount)
+
+	for i := range codes {
+		raw := make([]byte, recoveryCodeBytes)
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading random bytes: %v", err)
+		}
+
+		codes[i] = strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// Коды восстановления имеют достаточную энтропию, поэтому хранятся в виде sha256 без соли
+func hashRecoveryCode(code string) []byte {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return sum[:]
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestTOTP(t *testing.T) {
+	storage := mocks.NewUsersStorage(t)
+	authService, err := NewAuthService(storage, nil, fakeTxManager{}, testHasher, testPolicy, testAuthConfig)
+	require.NoError(t, err)
+
+	userId := "1"
+	user := &models.User{
+		Id:    userId,
+		Login: "login",
+	}
+
+	var savedSecret []byte
+	var savedCodes [][]byte
+	var enrollment *models.TOTPEnrollment
+
+	t.Run("test EnrollTOTP", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("SetTOTP", mock.Anything, userId, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				savedSecret = args.Get(2).([]byte)
+				savedCodes = args.Get(3).([][]byte)
+			}).
+			Return(nil).Once()
+
+		enrollment, err = authService.EnrollTOTP(context.Background(), userId)
+		require.NoError(t, err)
+		assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/")
+		assert.Len(t, enrollment.RecoveryCodes, recoveryCodesCount)
+		assert.Len(t, savedCodes, recoveryCodesCount)
+
+		// секрет не должен храниться в открытом виде
+		assert.NotContains(t, string(savedSecret), enrollment.Secret)
+		user.TOTPSecret = savedSecret
+	})
+
+	t.Run("test ConfirmTOTP with wrong code", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+
+		err := authService.ConfirmTOTP(context.Background(), userId, "000000x")
+		assert.ErrorIs(t, err, models.InvalidMFACode)
+	})
+
+	t.Run("test ConfirmTOTP", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("UseTOTPStep", mock.Anything, userId, mock.Anything).Return(true, nil).Once()
+		storage.On("EnableTOTP", mock.Anything, userId).Return(nil).Once()
+
+		err = authService.ConfirmTOTP(context.Background(), userId, code)
+		assert.NoError(t, err)
+		user.TOTPEnabled = true
+	})
+
+	t.Run("test EnrollTOTP again", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+
+		_, err := authService.EnrollTOTP(context.Background(), userId)
+		assert.ErrorIs(t, err, models.TOTPAlreadyEnabled)
+	})
+
+	t.Run("test VerifyMFA with totp code", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("AddMFAAttempt", mock.Anything, userId, 0, maxMFAAttempts, mfaAttemptsWindow).Return(1, nil).Once()
+		storage.On("UseTOTPStep", mock.Anything, userId, mock.Anything).Return(true, nil).Once()
+		storage.On("ResetMFAAttempts", mock.Anything, userId).Return(nil).Once()
+		storage.On("RevokeMFATokens", mock.Anything, userId).Return(nil).Once()
+
+		actual, err := authService.VerifyMFA(context.Background(), userId, 0, code)
+		assert.NoError(t, err)
+		assert.Equal(t, userId, actual.Id)
+	})
+
+	t.Run("test VerifyMFA with used totp code", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("AddMFAAttempt", mock.Anything, userId, 1, maxMFAAttempts, mfaAttemptsWindow).Return(1, nil).Once()
+		storage.On("UseTOTPStep", mock.Anything, userId, mock.Anything).Return(false, nil).Once()
+		storage.On("UseRecoveryCode", mock.Anything, userId, mock.Anything).Return(false, nil).Once()
+
+		_, err = authService.VerifyMFA(context.Background(), userId, 1, code)
+		assert.ErrorIs(t, err, models.InvalidMFACode)
+	})
+
+	t.Run("test VerifyMFA with recovery code", func(t *testing.T) {
+		code := enrollment.RecoveryCodes[0]
+
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("AddMFAAttempt", mock.Anything, userId, 1, maxMFAAttempts, mfaAttemptsWindow).Return(2, nil).Once()
+		storage.On("UseRecoveryCode", mock.Anything, userId, savedCodes[0]).Return(true, nil).Once()
+		storage.On("ResetMFAAttempts", mock.Anything, userId).Return(nil).Once()
+		storage.On("RevokeMFATokens", mock.Anything, userId).Return(nil).Once()
+
+		_, err := authService.VerifyMFA(context.Background(), userId, 1, code)
+		assert.NoError(t, err)
+	})
+
+	t.Run("test VerifyMFA with wrong code", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("AddMFAAttempt", mock.Anything, userId, 2, maxMFAAttempts, mfaAttemptsWindow).Return(1, nil).Once()
+		storage.On("UseRecoveryCode", mock.Anything, userId, mock.Anything).Return(false, nil).Once()
+
+		_, err := authService.VerifyMFA(context.Background(), userId, 2, "wrong")
+		assert.ErrorIs(t, err, models.InvalidMFACode)
+	})
+
+	t.Run("test VerifyMFA with revoked token", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, userId).Return(user, nil).Once()
+		storage.On("AddMFAAttempt", mock.Anything, userId, 1, maxMFAAttempts, mfaAttemptsWindow).Return(0, fmt.Errorf("%w", models.MFATokenRevoked)).Once()
+
+		_, err := authService.VerifyMFA(context.Background(), userId, 1, "123456")
+		assert.ErrorIs(t, err, models.MFATokenRevoked)
+	})
+
+	t.Run("test VerifyMFA without totp", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, "2").Return(&models.User{Id: "2"}, nil).Once()
+
+		_, err := authService.VerifyMFA(context.Background(), "2", 0, "123456")
+		assert.ErrorIs(t, err, models.TOTPNotEnrolled)
+	})
+
+	t.Run("test EnrollTOTP storage error", func(t *testing.T) {
+		storage.On("GetById", mock.Anything, "3").Return(nil, errors.New("storage error")).Once()
+
+		_, err := authService.EnrollTOTP(context.Background(), "3")
+		assert.Error(t, err)
+	})
+}
+
+// Защита второго фактора от повторного использования кода и перебора
+func TestMFAProtection(t *testing.T) {
+	ctx := context.Background()
+
+	db := inmemory.NewDB()
+	users := inmemory.NewUsersStorage(db)
+	authService, err := NewAuthService(users, inmemory.NewOutboxStorage(db), inmemory.NewTxManager(db), testHasher, testPolicy, testAuthConfig)
+	require.NoError(t, err)
+
+	userId, err := users.Add(ctx, &models.User{Email: "ivan@example.com", Login: "ivan"})
+	require.NoError(t, err)
+
+	enrollment, err := authService.EnrollTOTP(ctx, userId)
+	require.NoError(t, err)
+
+	// подтверждение кодом предыдущего шага, чтобы код текущего шага еще не был использован
+	confirmCode, err := totp.GenerateCode(enrollment.Secret, time.Now().Add(-totpPeriod*time.Second))
+	require.NoError(t, err)
+	require.NoError(t, authService.ConfirmTOTP(ctx, userId, confirmCode))
+
+	generation := func() int {
+		user, err := users.GetById(ctx, userId)
+		require.NoError(t, err)
+		return user.MFAGeneration
+	}
+
+	t.Run("test code is accepted only once", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		_, err = authService.VerifyMFA(ctx, userId, generation(), code)
+		require.NoError(t, err)
+
+		_, err = authService.VerifyMFA(ctx, userId, generation(), code)
+		assert.ErrorIs(t, err, models.InvalidMFACode)
+	})
+
+	t.Run("test code of earlier step is rejected", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now().Add(-totpPeriod*time.Second))
+		require.NoError(t, err)
+
+		_, err = authService.VerifyMFA(ctx, userId, generation(), code)
+		assert.ErrorIs(t, err, models.InvalidMFACode)
+	})
+
+	t.Run("test token is single use", func(t *testing.T) {
+		// предыдущие проверки не отозвали токен: ни один код не подошел
+		gen := generation()
+		_, err := authService.VerifyMFA(ctx, userId, gen, enrollment.RecoveryCodes[0])
+		require.NoError(t, err)
+
+		_, err = authService.VerifyMFA(ctx, userId, gen, enrollment.RecoveryCodes[1])
+		assert.ErrorIs(t, err, models.MFATokenRevoked)
+	})
+
+	t.Run("test accepted code resets failed attempts", func(t *testing.T) {
+		for _, recoveryCode := range enrollment.RecoveryCodes[1:3] {
+			gen := generation()
+			for i := 1; i < maxMFAAttempts; i++ {
+				_, err := authService.VerifyMFA(ctx, userId, gen, "000000")
+				require.ErrorIs(t, err, models.InvalidMFACode)
+			}
+
+			_, err := authService.VerifyMFA(ctx, userId, gen, recoveryCode)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("test token is revoked after failed attempts", func(t *testing.T) {
+		gen := generation()
+		for i := 1; i < maxMFAAttempts; i++ {
+			_, err := authService.VerifyMFA(ctx, userId, gen, "000000")
+			require.ErrorIs(t, err, models.InvalidMFACode)
+		}
+
+		_, err := authService.VerifyMFA(ctx, userId, gen, "000000")
+		require.ErrorIs(t, err, models.MFATokenRevoked)
+
+		// верный код по отозванному токену не принимается
+		_, err = authService.VerifyMFA(ctx, userId, gen, enrollment.RecoveryCodes[3])
+		require.ErrorIs(t, err, models.MFATokenRevoked)
+
+		// новый вход с паролем не сбрасывает счетчик: до конца окна код не принимается и по новому токену
+		require.NotEqual(t, gen, generation())
+		_, err = authService.VerifyMFA(ctx, userId, generation(), enrollment.RecoveryCodes[3])
+		assert.ErrorIs(t, err, models.MFATokenRevoked)
+	})
+}
+
+func TestSecretBox(t *testing.T) {
+	box, err := newSecretBox(make([]byte, 32))
+	require.NoError(t, err)
+
+	sealed, err := box.seal([]byte("secret"))
+	require.NoError(t, err)
+
+	opened, err := box.open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(opened))
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = box.open(sealed)
+	assert.Error(t, err)
+}
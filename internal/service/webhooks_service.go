@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Lucky112/social/internal/events"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/webhooks"
+)
+
+const webhookSecretBytes = 32
+
+// События, на которые может подписаться любой пользователь
+var publicEvents = []models.EventType{
+	models.EventProfileCreated,
+	models.EventProfileUpdated,
+}
+
+// События с персональными данными пользователей, на которые может подписаться только администратор
+var privateEvents = []models.EventType{
+	models.EventUserRegistered,
+}
+
+// Сервис подписок на события. Пользователь управляет своими подписками,
+// администратор - подписками всех пользователей
+type WebhooksService struct {
+	storage  WebhooksStorage
+	resolver webhooks.Resolver
+}
+
+// Подписки и журнал доставок по ним
+type WebhooksStorage interface {
+	Add(ctx context.Context, webhook *models.Webhook) (string, error)
+	Get(ctx context.Context, id string) (*models.Webhook, error)
+	GetAll(ctx context.Context) ([]*models.Webhook, error)
+	GetByUser(ctx context.Context, userId string) ([]*models.Webhook, error)
+	Subscribed(ctx context.Context, t models.EventType) ([]*models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userId string) error
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) (string, error)
+	Deliveries(ctx context.Context, webhookId string, limit int) ([]*models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error)
+}
+
+// resolver разрешает имена хостов из адресов подписок, чтобы отклонить адреса во внутренней сети
+func NewWebhooksService(storage WebhooksStorage, resolver webhooks.Resolver) WebhooksService {
+	return WebhooksService{
+		storage:  storage,
+		resolver: resolver,
+	}
+}
+
+// Создает подписку пользователя actor. Если типы событий не указаны, подписка оформляется
+// на все события, доступные пользователю. Секрет подписи генерируется и возвращается в подписке.
+// Адрес во внутренней сети отклоняется с *models.ValidationError
+func (s WebhooksService) Create(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error) {
+	events, err := checkEvents(actor, webhook.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkURL(ctx, webhook.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, fmt.Errorf("generating secret: %v", err)
+	}
+
+	w := &models.Webhook{
+		UserId: actor.UserId,
+		URL:    webhook.URL,
+		Events: events,
+		Secret: hex.EncodeToString(secret),
+		Active: true,
+	}
+
+	id, err := s.storage.Add(ctx, w)
+	if err != nil {
+		return nil, fmt.Errorf("adding webhook: %v", err)
+	}
+
+	created, err := s.storage.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting created webhook '%s': %v", id, err)
+	}
+
+	return created, nil
+}
+
+// Подписки пользователя, а для администратора - все подписки
+func (s WebhooksService) List(ctx context.Context, actor models.Actor) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	var err error
+
+	if role.AnyHas(actor.Roles, role.ManageAllWebhooks) {
+		webhooks, err = s.storage.GetAll(ctx)
+	} else {
+		webhooks, err = s.storage.GetByUser(ctx, actor.UserId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting webhooks: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+// Подписка, доступная actor. Чужая подписка для пользователя не существует
+func (s WebhooksService) Get(ctx context.Context, actor models.Actor, id string) (*models.Webhook, error) {
+	webhook, err := s.storage.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.WebhookNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("getting webhook '%s': %v", id, err)
+	}
+
+	if webhook.UserId != actor.UserId && !role.AnyHas(actor.Roles, role.ManageAllWebhooks) {
+		return nil, fmt.Errorf("webhook '%s' belongs to another user: %w", id, models.WebhookNotFound)
+	}
+
+	return webhook, nil
+}
+
+// Меняет адрес, типы событий и активность подписки. Секрет и владелец не меняются.
+// Адрес проверяется так же, как при создании
+func (s WebhooksService) Update(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error) {
+	w, err := s.Get(ctx, actor, webhook.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := checkEvents(actor, webhook.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkURL(ctx, webhook.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	w.URL = webhook.URL
+	w.Events = events
+	w.Active = webhook.Active
+
+	err = s.storage.Update(ctx, w)
+	if err != nil {
+		if errors.Is(err, models.WebhookNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("updating webhook '%s': %v", w.Id, err)
+	}
+
+	return w, nil
+}
+
+// Удаляет подписку вместе с журналом доставок
+func (s WebhooksService) Delete(ctx context.Context, actor models.Actor, id string) error {
+	_, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.Delete(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.WebhookNotFound) {
+			return err
+		}
+
+		return fmt.Errorf("deleting webhook '%s': %v", id, err)
+	}
+
+	return nil
+}
+
+// Не больше limit последних доставок по подписке, начиная с новых
+func (s WebhooksService) Deliveries(ctx context.Context, actor models.Actor, id string, limit int) ([]*models.WebhookDelivery, error) {
+	_, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.storage.Deliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("getting deliveries of webhook '%s': %v", id, err)
+	}
+
+	return deliveries, nil
+}
+
+// Создает новую доставку с тем же телом, что и у указанной. Она будет отправлена вместе
+// с остальными ожидающими доставками, даже если исходная доставка завершилась успешно
+func (s WebhooksService) Redeliver(ctx context.Context, actor models.Actor, webhookId, deliveryId string) (*models.WebhookDelivery, error) {
+	_, err := s.Get(ctx, actor, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.storage.GetDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		if errors.Is(err, models.DeliveryNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("getting delivery '%s': %v", deliveryId, err)
+	}
+
+	id, err := s.storage.AddDelivery(ctx, &models.WebhookDelivery{
+		WebhookId:    webhookId,
+		EventId:      delivery.EventId,
+		EventType:    delivery.EventType,
+		RedeliveryOf: delivery.Id,
+		Payload:      delivery.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("adding redelivery of '%s': %v", deliveryId, err)
+	}
+
+	redelivery, err := s.storage.GetDelivery(ctx, webhookId, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting created delivery '%s': %v", id, err)
+	}
+
+	return redelivery, nil
+}
+
+// Обработчик событий для events.Bus: добавляет доставку события по каждой активной подписке на него.
+// Вызывается в транзакции публикации события, поэтому доставки добавляются вместе с удалением события из outbox.
+// Повторная обработка того же события новых доставок не добавляет
+func (s WebhooksService) Enqueue(ctx context.Context, event *models.Event) error {
+	webhooks, err := s.storage.Subscribed(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("getting webhooks subscribed to %s: %v", event.Type, err)
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(events.NewMessage(event))
+	if err != nil {
+		return fmt.Errorf("marshalling event: %v", err)
+	}
+
+	for _, w := range webhooks {
+		_, err := s.storage.AddDelivery(ctx, &models.WebhookDelivery{
+			WebhookId: w.Id,
+			EventId:   event.Id,
+			EventType: event.Type,
+			Payload:   payload,
+		})
+		if err != nil {
+			return fmt.Errorf("adding delivery to webhook '%s': %v", w.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// События, на которые может подписаться actor
+func allowedEvents(actor models.Actor) []models.EventType {
+	if role.AnyHas(actor.Roles, role.ManageAllWebhooks) {
+		return append(slices.Clone(publicEvents), privateEvents...)
+	}
+
+	return slices.Clone(publicEvents)
+}
+
+// Проверяет, что адрес подписки не ведет во внутреннюю сеть (см. webhooks.CheckURL)
+func (s WebhooksService) checkURL(ctx context.Context, rawURL string) error {
+	err := webhooks.CheckURL(ctx, s.resolver, rawURL)
+	if err != nil {
+		verr := &models.ValidationError{}
+		verr.Add("url", "forbidden", fmt.Sprintf("url must point to a public address: %v", err))
+		return verr
+	}
+
+	return nil
+}
+
+// Проверяет, что actor может подписаться на указанные события, и возвращает их без повторов.
+// Пустой список означает все доступные события
+func checkEvents(actor models.Actor, events []models.EventType) ([]models.EventType, error) {
+	allowed := allowedEvents(actor)
+	if len(events) == 0 {
+		return allowed, nil
+	}
+
+	verr := &models.ValidationError{}
+	res := make([]models.EventType, 0, len(events))
+
+	for i, e := range events {
+		field := fmt.Sprintf("events[%d]", i)
+
+		switch {
+		case slices.Contains(allowed, e):
+			if !slices.Contains(res, e) {
+				res = append(res, e)
+			}
+		case slices.Contains(privateEvents, e):
+			verr.Add(field, "forbidden", fmt.Sprintf("subscription to %s requires the admin role", e))
+		default:
+			verr.Add(field, "oneof", fmt.Sprintf("unknown event type %s", e))
+		}
+	}
+
+	err := verr.OrNil()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+	"github.com/Lucky112/social/mocks"
+)
+
+// Разрешение имен без обращения к DNS
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host '%s'", host)
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+
+	return addrs, nil
+}
+
+var testResolver = fakeResolver{
+	"example.com":       {"93.184.215.14"},
+	"internal.example":  {"10.0.0.5"},
+	"metadata.internal": {"169.254.169.254"},
+}
+
+func TestWebhooksService(t *testing.T) {
+	ctx := context.Background()
+
+	user := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+	other := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+	admin := models.Actor{UserId: "3", Roles: []role.Role{role.Admin}}
+
+	service := NewWebhooksService(inmemory.NewWebhooksStorage(inmemory.NewDB()), testResolver)
+
+	t.Run("test Create subscribes to allowed events", func(t *testing.T) {
+		w, err := service.Create(ctx, user, &models.Webhook{URL: "https://example.com/hook"})
+		require.NoError(t, err)
+		assert.Equal(t, "1", w.UserId)
+		assert.True(t, w.Active)
+		assert.Len(t, w.Secret, 2*webhookSecretBytes)
+		assert.Equal(t, []models.EventType{models.EventProfileCreated, models.EventProfileUpdated}, w.Events)
+
+		w, err = service.Create(ctx, admin, &models.Webhook{URL: "https://example.com/admin"})
+		require.NoError(t, err)
+		assert.Contains(t, w.Events, models.EventUserRegistered)
+	})
+
+	t.Run("test Create with forbidden and unknown events", func(t *testing.T) {
+		_, err := service.Create(ctx, user, &models.Webhook{
+			URL:    "https://example.com/hook",
+			Events: []models.EventType{models.EventUserRegistered, "profile.deleted"},
+		})
+
+		var verr *models.ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, []models.FieldError{
+			{Field: "events[0]", Code: "forbidden", Message: "subscription to user.registered requires the admin role"},
+			{Field: "events[1]", Code: "oneof", Message: "unknown event type profile.deleted"},
+		}, verr.Fields)
+	})
+
+	t.Run("test foreign webhook is not found", func(t *testing.T) {
+		w, err := service.Create(ctx, user, &models.Webhook{URL: "https://example.com/own"})
+		require.NoError(t, err)
+
+		_, err = service.Get(ctx, other, w.Id)
+		assert.ErrorIs(t, err, models.WebhookNotFound)
+
+		err = service.Delete(ctx, other, w.Id)
+		assert.ErrorIs(t, err, models.WebhookNotFound)
+
+		_, err = service.Get(ctx, admin, w.Id)
+		assert.NoError(t, err)
+
+		own, err := service.List(ctx, other)
+		require.NoError(t, err)
+		assert.Empty(t, own)
+
+		all, err := service.List(ctx, admin)
+		require.NoError(t, err)
+		assert.Len(t, all, 3)
+	})
+
+	t.Run("test Update keeps secret", func(t *testing.T) {
+		w, err := service.Create(ctx, user, &models.Webhook{URL: "https://example.com/old"})
+		require.NoError(t, err)
+
+		updated, err := service.Update(ctx, user, &models.Webhook{
+			Id:     w.Id,
+			URL:    "https://example.com/new",
+			Events: []models.EventType{models.EventProfileUpdated, models.EventProfileUpdated},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", updated.URL)
+		assert.Equal(t, []models.EventType{models.EventProfileUpdated}, updated.Events)
+		assert.False(t, updated.Active)
+		assert.Equal(t, w.Secret, updated.Secret)
+	})
+
+	t.Run("test internal addresses are rejected", func(t *testing.T) {
+		for _, url := range []string{
+			"http://127.0.0.1:8080/hook",
+			"http://[::1]/hook",
+			"http://10.1.2.3/hook",
+			"http://192.168.0.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://0.0.0.0/hook",
+			"http://internal.example/hook",
+			"http://metadata.internal/hook",
+			"http://unknown.example/hook",
+		} {
+			_, err := service.Create(ctx, user, &models.Webhook{URL: url})
+
+			var verr *models.ValidationError
+			require.ErrorAs(t, err, &verr, url)
+			assert.Equal(t, "url", verr.Fields[0].Field, url)
+			assert.Equal(t, "forbidden", verr.Fields[0].Code, url)
+		}
+	})
+
+	t.Run("test Update to internal address is rejected", func(t *testing.T) {
+		w, err := service.Create(ctx, user, &models.Webhook{URL: "https://example.com/public"})
+		require.NoError(t, err)
+
+		_, err = service.Update(ctx, user, &models.Webhook{Id: w.Id, URL: "http://10.0.0.1/hook", Active: true})
+
+		var verr *models.ValidationError
+		require.ErrorAs(t, err, &verr)
+
+		stored, err := service.Get(ctx, user, w.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/public", stored.URL)
+	})
+}
+
+func TestWebhooksServiceEnqueue(t *testing.T) {
+	ctx := context.Background()
+	user := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+
+	service := NewWebhooksService(inmemory.NewWebhooksStorage(inmemory.NewDB()), testResolver)
+
+	w, err := service.Create(ctx, user, &models.Webhook{
+		URL:    "https://example.com/hook",
+		Events: []models.EventType{models.EventProfileCreated},
+	})
+	require.NoError(t, err)
+
+	event := &models.Event{
+		Id:            "100",
+		Type:          models.EventProfileCreated,
+		AggregateType: models.AggregateProfile,
+		AggregateId:   "5",
+		Payload:       json.RawMessage(`{"profile_id":"5"}`),
+	}
+
+	// повторная публикация того же события не создает новых доставок
+	for range 2 {
+		err = service.Enqueue(ctx, event)
+		require.NoError(t, err)
+	}
+
+	err = service.Enqueue(ctx, &models.Event{Id: "101", Type: models.EventProfileUpdated})
+	require.NoError(t, err)
+
+	deliveries, err := service.Deliveries(ctx, user, w.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "100", deliveries[0].EventId)
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+	assert.JSONEq(t, `{
+		"id": "100",
+		"type": "profile.created",
+		"aggregate_type": "profile",
+		"aggregate_id": "5",
+		"occurred_at": "0001-01-01T00:00:00Z",
+		"data": {"profile_id": "5"}
+	}`, string(deliveries[0].Payload))
+
+	t.Run("test Redeliver", func(t *testing.T) {
+		redelivery, err := service.Redeliver(ctx, user, w.Id, deliveries[0].Id)
+		require.NoError(t, err)
+		assert.Equal(t, deliveries[0].Id, redelivery.RedeliveryOf)
+		assert.Equal(t, deliveries[0].Payload, redelivery.Payload)
+
+		_, err = service.Redeliver(ctx, user, w.Id, "unknown")
+		assert.ErrorIs(t, err, models.DeliveryNotFound)
+	})
+}
+
+func TestWebhooksServiceStorageError(t *testing.T) {
+	storage := mocks.NewWebhooksStorage(t)
+	service := NewWebhooksService(storage, testResolver)
+
+	storage.On("Subscribed", mock.Anything, models.EventProfileCreated).Return(nil, assert.AnError).Once()
+
+	err := service.Enqueue(context.Background(), &models.Event{Type: models.EventProfileCreated})
+	assert.Error(t, err)
+}
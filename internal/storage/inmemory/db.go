@@ -0,0 +1,187 @@
+// Пакет inmemory содержит хранилища в памяти процесса с той же семантикой, что и хранилища postgres.
+// Подходит для тестов и локального запуска без базы
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// База в памяти, общая для хранилищ и менеджера транзакций
+type DB struct {
+	// Захватывается на время отдельной операции или на всю транзакцию,
+	// поэтому транзакции выполняются последовательно
+	mu    sync.Mutex
+	state *state
+}
+
+type state struct {
+	users       map[string]*userRecord
+	profiles    map[string]*models.Profile
+	outbox      []*outboxRecord
+	deadLetters []*outboxRecord
+	webhooks    map[string]*models.Webhook
+	deliveries  []*models.WebhookDelivery
+	photos      map[string]*models.Photo
+	privacy     map[string]*models.Privacy
+	// интересы по id анкеты
+	interests map[string][]string
+	lastId    int64
+}
+
+type userRecord struct {
+	user          models.User
+	recoveryCodes [][]byte
+	totpLastStep  int64
+	mfaAttempts   int
+	// начало окна, за которое считаются попытки
+	mfaWindowStart time.Time
+}
+
+func NewDB() *DB {
+	return &DB{
+		state: &state{
+			users:     make(map[string]*userRecord),
+			profiles:  make(map[string]*models.Profile),
+			webhooks:  make(map[string]*models.Webhook),
+			photos:    make(map[string]*models.Photo),
+			privacy:   make(map[string]*models.Privacy),
+			interests: make(map[string][]string),
+		},
+	}
+}
+
+// Копия состояния, изменения которой не затрагивают исходное
+func (s *state) clone() *state {
+	users := make(map[string]*userRecord, len(s.users))
+	for id, record := range s.users {
+		r := *record
+		users[id] = &r
+	}
+
+	profiles := make(map[string]*models.Profile, len(s.profiles))
+	for id, profile := range s.profiles {
+		p := *profile
+		profiles[id] = &p
+	}
+
+	webhooks := make(map[string]*models.Webhook, len(s.webhooks))
+	for id, webhook := range s.webhooks {
+		webhooks[id] = copyWebhook(webhook)
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0, len(s.deliveries))
+	for _, d := range s.deliveries {
+		copied := *d
+		deliveries = append(deliveries, &copied)
+	}
+
+	photos := make(map[string]*models.Photo, len(s.photos))
+	for id, photo := range s.photos {
+		photos[id] = copyPhoto(photo)
+	}
+
+	privacy := make(map[string]*models.Privacy, len(s.privacy))
+	for id, p := range s.privacy {
+		privacy[id] = copyPrivacy(p)
+	}
+
+	interests := make(map[string][]string, len(s.interests))
+	for id, names := range s.interests {
+		interests[id] = slices.Clone(names)
+	}
+
+	return &state{
+		users:       users,
+		profiles:    profiles,
+		outbox:      cloneRecords(s.outbox),
+		deadLetters: cloneRecords(s.deadLetters),
+		webhooks:    webhooks,
+		deliveries:  deliveries,
+		photos:      photos,
+		privacy:     privacy,
+		interests:   interests,
+		lastId:      s.lastId,
+	}
+}
+
+// Идентификаторы, как и в postgres, возрастают
+func (s *state) nextId() string {
+	s.lastId++
+	return strconv.FormatInt(s.lastId, 10)
+}
+
+// Ключ транзакции в контексте. Содержит базу, чтобы транзакция одной базы не использовалась в другой
+type txKey struct {
+	db *DB
+}
+
+// Выполняет функцию над состоянием транзакции из контекста, а если ее нет - над состоянием базы
+func (db *DB) do(ctx context.Context, fn func(s *state) error) error {
+	if s, ok := ctx.Value(txKey{db}).(*state); ok {
+		return fn(s)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return fn(db.state)
+}
+
+// Менеджер транзакций базы в памяти
+type TxManager struct {
+	db *DB
+}
+
+func NewTxManager(db *DB) TxManager {
+	return TxManager{db}
+}
+
+// Выполняет функцию над копией состояния базы и заменяет им состояние базы, если функция не вернула ошибку.
+// Если в контексте уже есть транзакция, ошибка функции откатывает только ее изменения, как точка сохранения.
+// Пока транзакция выполняется, остальные операции с базой ждут ее завершения, поэтому внутри функции
+// хранилища нужно вызывать только с переданным в нее контекстом
+func (m TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	key := txKey{m.db}
+
+	if s, ok := ctx.Value(key).(*state); ok {
+		savepoint := s.clone()
+
+		err := fn(ctx)
+		if err != nil {
+			*s = *savepoint
+			return err
+		}
+
+		return nil
+	}
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	s := m.db.state.clone()
+
+	err := fn(context.WithValue(ctx, key, s))
+	if err != nil {
+		return err
+	}
+
+	m.db.state = s
+
+	return nil
+}
+
+// Копия списка, чтобы вызывающая сторона не изменила хранимые данные
+func cloneBytes(list [][]byte) [][]byte {
+	res := make([][]byte, len(list))
+	for i, b := range list {
+		res[i] = append([]byte(nil), b...)
+	}
+
+	return res
+}
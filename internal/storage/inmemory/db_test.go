@@ -0,0 +1,164 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/service"
+)
+
+var (
+	_ service.UsersStorage     = UsersStorage{}
+	_ service.ProfilesStorage  = ProfilesStorage{}
+	_ service.TxManager        = TxManager{}
+	_ service.WebhooksStorage  = WebhooksStorage{}
+	_ service.PhotosStorage    = PhotosStorage{}
+	_ service.PrivacyStorage   = PrivacyStorage{}
+	_ service.InterestsStorage = InterestsStorage{}
+)
+
+func TestTxManager(t *testing.T) {
+	ctx := context.Background()
+
+	db := NewDB()
+	users := NewUsersStorage(db)
+	profiles := NewProfilesStorage(db)
+	tx := NewTxManager(db)
+
+	// пользователь и его анкета создаются вместе или не создаются вовсе
+	register := func(ctx context.Context, login string, fail bool) error {
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			userId, err := users.Add(ctx, &models.User{Login: login, Email: login + "@example.com"})
+			if err != nil {
+				return err
+			}
+
+			_, err = profiles.Add(ctx, &models.Profile{UserId: userId, Name: login})
+			if err != nil {
+				return err
+			}
+
+			if fail {
+				return errors.New("failed")
+			}
+
+			return nil
+		})
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		err := register(ctx, "ivan", false)
+		require.NoError(t, err)
+
+		user, err := users.Get(ctx, "ivan")
+		require.NoError(t, err)
+
+		userProfiles, err := profiles.GetByUser(ctx, user.Id)
+		require.NoError(t, err)
+		require.Len(t, userProfiles, 1)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		err := register(ctx, "petr", true)
+		require.Error(t, err)
+
+		_, err = users.Get(ctx, "petr")
+		require.ErrorIs(t, err, models.UserNotFound)
+
+		all, err := profiles.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+	})
+
+	t.Run("savepoint", func(t *testing.T) {
+		err := tx.InTx(ctx, func(ctx context.Context) error {
+			err := register(ctx, "anna", false)
+			require.NoError(t, err)
+
+			// ошибка вложенной транзакции откатывает только ее изменения
+			err = register(ctx, "olga", true)
+			require.Error(t, err)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = users.Get(ctx, "anna")
+		require.NoError(t, err)
+
+		_, err = users.Get(ctx, "olga")
+		require.ErrorIs(t, err, models.UserNotFound)
+	})
+
+	t.Run("isolation", func(t *testing.T) {
+		err := tx.InTx(ctx, func(txCtx context.Context) error {
+			_, err := users.Add(txCtx, &models.User{Login: "oleg", Email: "oleg@example.com"})
+			require.NoError(t, err)
+
+			_, err = users.Get(txCtx, "oleg")
+			require.NoError(t, err)
+
+			// изменения не видны за пределами транзакции до ее фиксации
+			require.ErrorIs(t, checkNotCommitted(db, "oleg"), models.UserNotFound)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = users.Get(ctx, "oleg")
+		require.NoError(t, err)
+	})
+}
+
+// Ищет пользователя в зафиксированном состоянии базы, не дожидаясь завершения транзакции
+func checkNotCommitted(db *DB, login string) error {
+	for _, r := range db.state.users {
+		if r.user.Login == login {
+			return nil
+		}
+	}
+
+	return models.UserNotFound
+}
+
+func TestUsersStorage(t *testing.T) {
+	ctx := context.Background()
+	users := NewUsersStorage(NewDB())
+
+	id, err := users.Add(ctx, &models.User{Login: "ivan", Email: "ivan@example.com", HashedPassword: []byte("pwd")})
+	require.NoError(t, err)
+
+	_, err = users.Add(ctx, &models.User{Login: "IVAN", Email: "other@example.com"})
+	var conflict *models.UserConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, "login", conflict.Field)
+
+	_, err = users.Add(ctx, &models.User{Login: "other", Email: "ivan@example.com"})
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, "email", conflict.Field)
+
+	user, err := users.Get(ctx, "ivan@example.com")
+	require.NoError(t, err)
+	require.Equal(t, id, user.Id)
+
+	err = users.SetTOTP(ctx, id, []byte("secret"), [][]byte{[]byte("code")})
+	require.NoError(t, err)
+
+	used, err := users.UseRecoveryCode(ctx, id, []byte("code"))
+	require.NoError(t, err)
+	require.True(t, used)
+
+	used, err = users.UseRecoveryCode(ctx, id, []byte("code"))
+	require.NoError(t, err)
+	require.False(t, used)
+
+	err = users.Delete(ctx, id)
+	require.NoError(t, err)
+
+	err = users.SetDisabled(ctx, id, true)
+	require.ErrorIs(t, err, models.UserNotFound)
+}
@@ -1,7 +0,0 @@
-package inmemory
-
-import "github.com/google/uuid"
-
-func generateId() string {
-	return uuid.NewString()
-}
@@ -0,0 +1,147 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Интересы анкет. Повторяет поведение postgres.InterestsProvider
+type InterestsStorage struct {
+	db *DB
+}
+
+func NewInterestsStorage(db *DB) InterestsStorage {
+	return InterestsStorage{db}
+}
+
+// Добавляет анкете интересы. Интересы должны быть нормализованы и не повторяться
+func (is InterestsStorage) AddInterests(ctx context.Context, profileId string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	err := is.db.do(ctx, func(s *state) error {
+		if _, exists := s.profiles[profileId]; !exists {
+			return models.ProfileNotFound
+		}
+
+		for _, name := range names {
+			if !slices.Contains(s.interests[profileId], name) {
+				s.interests[profileId] = append(s.interests[profileId], name)
+			}
+		}
+
+		slices.Sort(s.interests[profileId])
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("adding interests of profile '%s': %w", profileId, err)
+	}
+
+	return nil
+}
+
+// Интересы по id анкеты в алфавитном порядке. Анкет без интересов в результате нет
+func (is InterestsStorage) Interests(ctx context.Context, profileIds []string) (map[string][]string, error) {
+	res := make(map[string][]string)
+
+	_ = is.db.do(ctx, func(s *state) error {
+		for _, id := range profileIds {
+			if names := s.interests[id]; len(names) > 0 {
+				res[id] = slices.Clone(names)
+			}
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Интересы, начинающиеся с prefix, по числу анкет с ними. Учитываются только анкеты,
+// в которых видимость hobbies входит в visible
+func (is InterestsStorage) Autocomplete(ctx context.Context, prefix string, visible []models.Visibility, limit int) ([]models.InterestCount, error) {
+	counts := make(map[string]int)
+
+	_ = is.db.do(ctx, func(s *state) error {
+		for id, names := range s.interests {
+			if !slices.Contains(visible, s.privacy[id].Visibility(models.FieldHobbies)) {
+				continue
+			}
+
+			for _, name := range names {
+				if strings.HasPrefix(name, prefix) {
+					counts[name]++
+				}
+			}
+		}
+
+		return nil
+	})
+
+	res := make([]models.InterestCount, 0, len(counts))
+	for name, n := range counts {
+		res = append(res, models.InterestCount{Name: name, Profiles: n})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Profiles != res[j].Profiles {
+			return res[i].Profiles > res[j].Profiles
+		}
+
+		return res[i].Name < res[j].Name
+	})
+
+	return res[:min(limit, len(res))], nil
+}
+
+// Анкеты других пользователей с общими с анкетой profileId интересами, начиная с анкет с наибольшим
+// числом общих интересов. Учитываются только анкеты, в которых видимость hobbies входит в visible
+func (is InterestsStorage) Similar(ctx context.Context, profileId string, visible []models.Visibility, limit int) ([]*models.ProfileMatch, error) {
+	var res []*models.ProfileMatch
+
+	_ = is.db.do(ctx, func(s *state) error {
+		source, exists := s.profiles[profileId]
+		if !exists {
+			return nil
+		}
+
+		for id, p := range s.profiles {
+			if p.UserId == source.UserId || !slices.Contains(visible, s.privacy[id].Visibility(models.FieldHobbies)) {
+				continue
+			}
+
+			var shared []string
+			for _, name := range s.interests[id] {
+				if slices.Contains(s.interests[profileId], name) {
+					shared = append(shared, name)
+				}
+			}
+
+			if len(shared) == 0 {
+				continue
+			}
+
+			copied := *p
+			res = append(res, &models.ProfileMatch{Profile: &copied, Shared: shared})
+		}
+
+		return nil
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		if len(res[i].Shared) != len(res[j].Shared) {
+			return len(res[i].Shared) > len(res[j].Shared)
+		}
+
+		return lessId(res[i].Profile.Id, res[j].Profile.Id)
+	})
+
+	return res[:min(limit, len(res))], nil
+}
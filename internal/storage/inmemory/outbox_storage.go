@@ -0,0 +1,156 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type outboxRecord struct {
+	event         models.Event
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+// Исходящие события. Повторяет поведение postgres.OutboxProvider
+type OutboxStorage struct {
+	db *DB
+}
+
+func NewOutboxStorage(db *DB) OutboxStorage {
+	return OutboxStorage{db}
+}
+
+func (o OutboxStorage) Add(ctx context.Context, event *models.Event) error {
+	return o.db.do(ctx, func(s *state) error {
+		e := *event
+		e.Id = s.nextId()
+		e.Attempts = 0
+
+		s.outbox = append(s.outbox, &outboxRecord{event: e})
+
+		return nil
+	})
+}
+
+// Транзакции базы в памяти выполняются последовательно, поэтому блокировка всегда свободна
+func (o OutboxStorage) Lock(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// Возвращает не больше limit событий, готовых к публикации, в порядке записи.
+// События сущности, у которой есть более раннее событие в ожидании повторной попытки, не возвращаются
+func (o OutboxStorage) Pending(ctx context.Context, limit int) ([]*models.Event, error) {
+	var res []*models.Event
+
+	now := time.Now()
+
+	_ = o.db.do(ctx, func(s *state) error {
+		waiting := make(map[string]bool)
+
+		for _, r := range s.outbox {
+			if len(res) == limit {
+				break
+			}
+
+			key := r.event.AggregateType + "/" + r.event.AggregateId
+			if r.nextAttemptAt.After(now) {
+				waiting[key] = true
+			}
+			if waiting[key] {
+				continue
+			}
+
+			e := r.event
+			res = append(res, &e)
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Удаляет опубликованное событие
+func (o OutboxStorage) Delete(ctx context.Context, id string) error {
+	return o.db.do(ctx, func(s *state) error {
+		s.outbox = slices.DeleteFunc(s.outbox, func(r *outboxRecord) bool {
+			return r.event.Id == id
+		})
+
+		return nil
+	})
+}
+
+// Запоминает неудачную попытку публикации и откладывает следующую
+func (o OutboxStorage) Retry(ctx context.Context, id string, next time.Time, lastErr string) error {
+	return o.db.do(ctx, func(s *state) error {
+		for _, r := range s.outbox {
+			if r.event.Id == id {
+				r.event.Attempts++
+				r.nextAttemptAt = next
+				r.lastError = lastErr
+			}
+		}
+
+		return nil
+	})
+}
+
+// Переносит событие, которое не удалось опубликовать, в список недоставленных
+func (o OutboxStorage) DeadLetter(ctx context.Context, id string, lastErr string) error {
+	return o.db.do(ctx, func(s *state) error {
+		i := slices.IndexFunc(s.outbox, func(r *outboxRecord) bool {
+			return r.event.Id == id
+		})
+		if i < 0 {
+			return nil
+		}
+
+		r := s.outbox[i]
+		r.event.Attempts++
+		r.lastError = lastErr
+
+		s.outbox = slices.Delete(s.outbox, i, i+1)
+		s.deadLetters = append(s.deadLetters, r)
+
+		return nil
+	})
+}
+
+// Неопубликованные события в порядке записи
+func (o OutboxStorage) Events(ctx context.Context) []*models.Event {
+	return o.list(ctx, func(s *state) []*outboxRecord { return s.outbox })
+}
+
+// События, перенесенные в список недоставленных, в порядке переноса
+func (o OutboxStorage) DeadLetters(ctx context.Context) []*models.Event {
+	return o.list(ctx, func(s *state) []*outboxRecord { return s.deadLetters })
+}
+
+func (o OutboxStorage) list(ctx context.Context, records func(s *state) []*outboxRecord) []*models.Event {
+	var res []*models.Event
+
+	_ = o.db.do(ctx, func(s *state) error {
+		for _, r := range records(s) {
+			e := r.event
+			res = append(res, &e)
+		}
+
+		return nil
+	})
+
+	return res
+}
+
+func cloneRecords(records []*outboxRecord) []*outboxRecord {
+	res := make([]*outboxRecord, 0, len(records))
+	for _, r := range records {
+		copied := *r
+		res = append(res, &copied)
+	}
+
+	return res
+}
@@ -0,0 +1,126 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Метаданные фотографий анкет. Повторяет поведение postgres.PhotosProvider
+type PhotosStorage struct {
+	db *DB
+}
+
+func NewPhotosStorage(db *DB) PhotosStorage {
+	return PhotosStorage{db}
+}
+
+func (ps PhotosStorage) Add(ctx context.Context, photo *models.Photo) (string, error) {
+	var id string
+
+	err := ps.db.do(ctx, func(s *state) error {
+		if _, exists := s.profiles[photo.ProfileId]; !exists {
+			return models.ProfileNotFound
+		}
+
+		p := copyPhoto(photo)
+		p.CreatedAt = time.Now()
+
+		id = s.nextId()
+		p.Id = id
+		s.photos[id] = p
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("adding photo of profile '%s': %w", photo.ProfileId, err)
+	}
+
+	return id, nil
+}
+
+func (ps PhotosStorage) Get(ctx context.Context, id string) (*models.Photo, error) {
+	var res *models.Photo
+
+	err := ps.db.do(ctx, func(s *state) error {
+		p, exists := s.photos[id]
+		if !exists {
+			return models.PhotoNotFound
+		}
+
+		res = copyPhoto(p)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", id, err)
+	}
+
+	return res, nil
+}
+
+// Все фотографии анкет пользователя в порядке загрузки
+func (ps PhotosStorage) GetByUser(ctx context.Context, userId string) ([]*models.Photo, error) {
+	var res []*models.Photo
+
+	_ = ps.db.do(ctx, func(s *state) error {
+		for _, p := range s.photos {
+			if p.UserId == userId {
+				res = append(res, copyPhoto(p))
+			}
+		}
+
+		return nil
+	})
+
+	slices.SortFunc(res, func(a, b *models.Photo) int {
+		if lessId(a.Id, b.Id) {
+			return -1
+		}
+		return 1
+	})
+
+	return res, nil
+}
+
+// Последние фотографии анкет по id анкеты. Анкет без фотографий в результате нет
+func (ps PhotosStorage) Avatars(ctx context.Context, profileIds []string) (map[string]*models.Photo, error) {
+	res := make(map[string]*models.Photo)
+
+	_ = ps.db.do(ctx, func(s *state) error {
+		for id, p := range s.photos {
+			if !slices.Contains(profileIds, p.ProfileId) {
+				continue
+			}
+
+			latest, exists := res[p.ProfileId]
+			if !exists || lessId(latest.Id, id) {
+				res[p.ProfileId] = copyPhoto(p)
+			}
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Удаляет фотографии анкеты, как каскадное удаление в postgres
+func (s *state) deletePhotos(profileId string) {
+	for id, p := range s.photos {
+		if p.ProfileId == profileId {
+			delete(s.photos, id)
+		}
+	}
+}
+
+// Копия фотографии, не разделяющая с исходной список вариантов
+func copyPhoto(p *models.Photo) *models.Photo {
+	copied := *p
+	copied.Variants = slices.Clone(p.Variants)
+
+	return &copied
+}
@@ -0,0 +1,65 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Настройки видимости полей анкет. Повторяет поведение postgres.PrivacyProvider
+type PrivacyStorage struct {
+	db *DB
+}
+
+func NewPrivacyStorage(db *DB) PrivacyStorage {
+	return PrivacyStorage{db}
+}
+
+// Настройки видимости по id анкеты. Анкет, владельцы которых не меняли настройки, в результате нет
+func (ps PrivacyStorage) Privacy(ctx context.Context, profileIds []string) (map[string]*models.Privacy, error) {
+	res := make(map[string]*models.Privacy)
+
+	_ = ps.db.do(ctx, func(s *state) error {
+		for _, id := range profileIds {
+			if p, exists := s.privacy[id]; exists {
+				res[id] = copyPrivacy(p)
+			}
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Заменяет настройки видимости анкеты
+func (ps PrivacyStorage) SetPrivacy(ctx context.Context, profileId string, fields map[models.ProfileField]models.Visibility) error {
+	err := ps.db.do(ctx, func(s *state) error {
+		if _, exists := s.profiles[profileId]; !exists {
+			return models.ProfileNotFound
+		}
+
+		s.privacy[profileId] = &models.Privacy{
+			Fields:    maps.Clone(fields),
+			UpdatedAt: time.Now(),
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("setting privacy of profile '%s': %w", profileId, err)
+	}
+
+	return nil
+}
+
+// Копия настроек, не разделяющая с исходными список полей
+func copyPrivacy(p *models.Privacy) *models.Privacy {
+	copied := *p
+	copied.Fields = maps.Clone(p.Fields)
+
+	return &copied
+}
@@ -0,0 +1,154 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type ProfilesStorage struct {
+	db *DB
+}
+
+func NewProfilesStorage(db *DB) ProfilesStorage {
+	return ProfilesStorage{db}
+}
+
+func (ps ProfilesStorage) GetAll(ctx context.Context) ([]*models.Profile, error) {
+	return ps.filter(ctx, func(s *state, p *models.Profile) bool {
+		return true
+	}), nil
+}
+
+func (ps ProfilesStorage) Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error) {
+	return ps.filter(ctx, func(s *state, p *models.Profile) bool {
+		return strings.HasPrefix(p.Name, params.NamePrefix) && strings.HasPrefix(p.Surname, params.SurnamePrefix) &&
+			(params.Interest == "" || slices.Contains(s.interests[p.Id], params.Interest))
+	}), nil
+}
+
+func (ps ProfilesStorage) Get(ctx context.Context, id string) (*models.Profile, error) {
+	var res *models.Profile
+
+	err := ps.db.do(ctx, func(s *state) error {
+		p, exists := s.profiles[id]
+		if !exists {
+			return models.ProfileNotFound
+		}
+
+		copied := *p
+		res = &copied
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", id, err)
+	}
+
+	return res, nil
+}
+
+func (ps ProfilesStorage) Add(ctx context.Context, profile *models.Profile) (string, error) {
+	if profile == nil {
+		return "", fmt.Errorf("attempt to store nil profile")
+	}
+
+	var id string
+
+	_ = ps.db.do(ctx, func(s *state) error {
+		p := *profile
+		p.UpdatedAt = time.Now()
+		p.Avatar = nil
+
+		id = s.nextId()
+		p.Id = id
+		s.profiles[id] = &p
+
+		return nil
+	})
+
+	return id, nil
+}
+
+func (ps ProfilesStorage) Delete(ctx context.Context, id string) error {
+	err := ps.db.do(ctx, func(s *state) error {
+		if _, exists := s.profiles[id]; !exists {
+			return models.ProfileNotFound
+		}
+
+		delete(s.profiles, id)
+		s.deleteDetails(id)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleting profile '%s': %w", id, err)
+	}
+
+	return nil
+}
+
+// Возвращает все анкеты пользователя
+func (ps ProfilesStorage) GetByUser(ctx context.Context, userId string) ([]*models.Profile, error) {
+	return ps.filter(ctx, func(s *state, p *models.Profile) bool {
+		return p.UserId == userId
+	}), nil
+}
+
+// Удаляет все анкеты пользователя
+func (ps ProfilesStorage) DeleteByUser(ctx context.Context, userId string) error {
+	_ = ps.db.do(ctx, func(s *state) error {
+		for id, p := range s.profiles {
+			if p.UserId == userId {
+				delete(s.profiles, id)
+				s.deleteDetails(id)
+			}
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+// Удаляет данные анкеты в других таблицах, как каскадное удаление в postgres
+func (s *state) deleteDetails(profileId string) {
+	s.deletePhotos(profileId)
+	delete(s.privacy, profileId)
+	delete(s.interests, profileId)
+}
+
+// Копии анкет, удовлетворяющих условию, в порядке создания
+func (ps ProfilesStorage) filter(ctx context.Context, match func(s *state, p *models.Profile) bool) []*models.Profile {
+	var ids []string
+
+	res := make(map[string]*models.Profile)
+
+	_ = ps.db.do(ctx, func(s *state) error {
+		for id, p := range s.profiles {
+			if match(s, p) {
+				copied := *p
+				res[id] = &copied
+				ids = append(ids, id)
+			}
+		}
+
+		return nil
+	})
+
+	sort.Slice(ids, func(i, j int) bool {
+		return lessId(ids[i], ids[j])
+	})
+
+	profiles := make([]*models.Profile, 0, len(ids))
+	for _, id := range ids {
+		profiles = append(profiles, res[id])
+	}
+
+	return profiles
+}
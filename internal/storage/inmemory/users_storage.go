@@ -0,0 +1,314 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+)
+
+type UsersStorage struct {
+	db *DB
+}
+
+func NewUsersStorage(db *DB) UsersStorage {
+	return UsersStorage{db}
+}
+
+func (us UsersStorage) Add(ctx context.Context, user *models.User) (string, error) {
+	var id string
+
+	err := us.db.do(ctx, func(s *state) error {
+		for _, r := range s.users {
+			if strings.EqualFold(r.user.Login, user.Login) {
+				return &models.UserConflictError{Field: "login"}
+			}
+			if strings.EqualFold(r.user.Email, user.Email) {
+				return &models.UserConflictError{Field: "email"}
+			}
+		}
+
+		id = s.nextId()
+		s.users[id] = &userRecord{
+			user: models.User{
+				Id:             id,
+				Email:          user.Email,
+				Login:          user.Login,
+				HashedPassword: user.HashedPassword,
+				Roles:          []role.Role{role.User},
+			},
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("inserting user: %w", err)
+	}
+
+	return id, nil
+}
+
+// Ищет пользователя по логину или email. Совпадение по логину приоритетнее совпадения по email
+func (us UsersStorage) Get(ctx context.Context, identifier string) (*models.User, error) {
+	var res *models.User
+
+	err := us.db.do(ctx, func(s *state) error {
+		for _, r := range s.users {
+			if strings.EqualFold(r.user.Login, identifier) {
+				res = copyUser(&r.user)
+				return nil
+			}
+		}
+
+		for _, r := range s.users {
+			if strings.EqualFold(r.user.Email, identifier) {
+				res = copyUser(&r.user)
+				return nil
+			}
+		}
+
+		return models.UserNotFound
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", identifier, err)
+	}
+
+	return res, nil
+}
+
+func (us UsersStorage) GetById(ctx context.Context, userId string) (*models.User, error) {
+	var res *models.User
+
+	err := us.update(ctx, userId, func(r *userRecord) {
+		res = copyUser(&r.user)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", userId, err)
+	}
+
+	return res, nil
+}
+
+func (us UsersStorage) GetAll(ctx context.Context) ([]*models.User, error) {
+	var res []*models.User
+
+	_ = us.db.do(ctx, func(s *state) error {
+		for _, r := range s.users {
+			res = append(res, copyUser(&r.user))
+		}
+
+		return nil
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		return lessId(res[i].Id, res[j].Id)
+	})
+
+	return res, nil
+}
+
+func (us UsersStorage) SetDisabled(ctx context.Context, userId string, disabled bool) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.Disabled = disabled
+	})
+	if err != nil {
+		return fmt.Errorf("setting disabled of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (us UsersStorage) SetPassword(ctx context.Context, userId string, hashedPassword []byte) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.HashedPassword = hashedPassword
+		r.user.TokensValidAfter = time.Now()
+	})
+	if err != nil {
+		return fmt.Errorf("setting password of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Сохраняет новый (еще не подтвержденный) TOTP-секрет и хэши кодов восстановления
+func (us UsersStorage) SetTOTP(ctx context.Context, userId string, secret []byte, recoveryCodes [][]byte) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.TOTPSecret = secret
+		r.user.TOTPEnabled = false
+		r.recoveryCodes = cloneBytes(recoveryCodes)
+		r.totpLastStep = 0
+	})
+	if err != nil {
+		return fmt.Errorf("setting totp secret of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (us UsersStorage) EnableTOTP(ctx context.Context, userId string) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.TOTPEnabled = true
+	})
+	if err != nil {
+		return fmt.Errorf("enabling totp of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Удаляет код восстановления пользователя, если он есть.
+// Возвращает false, если такого кода у пользователя нет
+func (us UsersStorage) UseRecoveryCode(ctx context.Context, userId string, code []byte) (bool, error) {
+	used := false
+
+	_ = us.update(ctx, userId, func(r *userRecord) {
+		r.recoveryCodes = slices.DeleteFunc(r.recoveryCodes, func(c []byte) bool {
+			if bytes.Equal(c, code) {
+				used = true
+				return true
+			}
+
+			return false
+		})
+	})
+
+	return used, nil
+}
+
+// Запоминает шаг принятого TOTP-кода, если он больше сохраненного.
+// Возвращает false, если код этого шага уже использовался
+func (us UsersStorage) UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error) {
+	used := false
+
+	_ = us.update(ctx, userId, func(r *userRecord) {
+		if r.totpLastStep < step {
+			r.totpLastStep = step
+			used = true
+		}
+	})
+
+	return used, nil
+}
+
+// Учитывает попытку ввода кода по MFA-токену поколения generation и возвращает число попыток
+// пользователя за окно window, которое начинается с первой попытки. Если поколение устарело
+// или попыток за окно уже limit, возвращает models.MFATokenRevoked
+func (us UsersStorage) AddMFAAttempt(ctx context.Context, userId string, generation, limit int, window time.Duration) (int, error) {
+	var attempts int
+
+	now := time.Now()
+
+	err := us.update(ctx, userId, func(r *userRecord) {
+		if r.user.MFAGeneration != generation {
+			return
+		}
+
+		if !r.mfaWindowStart.After(now.Add(-window)) {
+			r.mfaWindowStart = now
+			r.mfaAttempts = 0
+		}
+
+		if r.mfaAttempts >= limit {
+			return
+		}
+
+		r.mfaAttempts++
+		attempts = r.mfaAttempts
+	})
+	if err == nil && attempts == 0 {
+		err = models.MFATokenRevoked
+	}
+	if err != nil {
+		return 0, fmt.Errorf("adding mfa attempt of '%s': %w", userId, err)
+	}
+
+	return attempts, nil
+}
+
+// Сбрасывает счетчик попыток ввода кода
+func (us UsersStorage) ResetMFAAttempts(ctx context.Context, userId string) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.mfaAttempts = 0
+		r.mfaWindowStart = time.Time{}
+	})
+	if err != nil {
+		return fmt.Errorf("resetting mfa attempts of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Отзывает выданные MFA-токены, начиная новое поколение. Счетчик попыток не сбрасывается
+func (us UsersStorage) RevokeMFATokens(ctx context.Context, userId string) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.MFAGeneration++
+	})
+	if err != nil {
+		return fmt.Errorf("revoking mfa tokens of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Обновляет хэш пароля без отзыва токенов: сам пароль не меняется
+func (us UsersStorage) UpdatePasswordHash(ctx context.Context, userId string, hashedPassword []byte) error {
+	err := us.update(ctx, userId, func(r *userRecord) {
+		r.user.HashedPassword = hashedPassword
+	})
+	if err != nil {
+		return fmt.Errorf("updating password hash of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (us UsersStorage) Delete(ctx context.Context, userId string) error {
+	err := us.db.do(ctx, func(s *state) error {
+		if _, exists := s.users[userId]; !exists {
+			return models.UserNotFound
+		}
+
+		delete(s.users, userId)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleting user '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Вызывает функцию для записи пользователя. Если пользователя нет, возвращает models.UserNotFound
+func (us UsersStorage) update(ctx context.Context, userId string, fn func(r *userRecord)) error {
+	return us.db.do(ctx, func(s *state) error {
+		r, exists := s.users[userId]
+		if !exists {
+			return models.UserNotFound
+		}
+
+		fn(r)
+
+		return nil
+	})
+}
+
+func copyUser(user *models.User) *models.User {
+	u := *user
+	u.Roles = slices.Clone(user.Roles)
+	return &u
+}
+
+func lessId(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+
+	return a < b
+}
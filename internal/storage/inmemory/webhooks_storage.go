@@ -0,0 +1,292 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Подписки и журнал доставок. Повторяет поведение postgres.WebhooksProvider
+type WebhooksStorage struct {
+	db *DB
+}
+
+func NewWebhooksStorage(db *DB) WebhooksStorage {
+	return WebhooksStorage{db}
+}
+
+func (ws WebhooksStorage) Add(ctx context.Context, webhook *models.Webhook) (string, error) {
+	var id string
+
+	_ = ws.db.do(ctx, func(s *state) error {
+		w := copyWebhook(webhook)
+		w.CreatedAt = time.Now()
+
+		id = s.nextId()
+		w.Id = id
+		s.webhooks[id] = w
+
+		return nil
+	})
+
+	return id, nil
+}
+
+func (ws WebhooksStorage) Get(ctx context.Context, id string) (*models.Webhook, error) {
+	var res *models.Webhook
+
+	err := ws.db.do(ctx, func(s *state) error {
+		w, exists := s.webhooks[id]
+		if !exists {
+			return models.WebhookNotFound
+		}
+
+		res = copyWebhook(w)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", id, err)
+	}
+
+	return res, nil
+}
+
+func (ws WebhooksStorage) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	return ws.filter(ctx, func(w *models.Webhook) bool {
+		return true
+	}), nil
+}
+
+// Возвращает все подписки пользователя
+func (ws WebhooksStorage) GetByUser(ctx context.Context, userId string) ([]*models.Webhook, error) {
+	return ws.filter(ctx, func(w *models.Webhook) bool {
+		return w.UserId == userId
+	}), nil
+}
+
+// Возвращает активные подписки на события указанного типа
+func (ws WebhooksStorage) Subscribed(ctx context.Context, t models.EventType) ([]*models.Webhook, error) {
+	return ws.filter(ctx, func(w *models.Webhook) bool {
+		return w.Active && w.Subscribed(t)
+	}), nil
+}
+
+// Обновляет адрес, типы событий и активность подписки
+func (ws WebhooksStorage) Update(ctx context.Context, webhook *models.Webhook) error {
+	return ws.db.do(ctx, func(s *state) error {
+		w, exists := s.webhooks[webhook.Id]
+		if !exists {
+			return models.WebhookNotFound
+		}
+
+		w.URL = webhook.URL
+		w.Events = slices.Clone(webhook.Events)
+		w.Active = webhook.Active
+
+		return nil
+	})
+}
+
+// Удаляет подписку вместе с журналом доставок
+func (ws WebhooksStorage) Delete(ctx context.Context, id string) error {
+	return ws.db.do(ctx, func(s *state) error {
+		if _, exists := s.webhooks[id]; !exists {
+			return models.WebhookNotFound
+		}
+
+		deleteWebhook(s, id)
+
+		return nil
+	})
+}
+
+// Удаляет все подписки пользователя
+func (ws WebhooksStorage) DeleteByUser(ctx context.Context, userId string) error {
+	return ws.db.do(ctx, func(s *state) error {
+		for id, w := range s.webhooks {
+			if w.UserId == userId {
+				deleteWebhook(s, id)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Добавляет доставку, ожидающую отправки. Если событие уже доставляется по этой подписке
+// и доставка не повторная, ничего не делает и возвращает пустой идентификатор
+func (ws WebhooksStorage) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) (string, error) {
+	var id string
+
+	err := ws.db.do(ctx, func(s *state) error {
+		if _, exists := s.webhooks[delivery.WebhookId]; !exists {
+			return models.WebhookNotFound
+		}
+
+		if delivery.RedeliveryOf == "" {
+			for _, d := range s.deliveries {
+				if d.WebhookId == delivery.WebhookId && d.EventId == delivery.EventId && d.RedeliveryOf == "" {
+					return nil
+				}
+			}
+		}
+
+		now := time.Now()
+
+		id = s.nextId()
+		s.deliveries = append(s.deliveries, &models.WebhookDelivery{
+			Id:            id,
+			WebhookId:     delivery.WebhookId,
+			EventId:       delivery.EventId,
+			EventType:     delivery.EventType,
+			RedeliveryOf:  delivery.RedeliveryOf,
+			Payload:       delivery.Payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("adding delivery: %w", err)
+	}
+
+	return id, nil
+}
+
+// Возвращает не больше limit последних доставок по подписке, начиная с новых
+func (ws WebhooksStorage) Deliveries(ctx context.Context, webhookId string, limit int) ([]*models.WebhookDelivery, error) {
+	var res []*models.WebhookDelivery
+
+	_ = ws.db.do(ctx, func(s *state) error {
+		for i := len(s.deliveries) - 1; i >= 0 && len(res) < limit; i-- {
+			if d := s.deliveries[i]; d.WebhookId == webhookId {
+				copied := *d
+				res = append(res, &copied)
+			}
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Возвращает доставку по подписке
+func (ws WebhooksStorage) GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error) {
+	var res *models.WebhookDelivery
+
+	err := ws.db.do(ctx, func(s *state) error {
+		for _, d := range s.deliveries {
+			if d.Id == deliveryId && d.WebhookId == webhookId {
+				copied := *d
+				res = &copied
+				return nil
+			}
+		}
+
+		return models.DeliveryNotFound
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking '%s' up: %w", deliveryId, err)
+	}
+
+	return res, nil
+}
+
+// Занимает не больше limit доставок по активным подпискам, время отправки которых наступило:
+// переносит их следующую попытку на leaseUntil, чтобы до этого времени их не заняли снова
+func (ws WebhooksStorage) ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]*models.OutgoingDelivery, error) {
+	var res []*models.OutgoingDelivery
+
+	now := time.Now()
+
+	_ = ws.db.do(ctx, func(s *state) error {
+		for _, d := range s.deliveries {
+			if len(res) == limit {
+				break
+			}
+
+			w := s.webhooks[d.WebhookId]
+			if d.Status != models.DeliveryPending || d.NextAttemptAt.After(now) || !w.Active {
+				continue
+			}
+
+			d.NextAttemptAt = leaseUntil
+
+			res = append(res, &models.OutgoingDelivery{
+				Delivery: *d,
+				URL:      w.URL,
+				Secret:   w.Secret,
+			})
+		}
+
+		return nil
+	})
+
+	return res, nil
+}
+
+// Сохраняет результат попытки доставки
+func (ws WebhooksStorage) RecordAttempt(ctx context.Context, deliveryId string, attempt models.DeliveryAttempt) error {
+	return ws.db.do(ctx, func(s *state) error {
+		i := slices.IndexFunc(s.deliveries, func(d *models.WebhookDelivery) bool {
+			return d.Id == deliveryId
+		})
+		if i < 0 {
+			return models.DeliveryNotFound
+		}
+
+		d := s.deliveries[i]
+		d.Status = attempt.Status
+		d.Attempts++
+		d.ResponseStatus = attempt.ResponseStatus
+		d.LastError = attempt.Error
+		d.NextAttemptAt = attempt.NextAttemptAt
+		if attempt.Status == models.DeliverySucceeded {
+			d.DeliveredAt = attempt.At
+		}
+
+		return nil
+	})
+}
+
+// Копии подписок, удовлетворяющих условию, в порядке создания
+func (ws WebhooksStorage) filter(ctx context.Context, match func(w *models.Webhook) bool) []*models.Webhook {
+	var res []*models.Webhook
+
+	_ = ws.db.do(ctx, func(s *state) error {
+		for _, w := range s.webhooks {
+			if match(w) {
+				res = append(res, copyWebhook(w))
+			}
+		}
+
+		return nil
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		return lessId(res[i].Id, res[j].Id)
+	})
+
+	return res
+}
+
+func deleteWebhook(s *state, id string) {
+	delete(s.webhooks, id)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *models.WebhookDelivery) bool {
+		return d.WebhookId == id
+	})
+}
+
+func copyWebhook(webhook *models.Webhook) *models.Webhook {
+	w := *webhook
+	w.Events = slices.Clone(webhook.Events)
+	return &w
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+type AuditProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewAuditProvider(querier pgxscan.Querier) AuditProvider {
+	return AuditProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p AuditProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+func (p AuditProvider) Add(ctx context.Context, record *models.AuditRecord) error {
+	query := `
+		insert into scl.audit_log(actor_id, action, target_id)
+		values (@actor, @action, @target)
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"actor":  record.ActorId,
+		"action": string(record.Action),
+		"target": record.TargetId,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("inserting into db: %v", err)
+	}
+
+	_, err = pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("collecting new audit record id: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestInsertAuditRecord(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := AuditProvider{mock}
+
+	record := &models.AuditRecord{
+		ActorId:  "1",
+		Action:   models.AuditDisableUser,
+		TargetId: "2",
+	}
+
+	t.Run("Insert successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("insert").WithArgs("1", "disable_user", "2").WillReturnRows(rows)
+
+		err := p.Add(context.Background(), record)
+		require.NoError(t, err)
+	})
+
+	t.Run("insert with error", func(t *testing.T) {
+		mock.ExpectQuery("insert").WithArgs("1", "disable_user", "2").WillReturnError(errors.New("db error"))
+
+		err := p.Add(context.Background(), record)
+		require.Error(t, err)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
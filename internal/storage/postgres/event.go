@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type event struct {
+	Id            int64     `db:"id"`
+	EventType     string    `db:"event_type"`
+	AggregateType string    `db:"aggregate_type"`
+	AggregateId   string    `db:"aggregate_id"`
+	Payload       []byte    `db:"payload"`
+	OccurredAt    time.Time `db:"occurred_at"`
+	Attempts      int       `db:"attempts"`
+}
+
+func (e *event) toModel() *models.Event {
+	return &models.Event{
+		Id:            fmt.Sprintf("%d", e.Id),
+		Type:          models.EventType(e.EventType),
+		AggregateType: e.AggregateType,
+		AggregateId:   e.AggregateId,
+		Payload:       e.Payload,
+		OccurredAt:    e.OccurredAt,
+		Attempts:      e.Attempts,
+	}
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type idempotencyKey struct {
+	Scope               string      `db:"scope"`
+	Key                 string      `db:"key"`
+	Fingerprint         string      `db:"fingerprint"`
+	Completed           bool        `db:"completed"`
+	ResponseStatus      null.Int32  `db:"response_status"`
+	ResponseContentType null.String `db:"response_content_type"`
+	ResponseBody        []byte      `db:"response_body"`
+	ExpiresAt           time.Time   `db:"expires_at"`
+}
+
+func (k *idempotencyKey) toModel() *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{
+		Scope:       k.Scope,
+		Key:         k.Key,
+		Fingerprint: k.Fingerprint,
+		Completed:   k.Completed,
+		Response: models.StoredResponse{
+			Status:      int(k.ResponseStatus.Int32),
+			ContentType: k.ResponseContentType.String,
+			Body:        k.ResponseBody,
+		},
+		ExpiresAt: k.ExpiresAt,
+	}
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+// Хранилище ключей идемпотентности, общее для всех экземпляров приложения
+type IdempotencyProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewIdempotencyProvider(querier pgxscan.Querier) IdempotencyProvider {
+	return IdempotencyProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p IdempotencyProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+// Резервирует ключ, если его нет или его срок истек. Иначе возвращает существующую запись
+func (p IdempotencyProvider) Acquire(ctx context.Context, r *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, bool, error) {
+	query := `
+		insert into scl.idempotency_keys as k(scope, key, fingerprint, expires_at)
+		values (@scope, @key, @fingerprint, @expires_at)
+		on conflict (scope, key) do update
+		set fingerprint = excluded.fingerprint,
+			completed = false,
+			response_status = null,
+			response_content_type = null,
+			response_body = null,
+			expires_at = excluded.expires_at
+		where k.expires_at <= @now
+		returning true
+	`
+
+	args := pgx.NamedArgs{
+		"scope":       r.Scope,
+		"key":         r.Key,
+		"fingerprint": r.Fingerprint,
+		"expires_at":  r.ExpiresAt,
+		"now":         now,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, false, fmt.Errorf("inserting into db: %v", err)
+	}
+
+	acquired, err := pgx.CollectRows(rows, pgx.RowTo[bool])
+	if err != nil {
+		return nil, false, fmt.Errorf("collecting acquired key: %v", err)
+	}
+
+	if len(acquired) > 0 {
+		return nil, true, nil
+	}
+
+	query = `
+		select scope, key, fingerprint, completed, response_status, response_content_type, response_body, expires_at
+		from scl.idempotency_keys
+		where scope = $1 and key = $2
+	`
+
+	var keys []idempotencyKey
+	err = pgxscan.Select(ctx, p.conn(ctx), &keys, query, r.Scope, r.Key)
+	if err != nil {
+		return nil, false, fmt.Errorf("selecting from db: %v", err)
+	}
+
+	if len(keys) == 0 {
+		// ключ освободили между запросами: выполняющийся запрос завершился ошибкой, его можно повторить
+		return &models.IdempotencyRecord{Scope: r.Scope, Key: r.Key, Fingerprint: r.Fingerprint}, false, nil
+	}
+
+	return keys[0].toModel(), false, nil
+}
+
+// Сохраняет ответ на запрос и продлевает срок хранения ключа
+func (p IdempotencyProvider) Complete(ctx context.Context, scope, key string, response models.StoredResponse, expiresAt time.Time) error {
+	query := `
+		update scl.idempotency_keys
+		set completed = true,
+			response_status = @status,
+			response_content_type = @content_type,
+			response_body = @body,
+			expires_at = @expires_at
+		where scope = @scope and key = @key
+	`
+
+	args := pgx.NamedArgs{
+		"status":       response.Status,
+		"content_type": response.ContentType,
+		"body":         response.Body,
+		"expires_at":   expiresAt,
+		"scope":        scope,
+		"key":          key,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("updating db: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+func (p IdempotencyProvider) Release(ctx context.Context, scope, key string) error {
+	query := `
+		delete from scl.idempotency_keys
+		where scope = $1 and key = $2
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, scope, key)
+	if err != nil {
+		return fmt.Errorf("deleting from db: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// Удаляет ключи, срок которых истек к now. Возвращает число удаленных ключей
+func (p IdempotencyProvider) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		with deleted as (
+			delete from scl.idempotency_keys
+			where expires_at <= $1
+			returning 1
+		)
+		select count(*) from deleted
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("deleting from db: %v", err)
+	}
+
+	n, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("collecting deleted count: %v", err)
+	}
+
+	return n, nil
+}
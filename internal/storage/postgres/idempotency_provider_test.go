@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestIdempotency(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := IdempotencyProvider{mock}
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	record := &models.IdempotencyRecord{
+		Scope:       "user:1",
+		Key:         "key",
+		Fingerprint: "fp",
+		ExpiresAt:   now.Add(time.Minute),
+	}
+
+	t.Run("Acquire new key", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.idempotency_keys").
+			WithArgs("user:1", "key", "fp", now.Add(time.Minute), now).
+			WillReturnRows(mock.NewRows([]string{"bool"}).AddRow(true))
+
+		existing, acquired, err := p.Acquire(ctx, record, now)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.Nil(t, existing)
+	})
+
+	t.Run("Acquire completed key", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.idempotency_keys").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(mock.NewRows([]string{"bool"}))
+
+		rows := mock.NewRows([]string{"scope", "key", "fingerprint", "completed", "response_status", "response_content_type", "response_body", "expires_at"}).
+			AddRow("user:1", "key", "fp", true, int32(201), "application/json", []byte(`{"id":"1"}`), now.Add(time.Hour))
+		mock.ExpectQuery("select").WithArgs("user:1", "key").WillReturnRows(rows)
+
+		existing, acquired, err := p.Acquire(ctx, record, now)
+		require.NoError(t, err)
+		require.False(t, acquired)
+		require.Equal(t, &models.IdempotencyRecord{
+			Scope:       "user:1",
+			Key:         "key",
+			Fingerprint: "fp",
+			Completed:   true,
+			Response: models.StoredResponse{
+				Status:      201,
+				ContentType: "application/json",
+				Body:        []byte(`{"id":"1"}`),
+			},
+			ExpiresAt: now.Add(time.Hour),
+		}, existing)
+	})
+
+	t.Run("Acquire with error", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.idempotency_keys").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("db error"))
+
+		_, _, err := p.Acquire(ctx, record, now)
+		require.Error(t, err)
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		mock.ExpectQuery("update scl.idempotency_keys").
+			WithArgs(201, "application/json", []byte(`{}`), now.Add(time.Hour), "user:1", "key").
+			WillReturnRows(mock.NewRows([]string{}))
+
+		err := p.Complete(ctx, "user:1", "key", models.StoredResponse{Status: 201, ContentType: "application/json", Body: []byte(`{}`)}, now.Add(time.Hour))
+		require.NoError(t, err)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		mock.ExpectQuery("delete from scl.idempotency_keys").
+			WithArgs("user:1", "key").
+			WillReturnRows(mock.NewRows([]string{}))
+
+		err := p.Release(ctx, "user:1", "key")
+		require.NoError(t, err)
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		mock.ExpectQuery("delete from scl.idempotency_keys").
+			WithArgs(now).
+			WillReturnRows(mock.NewRows([]string{"count"}).AddRow(int64(3)))
+
+		n, err := p.DeleteExpired(ctx, now)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), n)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
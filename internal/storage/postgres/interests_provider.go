@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+type InterestsProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewInterestsProvider(querier pgxscan.Querier) InterestsProvider {
+	return InterestsProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p InterestsProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+// Добавляет анкете интересы. Интересы должны быть нормализованы и не повторяться
+func (p InterestsProvider) AddInterests(ctx context.Context, profileId string, names []string) error {
+	id, err := parseId(profileId, models.ProfileNotFound)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	// do update, а не do nothing, чтобы получить id и уже существующих интересов
+	query := `
+		with ids as (
+			insert into scl.interests(name)
+			select unnest(@names::text[])
+			on conflict (name) do update
+			set name = excluded.name
+			returning id
+		)
+		insert into scl.profile_interests(profile_id, interest_id)
+		select @profile, id
+		from ids
+		on conflict do nothing
+		returning interest_id
+	`
+
+	args := pgx.NamedArgs{
+		"profile": id,
+		"names":   names,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err == nil {
+		_, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return fmt.Errorf("adding interests of profile '%d': %w", id, models.ProfileNotFound)
+		}
+
+		return fmt.Errorf("inserting interests: %v", err)
+	}
+
+	return nil
+}
+
+// Интересы по id анкеты в алфавитном порядке. Анкет без интересов в результате нет
+func (p InterestsProvider) Interests(ctx context.Context, profileIds []string) (map[string][]string, error) {
+	ids := parseIds(profileIds)
+
+	res := make(map[string][]string)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := `
+		select
+			pi.profile_id,
+			i.name
+		from scl.profile_interests pi
+		join scl.interests i on i.id = pi.interest_id
+		where pi.profile_id = any($1)
+		order by pi.profile_id, i.name
+	`
+
+	var rows []struct {
+		ProfileId int64  `db:"profile_id"`
+		Name      string `db:"name"`
+	}
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &rows, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	for _, r := range rows {
+		id := fmt.Sprintf("%d", r.ProfileId)
+		res[id] = append(res[id], r.Name)
+	}
+
+	return res, nil
+}
+
+// Интересы, начинающиеся с prefix, по числу анкет с ними. Учитываются только анкеты,
+// в которых видимость hobbies входит в visible
+func (p InterestsProvider) Autocomplete(ctx context.Context, prefix string, visible []models.Visibility, limit int) ([]models.InterestCount, error) {
+	query := `
+		select
+			i.name,
+			count(*) as profiles
+		from scl.interests i
+		join scl.profile_interests pi on pi.interest_id = i.id
+		left join scl.profile_privacy pp on pp.profile_id = pi.profile_id
+		where
+			i.name like @prefix
+			and
+			coalesce(pp.fields->>@field, @default) = any(@visible)
+		group by i.name
+		order by
+			profiles desc,
+			i.name
+		limit @limit
+	`
+
+	args := pgx.NamedArgs{
+		"prefix":  likePrefix(prefix),
+		"field":   string(models.FieldHobbies),
+		"default": string(models.DefaultVisibility),
+		"visible": visibilityNames(visible),
+		"limit":   limit,
+	}
+
+	var rows []struct {
+		Name     string `db:"name"`
+		Profiles int64  `db:"profiles"`
+	}
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &rows, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]models.InterestCount, len(rows))
+	for i, r := range rows {
+		res[i] = models.InterestCount{Name: r.Name, Profiles: int(r.Profiles)}
+	}
+
+	return res, nil
+}
+
+// Анкеты других пользователей с общими с анкетой profileId интересами, начиная с анкет с наибольшим
+// числом общих интересов. Учитываются только анкеты, в которых видимость hobbies входит в visible
+func (p InterestsProvider) Similar(ctx context.Context, profileId string, visible []models.Visibility, limit int) ([]*models.ProfileMatch, error) {
+	id, err := parseId(profileId, models.ProfileNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		select
+			ps.id,
+			ps.user_id,
+			ps.name,
+			ps.surname,
+			ps.birthdate,
+			ps.sex,
+			ps.address,
+			ps.hobbies,
+			ps.updated_at,
+			array_agg(i.name order by i.name) as shared
+		from scl.profile_interests src
+		join scl.profiles source on source.id = src.profile_id
+		join scl.profile_interests pi on pi.interest_id = src.interest_id
+		join scl.interests i on i.id = pi.interest_id
+		join scl.profiles ps on ps.id = pi.profile_id
+		left join scl.profile_privacy pp on pp.profile_id = ps.id
+		where
+			src.profile_id = @profile
+			and
+			ps.user_id <> source.user_id
+			and
+			coalesce(pp.fields->>@field, @default) = any(@visible)
+		group by ps.id
+		order by
+			count(*) desc,
+			ps.id
+		limit @limit
+	`
+
+	args := pgx.NamedArgs{
+		"profile": id,
+		"field":   string(models.FieldHobbies),
+		"default": string(models.DefaultVisibility),
+		"visible": visibilityNames(visible),
+		"limit":   limit,
+	}
+
+	var rows []struct {
+		profile
+		Shared []string `db:"shared"`
+	}
+
+	err = pgxscan.Select(ctx, p.conn(ctx), &rows, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.ProfileMatch, 0, len(rows))
+	for _, r := range rows {
+		profile, err := r.toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting profile info of '%d': %v", r.Id, err)
+		}
+
+		res = append(res, &models.ProfileMatch{Profile: profile, Shared: r.Shared})
+	}
+
+	return res, nil
+}
+
+// Шаблон like для строк, начинающихся с prefix. Символы шаблона в prefix экранируются
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+func visibilityNames(visibilities []models.Visibility) []string {
+	res := make([]string, len(visibilities))
+	for i, v := range visibilities {
+		res[i] = string(v)
+	}
+
+	return res
+}
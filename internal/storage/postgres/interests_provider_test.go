@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/sex"
+)
+
+func TestInterests(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := InterestsProvider{mock}
+	ctx := context.Background()
+	visible := []models.Visibility{models.VisibilityPublic, models.VisibilityRegistered}
+
+	t.Run("Add", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.interests").
+			WithArgs([]string{"чтение", "сериалы"}, int64(2)).
+			WillReturnRows(mock.NewRows([]string{"interest_id"}).AddRow(int64(1)).AddRow(int64(2)))
+
+		err := p.AddInterests(ctx, "2", []string{"чтение", "сериалы"})
+		require.NoError(t, err)
+
+		err = p.AddInterests(ctx, "2", nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("Add for missing profile", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.interests").
+			WithArgs([]string{"чтение"}, int64(3)).
+			WillReturnError(&pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		err := p.AddInterests(ctx, "3", []string{"чтение"})
+		require.ErrorIs(t, err, models.ProfileNotFound)
+
+		err = p.AddInterests(ctx, "abc", []string{"чтение"})
+		require.ErrorIs(t, err, models.ProfileNotFound)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rows := mock.NewRows([]string{"profile_id", "name"}).
+			AddRow(int64(2), "сериалы").
+			AddRow(int64(2), "чтение").
+			AddRow(int64(3), "шахматы")
+		mock.ExpectQuery("select").WithArgs([]int64{2, 3}).WillReturnRows(rows)
+
+		interests, err := p.Interests(ctx, []string{"2", "3", "abc"})
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{
+			"2": {"сериалы", "чтение"},
+			"3": {"шахматы"},
+		}, interests)
+	})
+
+	t.Run("Autocomplete", func(t *testing.T) {
+		rows := mock.NewRows([]string{"name", "profiles"}).
+			AddRow("чтение", int64(10)).
+			AddRow("чай", int64(3))
+		mock.ExpectQuery("select").
+			WithArgs(`ч\%`+"%", "hobbies", "registered", []string{"public", "registered"}, 5).
+			WillReturnRows(rows)
+
+		interests, err := p.Autocomplete(ctx, "ч%", visible, 5)
+		require.NoError(t, err)
+		require.Equal(t, []models.InterestCount{{Name: "чтение", Profiles: 10}, {Name: "чай", Profiles: 3}}, interests)
+	})
+
+	t.Run("Similar", func(t *testing.T) {
+		birthdate := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+		rows := mock.NewRows([]string{"id", "user_id", "name", "surname", "birthdate", "sex", "address", "hobbies", "updated_at", "shared"}).
+			AddRow(int64(5), "4", "Петр", "Петров", birthdate, "male", "Москва", "Чтение, сериалы", birthdate, []string{"сериалы", "чтение"})
+		mock.ExpectQuery("select").
+			WithArgs(int64(2), "hobbies", "registered", []string{"public", "registered"}, 10).
+			WillReturnRows(rows)
+
+		matches, err := p.Similar(ctx, "2", visible, 10)
+		require.NoError(t, err)
+		require.Equal(t, []*models.ProfileMatch{{
+			Profile: &models.Profile{
+				Id:        "5",
+				UserId:    "4",
+				Name:      "Петр",
+				Surname:   "Петров",
+				Sex:       sex.Male,
+				Birthdate: birthdate,
+				Address:   "Москва",
+				Hobbies:   "Чтение, сериалы",
+				UpdatedAt: birthdate,
+			},
+			Shared: []string{"сериалы", "чтение"},
+		}}, matches)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
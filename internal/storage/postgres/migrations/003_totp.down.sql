@@ -0,0 +1,4 @@
+alter table scl.users
+    drop column recovery_codes,
+    drop column totp_enabled,
+    drop column totp_secret;
@@ -0,0 +1,4 @@
+alter table scl.users
+    add column totp_secret bytea,
+    add column totp_enabled boolean NOT NULL default false,
+    add column recovery_codes bytea[];
@@ -0,0 +1,5 @@
+drop table scl.audit_log;
+
+alter table scl.users
+    drop column disabled,
+    drop column roles;
@@ -0,0 +1,13 @@
+alter table scl.users
+    add column roles varchar(20)[] NOT NULL default '{user}',
+    add column disabled boolean NOT NULL default false;
+
+create table scl.audit_log (
+    id bigserial PRIMARY KEY,
+    actor_id bigint NOT NULL,
+    action varchar(50) NOT NULL,
+    target_id varchar(50) NOT NULL,
+    created_at timestamptz NOT NULL default now()
+);
+
+create index audit_log_actor_idx on scl.audit_log(actor_id);
@@ -0,0 +1,2 @@
+alter table scl.users
+    drop column tokens_valid_after;
@@ -0,0 +1,2 @@
+alter table scl.users
+    add column tokens_valid_after timestamptz;
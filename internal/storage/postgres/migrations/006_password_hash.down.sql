@@ -0,0 +1,2 @@
+alter table scl.users
+    alter column password type varchar(100);
@@ -0,0 +1,6 @@
+drop index scl.users_email_lower_key;
+drop index scl.users_login_lower_key;
+
+alter table scl.users
+    add constraint users_login_key UNIQUE (login),
+    add constraint users_email_key UNIQUE (email);
@@ -0,0 +1,41 @@
+-- Логины и email сравниваются без учета регистра и после нормализации NFKC.
+-- Если среди существующих пользователей есть такие, чьи идентификаторы совпадают
+-- после нормализации, миграция прерывается со списком конфликтов:
+-- их нужно разрешить вручную и повторить миграцию
+do $$
+declare
+    collisions text;
+begin
+    select string_agg(format('%s %L: ids %s', kind, value, ids), E'\n')
+    into collisions
+    from (
+        select 'login' as kind, lower(normalize(login, NFKC)) as value, string_agg(id::text, ', ' order by id) as ids
+        from scl.users
+        group by 2
+        having count(*) > 1
+        union all
+        select 'email', lower(normalize(email, NFKC)), string_agg(id::text, ', ' order by id)
+        from scl.users
+        group by 2
+        having count(*) > 1
+    ) c;
+
+    if collisions is not null then
+        raise exception 'users with colliding identifiers found:%', E'\n' || collisions
+            using hint = 'rename or delete the conflicting users and rerun the migration';
+    end if;
+end
+$$;
+
+update scl.users
+set
+    login = lower(normalize(login, NFKC)),
+    email = lower(normalize(email, NFKC))
+where login <> lower(normalize(login, NFKC)) or email <> lower(normalize(email, NFKC));
+
+alter table scl.users
+    drop constraint users_login_key,
+    drop constraint users_email_key;
+
+create unique index users_login_lower_key on scl.users(lower(login));
+create unique index users_email_lower_key on scl.users(lower(email));
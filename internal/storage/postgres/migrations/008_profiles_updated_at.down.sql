@@ -0,0 +1,5 @@
+drop trigger profiles_set_updated_at on scl.profiles;
+
+drop function scl.set_updated_at();
+
+alter table scl.profiles drop column updated_at;
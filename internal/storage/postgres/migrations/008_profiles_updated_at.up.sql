@@ -0,0 +1,13 @@
+alter table scl.profiles add column updated_at timestamptz not null default now();
+
+create function scl.set_updated_at() returns trigger as $$
+begin
+    new.updated_at = now();
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger profiles_set_updated_at
+    before update on scl.profiles
+    for each row
+    execute function scl.set_updated_at();
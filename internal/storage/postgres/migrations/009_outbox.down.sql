@@ -0,0 +1,3 @@
+drop table scl.outbox_dead_letter;
+
+drop table scl.outbox;
@@ -0,0 +1,27 @@
+create table scl.outbox (
+    id bigserial PRIMARY KEY,
+    event_type varchar(100) NOT NULL,
+    aggregate_type varchar(50) NOT NULL,
+    aggregate_id varchar(50) NOT NULL,
+    payload jsonb NOT NULL,
+    occurred_at timestamptz NOT NULL default now(),
+    attempts int NOT NULL default 0,
+    next_attempt_at timestamptz NOT NULL default now(),
+    last_error text
+);
+
+-- события одной сущности публикуются по порядку id
+create index outbox_aggregate_idx on scl.outbox(aggregate_type, aggregate_id, id);
+
+-- события, которые не удалось опубликовать за отведенное число попыток
+create table scl.outbox_dead_letter (
+    id bigint PRIMARY KEY,
+    event_type varchar(100) NOT NULL,
+    aggregate_type varchar(50) NOT NULL,
+    aggregate_id varchar(50) NOT NULL,
+    payload jsonb NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    attempts int NOT NULL,
+    last_error text,
+    failed_at timestamptz NOT NULL default now()
+);
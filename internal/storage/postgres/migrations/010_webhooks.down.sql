@@ -0,0 +1,3 @@
+drop table scl.webhook_deliveries;
+
+drop table scl.webhooks;
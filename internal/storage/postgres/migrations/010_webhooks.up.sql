@@ -0,0 +1,36 @@
+create table scl.webhooks (
+    id bigserial PRIMARY KEY,
+    user_id varchar(50) NOT NULL,
+    url varchar(2000) NOT NULL,
+    events varchar(100)[] NOT NULL,
+    secret varchar(100) NOT NULL,
+    active boolean NOT NULL default true,
+    created_at timestamptz NOT NULL default now()
+);
+
+create index webhooks_user_idx on scl.webhooks(user_id);
+
+create table scl.webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL references scl.webhooks(id) on delete cascade,
+    event_id varchar(50) NOT NULL,
+    event_type varchar(100) NOT NULL,
+    -- доставка, которую повторяет эта доставка, созданная вручную
+    redelivery_of bigint references scl.webhook_deliveries(id) on delete set null,
+    payload jsonb NOT NULL,
+    status varchar(20) NOT NULL default 'pending',
+    attempts int NOT NULL default 0,
+    response_status int,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL default now(),
+    created_at timestamptz NOT NULL default now(),
+    delivered_at timestamptz
+);
+
+-- событие доставляется по подписке один раз, даже если обработчик события вызван повторно
+create unique index webhook_deliveries_event_idx on scl.webhook_deliveries(webhook_id, event_id) where redelivery_of is null;
+
+create index webhook_deliveries_webhook_idx on scl.webhook_deliveries(webhook_id, id);
+
+-- выборка доставок, ожидающих отправки
+create index webhook_deliveries_pending_idx on scl.webhook_deliveries(next_attempt_at) where status = 'pending';
@@ -0,0 +1 @@
+drop table scl.idempotency_keys;
@@ -0,0 +1,14 @@
+create table scl.idempotency_keys (
+    scope varchar(100) NOT NULL,
+    key varchar(255) NOT NULL,
+    fingerprint varchar(64) NOT NULL,
+    completed boolean NOT NULL default false,
+    response_status int,
+    response_content_type varchar(255),
+    response_body bytea,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+-- удаление истекших ключей
+create index idempotency_keys_expires_idx on scl.idempotency_keys(expires_at);
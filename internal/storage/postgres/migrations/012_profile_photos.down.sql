@@ -0,0 +1 @@
+drop table scl.profile_photos;
@@ -0,0 +1,11 @@
+create table scl.profile_photos (
+    id bigserial PRIMARY KEY,
+    profile_id bigint NOT NULL references scl.profiles(id) on delete cascade,
+    user_id varchar(50) NOT NULL,
+    -- исходная фотография и миниатюры: имя, ключ в хранилище файлов, тип, размеры
+    variants jsonb NOT NULL,
+    created_at timestamptz NOT NULL default now()
+);
+
+-- аватар анкеты - ее последняя фотография
+create index profile_photos_profile_idx on scl.profile_photos(profile_id, id desc);
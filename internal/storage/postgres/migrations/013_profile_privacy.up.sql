@@ -0,0 +1,6 @@
+create table scl.profile_privacy (
+    profile_id bigint PRIMARY KEY references scl.profiles(id) on delete cascade,
+    -- видимость полей, которые владелец изменил: {"birthdate": "hidden", ...}
+    fields jsonb NOT NULL,
+    updated_at timestamptz NOT NULL default now()
+);
@@ -0,0 +1,2 @@
+drop table scl.profile_interests;
+drop table scl.interests;
@@ -0,0 +1,38 @@
+create table scl.interests (
+    id bigserial PRIMARY KEY,
+    -- нормализованное название: NFKC, нижний регистр, одиночные пробелы
+    name varchar(50) NOT NULL UNIQUE
+);
+
+-- поиск интересов по началу названия (like 'prefix%')
+create index interests_name_prefix_idx on scl.interests(name text_pattern_ops);
+
+create table scl.profile_interests (
+    profile_id bigint NOT NULL references scl.profiles(id) on delete cascade,
+    interest_id bigint NOT NULL references scl.interests(id),
+    PRIMARY KEY (profile_id, interest_id)
+);
+
+create index profile_interests_interest_idx on scl.profile_interests(interest_id);
+
+-- интересы существующих анкет - части hobbies через запятую, нормализованные так же, как в приложении:
+-- пустые и длиннее 50 символов пропускаются
+create temporary table parsed_interests on commit drop as
+select distinct
+    p.id as profile_id,
+    trim(regexp_replace(lower(normalize(part, NFKC)), '\s+', ' ', 'g')) as name
+from scl.profiles p
+cross join lateral unnest(string_to_array(p.hobbies, ',')) as part;
+
+delete from parsed_interests
+where name = '' or char_length(name) > 50;
+
+insert into scl.interests(name)
+select distinct name
+from parsed_interests
+order by name;
+
+insert into scl.profile_interests(profile_id, interest_id)
+select pi.profile_id, i.id
+from parsed_interests pi
+join scl.interests i on i.name = pi.name;
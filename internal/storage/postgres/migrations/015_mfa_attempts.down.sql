@@ -0,0 +1,5 @@
+alter table scl.users
+    drop column mfa_window_start,
+    drop column mfa_attempts,
+    drop column mfa_generation,
+    drop column totp_last_step;
@@ -0,0 +1,5 @@
+alter table scl.users
+    add column totp_last_step bigint not null default 0,
+    add column mfa_generation integer not null default 0,
+    add column mfa_attempts integer not null default 0,
+    add column mfa_window_start timestamptz;
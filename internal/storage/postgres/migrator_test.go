@@ -14,7 +14,10 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var db *sql.DB
+var (
+	db       *sql.DB
+	dbConfig postgres.Config
+)
 
 func TestMain(m *testing.M) {
 	const (
@@ -58,7 +61,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Could not convert port '%s' to integer", portstr)
 	}
 
-	config := postgres.Config{
+	dbConfig = postgres.Config{
 		User:     user,
 		Password: password,
 		Database: dbname,
@@ -66,7 +69,7 @@ func TestMain(m *testing.M) {
 		Port:     uint16(port),
 	}
 
-	db, err = postgres.ViaSTD(&config)
+	db, err = postgres.ViaSTD(&dbConfig)
 	if err != nil {
 		log.Fatalf("Could not connect to db: %s", err)
 	}
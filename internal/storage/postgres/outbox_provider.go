@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+// Ключ advisory-блокировки, которую держит экземпляр приложения, публикующий события
+const outboxLockKey = 7_271_001
+
+type OutboxProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewOutboxProvider(querier pgxscan.Querier) OutboxProvider {
+	return OutboxProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p OutboxProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+// Записывает событие. Чтобы событие было записано вместе с изменением, которое его вызвало,
+// вызывается в транзакции этого изменения
+func (p OutboxProvider) Add(ctx context.Context, e *models.Event) error {
+	query := `
+		insert into scl.outbox(event_type, aggregate_type, aggregate_id, payload, occurred_at)
+		values (@type, @aggregate_type, @aggregate_id, @payload, @occurred_at)
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"type":           string(e.Type),
+		"aggregate_type": e.AggregateType,
+		"aggregate_id":   e.AggregateId,
+		"payload":        string(e.Payload),
+		"occurred_at":    e.OccurredAt,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("inserting into db: %v", err)
+	}
+
+	_, err = pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("collecting new event id: %v", err)
+	}
+
+	return nil
+}
+
+// Захватывает блокировку публикации до конца транзакции из контекста, если ее не держит другой экземпляр приложения.
+// Возвращает false, если блокировка занята
+func (p OutboxProvider) Lock(ctx context.Context) (bool, error) {
+	rows, err := p.conn(ctx).Query(ctx, `select pg_try_advisory_xact_lock($1)`, outboxLockKey)
+	if err != nil {
+		return false, fmt.Errorf("locking outbox: %v", err)
+	}
+
+	locked, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+	if err != nil {
+		return false, fmt.Errorf("collecting outbox lock result: %v", err)
+	}
+
+	return locked, nil
+}
+
+// Возвращает не больше limit событий, готовых к публикации, в порядке записи.
+// События сущности, у которой есть более раннее событие в ожидании повторной попытки, не возвращаются
+func (p OutboxProvider) Pending(ctx context.Context, limit int) ([]*models.Event, error) {
+	var events []event
+
+	query := `
+		select
+			o.id,
+			o.event_type,
+			o.aggregate_type,
+			o.aggregate_id,
+			o.payload,
+			o.occurred_at,
+			o.attempts
+		from scl.outbox as o
+		where not exists (
+			select 1
+			from scl.outbox as b
+			where
+				b.aggregate_type = o.aggregate_type
+				and
+				b.aggregate_id = o.aggregate_id
+				and
+				b.id <= o.id
+				and
+				b.next_attempt_at > now()
+		)
+		order by o.id
+		limit $1
+	`
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &events, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.Event, 0, len(events))
+	for i := range events {
+		res = append(res, events[i].toModel())
+	}
+
+	return res, nil
+}
+
+// Удаляет опубликованное событие
+func (p OutboxProvider) Delete(ctx context.Context, id string) error {
+	query := `
+		delete from scl.outbox
+		where id = $1
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("deleting from db: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// Запоминает неудачную попытку публикации и откладывает следующую
+func (p OutboxProvider) Retry(ctx context.Context, id string, next time.Time, lastErr string) error {
+	query := `
+		update scl.outbox
+		set
+			attempts = attempts + 1,
+			next_attempt_at = @next,
+			last_error = @error
+		where id = @id
+	`
+
+	args := pgx.NamedArgs{
+		"next":  next,
+		"error": lastErr,
+		"id":    id,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("updating db: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// Переносит событие, которое не удалось опубликовать, в scl.outbox_dead_letter
+func (p OutboxProvider) DeadLetter(ctx context.Context, id string, lastErr string) error {
+	query := `
+		with failed as (
+			delete from scl.outbox
+			where id = @id
+			returning *
+		)
+		insert into scl.outbox_dead_letter(id, event_type, aggregate_type, aggregate_id, payload, occurred_at, attempts, last_error)
+		select id, event_type, aggregate_type, aggregate_id, payload, occurred_at, attempts + 1, @error
+		from failed
+	`
+
+	args := pgx.NamedArgs{
+		"error": lastErr,
+		"id":    id,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("moving event to dead letter: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
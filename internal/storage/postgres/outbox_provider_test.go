@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestOutbox(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := OutboxProvider{mock}
+	ctx := context.Background()
+	occurredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	event := &models.Event{
+		Type:          models.EventUserRegistered,
+		AggregateType: models.AggregateUser,
+		AggregateId:   "1",
+		Payload:       []byte(`{"user_id":"1"}`),
+		OccurredAt:    occurredAt,
+	}
+
+	t.Run("Insert successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("insert into scl.outbox").
+			WithArgs("user.registered", "user", "1", `{"user_id":"1"}`, occurredAt).
+			WillReturnRows(rows)
+
+		err := p.Add(ctx, event)
+		require.NoError(t, err)
+	})
+
+	t.Run("insert with error", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.outbox").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("db error"))
+
+		err := p.Add(ctx, event)
+		require.Error(t, err)
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		mock.ExpectQuery("pg_try_advisory_xact_lock").WithArgs(outboxLockKey).
+			WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(false))
+
+		locked, err := p.Lock(ctx)
+		require.NoError(t, err)
+		require.False(t, locked)
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id", "event_type", "aggregate_type", "aggregate_id", "payload", "occurred_at", "attempts"}).
+			AddRow(int64(5), "user.registered", "user", "1", []byte(`{"user_id":"1"}`), occurredAt, 2)
+		mock.ExpectQuery("select").WithArgs(10).WillReturnRows(rows)
+
+		events, err := p.Pending(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, []*models.Event{{
+			Id:            "5",
+			Type:          models.EventUserRegistered,
+			AggregateType: models.AggregateUser,
+			AggregateId:   "1",
+			Payload:       []byte(`{"user_id":"1"}`),
+			OccurredAt:    occurredAt,
+			Attempts:      2,
+		}}, events)
+	})
+
+	t.Run("DeadLetter", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.outbox_dead_letter").WithArgs("5", "failed").
+			WillReturnRows(mock.NewRows([]string{}))
+
+		err := p.DeadLetter(ctx, "5", "failed")
+		require.NoError(t, err)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type photo struct {
+	Id        int64     `db:"id"`
+	ProfileId int64     `db:"profile_id"`
+	UserId    string    `db:"user_id"`
+	Variants  []byte    `db:"variants"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Вариант фотографии в колонке variants
+type photoVariant struct {
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+func (p *photo) toModel() (*models.Photo, error) {
+	var variants []photoVariant
+
+	err := json.Unmarshal(p.Variants, &variants)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling variants: %v", err)
+	}
+
+	res := &models.Photo{
+		Id:        fmt.Sprintf("%d", p.Id),
+		ProfileId: fmt.Sprintf("%d", p.ProfileId),
+		UserId:    p.UserId,
+		Variants:  make([]models.PhotoVariant, len(variants)),
+		CreatedAt: p.CreatedAt,
+	}
+
+	for i, v := range variants {
+		res.Variants[i] = models.PhotoVariant(v)
+	}
+
+	return res, nil
+}
+
+func photoVariants(variants []models.PhotoVariant) ([]byte, error) {
+	res := make([]photoVariant, len(variants))
+	for i, v := range variants {
+		res[i] = photoVariant(v)
+	}
+
+	return json.Marshal(res)
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+type PhotosProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewPhotosProvider(querier pgxscan.Querier) PhotosProvider {
+	return PhotosProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p PhotosProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+func (p PhotosProvider) Add(ctx context.Context, ph *models.Photo) (string, error) {
+	profileId, err := parseId(ph.ProfileId, models.ProfileNotFound)
+	if err != nil {
+		return "", err
+	}
+
+	variants, err := photoVariants(ph.Variants)
+	if err != nil {
+		return "", fmt.Errorf("marshalling variants: %v", err)
+	}
+
+	query := `
+		insert into scl.profile_photos(profile_id, user_id, variants)
+		values (@profile, @user, @variants)
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"profile":  profileId,
+		"user":     ph.UserId,
+		"variants": string(variants),
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return "", fmt.Errorf("inserting into db: %v", err)
+	}
+
+	id, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return "", fmt.Errorf("collecting new photo id: %v", err)
+	}
+
+	return fmt.Sprintf("%d", id), nil
+}
+
+func (p PhotosProvider) Get(ctx context.Context, photoId string) (*models.Photo, error) {
+	id, err := parseId(photoId, models.PhotoNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := p.selectPhotos(ctx, `where id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(photos) == 0 {
+		return nil, fmt.Errorf("looking '%d' up: %w", id, models.PhotoNotFound)
+	}
+
+	return photos[0], nil
+}
+
+// Все фотографии анкет пользователя в порядке загрузки
+func (p PhotosProvider) GetByUser(ctx context.Context, userId string) ([]*models.Photo, error) {
+	return p.selectPhotos(ctx, `where user_id = $1`, userId)
+}
+
+// Последние фотографии анкет по id анкеты. Анкет без фотографий в результате нет
+func (p PhotosProvider) Avatars(ctx context.Context, profileIds []string) (map[string]*models.Photo, error) {
+	ids := parseIds(profileIds)
+
+	res := make(map[string]*models.Photo)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := `
+		select distinct on (profile_id)
+			id,
+			profile_id,
+			user_id,
+			variants,
+			created_at
+		from scl.profile_photos
+		where profile_id = any($1)
+		order by profile_id, id desc
+	`
+
+	var photos []photo
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &photos, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	for i := range photos {
+		ph, err := photos[i].toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting photo '%d': %v", photos[i].Id, err)
+		}
+
+		res[ph.ProfileId] = ph
+	}
+
+	return res, nil
+}
+
+func (p PhotosProvider) selectPhotos(ctx context.Context, where string, args ...any) ([]*models.Photo, error) {
+	var photos []photo
+
+	query := `
+		select
+			id,
+			profile_id,
+			user_id,
+			variants,
+			created_at
+		from scl.profile_photos
+		` + where + `
+		order by id
+	`
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &photos, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.Photo, 0, len(photos))
+	for i := range photos {
+		ph, err := photos[i].toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting photo '%d': %v", photos[i].Id, err)
+		}
+
+		res = append(res, ph)
+	}
+
+	return res, nil
+}
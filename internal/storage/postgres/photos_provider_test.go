@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestPhotos(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := PhotosProvider{mock}
+	ctx := context.Background()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	photoColumns := []string{"id", "profile_id", "user_id", "variants", "created_at"}
+	variants := `[{"name":"original","key":"photos/2/a/original.jpg","content_type":"image/jpeg","width":800,"height":600,"size":1000}]`
+	expected := &models.Photo{
+		Id:        "5",
+		ProfileId: "2",
+		UserId:    "1",
+		Variants: []models.PhotoVariant{
+			{Name: "original", Key: "photos/2/a/original.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1000},
+		},
+		CreatedAt: createdAt,
+	}
+
+	t.Run("Insert successfully", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.profile_photos").
+			WithArgs(int64(2), "1", variants).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(5)))
+
+		id, err := p.Add(ctx, expected)
+		require.NoError(t, err)
+		require.Equal(t, "5", id)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rows := mock.NewRows(photoColumns).AddRow(int64(5), int64(2), "1", []byte(variants), createdAt)
+		mock.ExpectQuery("select").WithArgs(int64(5)).WillReturnRows(rows)
+
+		photo, err := p.Get(ctx, "5")
+		require.NoError(t, err)
+		require.Equal(t, expected, photo)
+	})
+
+	t.Run("Get not found", func(t *testing.T) {
+		mock.ExpectQuery("select").WithArgs(int64(6)).WillReturnRows(mock.NewRows(photoColumns))
+
+		_, err := p.Get(ctx, "6")
+		require.ErrorIs(t, err, models.PhotoNotFound)
+
+		_, err = p.Get(ctx, "abc")
+		require.ErrorIs(t, err, models.PhotoNotFound)
+	})
+
+	t.Run("GetByUser", func(t *testing.T) {
+		rows := mock.NewRows(photoColumns).AddRow(int64(5), int64(2), "1", []byte(variants), createdAt)
+		mock.ExpectQuery("where user_id").WithArgs("1").WillReturnRows(rows)
+
+		photos, err := p.GetByUser(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, []*models.Photo{expected}, photos)
+	})
+
+	t.Run("Avatars", func(t *testing.T) {
+		rows := mock.NewRows(photoColumns).AddRow(int64(5), int64(2), "1", []byte(variants), createdAt)
+		mock.ExpectQuery("select distinct on").WithArgs([]int64{2, 3}).WillReturnRows(rows)
+
+		avatars, err := p.Avatars(ctx, []string{"2", "3", "abc"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]*models.Photo{"2": expected}, avatars)
+	})
+
+	t.Run("Avatars without profiles", func(t *testing.T) {
+		avatars, err := p.Avatars(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, avatars)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type privacy struct {
+	ProfileId int64     `db:"profile_id"`
+	Fields    []byte    `db:"fields"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (p *privacy) toModel() (*models.Privacy, error) {
+	var fields map[string]string
+
+	err := json.Unmarshal(p.Fields, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling fields: %v", err)
+	}
+
+	res := &models.Privacy{
+		Fields:    make(map[models.ProfileField]models.Visibility, len(fields)),
+		UpdatedAt: p.UpdatedAt,
+	}
+
+	for f, v := range fields {
+		field, err := models.ProfileFieldFromString(f)
+		if err != nil {
+			return nil, err
+		}
+
+		visibility, err := models.VisibilityFromString(v)
+		if err != nil {
+			return nil, fmt.Errorf("visibility of %s: %v", f, err)
+		}
+
+		res.Fields[field] = visibility
+	}
+
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+type PrivacyProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewPrivacyProvider(querier pgxscan.Querier) PrivacyProvider {
+	return PrivacyProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p PrivacyProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+// Настройки видимости по id анкеты. Анкет, владельцы которых не меняли настройки, в результате нет
+func (p PrivacyProvider) Privacy(ctx context.Context, profileIds []string) (map[string]*models.Privacy, error) {
+	ids := parseIds(profileIds)
+
+	res := make(map[string]*models.Privacy)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := `
+		select
+			profile_id,
+			fields,
+			updated_at
+		from scl.profile_privacy
+		where profile_id = any($1)
+	`
+
+	var rows []privacy
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &rows, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	for i := range rows {
+		pr, err := rows[i].toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting privacy of profile '%d': %v", rows[i].ProfileId, err)
+		}
+
+		res[fmt.Sprintf("%d", rows[i].ProfileId)] = pr
+	}
+
+	return res, nil
+}
+
+// Заменяет настройки видимости анкеты
+func (p PrivacyProvider) SetPrivacy(ctx context.Context, profileId string, fields map[models.ProfileField]models.Visibility) error {
+	id, err := parseId(profileId, models.ProfileNotFound)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("marshalling fields: %v", err)
+	}
+
+	query := `
+		insert into scl.profile_privacy(profile_id, fields)
+		values (@profile, @fields)
+		on conflict (profile_id) do update
+		set
+			fields = excluded.fields,
+			updated_at = now()
+		returning profile_id
+	`
+
+	args := pgx.NamedArgs{
+		"profile": id,
+		"fields":  string(data),
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err == nil {
+		_, err = pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return fmt.Errorf("setting privacy of profile '%d': %w", id, models.ProfileNotFound)
+		}
+
+		return fmt.Errorf("upserting privacy: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestPrivacy(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := PrivacyProvider{mock}
+	ctx := context.Background()
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fields := map[models.ProfileField]models.Visibility{
+		models.FieldBirthdate: models.VisibilityHidden,
+		models.FieldCity:      models.VisibilityFriends,
+	}
+	data := `{"birthdate":"hidden","city":"friends"}`
+
+	t.Run("Set", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.profile_privacy").
+			WithArgs(int64(2), data).
+			WillReturnRows(mock.NewRows([]string{"profile_id"}).AddRow(int64(2)))
+
+		err := p.SetPrivacy(ctx, "2", fields)
+		require.NoError(t, err)
+	})
+
+	t.Run("Set for missing profile", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.profile_privacy").
+			WithArgs(int64(3), data).
+			WillReturnError(&pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		err := p.SetPrivacy(ctx, "3", fields)
+		require.ErrorIs(t, err, models.ProfileNotFound)
+
+		err = p.SetPrivacy(ctx, "abc", fields)
+		require.ErrorIs(t, err, models.ProfileNotFound)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rows := mock.NewRows([]string{"profile_id", "fields", "updated_at"}).AddRow(int64(2), []byte(data), updatedAt)
+		mock.ExpectQuery("select").WithArgs([]int64{2, 3}).WillReturnRows(rows)
+
+		privacy, err := p.Privacy(ctx, []string{"2", "3", "abc"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]*models.Privacy{"2": {Fields: fields, UpdatedAt: updatedAt}}, privacy)
+	})
+
+	t.Run("Get unknown visibility", func(t *testing.T) {
+		rows := mock.NewRows([]string{"profile_id", "fields", "updated_at"}).AddRow(int64(2), []byte(`{"city":"everyone"}`), updatedAt)
+		mock.ExpectQuery("select").WithArgs([]int64{2}).WillReturnRows(rows)
+
+		_, err := p.Privacy(ctx, []string{"2"})
+		require.Error(t, err)
+	})
+
+	t.Run("Get without profiles", func(t *testing.T) {
+		privacy, err := p.Privacy(ctx, nil)
+		require.NoError(t, err)
+		require.Empty(t, privacy)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/guregu/null/v5"
 
@@ -11,12 +12,14 @@ import (
 
 type profile struct {
 	Id        int64       `db:"id"`
+	UserId    string      `db:"user_id"`
 	Name      null.String `db:"name"`
 	Surname   null.String `db:"surname"`
 	Sex       null.String `db:"sex"`
 	Birthdate null.Time   `db:"birthdate"`
 	Address   null.String `db:"address"`
 	Hobbies   null.String `db:"hobbies"`
+	UpdatedAt time.Time   `db:"updated_at"`
 }
 
 func (p *profile) toModel() (*models.Profile, error) {
@@ -26,11 +29,14 @@ func (p *profile) toModel() (*models.Profile, error) {
 	}
 
 	return &models.Profile{
+		Id:        fmt.Sprintf("%d", p.Id),
+		UserId:    p.UserId,
 		Name:      p.Name.String,
 		Surname:   p.Surname.String,
 		Birthdate: p.Birthdate.Time,
 		Sex:       sex,
 		Address:   p.Address.String,
 		Hobbies:   p.Hobbies.String,
+		UpdatedAt: p.UpdatedAt,
 	}, nil
 }
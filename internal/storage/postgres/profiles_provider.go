@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
 )
@@ -18,6 +19,11 @@ func NewProfilesProvider(querier pgxscan.Querier) ProfilesProvider {
 	return ProfilesProvider{querier}
 }
 
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p ProfilesProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
 // TODO : add pagination
 func (p ProfilesProvider) GetAll(ctx context.Context) ([]*models.Profile, error) {
 	var res []*models.Profile
@@ -67,7 +73,7 @@ func (p ProfilesProvider) Get(ctx context.Context, profileID string) (*models.Pr
 
 	profileInfo, err := p.getProfileInfo(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("getting profile info of '%d': %v", id, err)
+		return nil, fmt.Errorf("getting profile info of '%d': %w", id, err)
 	}
 
 	profile, err := profileInfo.toModel()
@@ -95,7 +101,7 @@ func (p ProfilesProvider) Add(ctx context.Context, profile *models.Profile) (str
 		"hobbies":   profile.Hobbies,
 	}
 
-	rows, err := p.querier.Query(ctx, query, args)
+	rows, err := p.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return "", fmt.Errorf("inserting into db: %v", err)
 	}
@@ -116,29 +122,102 @@ func (p ProfilesProvider) Add(ctx context.Context, profile *models.Profile) (str
 	return fmt.Sprintf("%d", id), nil
 }
 
+func (p ProfilesProvider) Delete(ctx context.Context, profileID string) error {
+	id, err := strconv.ParseInt(profileID, 10, 0)
+	if err != nil {
+		return fmt.Errorf("illegal id '%s': %v : int64 expected", profileID, err)
+	}
+
+	query := `
+		delete from scl.profiles
+		where id = $1
+		returning id
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("deleting from db: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("collecting deleted profile id: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return fmt.Errorf("deleting profile '%d': %w", id, models.ProfileNotFound)
+	}
+
+	return nil
+}
+
+// Возвращает все анкеты пользователя
+func (p ProfilesProvider) GetByUser(ctx context.Context, userId string) ([]*models.Profile, error) {
+	var res []*models.Profile
+
+	profilesInfo, err := p.getProfilesInfoByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("getting profiles info of user '%s': %v", userId, err)
+	}
+
+	for _, profileInfo := range profilesInfo {
+		profile, err := profileInfo.toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting profile info of '%d': %v", profileInfo.Id, err)
+		}
+
+		res = append(res, profile)
+	}
+
+	return res, nil
+}
+
+// Удаляет все анкеты пользователя
+func (p ProfilesProvider) DeleteByUser(ctx context.Context, userId string) error {
+	query := `
+		delete from scl.profiles
+		where user_id = $1
+		returning id
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, userId)
+	if err != nil {
+		return fmt.Errorf("deleting from db: %v", err)
+	}
+
+	_, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("collecting deleted profiles ids: %v", err)
+	}
+
+	return nil
+}
+
 func (p ProfilesProvider) getProfileInfo(ctx context.Context, profileID int64) (*profile, error) {
 	var profiles []profile
 
 	query := `
 		select
 			ps.id,
+			user_id,
 			name,
 			surname,
 			birthdate,
 			sex,
 			address,
-			hobbies
+			hobbies,
+			updated_at
 		from scl.profiles as ps
 		where id = $1
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &profiles, query, profileID)
+	err := pgxscan.Select(ctx, p.conn(ctx), &profiles, query, profileID)
 	if err != nil {
 		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
 	}
 
 	if len(profiles) == 0 {
-		return nil, fmt.Errorf("querying db: %v", models.ProfileNotFound)
+		return nil, fmt.Errorf("querying db: %w", models.ProfileNotFound)
 	}
 
 	return &profiles[0], nil
@@ -150,19 +229,49 @@ func (p ProfilesProvider) getAllProfileInfo(ctx context.Context) ([]profile, err
 	query := `
 		select
 			ps.id,
+			user_id,
+			name,
+			surname,
+			birthdate,
+			sex,
+			address,
+			hobbies,
+			updated_at
+		from scl.profiles as ps
+	`
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &profiles, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+	return profiles, nil
+}
+
+func (p ProfilesProvider) getProfilesInfoByUser(ctx context.Context, userId string) ([]profile, error) {
+	var profiles []profile
+
+	query := `
+		select
+			ps.id,
+			user_id,
 			name,
 			surname,
 			birthdate,
 			sex,
 			address,
-			hobbies
+			hobbies,
+			updated_at
 		from scl.profiles as ps
+		where user_id = $1
+		order by
+			ps.id
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &profiles, query)
+	err := pgxscan.Select(ctx, p.conn(ctx), &profiles, query, userId)
 	if err != nil {
 		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
 	}
+
 	return profiles, nil
 }
 
@@ -170,36 +279,42 @@ func (p ProfilesProvider) getProfilesInfoByParams(ctx context.Context, params *m
 	var profiles []profile
 
 	args := pgx.NamedArgs{
-		"name":    fmt.Sprintf("%s%%", params.NamePrefix),
-		"surname": fmt.Sprintf("%s%%", params.SurnamePrefix),
+		"name":     fmt.Sprintf("%s%%", params.NamePrefix),
+		"surname":  fmt.Sprintf("%s%%", params.SurnamePrefix),
+		"interest": params.Interest,
 	}
 
 	query := `
 		select
 			ps.id,
+			user_id,
 			name,
 			surname,
 			birthdate,
 			sex,
 			address,
-			hobbies
+			hobbies,
+			updated_at
 		from scl.profiles as ps
 		where
 			name LIKE @name
 			and
 			surname LIKE @surname
+			and
+			(@interest = '' or exists (
+				select 1
+				from scl.profile_interests pi
+				join scl.interests i on i.id = pi.interest_id
+				where pi.profile_id = ps.id and i.name = @interest
+			))
 		order by
 			ps.id
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &profiles, query, args)
+	err := pgxscan.Select(ctx, p.conn(ctx), &profiles, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
 	}
 
-	if len(profiles) == 0 {
-		return nil, fmt.Errorf("querying db: %v", models.ProfileNotFound)
-	}
-
 	return profiles, nil
 }
@@ -26,6 +26,8 @@ func TestAllProfiles(t *testing.T) {
 		birthdate := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
 		expected := []*models.Profile{
 			{
+				Id:        "1",
+				UserId:    "3",
 				Name:      "user1",
 				Surname:   "surname1",
 				Sex:       sex.Male,
@@ -34,6 +36,8 @@ func TestAllProfiles(t *testing.T) {
 				Hobbies:   "reading, dancing",
 			},
 			{
+				Id:        "2",
+				UserId:    "4",
 				Name:      "user2",
 				Surname:   "surname2",
 				Sex:       sex.Female,
@@ -43,9 +47,9 @@ func TestAllProfiles(t *testing.T) {
 			},
 		}
 
-		profiles := mock.NewRows([]string{"id", "name", "surname", "birthdate", "sex", "address", "hobbies"}).
-			AddRow(int64(1), "user1", "surname1", birthdate, "male", "Moscow", "reading, dancing").
-			AddRow(int64(2), "user2", "surname2", birthdate, "female", "Los-Angeles", "youtube")
+		profiles := mock.NewRows([]string{"id", "user_id", "name", "surname", "birthdate", "sex", "address", "hobbies"}).
+			AddRow(int64(1), "3", "user1", "surname1", birthdate, "male", "Moscow", "reading, dancing").
+			AddRow(int64(2), "4", "user2", "surname2", birthdate, "female", "Los-Angeles", "youtube")
 
 		mock.ExpectQuery("select").WithArgs().WillReturnRows(profiles)
 
@@ -79,6 +83,8 @@ func TestSearchProfiles(t *testing.T) {
 		birthdate := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
 		expected := []*models.Profile{
 			{
+				Id:        "1",
+				UserId:    "3",
 				Name:      "user1",
 				Surname:   "surname1",
 				Sex:       sex.Male,
@@ -87,6 +93,8 @@ func TestSearchProfiles(t *testing.T) {
 				Hobbies:   "reading, dancing",
 			},
 			{
+				Id:        "2",
+				UserId:    "4",
 				Name:      "user1",
 				Surname:   "surname1",
 				Sex:       sex.Female,
@@ -96,16 +104,17 @@ func TestSearchProfiles(t *testing.T) {
 			},
 		}
 
-		profiles := mock.NewRows([]string{"id", "name", "surname", "birthdate", "sex", "address", "hobbies"}).
-			AddRow(int64(1), "user1", "surname1", birthdate, "male", "Moscow", "reading, dancing").
-			AddRow(int64(2), "user1", "surname1", birthdate, "female", "Los-Angeles", "youtube")
+		profiles := mock.NewRows([]string{"id", "user_id", "name", "surname", "birthdate", "sex", "address", "hobbies"}).
+			AddRow(int64(1), "3", "user1", "surname1", birthdate, "male", "Moscow", "reading, dancing").
+			AddRow(int64(2), "4", "user1", "surname1", birthdate, "female", "Los-Angeles", "youtube")
 
 		params := &models.SearchParams{
 			NamePrefix:    "user1",
 			SurnamePrefix: "surname1",
+			Interest:      "reading",
 		}
 
-		mock.ExpectQuery("select").WithArgs(params.NamePrefix+"%", params.SurnamePrefix+"%").WillReturnRows(profiles)
+		mock.ExpectQuery("select").WithArgs(params.NamePrefix+"%", params.SurnamePrefix+"%", params.Interest).WillReturnRows(profiles)
 
 		actual, err := p.Search(context.Background(), params)
 		require.NoError(t, err)
@@ -118,7 +127,7 @@ func TestSearchProfiles(t *testing.T) {
 			SurnamePrefix: "surname1",
 		}
 
-		mock.ExpectQuery("select").WithArgs(params.NamePrefix+"%", params.SurnamePrefix+"%").WillReturnError(errors.New("db error"))
+		mock.ExpectQuery("select").WithArgs(params.NamePrefix+"%", params.SurnamePrefix+"%", params.Interest).WillReturnError(errors.New("db error"))
 
 		actual, err := p.Search(context.Background(), params)
 		require.Error(t, err)
@@ -140,25 +149,31 @@ func TestSingleProfile(t *testing.T) {
 
 	t.Run("Select successfully", func(t *testing.T) {
 		expected := models.Profile{
+			Id:        "5",
+			UserId:    "3",
 			Name:      "user1",
 			Surname:   "surname1",
 			Sex:       sex.Male,
 			Birthdate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
 			Address:   "Moscow",
 			Hobbies:   "reading, dancing",
+			UpdatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
 		}
 
-		profile := mock.NewRows([]string{"name", "surname", "birthdate", "sex", "address", "hobbies"}).
+		profile := mock.NewRows([]string{"id", "user_id", "name", "surname", "birthdate", "sex", "address", "hobbies", "updated_at"}).
 			AddRow(
+				int64(5),
+				expected.UserId,
 				expected.Name,
 				expected.Surname,
 				expected.Birthdate,
 				expected.Sex.String(),
 				expected.Address,
 				expected.Hobbies,
+				expected.UpdatedAt,
 			)
 
-		profileId := int64(0)
+		profileId := int64(5)
 		mock.ExpectQuery("select").WithArgs(profileId).WillReturnRows(profile)
 
 		actual, err := p.Get(context.Background(), fmt.Sprintf("%d", profileId))
@@ -173,7 +188,7 @@ func TestSingleProfile(t *testing.T) {
 		mock.ExpectQuery("select").WithArgs(id).WillReturnRows(rows)
 
 		actual, err := p.Get(context.Background(), fmt.Sprintf("%d", id))
-		require.Error(t, err)
+		require.ErrorIs(t, err, models.ProfileNotFound)
 		require.Nil(t, actual)
 	})
 
@@ -256,3 +271,92 @@ func TestInsertProfile(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	require.NoError(t, err)
 }
+
+func TestDeleteProfile(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := ProfilesProvider{mock}
+
+	t.Run("Delete successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("delete").WithArgs(int64(1)).WillReturnRows(rows)
+
+		err := p.Delete(context.Background(), "1")
+		require.NoError(t, err)
+	})
+
+	t.Run("Delete nothing found", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("delete").WithArgs(int64(2)).WillReturnRows(rows)
+
+		err := p.Delete(context.Background(), "2")
+		require.ErrorIs(t, err, models.ProfileNotFound)
+	})
+
+	t.Run("Delete with error", func(t *testing.T) {
+		mock.ExpectQuery("delete").WithArgs(int64(3)).WillReturnError(errors.New("db error"))
+
+		err := p.Delete(context.Background(), "3")
+		require.Error(t, err)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
+
+func TestUserProfiles(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := ProfilesProvider{mock}
+
+	t.Run("Select by user successfully", func(t *testing.T) {
+		birthdate := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+		expected := []*models.Profile{
+			{
+				Id:        "1",
+				UserId:    "7",
+				Name:      "user1",
+				Surname:   "surname1",
+				Sex:       sex.Male,
+				Birthdate: birthdate,
+				Address:   "Moscow",
+				Hobbies:   "reading, dancing",
+			},
+		}
+
+		profiles := mock.NewRows([]string{"id", "user_id", "name", "surname", "birthdate", "sex", "address", "hobbies"}).
+			AddRow(int64(1), "7", "user1", "surname1", birthdate, "male", "Moscow", "reading, dancing")
+
+		mock.ExpectQuery("select").WithArgs("7").WillReturnRows(profiles)
+
+		actual, err := p.GetByUser(context.Background(), "7")
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("Delete by user successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(2))
+		mock.ExpectQuery("delete").WithArgs("7").WillReturnRows(rows)
+
+		err := p.DeleteByUser(context.Background(), "7")
+		require.NoError(t, err)
+	})
+
+	t.Run("Delete by user with error", func(t *testing.T) {
+		mock.ExpectQuery("delete").WithArgs("7").WillReturnError(errors.New("db error"))
+
+		err := p.DeleteByUser(context.Background(), "7")
+		require.Error(t, err)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// Одновременные регистрации с одним email: ровно одна должна пройти,
+// остальные - получить конфликт по полю email, а не внутреннюю ошибку
+func TestConcurrentRegistration(t *testing.T) {
+	err := ApplyMigrations(db)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	pool, err := postgres.ViaPGX(ctx, &dbConfig)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	p := NewUsersProvider(pool)
+
+	const n = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			user := &models.User{
+				Email:          "race@example.com",
+				Login:          fmt.Sprintf("racer%d", i),
+				HashedPassword: []byte("pwd"),
+			}
+
+			_, errs[i] = p.Add(ctx, user)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	winner := ""
+	for i, err := range errs {
+		if err == nil {
+			created++
+			winner = fmt.Sprintf("RACER%d", i)
+			continue
+		}
+
+		var conflict *models.UserConflictError
+		require.ErrorAs(t, err, &conflict)
+		require.Equal(t, "email", conflict.Field)
+	}
+	require.Equal(t, 1, created)
+
+	// логин зарегистрированного пользователя в другом регистре
+	_, err = p.Add(ctx, &models.User{Email: "other@example.com", Login: winner, HashedPassword: []byte("pwd")})
+
+	var conflict *models.UserConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, "login", conflict.Field)
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// Провайдеры, вызванные внутри TxManager.InTx, работают в транзакции из контекста
+func TestTxManager(t *testing.T) {
+	err := ApplyMigrations(db)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	pool, err := postgres.ViaPGX(ctx, &dbConfig)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	users := NewUsersProvider(pool)
+	profiles := NewProfilesProvider(pool)
+	tx := postgres.NewTxManager(pool)
+
+	register := func(ctx context.Context, login string, fail bool) error {
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			userId, err := users.Add(ctx, &models.User{Login: login, Email: login + "@example.com", HashedPassword: []byte("pwd")})
+			if err != nil {
+				return err
+			}
+
+			_, err = profiles.Add(ctx, &models.Profile{UserId: userId, Name: login})
+			if err != nil {
+				return err
+			}
+
+			if fail {
+				return errors.New("failed")
+			}
+
+			return nil
+		})
+	}
+
+	err = register(ctx, "txcommitted", false)
+	require.NoError(t, err)
+
+	user, err := users.Get(ctx, "txcommitted")
+	require.NoError(t, err)
+
+	userProfiles, err := profiles.GetByUser(ctx, user.Id)
+	require.NoError(t, err)
+	require.Len(t, userProfiles, 1)
+
+	err = register(ctx, "txrolledback", true)
+	require.Error(t, err)
+
+	_, err = users.Get(ctx, "txrolledback")
+	require.ErrorIs(t, err, models.UserNotFound)
+
+	// ошибка во вложенной транзакции откатывает изменения до точки сохранения
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		err := register(ctx, "txouter", false)
+		require.NoError(t, err)
+
+		err = register(ctx, "txinner", true)
+		require.Error(t, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = users.Get(ctx, "txouter")
+	require.NoError(t, err)
+
+	_, err = users.Get(ctx, "txinner")
+	require.ErrorIs(t, err, models.UserNotFound)
+}
@@ -1,8 +1,51 @@
 package postgres
 
+import (
+	"fmt"
+
+	"github.com/guregu/null/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+)
+
 type user struct {
-	Id       int64  `db:"id"`
-	Login    string `db:"login"`
-	Password []byte `db:"password"`
-	Email    string `db:"email"`
+	Id          int64    `db:"id"`
+	Login       string   `db:"login"`
+	Password    []byte   `db:"password"`
+	Email       string   `db:"email"`
+	TOTPSecret  []byte   `db:"totp_secret"`
+	TOTPEnabled bool     `db:"totp_enabled"`
+	Roles       []string `db:"roles"`
+	Disabled    bool     `db:"disabled"`
+
+	MFAGeneration int `db:"mfa_generation"`
+
+	TokensValidAfter null.Time `db:"tokens_valid_after"`
+}
+
+func (u *user) toModel() (*models.User, error) {
+	var roles []role.Role
+	for _, r := range u.Roles {
+		parsed, err := role.FromString(r)
+		if err != nil {
+			return nil, fmt.Errorf("parsing role: %v", err)
+		}
+
+		roles = append(roles, parsed)
+	}
+
+	return &models.User{
+		Id:             fmt.Sprintf("%d", u.Id),
+		Email:          u.Email,
+		Login:          u.Login,
+		HashedPassword: u.Password,
+		TOTPSecret:     u.TOTPSecret,
+		TOTPEnabled:    u.TOTPEnabled,
+		Roles:          roles,
+		Disabled:       u.Disabled,
+		MFAGeneration:  u.MFAGeneration,
+
+		TokensValidAfter: u.TokensValidAfter.Time,
+	}, nil
 }
@@ -2,13 +2,25 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
 	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// Уникальные индексы scl.users и соответствующие им поля пользователя
+var uniqueUserFields = map[string]string{
+	"users_login_lower_key": "login",
+	"users_email_lower_key": "email",
+}
+
 type UsersProvider struct {
 	querier pgxscan.Querier
 }
@@ -17,38 +29,291 @@ func NewUsersProvider(querier pgxscan.Querier) UsersProvider {
 	return UsersProvider{querier}
 }
 
-func (p UsersProvider) Exists(ctx context.Context, user *models.User) (bool, error) {
-	emailExists, err := p.checkEmailExists(ctx, user.Email)
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p UsersProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+// Ищет пользователя по логину или email.
+// Идентификатор должен быть уже нормализован: сравнение идет с lower() колонок
+func (p UsersProvider) Get(ctx context.Context, identifier string) (*models.User, error) {
+	user, err := p.getUserInfo(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("getting user info of '%s': %w", identifier, err)
+	}
+
+	res, err := user.toModel()
+	if err != nil {
+		return nil, fmt.Errorf("converting user info of '%d': %v", user.Id, err)
+	}
+
+	return res, nil
+}
+
+func (p UsersProvider) GetById(ctx context.Context, userId string) (*models.User, error) {
+	id, err := strconv.ParseInt(userId, 10, 0)
+	if err != nil {
+		return nil, fmt.Errorf("illegal id '%s': %v : int64 expected", userId, err)
+	}
+
+	user, err := p.getUserInfoById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting user info of '%d': %w", id, err)
+	}
+
+	res, err := user.toModel()
+	if err != nil {
+		return nil, fmt.Errorf("converting user info of '%d': %v", id, err)
+	}
+
+	return res, nil
+}
+
+// TODO : add pagination
+func (p UsersProvider) GetAll(ctx context.Context) ([]*models.User, error) {
+	var res []*models.User
+
+	usersInfo, err := p.getAllUsersInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting all users info: %v", err)
+	}
+
+	for _, userInfo := range usersInfo {
+		user, err := userInfo.toModel()
+		if err != nil {
+			return nil, fmt.Errorf("converting user info of '%d': %v", userInfo.Id, err)
+		}
+
+		res = append(res, user)
+	}
+
+	return res, nil
+}
+
+func (p UsersProvider) SetDisabled(ctx context.Context, userId string, disabled bool) error {
+	query := `
+		update scl.users
+		set disabled = @disabled
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"disabled": disabled,
+		"id":       userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("setting disabled of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (p UsersProvider) SetPassword(ctx context.Context, userId string, hashedPassword []byte) error {
+	query := `
+		update scl.users
+		set
+			password = @password,
+			tokens_valid_after = now()
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"password": hashedPassword,
+		"id":       userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("setting password of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Сохраняет новый (еще не подтвержденный) TOTP-секрет и хэши кодов восстановления
+func (p UsersProvider) SetTOTP(ctx context.Context, userId string, secret []byte, recoveryCodes [][]byte) error {
+	query := `
+		update scl.users
+		set
+			totp_secret = @secret,
+			totp_enabled = false,
+			totp_last_step = 0,
+			recovery_codes = @codes
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"secret": secret,
+		"codes":  recoveryCodes,
+		"id":     userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("setting totp secret of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (p UsersProvider) EnableTOTP(ctx context.Context, userId string) error {
+	query := `
+		update scl.users
+		set totp_enabled = true
+		where id = @id
+		returning id
+	`
+
+	err := p.updateUser(ctx, query, pgx.NamedArgs{"id": userId})
+	if err != nil {
+		return fmt.Errorf("enabling totp of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Удаляет код восстановления пользователя, если он есть.
+// Возвращает false, если такого кода у пользователя нет
+func (p UsersProvider) UseRecoveryCode(ctx context.Context, userId string, code []byte) (bool, error) {
+	query := `
+		update scl.users
+		set recovery_codes = array_remove(recovery_codes, @code)
+		where
+			id = @id
+			and
+			@code = any(recovery_codes)
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"code": code,
+		"id":   userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("using recovery code of '%s': %v", userId, err)
+	}
+
+	return true, nil
+}
+
+// Запоминает шаг принятого TOTP-кода, если он больше сохраненного.
+// Возвращает false, если код этого шага уже использовался
+func (p UsersProvider) UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error) {
+	query := `
+		update scl.users
+		set totp_last_step = @step
+		where
+			id = @id
+			and
+			totp_last_step < @step
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"step": step,
+		"id":   userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
 	if err != nil {
-		return false, fmt.Errorf("checking email exists: %v", err)
+		if errors.Is(err, models.UserNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("using totp step of '%s': %v", userId, err)
 	}
-	if emailExists {
-		return true, nil
+
+	return true, nil
+}
+
+// Учитывает попытку ввода кода по MFA-токену поколения generation и возвращает число попыток
+// пользователя за окно window, которое начинается с первой попытки. Если поколение устарело
+// или попыток за окно уже limit, возвращает models.MFATokenRevoked
+func (p UsersProvider) AddMFAAttempt(ctx context.Context, userId string, generation, limit int, window time.Duration) (int, error) {
+	// окно истекло, если оно началось раньше now() - window или еще не начиналось (null)
+	query := `
+		update scl.users
+		set
+			mfa_attempts = case when mfa_window_start > now() - make_interval(secs => @window) then mfa_attempts + 1 else 1 end,
+			mfa_window_start = case when mfa_window_start > now() - make_interval(secs => @window) then mfa_window_start else now() end
+		where
+			id = @id
+			and
+			mfa_generation = @generation
+			and
+			(mfa_attempts < @limit or coalesce(mfa_window_start <= now() - make_interval(secs => @window), true))
+		returning mfa_attempts
+	`
+
+	args := pgx.NamedArgs{
+		"window":     window.Seconds(),
+		"generation": generation,
+		"limit":      limit,
+		"id":         userId,
 	}
 
-	loginExists, err := p.checkLoginExists(ctx, user.Login)
+	rows, err := p.conn(ctx).Query(ctx, query, args)
 	if err != nil {
-		return false, fmt.Errorf("checking login exists: %v", err)
+		return 0, fmt.Errorf("updating db: %v", err)
 	}
-	if loginExists {
-		return true, nil
+
+	attempts, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return 0, fmt.Errorf("collecting mfa attempts of '%s': %v", userId, err)
 	}
 
-	return false, nil
+	if len(attempts) == 0 {
+		return 0, fmt.Errorf("adding mfa attempt of '%s': %w", userId, models.MFATokenRevoked)
+	}
+
+	return int(attempts[0]), nil
 }
 
-func (p UsersProvider) Get(ctx context.Context, login string) (*models.User, error) {
-	user, err := p.getUserInfo(ctx, login)
+// Сбрасывает счетчик попыток ввода кода
+func (p UsersProvider) ResetMFAAttempts(ctx context.Context, userId string) error {
+	query := `
+		update scl.users
+		set
+			mfa_attempts = 0,
+			mfa_window_start = null
+		where id = @id
+		returning id
+	`
+
+	err := p.updateUser(ctx, query, pgx.NamedArgs{"id": userId})
+	if err != nil {
+		return fmt.Errorf("resetting mfa attempts of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+// Отзывает выданные MFA-токены, начиная новое поколение. Счетчик попыток не сбрасывается
+func (p UsersProvider) RevokeMFATokens(ctx context.Context, userId string) error {
+	query := `
+		update scl.users
+		set mfa_generation = mfa_generation + 1
+		where id = @id
+		returning id
+	`
+
+	err := p.updateUser(ctx, query, pgx.NamedArgs{"id": userId})
 	if err != nil {
-		return nil, fmt.Errorf("getting user info of '%s': %v", login, err)
+		return fmt.Errorf("revoking mfa tokens of '%s': %w", userId, err)
 	}
 
-	return &models.User{
-		Id:             fmt.Sprintf("%d", user.Id),
-		Email:          user.Email,
-		Login:          user.Login,
-		HashedPassword: user.Password,
-	}, nil
+	return nil
 }
 
 func (p UsersProvider) Add(ctx context.Context, user *models.User) (string, error) {
@@ -64,8 +329,13 @@ func (p UsersProvider) Add(ctx context.Context, user *models.User) (string, erro
 		"password": user.HashedPassword,
 	}
 
-	rows, err := p.querier.Query(ctx, query, args)
+	rows, err := p.conn(ctx).Query(ctx, query, args)
 	if err != nil {
+		conflict := userConflict(err)
+		if conflict != nil {
+			return "", fmt.Errorf("inserting into db: %w", conflict)
+		}
+
 		return "", fmt.Errorf("inserting into db: %v", err)
 	}
 
@@ -79,13 +349,56 @@ func (p UsersProvider) Add(ctx context.Context, user *models.User) (string, erro
 		return id, nil
 	})
 	if err != nil {
+		// нарушение уникальности приходит от сервера при чтении результата
+		conflict := userConflict(err)
+		if conflict != nil {
+			return "", fmt.Errorf("inserting into db: %w", conflict)
+		}
+
 		return "", fmt.Errorf("collecting new user id: %v", err)
 	}
 
 	return fmt.Sprintf("%d", id), nil
 }
 
-func (p UsersProvider) getUserInfo(ctx context.Context, login string) (*user, error) {
+// Обновляет хэш пароля без отзыва токенов: сам пароль не меняется
+func (p UsersProvider) UpdatePasswordHash(ctx context.Context, userId string, hashedPassword []byte) error {
+	query := `
+		update scl.users
+		set password = @password
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"password": hashedPassword,
+		"id":       userId,
+	}
+
+	err := p.updateUser(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("updating password hash of '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (p UsersProvider) Delete(ctx context.Context, userId string) error {
+	query := `
+		delete from scl.users
+		where id = @id
+		returning id
+	`
+
+	err := p.updateUser(ctx, query, pgx.NamedArgs{"id": userId})
+	if err != nil {
+		return fmt.Errorf("deleting user '%s': %w", userId, err)
+	}
+
+	return nil
+}
+
+func (p UsersProvider) getUserInfo(ctx context.Context, identifier string) (*user, error) {
 	var users []user
 
 	query := `
@@ -93,71 +406,118 @@ func (p UsersProvider) getUserInfo(ctx context.Context, login string) (*user, er
 			id,
 			login,
 			password,
-			email
+			email,
+			totp_secret,
+			totp_enabled,
+			roles,
+			disabled,
+			mfa_generation,
+			tokens_valid_after
 		from scl.users
-		where login = $1
+		where lower(login) = $1 or lower(email) = $1
+		-- совпадение по логину приоритетнее совпадения по email
+		order by lower(login) = $1 desc
+		limit 1
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &users, query, login)
+	err := pgxscan.Select(ctx, p.conn(ctx), &users, query, identifier)
 	if err != nil {
 		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
 	}
 
 	if len(users) == 0 {
-		return nil, fmt.Errorf("querying db: %v", models.UserNotFound)
+		return nil, fmt.Errorf("querying db: %w", models.UserNotFound)
 	}
 
 	return &users[0], nil
 }
 
-func (p UsersProvider) checkEmailExists(ctx context.Context, email string) (bool, error) {
-	var res []bool
+func (p UsersProvider) getUserInfoById(ctx context.Context, id int64) (*user, error) {
+	var users []user
 
 	query := `
 		select
-			exists(
-				select
-					1
-				from scl.users
-				where email = $1
-				limit 1
-			) as exists
+			id,
+			login,
+			password,
+			email,
+			totp_secret,
+			totp_enabled,
+			roles,
+			disabled,
+			mfa_generation,
+			tokens_valid_after
+		from scl.users
+		where id = $1
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &res, query, email)
+	err := pgxscan.Select(ctx, p.conn(ctx), &users, query, id)
 	if err != nil {
-		return false, fmt.Errorf("executing query `%s`: %v", query, err)
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
 	}
 
-	if len(res) == 0 {
-		return false, nil
+	if len(users) == 0 {
+		return nil, fmt.Errorf("querying db: %w", models.UserNotFound)
 	}
 
-	return res[0], nil
+	return &users[0], nil
 }
 
-func (p UsersProvider) checkLoginExists(ctx context.Context, login string) (bool, error) {
-	var res []bool
+func (p UsersProvider) getAllUsersInfo(ctx context.Context) ([]user, error) {
+	var users []user
 
 	query := `
 		select
-			exists(
-				select
-					1
-				from scl.users
-				where login = $1
-				limit 1
-			) as exists
+			id,
+			login,
+			email,
+			totp_enabled,
+			roles,
+			disabled
+		from scl.users
+		order by id
 	`
 
-	err := pgxscan.Select(ctx, p.querier, &res, query, login)
+	err := pgxscan.Select(ctx, p.conn(ctx), &users, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	return users, nil
+}
+
+// Если err - нарушение уникального индекса по email или login,
+// возвращает *models.UserConflictError с именем поля, иначе nil
+func userConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.UniqueViolation {
+		return nil
+	}
+
+	field, known := uniqueUserFields[pgErr.ConstraintName]
+	if !known {
+		return nil
+	}
+
+	return &models.UserConflictError{Field: field}
+}
+
+// Выполняет запрос на изменение пользователя, возвращающий его id.
+// Если ни одна строка не изменена, возвращает models.UserNotFound
+func (p UsersProvider) updateUser(ctx context.Context, query string, args pgx.NamedArgs) error {
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("updating db: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
 	if err != nil {
-		return false, fmt.Errorf("executing query `%s`: %v", query, err)
+		return fmt.Errorf("collecting updated user id: %v", err)
 	}
 
-	if len(res) == 0 {
-		return false, nil
+	if len(ids) == 0 {
+		return models.UserNotFound
 	}
 
-	return res[0], nil
+	return nil
 }
@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/require"
 )
 
-func TestUserExists(t *testing.T) {
+func TestSingleUser(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Fatal(err)
@@ -19,74 +22,151 @@ func TestUserExists(t *testing.T) {
 
 	p := UsersProvider{mock}
 
-	t.Run("Email exists", func(t *testing.T) {
-		user := &models.User{
+	t.Run("Select successfully", func(t *testing.T) {
+		expected := models.User{
+			Id:             "1",
 			Email:          "myemail@index.com",
 			Login:          "mylogin",
 			HashedPassword: []byte("pwd"),
 		}
 
-		exists := mock.NewRows([]string{"exists"}).
-			AddRow(true)
+		users := mock.NewRows([]string{"id", "email", "login", "password"}).
+			AddRow(int64(1), "myemail@index.com", "mylogin", []byte("pwd"))
+
+		mock.ExpectQuery("select").WithArgs("login").WillReturnRows(users)
+
+		actual, err := p.Get(context.Background(), "login")
+		require.NoError(t, err)
+		require.Equal(t, expected, *actual)
+	})
+
+	t.Run("Select by email", func(t *testing.T) {
+		users := mock.NewRows([]string{"id", "email", "login", "password"}).
+			AddRow(int64(1), "myemail@index.com", "mylogin", []byte("pwd"))
 
-		mock.ExpectQuery("select").WithArgs(user.Email).WillReturnRows(exists)
+		mock.ExpectQuery(`where lower\(login\) = \$1 or lower\(email\) = \$1`).
+			WithArgs("myemail@index.com").WillReturnRows(users)
 
-		actual, err := p.Exists(context.Background(), user)
+		actual, err := p.Get(context.Background(), "myemail@index.com")
 		require.NoError(t, err)
-		require.True(t, actual)
+		require.Equal(t, "1", actual.Id)
+	})
+
+	t.Run("select nothing found", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id", "email", "login", "password"})
+
+		mock.ExpectQuery("select").WithArgs("login").WillReturnRows(rows)
+
+		actual, err := p.Get(context.Background(), "login")
+		require.Error(t, err)
+		require.Nil(t, actual)
 	})
 
-	t.Run("Login exists", func(t *testing.T) {
-		user := &models.User{
+	t.Run("select with error", func(t *testing.T) {
+		mock.ExpectQuery("select").WithArgs("login").WillReturnError(errors.New("db error"))
+
+		actual, err := p.Get(context.Background(), "login")
+		require.Error(t, err)
+		require.Nil(t, actual)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
+
+func TestInsertUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := UsersProvider{mock}
+
+	t.Run("Insert successfully", func(t *testing.T) {
+		user := models.User{
 			Email:          "myemail@index.com",
 			Login:          "mylogin",
 			HashedPassword: []byte("pwd"),
 		}
 
-		notExists := mock.NewRows([]string{"exists"}).
-			AddRow(false)
-		exists := mock.NewRows([]string{"exists"}).
-			AddRow(true)
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
 
-		mock.ExpectQuery("select").WithArgs(user.Email).WillReturnRows(notExists)
-		mock.ExpectQuery("select").WithArgs(user.Login).WillReturnRows(exists)
+		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnRows(rows)
 
-		actual, err := p.Exists(context.Background(), user)
+		id, err := p.Add(context.Background(), &user)
 		require.NoError(t, err)
-		require.True(t, actual)
+		require.Equal(t, "1", id)
 	})
 
-	t.Run("Nothing exists", func(t *testing.T) {
-		user := &models.User{
+	t.Run("insert with conflicting email", func(t *testing.T) {
+		user := models.User{
 			Email:          "myemail@index.com",
 			Login:          "mylogin",
 			HashedPassword: []byte("pwd"),
 		}
 
-		notExists := mock.NewRows([]string{"exists"}).
-			AddRow(false)
+		pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "users_email_lower_key"}
+		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnError(pgErr)
 
-		mock.ExpectQuery("select").WithArgs(user.Email).WillReturnRows(notExists)
-		mock.ExpectQuery("select").WithArgs(user.Login).WillReturnRows(notExists)
+		_, err := p.Add(context.Background(), &user)
+		require.ErrorIs(t, err, models.UserAlreadyExists)
 
-		actual, err := p.Exists(context.Background(), user)
-		require.NoError(t, err)
-		require.False(t, actual)
+		var conflict *models.UserConflictError
+		require.ErrorAs(t, err, &conflict)
+		require.Equal(t, "email", conflict.Field)
+	})
+
+	t.Run("insert with conflicting login", func(t *testing.T) {
+		user := models.User{
+			Email:          "myemail@index.com",
+			Login:          "mylogin",
+			HashedPassword: []byte("pwd"),
+		}
+
+		pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "users_login_lower_key"}
+		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnError(pgErr)
+
+		var conflict *models.UserConflictError
+		_, err := p.Add(context.Background(), &user)
+		require.ErrorAs(t, err, &conflict)
+		require.Equal(t, "login", conflict.Field)
 	})
 
-	t.Run("exists with error", func(t *testing.T) {
-		mock.ExpectQuery("select").WithArgs("").WillReturnError(errors.New("db error"))
+	t.Run("insert with unknown unique violation", func(t *testing.T) {
+		user := models.User{
+			Email:          "myemail@index.com",
+			Login:          "mylogin",
+			HashedPassword: []byte("pwd"),
+		}
+
+		pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"}
+		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnError(pgErr)
 
-		actual, err := p.Exists(context.Background(), &models.User{})
+		_, err := p.Add(context.Background(), &user)
 		require.Error(t, err)
-		require.False(t, actual)
+		require.NotErrorIs(t, err, models.UserAlreadyExists)
+	})
+
+	t.Run("insert with error", func(t *testing.T) {
+		user := models.User{
+			Email:          "myemail@index.com",
+			Login:          "mylogin",
+			HashedPassword: []byte("pwd"),
+		}
+
+		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnError(errors.New("db error"))
+
+		id, err := p.Add(context.Background(), &user)
+		require.Error(t, err)
+		require.Equal(t, "", id)
 	})
 
 	err = mock.ExpectationsWereMet()
 	require.NoError(t, err)
 }
 
-func TestSingleUser(t *testing.T) {
+func TestUserTOTP(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Fatal(err)
@@ -95,47 +175,215 @@ func TestSingleUser(t *testing.T) {
 
 	p := UsersProvider{mock}
 
-	t.Run("Select successfully", func(t *testing.T) {
+	t.Run("Set totp successfully", func(t *testing.T) {
+		secret := []byte("encrypted secret")
+		codes := [][]byte{[]byte("code1"), []byte("code2")}
+
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("update").WithArgs(secret, codes, "1").WillReturnRows(rows)
+
+		err := p.SetTOTP(context.Background(), "1", secret, codes)
+		require.NoError(t, err)
+	})
+
+	t.Run("Enable totp of unknown user", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("update").WithArgs("2").WillReturnRows(rows)
+
+		err := p.EnableTOTP(context.Background(), "2")
+		require.ErrorIs(t, err, models.UserNotFound)
+	})
+
+	t.Run("Use recovery code", func(t *testing.T) {
+		code := []byte("code1")
+
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("update").WithArgs(code, "1").WillReturnRows(rows)
+
+		used, err := p.UseRecoveryCode(context.Background(), "1", code)
+		require.NoError(t, err)
+		require.True(t, used)
+	})
+
+	t.Run("Use unknown recovery code", func(t *testing.T) {
+		code := []byte("unknown")
+
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("update").WithArgs(code, "1").WillReturnRows(rows)
+
+		used, err := p.UseRecoveryCode(context.Background(), "1", code)
+		require.NoError(t, err)
+		require.False(t, used)
+	})
+
+	t.Run("Use recovery code with error", func(t *testing.T) {
+		code := []byte("code1")
+
+		mock.ExpectQuery("update").WithArgs(code, "1").WillReturnError(errors.New("db error"))
+
+		used, err := p.UseRecoveryCode(context.Background(), "1", code)
+		require.Error(t, err)
+		require.False(t, used)
+	})
+
+	t.Run("Use totp step", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("update").WithArgs(int64(100), "1").WillReturnRows(rows)
+
+		used, err := p.UseTOTPStep(context.Background(), "1", 100)
+		require.NoError(t, err)
+		require.True(t, used)
+	})
+
+	t.Run("Use already used totp step", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("update").WithArgs(int64(100), "1").WillReturnRows(rows)
+
+		used, err := p.UseTOTPStep(context.Background(), "1", 100)
+		require.NoError(t, err)
+		require.False(t, used)
+	})
+
+	t.Run("Add mfa attempt", func(t *testing.T) {
+		rows := mock.NewRows([]string{"mfa_attempts"}).AddRow(int32(2))
+		mock.ExpectQuery("update").WithArgs(float64(900), "1", 3, 5).WillReturnRows(rows)
+
+		attempts, err := p.AddMFAAttempt(context.Background(), "1", 3, 5, 15*time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("Add mfa attempt with revoked token", func(t *testing.T) {
+		rows := mock.NewRows([]string{"mfa_attempts"})
+		mock.ExpectQuery("update").WithArgs(float64(900), "1", 2, 5).WillReturnRows(rows)
+
+		_, err := p.AddMFAAttempt(context.Background(), "1", 2, 5, 15*time.Minute)
+		require.ErrorIs(t, err, models.MFATokenRevoked)
+	})
+
+	t.Run("Reset mfa attempts", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("mfa_window_start = null").WithArgs("1").WillReturnRows(rows)
+
+		err := p.ResetMFAAttempts(context.Background(), "1")
+		require.NoError(t, err)
+	})
+
+	t.Run("Revoke mfa tokens", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("update").WithArgs("1").WillReturnRows(rows)
+
+		err := p.RevokeMFATokens(context.Background(), "1")
+		require.NoError(t, err)
+	})
+
+	t.Run("Get by id successfully", func(t *testing.T) {
 		expected := models.User{
 			Id:             "1",
 			Email:          "myemail@index.com",
 			Login:          "mylogin",
 			HashedPassword: []byte("pwd"),
+			TOTPSecret:     []byte("secret"),
+			TOTPEnabled:    true,
 		}
 
-		users := mock.NewRows([]string{"id", "email", "login", "password"}).
-			AddRow(int64(1), "myemail@index.com", "mylogin", []byte("pwd"))
+		users := mock.NewRows([]string{"id", "email", "login", "password", "totp_secret", "totp_enabled"}).
+			AddRow(int64(1), "myemail@index.com", "mylogin", []byte("pwd"), []byte("secret"), true)
 
-		mock.ExpectQuery("select").WithArgs("login").WillReturnRows(users)
+		mock.ExpectQuery("select").WithArgs(int64(1)).WillReturnRows(users)
 
-		actual, err := p.Get(context.Background(), "login")
+		actual, err := p.GetById(context.Background(), "1")
 		require.NoError(t, err)
 		require.Equal(t, expected, *actual)
 	})
 
-	t.Run("select nothing found", func(t *testing.T) {
-		rows := mock.NewRows([]string{"id", "email", "login", "password"})
-
-		mock.ExpectQuery("select").WithArgs("login").WillReturnRows(rows)
-
-		actual, err := p.Get(context.Background(), "login")
+	t.Run("Get by illegal id", func(t *testing.T) {
+		actual, err := p.GetById(context.Background(), "abc")
 		require.Error(t, err)
 		require.Nil(t, actual)
 	})
 
-	t.Run("select with error", func(t *testing.T) {
-		mock.ExpectQuery("select").WithArgs("login").WillReturnError(errors.New("db error"))
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
 
-		actual, err := p.Get(context.Background(), "login")
+func TestUsersAdministration(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := UsersProvider{mock}
+
+	t.Run("Select all successfully", func(t *testing.T) {
+		expected := []*models.User{
+			{
+				Id:    "1",
+				Email: "admin@index.com",
+				Login: "admin",
+				Roles: []role.Role{role.User, role.Admin},
+			},
+			{
+				Id:       "2",
+				Email:    "user@index.com",
+				Login:    "user",
+				Roles:    []role.Role{role.User},
+				Disabled: true,
+			},
+		}
+
+		users := mock.NewRows([]string{"id", "login", "email", "totp_enabled", "roles", "disabled"}).
+			AddRow(int64(1), "admin", "admin@index.com", false, []string{"user", "admin"}, false).
+			AddRow(int64(2), "user", "user@index.com", false, []string{"user"}, true)
+
+		mock.ExpectQuery("select").WillReturnRows(users)
+
+		actual, err := p.GetAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("Select all with unknown role", func(t *testing.T) {
+		users := mock.NewRows([]string{"id", "login", "email", "totp_enabled", "roles", "disabled"}).
+			AddRow(int64(1), "admin", "admin@index.com", false, []string{"superuser"}, false)
+
+		mock.ExpectQuery("select").WillReturnRows(users)
+
+		actual, err := p.GetAll(context.Background())
 		require.Error(t, err)
 		require.Nil(t, actual)
 	})
 
+	t.Run("Disable successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(2))
+		mock.ExpectQuery("update").WithArgs(true, "2").WillReturnRows(rows)
+
+		err := p.SetDisabled(context.Background(), "2", true)
+		require.NoError(t, err)
+	})
+
+	t.Run("Update password hash successfully", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"}).AddRow(int64(2))
+		mock.ExpectQuery("update").WithArgs([]byte("hash"), "2").WillReturnRows(rows)
+
+		err := p.UpdatePasswordHash(context.Background(), "2", []byte("hash"))
+		require.NoError(t, err)
+	})
+
+	t.Run("Set password of unknown user", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("update").WithArgs([]byte("hash"), "3").WillReturnRows(rows)
+
+		err := p.SetPassword(context.Background(), "3", []byte("hash"))
+		require.ErrorIs(t, err, models.UserNotFound)
+	})
+
 	err = mock.ExpectationsWereMet()
 	require.NoError(t, err)
 }
 
-func TestInsertUser(t *testing.T) {
+func TestDeleteUser(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Fatal(err)
@@ -144,34 +392,20 @@ func TestInsertUser(t *testing.T) {
 
 	p := UsersProvider{mock}
 
-	t.Run("Insert successfully", func(t *testing.T) {
-		user := models.User{
-			Email:          "myemail@index.com",
-			Login:          "mylogin",
-			HashedPassword: []byte("pwd"),
-		}
-
+	t.Run("Delete successfully", func(t *testing.T) {
 		rows := mock.NewRows([]string{"id"}).AddRow(int64(1))
+		mock.ExpectQuery("delete").WithArgs("1").WillReturnRows(rows)
 
-		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnRows(rows)
-
-		id, err := p.Add(context.Background(), &user)
+		err := p.Delete(context.Background(), "1")
 		require.NoError(t, err)
-		require.Equal(t, "1", id)
 	})
 
-	t.Run("insert with error", func(t *testing.T) {
-		user := models.User{
-			Email:          "myemail@index.com",
-			Login:          "mylogin",
-			HashedPassword: []byte("pwd"),
-		}
-
-		mock.ExpectQuery("insert").WithArgs(user.Email, user.Login, user.HashedPassword).WillReturnError(errors.New("db error"))
+	t.Run("Delete nothing found", func(t *testing.T) {
+		rows := mock.NewRows([]string{"id"})
+		mock.ExpectQuery("delete").WithArgs("2").WillReturnRows(rows)
 
-		id, err := p.Add(context.Background(), &user)
-		require.Error(t, err)
-		require.Equal(t, "", id)
+		err := p.Delete(context.Background(), "2")
+		require.ErrorIs(t, err, models.UserNotFound)
 	})
 
 	err = mock.ExpectationsWereMet()
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/guregu/null/v5"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+type webhook struct {
+	Id        int64     `db:"id"`
+	UserId    string    `db:"user_id"`
+	URL       string    `db:"url"`
+	Events    []string  `db:"events"`
+	Secret    string    `db:"secret"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (w *webhook) toModel() *models.Webhook {
+	events := make([]models.EventType, len(w.Events))
+	for i, e := range w.Events {
+		events[i] = models.EventType(e)
+	}
+
+	return &models.Webhook{
+		Id:        fmt.Sprintf("%d", w.Id),
+		UserId:    w.UserId,
+		URL:       w.URL,
+		Events:    events,
+		Secret:    w.Secret,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+type delivery struct {
+	Id             int64       `db:"id"`
+	WebhookId      int64       `db:"webhook_id"`
+	EventId        string      `db:"event_id"`
+	EventType      string      `db:"event_type"`
+	RedeliveryOf   null.Int    `db:"redelivery_of"`
+	Payload        []byte      `db:"payload"`
+	Status         string      `db:"status"`
+	Attempts       int         `db:"attempts"`
+	ResponseStatus null.Int32  `db:"response_status"`
+	LastError      null.String `db:"last_error"`
+	NextAttemptAt  time.Time   `db:"next_attempt_at"`
+	CreatedAt      time.Time   `db:"created_at"`
+	DeliveredAt    null.Time   `db:"delivered_at"`
+}
+
+func (d *delivery) toModel() *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		Id:             fmt.Sprintf("%d", d.Id),
+		WebhookId:      fmt.Sprintf("%d", d.WebhookId),
+		EventId:        d.EventId,
+		EventType:      models.EventType(d.EventType),
+		RedeliveryOf:   nullId(d.RedeliveryOf),
+		Payload:        d.Payload,
+		Status:         models.DeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		ResponseStatus: int(d.ResponseStatus.Int32),
+		LastError:      d.LastError.String,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt.Time,
+	}
+}
+
+// Доставка с адресом и секретом подписки
+type outgoingDelivery struct {
+	delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+func nullId(id null.Int) string {
+	if !id.Valid {
+		return ""
+	}
+
+	return fmt.Sprintf("%d", id.Int64)
+}
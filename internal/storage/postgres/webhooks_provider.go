@@ -0,0 +1,448 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/guregu/null/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/pkg/postgres"
+)
+
+type WebhooksProvider struct {
+	querier pgxscan.Querier
+}
+
+func NewWebhooksProvider(querier pgxscan.Querier) WebhooksProvider {
+	return WebhooksProvider{querier}
+}
+
+// Транзакция из контекста или querier, с которым создан провайдер
+func (p WebhooksProvider) conn(ctx context.Context) postgres.Querier {
+	return postgres.QuerierFrom(ctx, p.querier)
+}
+
+func (p WebhooksProvider) Add(ctx context.Context, w *models.Webhook) (string, error) {
+	query := `
+		insert into scl.webhooks(user_id, url, events, secret, active)
+		values (@user, @url, @events, @secret, @active)
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"user":   w.UserId,
+		"url":    w.URL,
+		"events": eventTypes(w.Events),
+		"secret": w.Secret,
+		"active": w.Active,
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return "", fmt.Errorf("inserting into db: %v", err)
+	}
+
+	id, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return "", fmt.Errorf("collecting new webhook id: %v", err)
+	}
+
+	return fmt.Sprintf("%d", id), nil
+}
+
+func (p WebhooksProvider) Get(ctx context.Context, webhookId string) (*models.Webhook, error) {
+	id, err := parseId(webhookId, models.WebhookNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := p.selectWebhooks(ctx, `where id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) == 0 {
+		return nil, fmt.Errorf("looking '%d' up: %w", id, models.WebhookNotFound)
+	}
+
+	return webhooks[0], nil
+}
+
+func (p WebhooksProvider) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	return p.selectWebhooks(ctx, ``)
+}
+
+// Возвращает все подписки пользователя
+func (p WebhooksProvider) GetByUser(ctx context.Context, userId string) ([]*models.Webhook, error) {
+	return p.selectWebhooks(ctx, `where user_id = $1`, userId)
+}
+
+// Возвращает активные подписки на события указанного типа
+func (p WebhooksProvider) Subscribed(ctx context.Context, t models.EventType) ([]*models.Webhook, error) {
+	return p.selectWebhooks(ctx, `where active and $1 = any(events)`, string(t))
+}
+
+// Обновляет адрес, типы событий и активность подписки
+func (p WebhooksProvider) Update(ctx context.Context, w *models.Webhook) error {
+	id, err := parseId(w.Id, models.WebhookNotFound)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		update scl.webhooks
+		set
+			url = @url,
+			events = @events,
+			active = @active
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"url":    w.URL,
+		"events": eventTypes(w.Events),
+		"active": w.Active,
+		"id":     id,
+	}
+
+	return p.execOne(ctx, query, args, models.WebhookNotFound)
+}
+
+// Удаляет подписку вместе с журналом доставок
+func (p WebhooksProvider) Delete(ctx context.Context, webhookId string) error {
+	id, err := parseId(webhookId, models.WebhookNotFound)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		delete from scl.webhooks
+		where id = $1
+		returning id
+	`
+
+	return p.execOne(ctx, query, id, models.WebhookNotFound)
+}
+
+// Удаляет все подписки пользователя
+func (p WebhooksProvider) DeleteByUser(ctx context.Context, userId string) error {
+	query := `
+		delete from scl.webhooks
+		where user_id = $1
+	`
+
+	rows, err := p.conn(ctx).Query(ctx, query, userId)
+	if err != nil {
+		return fmt.Errorf("deleting from db: %v", err)
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// Добавляет доставку, ожидающую отправки. Если событие уже доставляется по этой подписке
+// и доставка не повторная, ничего не делает и возвращает пустой идентификатор
+func (p WebhooksProvider) AddDelivery(ctx context.Context, d *models.WebhookDelivery) (string, error) {
+	webhookId, err := parseId(d.WebhookId, models.WebhookNotFound)
+	if err != nil {
+		return "", err
+	}
+
+	var redeliveryOf null.Int
+	if d.RedeliveryOf != "" {
+		id, err := parseId(d.RedeliveryOf, models.DeliveryNotFound)
+		if err != nil {
+			return "", err
+		}
+
+		redeliveryOf = null.IntFrom(id)
+	}
+
+	query := `
+		insert into scl.webhook_deliveries(webhook_id, event_id, event_type, redelivery_of, payload)
+		values (@webhook, @event, @type, @redelivery_of, @payload)
+		on conflict (webhook_id, event_id) where redelivery_of is null do nothing
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"webhook":       webhookId,
+		"event":         d.EventId,
+		"type":          string(d.EventType),
+		"redelivery_of": redeliveryOf,
+		"payload":       string(d.Payload),
+	}
+
+	rows, err := p.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return "", fmt.Errorf("inserting into db: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return "", fmt.Errorf("collecting new delivery id: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%d", ids[0]), nil
+}
+
+// Возвращает не больше limit последних доставок по подписке, начиная с новых
+func (p WebhooksProvider) Deliveries(ctx context.Context, webhookId string, limit int) ([]*models.WebhookDelivery, error) {
+	id, err := parseId(webhookId, models.WebhookNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []delivery
+
+	query := deliverySelect + `
+		where webhook_id = $1
+		order by id desc
+		limit $2
+	`
+
+	err = pgxscan.Select(ctx, p.conn(ctx), &deliveries, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		res = append(res, deliveries[i].toModel())
+	}
+
+	return res, nil
+}
+
+// Возвращает доставку по подписке
+func (p WebhooksProvider) GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error) {
+	wId, err := parseId(webhookId, models.WebhookNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	dId, err := parseId(deliveryId, models.DeliveryNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []delivery
+
+	query := deliverySelect + `
+		where webhook_id = $1 and id = $2
+	`
+
+	err = pgxscan.Select(ctx, p.conn(ctx), &deliveries, query, wId, dId)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("looking '%d' up: %w", dId, models.DeliveryNotFound)
+	}
+
+	return deliveries[0].toModel(), nil
+}
+
+// Занимает не больше limit доставок, ожидающих отправки: переносит их следующую попытку на leaseUntil,
+// чтобы до этого времени их не заняли снова. Доставки, заблокированные другой транзакцией, пропускаются,
+// поэтому несколько экземпляров приложения занимают разные доставки
+func (p WebhooksProvider) ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]*models.OutgoingDelivery, error) {
+	var deliveries []outgoingDelivery
+
+	query := `
+		with claimed as (
+			select d.id
+			from scl.webhook_deliveries as d
+			join scl.webhooks as w on w.id = d.webhook_id
+			where
+				d.status = 'pending'
+				and
+				d.next_attempt_at <= now()
+				and
+				w.active
+			order by d.next_attempt_at, d.id
+			limit $1
+			for update of d skip locked
+		)
+		update scl.webhook_deliveries as d
+		set next_attempt_at = $2
+		from claimed, scl.webhooks as w
+		where d.id = claimed.id and w.id = d.webhook_id
+		returning
+			d.id,
+			d.webhook_id,
+			d.event_id,
+			d.event_type,
+			d.redelivery_of,
+			d.payload,
+			d.status,
+			d.attempts,
+			d.response_status,
+			d.last_error,
+			d.next_attempt_at,
+			d.created_at,
+			d.delivered_at,
+			w.url,
+			w.secret
+	`
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &deliveries, query, limit, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.OutgoingDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		res = append(res, &models.OutgoingDelivery{
+			Delivery: *deliveries[i].toModel(),
+			URL:      deliveries[i].URL,
+			Secret:   deliveries[i].Secret,
+		})
+	}
+
+	return res, nil
+}
+
+// Сохраняет результат попытки доставки
+func (p WebhooksProvider) RecordAttempt(ctx context.Context, deliveryId string, a models.DeliveryAttempt) error {
+	id, err := parseId(deliveryId, models.DeliveryNotFound)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		update scl.webhook_deliveries
+		set
+			status = @status,
+			attempts = attempts + 1,
+			response_status = @response_status,
+			last_error = @error,
+			next_attempt_at = @next,
+			delivered_at = @delivered_at
+		where id = @id
+		returning id
+	`
+
+	args := pgx.NamedArgs{
+		"status":          string(a.Status),
+		"response_status": null.NewInt32(int32(a.ResponseStatus), a.ResponseStatus != 0),
+		"error":           null.NewString(a.Error, a.Error != ""),
+		"next":            a.NextAttemptAt,
+		"delivered_at":    null.NewTime(a.At, a.Status == models.DeliverySucceeded),
+		"id":              id,
+	}
+
+	return p.execOne(ctx, query, args, models.DeliveryNotFound)
+}
+
+const deliverySelect = `
+		select
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			redelivery_of,
+			payload,
+			status,
+			attempts,
+			response_status,
+			last_error,
+			next_attempt_at,
+			created_at,
+			delivered_at
+		from scl.webhook_deliveries
+`
+
+func (p WebhooksProvider) selectWebhooks(ctx context.Context, where string, args ...any) ([]*models.Webhook, error) {
+	var webhooks []webhook
+
+	query := `
+		select
+			id,
+			user_id,
+			url,
+			events,
+			secret,
+			active,
+			created_at
+		from scl.webhooks
+		` + where + `
+		order by id
+	`
+
+	err := pgxscan.Select(ctx, p.conn(ctx), &webhooks, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("executing query `%s`: %v", query, err)
+	}
+
+	res := make([]*models.Webhook, 0, len(webhooks))
+	for i := range webhooks {
+		res = append(res, webhooks[i].toModel())
+	}
+
+	return res, nil
+}
+
+// Выполняет запрос, изменяющий одну строку и возвращающий ее id. Если строки нет, возвращает notFound
+func (p WebhooksProvider) execOne(ctx context.Context, query string, arg any, notFound error) error {
+	rows, err := p.conn(ctx).Query(ctx, query, arg)
+	if err != nil {
+		return fmt.Errorf("updating db: %v", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("collecting updated ids: %v", err)
+	}
+
+	if len(ids) == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// Идентификаторы в базе числовые, поэтому нечисловой идентификатор означает отсутствие записи
+func parseId(id string, notFound error) (int64, error) {
+	res, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("illegal id '%s': %w", id, notFound)
+	}
+
+	return res, nil
+}
+
+// Числовые идентификаторы из списка. Нечисловые пропускаются: записей с ними нет
+func parseIds(ids []string) []int64 {
+	res := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		parsed, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, parsed)
+	}
+
+	return res
+}
+
+func eventTypes(events []models.EventType) []string {
+	res := make([]string, len(events))
+	for i, e := range events {
+		res[i] = string(e)
+	}
+
+	return res
+}
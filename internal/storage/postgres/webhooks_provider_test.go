@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestWebhooks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	p := WebhooksProvider{mock}
+	ctx := context.Background()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaseUntil := createdAt.Add(time.Minute)
+
+	webhookColumns := []string{"id", "user_id", "url", "events", "secret", "active", "created_at"}
+
+	t.Run("Insert successfully", func(t *testing.T) {
+		mock.ExpectQuery("insert into scl.webhooks").
+			WithArgs("1", "https://example.com/hook", []string{"profile.created"}, "secret", true).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(3)))
+
+		id, err := p.Add(ctx, &models.Webhook{
+			UserId: "1",
+			URL:    "https://example.com/hook",
+			Events: []models.EventType{models.EventProfileCreated},
+			Secret: "secret",
+			Active: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "3", id)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rows := mock.NewRows(webhookColumns).
+			AddRow(int64(3), "1", "https://example.com/hook", []string{"profile.created"}, "secret", true, createdAt)
+		mock.ExpectQuery("select").WithArgs(int64(3)).WillReturnRows(rows)
+
+		webhook, err := p.Get(ctx, "3")
+		require.NoError(t, err)
+		require.Equal(t, &models.Webhook{
+			Id:        "3",
+			UserId:    "1",
+			URL:       "https://example.com/hook",
+			Events:    []models.EventType{models.EventProfileCreated},
+			Secret:    "secret",
+			Active:    true,
+			CreatedAt: createdAt,
+		}, webhook)
+	})
+
+	t.Run("Get not found", func(t *testing.T) {
+		mock.ExpectQuery("select").WithArgs(int64(4)).WillReturnRows(mock.NewRows(webhookColumns))
+
+		_, err := p.Get(ctx, "4")
+		require.ErrorIs(t, err, models.WebhookNotFound)
+
+		_, err = p.Get(ctx, "abc")
+		require.ErrorIs(t, err, models.WebhookNotFound)
+	})
+
+	t.Run("Delete not found", func(t *testing.T) {
+		mock.ExpectQuery("delete from scl.webhooks").WithArgs(int64(4)).WillReturnRows(mock.NewRows([]string{"id"}))
+
+		err := p.Delete(ctx, "4")
+		require.ErrorIs(t, err, models.WebhookNotFound)
+	})
+
+	t.Run("ClaimDeliveries", func(t *testing.T) {
+		rows := mock.NewRows([]string{
+			"id", "webhook_id", "event_id", "event_type", "redelivery_of", "payload", "status", "attempts",
+			"response_status", "last_error", "next_attempt_at", "created_at", "delivered_at", "url", "secret",
+		}).AddRow(
+			int64(7), int64(3), "5", "profile.created", nil, []byte(`{}`), "pending", 1,
+			nil, nil, leaseUntil, createdAt, nil, "https://example.com/hook", "secret",
+		)
+		mock.ExpectQuery("skip locked").WithArgs(10, leaseUntil).WillReturnRows(rows)
+
+		deliveries, err := p.ClaimDeliveries(ctx, 10, leaseUntil)
+		require.NoError(t, err)
+		require.Equal(t, []*models.OutgoingDelivery{{
+			Delivery: models.WebhookDelivery{
+				Id:            "7",
+				WebhookId:     "3",
+				EventId:       "5",
+				EventType:     models.EventProfileCreated,
+				Payload:       []byte(`{}`),
+				Status:        models.DeliveryPending,
+				Attempts:      1,
+				NextAttemptAt: leaseUntil,
+				CreatedAt:     createdAt,
+			},
+			URL:    "https://example.com/hook",
+			Secret: "secret",
+		}}, deliveries)
+	})
+
+	t.Run("RecordAttempt with error", func(t *testing.T) {
+		mock.ExpectQuery("update scl.webhook_deliveries").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("db error"))
+
+		err := p.RecordAttempt(ctx, "7", models.DeliveryAttempt{Status: models.DeliverySucceeded, ResponseStatus: 200, At: createdAt})
+		require.Error(t, err)
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
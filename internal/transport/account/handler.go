@@ -0,0 +1,181 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+const (
+	exportFormatZip  = "zip"
+	exportFormatJSON = "json"
+)
+
+// Обработчик HTTP-запросов пользователя на управление своим аккаунтом
+type AccountHandler struct {
+	service  AccountService
+	jwtKeys  *jwt.Keys
+	validate *validator.Validate
+}
+
+func NewAccountHandler(service AccountService, jwtKeys *jwt.Keys) AccountHandler {
+	return AccountHandler{
+		service:  service,
+		jwtKeys:  jwtKeys,
+		validate: problem.NewValidator(),
+	}
+}
+
+// Обработчик HTTP-запросов на смену пароля
+func (h *AccountHandler) ChangePassword(c *fiber.Ctx) error {
+	changeReq := changePasswordRequest{}
+	err := c.BodyParser(&changeReq)
+	if err != nil {
+		return problem.InvalidBody(err)
+	}
+
+	err = h.validate.Struct(changeReq)
+	if err != nil {
+		return problem.Validation(err)
+	}
+
+	userId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	roles, err := jwt.ExtractRoles(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract roles from token")
+	}
+
+	err = h.service.ChangePassword(c.Context(), userId, changeReq.OldPassword, changeReq.NewPassword)
+	if err != nil {
+		if errors.Is(err, models.UserBadCredentials) {
+			return problem.New(fiber.StatusBadRequest, problem.CodeBadCredentials, "old password is incorrect")
+		}
+
+		return fmt.Errorf("changing password: %w", err)
+	}
+
+	token, err := jwt.MakeToken(userId, roles, h.jwtKeys)
+	if err != nil {
+		return fmt.Errorf("creating JWT-token: %v", err)
+	}
+
+	err = c.JSON(changePasswordResponse{AccessToken: token})
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на удаление аккаунта
+func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	userId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	err = h.service.DeleteAccount(c.Context(), userId)
+	if err != nil {
+		return fmt.Errorf("deleting account: %w", err)
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// Обработчик HTTP-запросов на выгрузку всех данных пользователя.
+// По умолчанию отдает ZIP-архив, с параметром format=json — JSON-документ
+func (h *AccountHandler) Export(c *fiber.Ctx) error {
+	format := c.Query("format", exportFormatZip)
+	if format != exportFormatZip && format != exportFormatJSON {
+		detail := fmt.Sprintf("unknown format '%s': only %v are available", format, []string{exportFormatZip, exportFormatJSON})
+		return problem.New(fiber.StatusBadRequest, problem.CodeValidationFailed, detail)
+	}
+
+	userId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	data, err := h.service.Export(c.Context(), userId)
+	if err != nil {
+		return fmt.Errorf("exporting account: %w", err)
+	}
+
+	payload := fromModel(data)
+
+	if format == exportFormatJSON {
+		err = c.JSON(payload)
+		if err != nil {
+			return fmt.Errorf("sending response: %v", err)
+		}
+
+		return nil
+	}
+
+	archive, err := makeArchive(payload)
+	if err != nil {
+		return fmt.Errorf("building archive: %v", err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Attachment(fmt.Sprintf("account-%s.zip", userId))
+
+	err = c.Send(archive)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Упаковывает выгрузку в ZIP-архив: по одному JSON-файлу на каждый вид данных
+func makeArchive(payload *accountExport) ([]byte, error) {
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"user.json", payload.User},
+		{"profiles.json", payload.Profiles},
+		{"photos.json", payload.Photos},
+		{"webhooks.json", payload.Webhooks},
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		f, err := w.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("creating '%s': %v", file.name, err)
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(file.content)
+		if err != nil {
+			return nil, fmt.Errorf("writing '%s': %v", file.name, err)
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("closing archive: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestAccount(t *testing.T) {
+	service := mocks.NewAccountService(t)
+	sessions := mocks.NewAuthService(t)
+	signingKey := jwt.NewKeys("signing-key")
+	accountHandler := NewAccountHandler(service, signingKey)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(jwt.Middleware(signingKey), jwt.RejectRevoked(sessions))
+	app.Post("/me/password", accountHandler.ChangePassword)
+	app.Delete("/me", accountHandler.DeleteAccount)
+	app.Get("/me/export", accountHandler.Export)
+
+	userId := "1"
+	token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
+	require.NoError(t, err)
+
+	t.Run("test ChangePassword", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("ChangePassword", mock.Anything, userId, "old", "new").Return(nil).Once()
+
+		body := strings.NewReader(`{"old_password": "old", "new_password": "new"}`)
+
+		req := httptest.NewRequest("POST", "/me/password", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload changePasswordResponse
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.NotEmpty(t, payload.AccessToken)
+	})
+
+	t.Run("test ChangePassword with wrong old password", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("ChangePassword", mock.Anything, userId, "wrong", "new").Return(fmt.Errorf("%w", models.UserBadCredentials)).Once()
+
+		body := strings.NewReader(`{"old_password": "wrong", "new_password": "new"}`)
+
+		req := httptest.NewRequest("POST", "/me/password", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test ChangePassword with weak new password", func(t *testing.T) {
+		verr := &models.ValidationError{}
+		verr.Add("new_password", "too_short", "password must be at least 8 characters long")
+
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("ChangePassword", mock.Anything, userId, "old", "new").Return(verr).Once()
+
+		body := strings.NewReader(`{"old_password": "old", "new_password": "new"}`)
+
+		req := httptest.NewRequest("POST", "/me/password", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var payload problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeValidationFailed, payload.Code)
+		assert.Equal(t, []problem.FieldError{{Field: "new_password", Code: "too_short", Message: "password must be at least 8 characters long"}}, payload.Errors)
+	})
+
+	t.Run("test revoked token", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(fmt.Errorf("%w", models.SessionRevoked)).Once()
+
+		req := httptest.NewRequest("DELETE", "/me", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("test DeleteAccount", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("DeleteAccount", mock.Anything, userId).Return(nil).Once()
+
+		req := httptest.NewRequest("DELETE", "/me", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	data := &models.AccountData{
+		User: &models.User{
+			Id:    userId,
+			Email: "email",
+			Login: "login",
+			Roles: []role.Role{role.User},
+		},
+		Profiles: []*models.Profile{
+			{
+				Id:        "2",
+				Name:      "name",
+				Birthdate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+				Interests: []string{"chess"},
+				Privacy: &models.Privacy{
+					Fields: map[models.ProfileField]models.Visibility{models.FieldCity: models.VisibilityHidden},
+				},
+			},
+		},
+		Photos: []*models.Photo{
+			{
+				Id:        "5",
+				ProfileId: "2",
+				UserId:    userId,
+				Variants: []models.PhotoVariant{
+					{Name: "original", Key: "photos/2/a/original.jpg", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1000},
+				},
+			},
+		},
+		Webhooks: []*models.Webhook{
+			{
+				Id:     "3",
+				UserId: userId,
+				URL:    "https://example.com/hook",
+				Events: []models.EventType{models.EventProfileCreated},
+				Secret: "secret",
+				Active: true,
+			},
+		},
+	}
+
+	t.Run("test Export json", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("Export", mock.Anything, userId).Return(data, nil).Once()
+
+		req := httptest.NewRequest("GET", "/me/export?format=json", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload accountExport
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, "login", payload.User.Login)
+		assert.Equal(t, "1990-01-01", payload.Profiles[0].Birthdate)
+		assert.Equal(t, []string{"chess"}, payload.Profiles[0].Interests)
+		assert.Equal(t, "hidden", payload.Profiles[0].Privacy["city"])
+		assert.Equal(t, "registered", payload.Profiles[0].Privacy["surname"])
+
+		require.Len(t, payload.Photos, 1)
+		assert.Equal(t, "2", payload.Photos[0].ProfileId)
+		assert.Equal(t, []exportVariant{{Name: "original", ContentType: "image/jpeg", Width: 800, Height: 600, Size: 1000}}, payload.Photos[0].Variants)
+
+		require.Len(t, payload.Webhooks, 1)
+		assert.Equal(t, []string{"profile.created"}, payload.Webhooks[0].Events)
+	})
+
+	t.Run("test Export json does not contain secrets", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("Export", mock.Anything, userId).Return(data, nil).Once()
+
+		req := httptest.NewRequest("GET", "/me/export?format=json", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "secret")
+		assert.NotContains(t, string(body), "photos/2/a/original.jpg")
+	})
+
+	t.Run("test Export zip", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+		service.On("Export", mock.Anything, userId).Return(data, nil).Once()
+
+		req := httptest.NewRequest("GET", "/me/export", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		require.Len(t, archive.File, 4)
+		assert.Equal(t, "user.json", archive.File[0].Name)
+		assert.Equal(t, "profiles.json", archive.File[1].Name)
+		assert.Equal(t, "photos.json", archive.File[2].Name)
+		assert.Equal(t, "webhooks.json", archive.File[3].Name)
+	})
+
+	t.Run("test Export unknown format", func(t *testing.T) {
+		sessions.On("ValidateSession", mock.Anything, userId, mock.Anything).Return(nil).Once()
+
+		req := httptest.NewRequest("GET", "/me/export?format=xml", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,157 @@
+package account
+
+import (
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+const birthdateFormat = "2006-01-02"
+
+// Структура HTTP-запроса на смену пароля
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// Структура HTTP-ответа на смену пароля
+// В ответе содержится новый JWT-токен: все ранее выпущенные токены отозваны
+type changePasswordResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// Выгрузка всех данных пользователя
+type accountExport struct {
+	User     exportUser      `json:"user"`
+	Profiles []exportProfile `json:"profiles"`
+	Photos   []exportPhoto   `json:"photos"`
+	Webhooks []exportWebhook `json:"webhooks"`
+}
+
+type exportUser struct {
+	Id          string   `json:"id"`
+	Email       string   `json:"email"`
+	Login       string   `json:"login"`
+	Roles       []string `json:"roles"`
+	TOTPEnabled bool     `json:"totp_enabled"`
+}
+
+type exportProfile struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Surname   string   `json:"surname"`
+	Sex       string   `json:"sex"`
+	Birthdate string   `json:"birthdate"`
+	City      string   `json:"city"`
+	Hobbies   string   `json:"hobbies"`
+	Interests []string `json:"interests"`
+	// Видимость каждого поля, в том числе не измененная владельцем
+	Privacy map[string]string `json:"privacy"`
+}
+
+// Метаданные фотографии и ее вариантов. Сами файлы доступны по адресам из API фотографий
+type exportPhoto struct {
+	Id        string          `json:"id"`
+	ProfileId string          `json:"profile_id"`
+	Variants  []exportVariant `json:"variants"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type exportVariant struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+// Подписка без секрета: он показывается только при создании
+type exportWebhook struct {
+	Id        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func fromModel(data *models.AccountData) *accountExport {
+	roles := make([]string, len(data.User.Roles))
+	for i, r := range data.User.Roles {
+		roles[i] = r.String()
+	}
+
+	profiles := make([]exportProfile, len(data.Profiles))
+	for i, p := range data.Profiles {
+		privacy := make(map[string]string, len(models.ProfileFields))
+		for _, f := range models.ProfileFields {
+			privacy[string(f)] = string(p.Privacy.Visibility(f))
+		}
+
+		interests := p.Interests
+		if interests == nil {
+			interests = []string{}
+		}
+
+		profiles[i] = exportProfile{
+			Id:        p.Id,
+			Name:      p.Name,
+			Surname:   p.Surname,
+			Sex:       p.Sex.String(),
+			Birthdate: p.Birthdate.Format(birthdateFormat),
+			City:      p.Address,
+			Hobbies:   p.Hobbies,
+			Interests: interests,
+			Privacy:   privacy,
+		}
+	}
+
+	photos := make([]exportPhoto, len(data.Photos))
+	for i, p := range data.Photos {
+		variants := make([]exportVariant, len(p.Variants))
+		for j, v := range p.Variants {
+			variants[j] = exportVariant{
+				Name:        v.Name,
+				ContentType: v.ContentType,
+				Width:       v.Width,
+				Height:      v.Height,
+				Size:        v.Size,
+			}
+		}
+
+		photos[i] = exportPhoto{
+			Id:        p.Id,
+			ProfileId: p.ProfileId,
+			Variants:  variants,
+			CreatedAt: p.CreatedAt,
+		}
+	}
+
+	webhooks := make([]exportWebhook, len(data.Webhooks))
+	for i, w := range data.Webhooks {
+		events := make([]string, len(w.Events))
+		for j, e := range w.Events {
+			events[j] = string(e)
+		}
+
+		webhooks[i] = exportWebhook{
+			Id:        w.Id,
+			URL:       w.URL,
+			Events:    events,
+			Active:    w.Active,
+			CreatedAt: w.CreatedAt,
+		}
+	}
+
+	return &accountExport{
+		User: exportUser{
+			Id:          data.User.Id,
+			Email:       data.User.Email,
+			Login:       data.User.Login,
+			Roles:       roles,
+			TOTPEnabled: data.User.TOTPEnabled,
+		},
+		Profiles: profiles,
+		Photos:   photos,
+		Webhooks: webhooks,
+	}
+}
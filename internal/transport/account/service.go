@@ -0,0 +1,14 @@
+package account
+
+import (
+	"context"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис управления пользователем своим аккаунтом
+type AccountService interface {
+	ChangePassword(ctx context.Context, userId, oldPassword, newPassword string) error
+	DeleteAccount(ctx context.Context, userId string) error
+	Export(ctx context.Context, userId string) (*models.AccountData, error)
+}
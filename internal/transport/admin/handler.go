@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Обработчик HTTP-запросов администраторов
+type AdminHandler struct {
+	service AdminService
+}
+
+func NewAdminHandler(service AdminService) AdminHandler {
+	return AdminHandler{
+		service: service,
+	}
+}
+
+// Обработчик HTTP-запросов на список пользователей
+func (h *AdminHandler) ListUsers(c *fiber.Ctx) error {
+	actorId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	users, err := h.service.ListUsers(c.Context(), actorId)
+	if err != nil {
+		return fmt.Errorf("listing users: %w", err)
+	}
+
+	payload := make([]*user, len(users))
+
+	for i, u := range users {
+		payload[i] = fromModel(u)
+	}
+
+	err = c.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на блокировку пользователя
+func (h *AdminHandler) DisableUser(c *fiber.Ctx) error {
+	return h.setUserDisabled(c, true)
+}
+
+// Обработчик HTTP-запросов на разблокировку пользователя
+func (h *AdminHandler) EnableUser(c *fiber.Ctx) error {
+	return h.setUserDisabled(c, false)
+}
+
+func (h *AdminHandler) setUserDisabled(c *fiber.Ctx, disabled bool) error {
+	userId := c.Params("id")
+
+	actorId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	err = h.service.SetUserDisabled(c.Context(), actorId, userId, disabled)
+	if err != nil {
+		return fmt.Errorf("updating user: %w", err)
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// Обработчик HTTP-запросов на удаление любой анкеты
+func (h *AdminHandler) DeleteProfile(c *fiber.Ctx) error {
+	profileId := c.Params("id")
+
+	actorId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	err = h.service.DeleteProfile(c.Context(), actorId, profileId)
+	if err != nil {
+		return fmt.Errorf("deleting profile: %w", err)
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// Обработчик HTTP-запросов на сброс пароля пользователя
+func (h *AdminHandler) ResetPassword(c *fiber.Ctx) error {
+	userId := c.Params("id")
+
+	actorId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	password, err := h.service.ResetPassword(c.Context(), actorId, userId)
+	if err != nil {
+		return fmt.Errorf("resetting password: %w", err)
+	}
+
+	err = c.JSON(resetPasswordResponse{TemporaryPassword: password})
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestAdmin(t *testing.T) {
+	service := mocks.NewAdminService(t)
+	adminHandler := NewAdminHandler(service)
+	signingKey := jwt.NewKeys("signing-key")
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(jwt.Middleware(signingKey))
+	app.Get("/admin/users", jwt.RequirePermission(role.ManageUsers), adminHandler.ListUsers)
+	app.Post("/admin/users/:id/disable", jwt.RequirePermission(role.ManageUsers), adminHandler.DisableUser)
+	app.Post("/admin/users/:id/password/reset", jwt.RequirePermission(role.ManageUsers), adminHandler.ResetPassword)
+	app.Delete("/admin/profiles/:id", jwt.RequirePermission(role.ManageProfiles), adminHandler.DeleteProfile)
+
+	adminId := "1"
+	adminToken, err := jwt.MakeToken(adminId, []role.Role{role.User, role.Admin}, signingKey)
+	require.NoError(t, err)
+
+	t.Run("test ListUsers", func(t *testing.T) {
+		users := []*models.User{
+			{
+				Id:    "2",
+				Login: "login",
+				Roles: []role.Role{role.User},
+			},
+		}
+
+		service.On("ListUsers", mock.Anything, adminId).Return(users, nil).Once()
+
+		req := httptest.NewRequest("GET", "/admin/users", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload []user
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user"}, payload[0].Roles)
+	})
+
+	t.Run("test ListUsers by regular user", func(t *testing.T) {
+		token, err := jwt.MakeToken("2", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/admin/users", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("test ListUsers by token without roles", func(t *testing.T) {
+		token, err := jwt.MakeToken("2", nil, signingKey)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/admin/users", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("test DisableUser", func(t *testing.T) {
+		service.On("SetUserDisabled", mock.Anything, adminId, "2", true).Return(nil).Once()
+
+		req := httptest.NewRequest("POST", "/admin/users/2/disable", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("test DisableUser not found", func(t *testing.T) {
+		service.On("SetUserDisabled", mock.Anything, adminId, "3", true).Return(fmt.Errorf("%w", models.UserNotFound)).Once()
+
+		req := httptest.NewRequest("POST", "/admin/users/3/disable", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("test ResetPassword", func(t *testing.T) {
+		service.On("ResetPassword", mock.Anything, adminId, "2").Return("temporary", nil).Once()
+
+		req := httptest.NewRequest("POST", "/admin/users/2/password/reset", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload resetPasswordResponse
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, "temporary", payload.TemporaryPassword)
+	})
+
+	t.Run("test DeleteProfile", func(t *testing.T) {
+		service.On("DeleteProfile", mock.Anything, adminId, "23").Return(nil).Once()
+
+		req := httptest.NewRequest("DELETE", "/admin/profiles/23", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("test DeleteProfile failed", func(t *testing.T) {
+		service.On("DeleteProfile", mock.Anything, adminId, "24").Return(fmt.Errorf("service error")).Once()
+
+		req := httptest.NewRequest("DELETE", "/admin/profiles/24", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
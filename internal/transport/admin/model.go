@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Информация о пользователе, доступная администратору
+type user struct {
+	Id          string   `json:"id"`
+	Email       string   `json:"email"`
+	Login       string   `json:"login"`
+	Roles       []string `json:"roles"`
+	Disabled    bool     `json:"disabled"`
+	TOTPEnabled bool     `json:"totp_enabled"`
+}
+
+// Структура HTTP-ответа на сброс пароля
+type resetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+func fromModel(mu *models.User) *user {
+	roles := make([]string, len(mu.Roles))
+	for i, r := range mu.Roles {
+		roles[i] = r.String()
+	}
+
+	return &user{
+		Id:          mu.Id,
+		Email:       mu.Email,
+		Login:       mu.Login,
+		Roles:       roles,
+		Disabled:    mu.Disabled,
+		TOTPEnabled: mu.TOTPEnabled,
+	}
+}
@@ -0,0 +1,15 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис административных действий
+type AdminService interface {
+	ListUsers(ctx context.Context, actorId string) ([]*models.User, error)
+	SetUserDisabled(ctx context.Context, actorId, userId string, disabled bool) error
+	DeleteProfile(ctx context.Context, actorId, profileId string) error
+	ResetPassword(ctx context.Context, actorId, userId string) (string, error)
+}
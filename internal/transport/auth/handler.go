@@ -9,20 +9,25 @@ import (
 
 	"github.com/Lucky112/social/internal/models"
 	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
 )
 
+// Ответ на недействительный или просроченный MFA-токен.
+// Отсутствие пользователя или TOTP не раскрывается
+var invalidMFAToken = problem.New(fiber.StatusUnauthorized, problem.CodeInvalidMFAToken, "mfa token is invalid or expired")
+
 // Обработчик HTTP-запросов на регистрацию и аутентификацию пользователей
 type AuthHandler struct {
 	service  AuthService
-	jwtKey   []byte
+	jwtKeys  *jwt.Keys
 	validate *validator.Validate
 }
 
-func NewAuthHandler(service AuthService, jwtKey []byte) AuthHandler {
+func NewAuthHandler(service AuthService, jwtKeys *jwt.Keys) AuthHandler {
 	return AuthHandler{
 		service:  service,
-		jwtKey:   jwtKey,
-		validate: validator.New(validator.WithRequiredStructEnabled()),
+		jwtKeys:  jwtKeys,
+		validate: problem.NewValidator(),
 	}
 }
 
@@ -31,18 +36,12 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	regReq := registerRequest{}
 	err := c.BodyParser(&regReq)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			registerError{fmt.Sprintf("failed to parse body: %v", err)},
-		)
-		return nil
+		return problem.InvalidBody(err)
 	}
 
 	err = h.validate.Struct(regReq)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			registerError{fmt.Sprintf("invalid body: %v", err)},
-		)
-		return nil
+		return problem.Validation(err)
 	}
 
 	user := &models.User{
@@ -53,17 +52,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 	id, err := h.service.NewUser(c.Context(), user)
 	if err != nil {
-		if errors.Is(err, models.UserAlreadyExists) {
-			c.Status(fiber.StatusBadRequest).JSON(
-				registerError{"the user for given email or login already exists"},
-			)
-		} else {
-			c.Status(fiber.StatusInternalServerError).JSON(
-				registerError{fmt.Sprintf("failed to create new user: %v", err)},
-			)
-		}
-
-		return nil
+		return fmt.Errorf("creating new user: %w", err)
 	}
 
 	err = c.Status(fiber.StatusCreated).JSON(
@@ -81,46 +70,81 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	loginReq := loginRequest{}
 	err := c.BodyParser(&loginReq)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			loginError{fmt.Sprintf("failed to parse body: %v", err)},
-		)
-		return nil
+		return problem.InvalidBody(err)
 	}
 
 	err = h.validate.Struct(loginReq)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			loginError{fmt.Sprintf("invalid body: %v", err)},
-		)
+		return problem.Validation(err)
+	}
+
+	user, err := h.service.Login(c.Context(), loginReq.identifier(), loginReq.Password)
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return problem.New(fiber.StatusNotFound, problem.CodeUserNotFound, "the user for given login or email not found")
+		}
+
+		return fmt.Errorf("logging in: %w", err)
+	}
+
+	if user.TOTPEnabled {
+		token, err := jwt.MakeMFAToken(user.Id, user.MFAGeneration, h.jwtKeys)
+		if err != nil {
+			return fmt.Errorf("creating JWT-token: %v", err)
+		}
+
+		err = c.JSON(loginResponse{MFAToken: token, MFARequired: true})
+		if err != nil {
+			return fmt.Errorf("sending response: %v", err)
+		}
+
 		return nil
 	}
 
-	userId, err := h.service.Login(c.Context(), loginReq.Login, loginReq.Password)
+	token, err := jwt.MakeToken(user.Id, user.Roles, h.jwtKeys)
+	if err != nil {
+		return fmt.Errorf("creating JWT-token: %v", err)
+	}
+
+	err = c.JSON(loginResponse{AccessToken: token})
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на обмен MFA-токена и TOTP-кода на access-токен
+func (h *AuthHandler) LoginMFA(c *fiber.Ctx) error {
+	mfaReq := loginMFARequest{}
+	err := c.BodyParser(&mfaReq)
+	if err != nil {
+		return problem.InvalidBody(err)
+	}
+
+	err = h.validate.Struct(mfaReq)
+	if err != nil {
+		return problem.Validation(err)
+	}
+
+	userId, generation, err := jwt.ParseMFAToken(mfaReq.MFAToken, h.jwtKeys)
+	if err != nil {
+		return invalidMFAToken
+	}
+
+	user, err := h.service.VerifyMFA(c.Context(), userId, generation, mfaReq.Code)
 	if err != nil {
 		switch {
-		case errors.Is(err, models.UserNotFound):
-			c.Status(fiber.StatusNotFound).JSON(
-				loginError{"the user for given login not found"},
-			)
-		case errors.Is(err, models.UserBadCredentials):
-			c.Status(fiber.StatusBadRequest).JSON(
-				loginError{"login or password is incorrect"},
-			)
+		case errors.Is(err, models.UserNotFound), errors.Is(err, models.TOTPNotEnrolled), errors.Is(err, models.MFATokenRevoked):
+			return invalidMFAToken
 		default:
-			c.Status(fiber.StatusInternalServerError).JSON(
-				loginError{fmt.Sprintf("failed to login: %v", err)},
-			)
+			return fmt.Errorf("verifying mfa code: %w", err)
 		}
-
-		return nil
 	}
 
-	token, err := jwt.MakeToken(userId, h.jwtKey)
+	token, err := jwt.MakeToken(user.Id, user.Roles, h.jwtKeys)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError).JSON(
-			loginError{fmt.Sprintf("failed to create JWT-token: %v", err)},
-		)
-		return nil
+		return fmt.Errorf("creating JWT-token: %v", err)
 	}
 
 	err = c.JSON(loginResponse{AccessToken: token})
@@ -130,3 +154,54 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	return nil
 }
+
+// Обработчик HTTP-запросов на подключение TOTP
+func (h *AuthHandler) EnrollTOTP(c *fiber.Ctx) error {
+	userId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	enrollment, err := h.service.EnrollTOTP(c.Context(), userId)
+	if err != nil {
+		return fmt.Errorf("enrolling totp: %w", err)
+	}
+
+	err = c.Status(fiber.StatusCreated).JSON(totpEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+		RecoveryCodes:   enrollment.RecoveryCodes,
+	})
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на подтверждение подключения TOTP
+func (h *AuthHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	confirmReq := totpConfirmRequest{}
+	err := c.BodyParser(&confirmReq)
+	if err != nil {
+		return problem.InvalidBody(err)
+	}
+
+	err = h.validate.Struct(confirmReq)
+	if err != nil {
+		return problem.Validation(err)
+	}
+
+	userId, err := jwt.ExtractUserId(c)
+	if err != nil {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	err = h.service.ConfirmTOTP(c.Context(), userId, confirmReq.Code)
+	if err != nil {
+		return fmt.Errorf("confirming totp: %w", err)
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,17 +9,21 @@ import (
 	"testing"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
 	"github.com/Lucky112/social/mocks"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAuth(t *testing.T) {
 	service := mocks.NewAuthService(t)
-	authHandler := NewAuthHandler(service, []byte("encription-key"))
+	authHandler := NewAuthHandler(service, jwt.NewKeys("encription-key"))
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
 	app.Post("/register", authHandler.Register)
 	app.Post("/login", authHandler.Login)
 
@@ -56,6 +61,59 @@ func TestAuth(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("test Register with taken login", func(t *testing.T) {
+		conflict := &models.UserConflictError{Field: "login"}
+		service.On("NewUser", mock.Anything, mock.Anything).Return("", fmt.Errorf("creating new user: %w", conflict)).Once()
+
+		body := strings.NewReader(`{
+			"email": "any string",
+			"login": "any string",
+			"password": "any string"
+		}`)
+
+		req := httptest.NewRequest("POST", "/register", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var payload problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeUserAlreadyExists, payload.Code)
+		assert.Equal(t, "the user with given login already exists", payload.Detail)
+		assert.Equal(t, []problem.FieldError{{Field: "login", Code: "already_exists", Message: payload.Detail}}, payload.Errors)
+	})
+
+	t.Run("test Register policy violations", func(t *testing.T) {
+		verr := &models.ValidationError{}
+		verr.Add("email", "invalid_email", "email must be a valid RFC 5322 address")
+		verr.Add("password", "common_password", "password is too common")
+
+		service.On("NewUser", mock.Anything, mock.Anything).Return("", fmt.Errorf("validating user: %w", verr)).Once()
+
+		body := strings.NewReader(`{
+			"email": "any string",
+			"login": "any string",
+			"password": "password"
+		}`)
+
+		req := httptest.NewRequest("POST", "/register", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var payload problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		require.Len(t, payload.Errors, 2)
+		assert.Equal(t, "email", payload.Errors[0].Field)
+		assert.Equal(t, "common_password", payload.Errors[1].Code)
+	})
+
 	t.Run("test Register bad json", func(t *testing.T) {
 		body := strings.NewReader(`{`)
 
@@ -79,7 +137,7 @@ func TestAuth(t *testing.T) {
 	})
 
 	t.Run("test Login successfully", func(t *testing.T) {
-		service.On("Login", mock.Anything, "login", "password").Return("3", nil).Once()
+		service.On("Login", mock.Anything, "login", "password").Return(&models.User{Id: "3"}, nil).Once()
 
 		body := strings.NewReader(`{
 			"email": "email",
@@ -95,8 +153,35 @@ func TestAuth(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 
+	t.Run("test Login by email", func(t *testing.T) {
+		service.On("Login", mock.Anything, "user@example.com", "password").Return(&models.User{Id: "3"}, nil).Once()
+
+		body := strings.NewReader(`{
+			"email": "user@example.com",
+			"password": "password"
+		}`)
+
+		req := httptest.NewRequest("POST", "/login", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test Login without identifier", func(t *testing.T) {
+		body := strings.NewReader(`{"password": "password"}`)
+
+		req := httptest.NewRequest("POST", "/login", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
 	t.Run("test Login with wrong password", func(t *testing.T) {
-		service.On("Login", mock.Anything, "login", "wrong password").Return("", fmt.Errorf("%w", models.UserBadCredentials)).Once()
+		service.On("Login", mock.Anything, "login", "wrong password").Return(nil, fmt.Errorf("%w", models.UserBadCredentials)).Once()
 
 		body := strings.NewReader(`{
 			"email": "email",
@@ -113,7 +198,7 @@ func TestAuth(t *testing.T) {
 	})
 
 	t.Run("test Login with unknown login", func(t *testing.T) {
-		service.On("Login", mock.Anything, "unknown login", "password").Return("", fmt.Errorf("%w", models.UserNotFound)).Once()
+		service.On("Login", mock.Anything, "unknown login", "password").Return(nil, fmt.Errorf("%w", models.UserNotFound)).Once()
 
 		body := strings.NewReader(`{
 			"email": "email",
@@ -140,6 +225,22 @@ func TestAuth(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("test Login disabled user", func(t *testing.T) {
+		service.On("Login", mock.Anything, "login", "password").Return(nil, fmt.Errorf("%w", models.UserDisabled)).Once()
+
+		body := strings.NewReader(`{
+			"login": "login",
+			"password": "password"
+		}`)
+
+		req := httptest.NewRequest("POST", "/login", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
 	t.Run("test Login empty json", func(t *testing.T) {
 		body := strings.NewReader(`{}`)
 
@@ -151,3 +252,188 @@ func TestAuth(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	})
 }
+
+func TestMFA(t *testing.T) {
+	service := mocks.NewAuthService(t)
+	signingKey := jwt.NewKeys("encription-key")
+	authHandler := NewAuthHandler(service, signingKey)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Post("/login", authHandler.Login)
+	app.Post("/login/mfa", authHandler.LoginMFA)
+
+	authorized := app.Group("")
+	authorized.Use(jwt.Middleware(signingKey))
+	authorized.Post("/mfa/totp", authHandler.EnrollTOTP)
+	authorized.Post("/mfa/totp/confirm", authHandler.ConfirmTOTP)
+	authorized.Get("/protected", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	var mfaToken string
+
+	t.Run("test Login with totp returns mfa token", func(t *testing.T) {
+		user := &models.User{Id: "3", TOTPEnabled: true, MFAGeneration: 2}
+		service.On("Login", mock.Anything, "login", "password").Return(user, nil).Once()
+
+		body := strings.NewReader(`{"login": "login", "password": "password"}`)
+
+		req := httptest.NewRequest("POST", "/login", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload loginResponse
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.True(t, payload.MFARequired)
+		assert.Empty(t, payload.AccessToken)
+		assert.NotEmpty(t, payload.MFAToken)
+
+		mfaToken = payload.MFAToken
+	})
+
+	t.Run("test mfa token is not an access token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", mfaToken))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("test LoginMFA with wrong code", func(t *testing.T) {
+		service.On("VerifyMFA", mock.Anything, "3", 2, "000000").Return(nil, fmt.Errorf("%w", models.InvalidMFACode)).Once()
+
+		body := strings.NewReader(fmt.Sprintf(`{"mfa_token": "%s", "code": "000000"}`, mfaToken))
+
+		req := httptest.NewRequest("POST", "/login/mfa", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("test LoginMFA with revoked token", func(t *testing.T) {
+		service.On("VerifyMFA", mock.Anything, "3", 2, "000000").Return(nil, fmt.Errorf("%w", models.MFATokenRevoked)).Once()
+
+		body := strings.NewReader(fmt.Sprintf(`{"mfa_token": "%s", "code": "000000"}`, mfaToken))
+
+		req := httptest.NewRequest("POST", "/login/mfa", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var payload problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeInvalidMFAToken, payload.Code)
+	})
+
+	t.Run("test LoginMFA successfully", func(t *testing.T) {
+		service.On("VerifyMFA", mock.Anything, "3", 2, "123456").Return(&models.User{Id: "3"}, nil).Once()
+
+		body := strings.NewReader(fmt.Sprintf(`{"mfa_token": "%s", "code": "123456"}`, mfaToken))
+
+		req := httptest.NewRequest("POST", "/login/mfa", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload loginResponse
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.NotEmpty(t, payload.AccessToken)
+	})
+
+	t.Run("test LoginMFA with access token", func(t *testing.T) {
+		token, err := jwt.MakeToken("3", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		body := strings.NewReader(fmt.Sprintf(`{"mfa_token": "%s", "code": "123456"}`, token))
+
+		req := httptest.NewRequest("POST", "/login/mfa", body)
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("test EnrollTOTP", func(t *testing.T) {
+		enrollment := &models.TOTPEnrollment{
+			Secret:          "SECRET",
+			ProvisioningURI: "otpauth://totp/social:login?secret=SECRET",
+			RecoveryCodes:   []string{"code"},
+		}
+		service.On("EnrollTOTP", mock.Anything, "3").Return(enrollment, nil).Once()
+
+		token, err := jwt.MakeToken("3", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/mfa/totp", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("test EnrollTOTP already enabled", func(t *testing.T) {
+		service.On("EnrollTOTP", mock.Anything, "3").Return(nil, fmt.Errorf("%w", models.TOTPAlreadyEnabled)).Once()
+
+		token, err := jwt.MakeToken("3", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/mfa/totp", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("test ConfirmTOTP", func(t *testing.T) {
+		service.On("ConfirmTOTP", mock.Anything, "3", "123456").Return(nil).Once()
+
+		token, err := jwt.MakeToken("3", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		body := strings.NewReader(`{"code": "123456"}`)
+
+		req := httptest.NewRequest("POST", "/mfa/totp/confirm", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("test ConfirmTOTP with wrong code", func(t *testing.T) {
+		service.On("ConfirmTOTP", mock.Anything, "3", "000000").Return(models.InvalidMFACode).Once()
+
+		token, err := jwt.MakeToken("3", []role.Role{role.User}, signingKey)
+		require.NoError(t, err)
+
+		body := strings.NewReader(`{"code": "000000"}`)
+
+		req := httptest.NewRequest("POST", "/mfa/totp/confirm", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var payload problem.Problem
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeInvalidMFACode, payload.Code)
+	})
+}
@@ -9,22 +9,47 @@ type registerRequest struct {
 type registerResponse struct {
 	Id string `json:"id"`
 }
-type registerError struct {
-	Message string `json:"msg"`
-}
 
 // Структура HTTP-запроса на вход в аккаунт
+// Пользователь указывает логин или email: в поле login допустимо передать и email
 type loginRequest struct {
-	Login    string `json:"login"    validate:"required"`
+	Login    string `json:"login"    validate:"required_without=Email"`
+	Email    string `json:"email"    validate:"required_without=Login"`
 	Password string `json:"password" validate:"required"`
 }
 
+// Идентификатор, по которому ищется пользователь
+func (r loginRequest) identifier() string {
+	if r.Login != "" {
+		return r.Login
+	}
+
+	return r.Email
+}
+
 // Структура HTTP-ответа на вход в аккаунт
-// В ответе содержится JWT-токен авторизованного пользователя
+// В ответе содержится JWT-токен авторизованного пользователя,
+// либо, если у пользователя включен TOTP, короткоживущий токен для прохождения второго фактора
 type loginResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	MFARequired bool   `json:"mfa_required"`
+}
+
+// Структура HTTP-запроса на проверку второго фактора при входе
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code"      validate:"required"`
+}
+
+// Структура HTTP-ответа на подключение TOTP
+type totpEnrollmentResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
 }
 
-type loginError struct {
-	Message string `json:"msg"`
+// Структура HTTP-запроса на подтверждение подключения TOTP
+type totpConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
 }
@@ -2,12 +2,17 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
 )
 
 // Сервис зарегистрированных пользователей
 type AuthService interface {
-	Login(ctx context.Context, login, password string) (string, error)
+	Login(ctx context.Context, identifier, password string) (*models.User, error)
 	NewUser(ctx context.Context, user *models.User) (string, error)
+	EnrollTOTP(ctx context.Context, userId string) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userId, code string) error
+	VerifyMFA(ctx context.Context, userId string, generation int, code string) (*models.User, error)
+	ValidateSession(ctx context.Context, userId string, issuedAt time.Time) error
 }
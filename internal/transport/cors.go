@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+
+	"github.com/Lucky112/social/config"
+)
+
+// Источники (Origin), из которых браузерам разрешены запросы к API. Список можно заменить во время работы
+type corsOrigins struct {
+	set atomic.Pointer[originSet]
+}
+
+type originSet struct {
+	any     bool
+	origins map[string]struct{}
+}
+
+func newCORSOrigins(cfg *config.CORSConfig) (*corsOrigins, error) {
+	set, err := parseOrigins(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &corsOrigins{}
+	o.set.Store(set)
+
+	return o, nil
+}
+
+func parseOrigins(cfg *config.CORSConfig) (*originSet, error) {
+	set := &originSet{origins: make(map[string]struct{})}
+	if cfg == nil {
+		return set, nil
+	}
+
+	for _, origin := range cfg.AllowOrigins {
+		if origin == "*" {
+			set.any = true
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			return nil, fmt.Errorf("invalid cors origin '%s': scheme://host[:port] expected", origin)
+		}
+
+		set.origins[strings.ToLower(u.Scheme+"://"+u.Host)] = struct{}{}
+	}
+
+	return set, nil
+}
+
+func (o *corsOrigins) allowed(origin string) bool {
+	set := o.set.Load()
+	if set.any {
+		return true
+	}
+
+	_, ok := set.origins[strings.ToLower(origin)]
+	return ok
+}
+
+// Без разрешенных источников ответы не содержат заголовков CORS, и браузеры блокируют запросы с чужих страниц
+func (o *corsOrigins) middleware() fiber.Handler {
+	return cors.New(cors.Config{
+		AllowOriginsFunc: o.allowed,
+		ExposeHeaders:    "ETag, Last-Modified, Deprecation, Sunset, Link, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Idempotent-Replayed",
+	})
+}
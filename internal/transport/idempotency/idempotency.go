@@ -0,0 +1,195 @@
+// Пакет idempotency позволяет безопасно повторять POST-запросы с заголовком Idempotency-Key
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+const (
+	HeaderKey = "Idempotency-Key"
+	// Выставляется в ответах, повторенных из хранилища
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+const maxKeyLength = 255
+
+type Config struct {
+	Store Store
+	// Сколько хранить ответ на выполненный запрос
+	TTL time.Duration
+	// Сколько ключ считается занятым выполняющимся запросом. Если экземпляр приложения
+	// упал, не завершив запрос, ключ освобождается по истечении этого времени
+	LockTimeout time.Duration
+	// Владелец ключа, например пользователь или IP-адрес: одинаковые ключи разных клиентов не пересекаются
+	Scope func(c *fiber.Ctx) string
+	// Префикс версии API, который отбрасывается при вычислении отпечатка запроса:
+	// повтор запроса по пути с версией и без нее считается тем же запросом
+	Prefix string
+}
+
+// Middleware, который запоминает ответы на POST-запросы с заголовком Idempotency-Key
+// и отдает их повторным запросам с тем же ключом вместо повторного выполнения
+type Middleware struct {
+	store       Store
+	ttl         time.Duration
+	lockTimeout time.Duration
+	scope       func(c *fiber.Ctx) string
+	prefix      string
+	now         func() time.Time
+}
+
+func New(cfg Config) *Middleware {
+	return &Middleware{
+		store:       cfg.Store,
+		ttl:         cfg.TTL,
+		lockTimeout: cfg.LockTimeout,
+		scope:       cfg.Scope,
+		prefix:      cfg.Prefix,
+		now:         time.Now,
+	}
+}
+
+func (m *Middleware) Handler() fiber.Handler {
+	return m.handle
+}
+
+func (m *Middleware) handle(c *fiber.Ctx) error {
+	if c.Method() != fiber.MethodPost {
+		return c.Next()
+	}
+
+	key := c.Get(HeaderKey)
+	if key == "" {
+		return c.Next()
+	}
+
+	if len(key) > maxKeyLength {
+		detail := fmt.Sprintf("%s must not be longer than %d characters", HeaderKey, maxKeyLength)
+
+		p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, detail)
+		p.Errors = []problem.FieldError{{Field: HeaderKey, Code: "max", Message: detail}}
+
+		return p
+	}
+
+	scope := m.scope(c)
+	fingerprint := fingerprintOf(c.Method(), m.path(c), c.Body())
+	now := m.now()
+
+	existing, acquired, err := m.store.Acquire(c.Context(), &models.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(m.lockTimeout),
+	}, now)
+	if err != nil {
+		// недоступность хранилища не должна останавливать API
+		slog.Warn("acquiring idempotency key", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+		return c.Next()
+	}
+
+	if !acquired {
+		return replay(c, existing, fingerprint)
+	}
+
+	err = c.Next()
+	if err != nil {
+		// ответ с ошибкой формирует обработчик ошибок приложения: вызываем его здесь, чтобы сохранить ответ
+		err = c.App().Config().ErrorHandler(c, err)
+		if err != nil {
+			m.release(c, scope, key)
+			return err
+		}
+	}
+
+	resp := c.Response()
+	if retryable(resp.StatusCode()) {
+		m.release(c, scope, key)
+		return nil
+	}
+
+	err = m.store.Complete(c.Context(), scope, key, models.StoredResponse{
+		Status:      resp.StatusCode(),
+		ContentType: string(resp.Header.ContentType()),
+		Body:        resp.Body(),
+	}, m.now().Add(m.ttl))
+	if err != nil {
+		slog.Warn("completing idempotency key", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+	}
+
+	return nil
+}
+
+// Ответы, которые не сохраняются: запрос не выполнился из-за временного сбоя, отказа в доступе
+// (например, отозванного токена, после которого клиент входит заново) или ограничения частоты,
+// и клиент должен иметь возможность повторить его с тем же ключом.
+// Middleware подключается до проверки токена, поэтому такие ответы доходят до него
+func retryable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
+
+func replay(c *fiber.Ctx, record *models.IdempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return problem.New(http.StatusUnprocessableEntity, problem.CodeIdempotencyReused,
+			fmt.Sprintf("%s was already used for a different request", HeaderKey))
+	}
+
+	if !record.Completed {
+		return problem.New(http.StatusConflict, problem.CodeIdempotencyInUse,
+			fmt.Sprintf("a request with the same %s is in progress, retry later", HeaderKey))
+	}
+
+	c.Set(HeaderReplayed, "true")
+	if record.Response.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.Response.ContentType)
+	}
+
+	return c.Status(record.Response.Status).Send(record.Response.Body)
+}
+
+func (m *Middleware) release(c *fiber.Ctx, scope, key string) {
+	err := m.store.Release(c.Context(), scope, key)
+	if err != nil {
+		slog.Warn("releasing idempotency key", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+	}
+}
+
+// Путь запроса без префикса версии
+func (m *Middleware) path(c *fiber.Ctx) string {
+	path := c.Path()
+	if m.prefix != "" {
+		if trimmed, ok := strings.CutPrefix(path, m.prefix); ok && strings.HasPrefix(trimmed, "/") {
+			path = trimmed
+		}
+	}
+
+	return path
+}
+
+// Отпечаток запроса: метод, путь и тело
+func fingerprintOf(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
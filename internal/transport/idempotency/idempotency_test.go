@@ -0,0 +1,274 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Приложение, в котором владелец ключа берется из заголовка X-Client, а обработчик
+// POST /profiles считает вызовы и отвечает статусом из заголовка X-Status
+func newTestApp(t *testing.T, store Store) (*fiber.App, *atomic.Int32, *time.Time) {
+	t.Helper()
+
+	m := New(Config{
+		Store:       store,
+		TTL:         time.Hour,
+		LockTimeout: time.Minute,
+		Scope: func(c *fiber.Ctx) string {
+			return c.Get("X-Client")
+		},
+		Prefix: "/api/v1",
+	})
+
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	var calls atomic.Int32
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(m.Handler())
+	app.Post("/*", func(c *fiber.Ctx) error {
+		n := calls.Add(1)
+
+		switch c.Get("X-Status") {
+		case "500":
+			return errors.New("storage is down")
+		case "404":
+			return models.ProfileNotFound
+		case "401":
+			return problem.New(http.StatusUnauthorized, problem.CodeTokenRevoked, "token was revoked")
+		case "429":
+			return problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "too many requests")
+		}
+
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"id": n})
+	})
+
+	return app, &calls, &now
+}
+
+func post(t *testing.T, app *fiber.App, path, client, key, body string, headers ...string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Client", client)
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func readProblem(t *testing.T, resp *http.Response) problem.Problem {
+	t.Helper()
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+
+	return p
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("test retry replays the response", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderReplayed))
+		assert.Equal(t, `{"id":1}`, readBody(t, resp))
+
+		resp = post(t, app, "/api/v1/profiles", "user:1", "key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "true", resp.Header.Get(HeaderReplayed))
+		assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, `{"id":1}`, readBody(t, resp))
+
+		// путь без версии - тот же запрос
+		resp = post(t, app, "/profiles", "user:1", "key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, `{"id":1}`, readBody(t, resp))
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("test requests without key are not deduplicated", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		post(t, app, "/api/v1/profiles", "user:1", "", `{}`)
+		post(t, app, "/api/v1/profiles", "user:1", "", `{}`)
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("test keys are scoped to the client", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		resp := post(t, app, "/api/v1/profiles", "user:2", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderReplayed))
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("test key reused with a different payload", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		post(t, app, "/api/v1/profiles", "user:1", "key-1", `{"name":"a"}`)
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{"name":"b"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, problem.CodeIdempotencyReused, readProblem(t, resp).Code)
+
+		resp = post(t, app, "/api/v1/register", "user:1", "key-1", `{"name":"a"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("test concurrent request with the same key", func(t *testing.T) {
+		store := NewMemoryStore()
+		app, calls, now := newTestApp(t, store)
+
+		// ключ занят запросом, который еще выполняется
+		_, acquired, err := store.Acquire(context.Background(), &models.IdempotencyRecord{
+			Scope:       "user:1",
+			Key:         "key-1",
+			Fingerprint: fingerprintOf(http.MethodPost, "/profiles", []byte(`{}`)),
+			ExpiresAt:   now.Add(time.Minute),
+		}, *now)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, problem.CodeIdempotencyInUse, readProblem(t, resp).Code)
+		assert.Equal(t, int32(0), calls.Load())
+
+		// запрос не завершился до истечения блокировки
+		*now = now.Add(2 * time.Minute)
+
+		resp = post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("test server errors are not stored", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`, "X-Status", "500")
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		resp = post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderReplayed))
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("test auth and rate limit errors are not stored", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		for _, status := range []string{"401", "429"} {
+			resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`, "X-Status", status)
+			assert.Equal(t, status, fmt.Sprint(resp.StatusCode))
+		}
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderReplayed))
+
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("test client errors are stored", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`, "X-Status", "404")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "true", resp.Header.Get(HeaderReplayed))
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, problem.CodeProfileNotFound, readProblem(t, resp).Code)
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("test stored response expires", func(t *testing.T) {
+		app, calls, now := newTestApp(t, NewMemoryStore())
+
+		post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+
+		*now = now.Add(2 * time.Hour)
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderReplayed))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("test too long key", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, NewMemoryStore())
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", strings.Repeat("k", maxKeyLength+1), `{}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, problem.CodeValidationFailed, readProblem(t, resp).Code)
+		assert.Equal(t, int32(0), calls.Load())
+	})
+
+	t.Run("test store failure does not block requests", func(t *testing.T) {
+		app, calls, _ := newTestApp(t, failingStore{})
+
+		resp := post(t, app, "/api/v1/profiles", "user:1", "key-1", `{}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+type failingStore struct{}
+
+func (failingStore) Acquire(context.Context, *models.IdempotencyRecord, time.Time) (*models.IdempotencyRecord, bool, error) {
+	return nil, false, errors.New("store is down")
+}
+
+func (failingStore) Complete(context.Context, string, string, models.StoredResponse, time.Time) error {
+	return errors.New("store is down")
+}
+
+func (failingStore) Release(context.Context, string, string) error {
+	return errors.New("store is down")
+}
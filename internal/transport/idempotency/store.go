@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Хранилище ключей идемпотентности. Общее хранилище (например, в Postgres) позволяет
+// нескольким экземплярам приложения узнавать повторы запросов, пришедших на другой экземпляр
+type Store interface {
+	// Резервирует ключ record.Scope/record.Key до record.ExpiresAt. Если ключ уже зарезервирован
+	// или выполнен и его срок не истек, ничего не меняет и возвращает существующую запись
+	Acquire(ctx context.Context, record *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, bool, error)
+	// Сохраняет ответ на запрос и продлевает срок хранения ключа до expiresAt
+	Complete(ctx context.Context, scope, key string, response models.StoredResponse, expiresAt time.Time) error
+	// Удаляет ключ, чтобы запрос можно было повторить
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Хранилище, из которого истекшие ключи удаляются отдельно от запросов
+type ExpiringStore interface {
+	// Удаляет ключи, срок которых истек к now. Возвращает число удаленных ключей
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Удаляет истекшие ключи раз в interval, пока не отменен контекст
+func RunSweeper(ctx context.Context, store ExpiringStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := store.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			slog.Warn("deleting expired idempotency keys", "error", err)
+			continue
+		}
+
+		slog.Debug("deleted expired idempotency keys", "count", n)
+	}
+}
+
+// Как часто удалять из памяти истекшие ключи
+const sweepInterval = time.Minute
+
+// Хранилище ключей в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[storeKey]*models.IdempotencyRecord
+	lastSweep time.Time
+}
+
+type storeKey struct {
+	scope string
+	key   string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[storeKey]*models.IdempotencyRecord),
+	}
+}
+
+func (s *MemoryStore) Acquire(_ context.Context, record *models.IdempotencyRecord, now time.Time) (*models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	k := storeKey{record.Scope, record.Key}
+	if r, ok := s.records[k]; ok && r.ExpiresAt.After(now) {
+		return clone(r), false, nil
+	}
+
+	r := clone(record)
+	r.Completed = false
+	r.Response = models.StoredResponse{}
+	s.records[k] = r
+
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, scope, key string, response models.StoredResponse, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[storeKey{scope, key}]
+	if !ok {
+		return nil
+	}
+
+	r.Completed = true
+	r.Response = response
+	r.Response.Body = append([]byte(nil), response.Body...)
+	r.ExpiresAt = expiresAt
+
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, storeKey{scope, key})
+
+	return nil
+}
+
+// Число ключей в памяти
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.records)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for k, r := range s.records {
+		if !r.ExpiresAt.After(now) {
+			delete(s.records, k)
+		}
+	}
+}
+
+func clone(r *models.IdempotencyRecord) *models.IdempotencyRecord {
+	c := *r
+	c.Response.Body = append([]byte(nil), r.Response.Body...)
+	return &c
+}
@@ -0,0 +1,85 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	record := func(expiresAt time.Time) *models.IdempotencyRecord {
+		return &models.IdempotencyRecord{Scope: "user:1", Key: "key", Fingerprint: "fp", ExpiresAt: expiresAt}
+	}
+
+	t.Run("test key is acquired once", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		existing, acquired, err := store.Acquire(ctx, record(now.Add(time.Minute)), now)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Nil(t, existing)
+
+		existing, acquired, err = store.Acquire(ctx, record(now.Add(time.Minute)), now)
+		require.NoError(t, err)
+		assert.False(t, acquired)
+		assert.Equal(t, "fp", existing.Fingerprint)
+		assert.False(t, existing.Completed)
+	})
+
+	t.Run("test completed key keeps the response", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		_, _, err := store.Acquire(ctx, record(now.Add(time.Minute)), now)
+		require.NoError(t, err)
+
+		body := []byte(`{"id":1}`)
+		err = store.Complete(ctx, "user:1", "key", models.StoredResponse{Status: 201, ContentType: "application/json", Body: body}, now.Add(time.Hour))
+		require.NoError(t, err)
+		body[0] = 'x'
+
+		existing, acquired, err := store.Acquire(ctx, record(now.Add(time.Minute)), now.Add(30*time.Minute))
+		require.NoError(t, err)
+		assert.False(t, acquired)
+		assert.True(t, existing.Completed)
+		assert.Equal(t, models.StoredResponse{Status: 201, ContentType: "application/json", Body: []byte(`{"id":1}`)}, existing.Response)
+	})
+
+	t.Run("test expired and released keys are acquired again", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		_, _, err := store.Acquire(ctx, record(now.Add(time.Minute)), now)
+		require.NoError(t, err)
+
+		_, acquired, err := store.Acquire(ctx, record(now.Add(2*time.Minute)), now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, acquired)
+
+		require.NoError(t, store.Release(ctx, "user:1", "key"))
+
+		_, acquired, err = store.Acquire(ctx, record(now.Add(2*time.Minute)), now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	})
+
+	t.Run("test expired keys are swept", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		_, _, err := store.Acquire(ctx, record(now.Add(time.Minute)), now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, store.Len())
+
+		other := record(now.Add(3 * sweepInterval))
+		other.Key = "other"
+		_, _, err = store.Acquire(ctx, other, now.Add(2*sweepInterval))
+		require.NoError(t, err)
+		assert.Equal(t, 1, store.Len())
+	})
+}
@@ -0,0 +1,62 @@
+package interests
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Число подсказок по умолчанию и наибольшее
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
+// Обработчик HTTP-запросов на подсказки интересов
+type InterestsHandler struct {
+	service InterestsService
+}
+
+func NewInterestsHandler(service InterestsService) InterestsHandler {
+	return InterestsHandler{service}
+}
+
+// Обработчик HTTP-запросов на интересы, начинающиеся с prefix, начиная с самых популярных
+func (h *InterestsHandler) Autocomplete(c *fiber.Ctx) error {
+	limit := defaultAutocompleteLimit
+	if v := c.Query("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAutocompleteLimit {
+			detail := fmt.Sprintf("limit must be an integer from 1 to %d", maxAutocompleteLimit)
+			return problem.New(fiber.StatusBadRequest, problem.CodeValidationFailed, detail)
+		}
+	}
+
+	viewer, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	found, err := h.service.Autocomplete(c.Context(), viewer, c.Query("prefix"), limit)
+	if err != nil {
+		return fmt.Errorf("autocompleting interests: %w", err)
+	}
+
+	payload := make([]interest, len(found))
+	for i, f := range found {
+		payload[i] = fromModel(f)
+	}
+
+	err = c.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package interests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestInterests(t *testing.T) {
+	service := mocks.NewInterestsService(t)
+	interestsHandler := NewInterestsHandler(service)
+	signingKey := jwt.NewKeys("signing-key")
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(jwt.Middleware(signingKey))
+	app.Get("/interests/autocomplete", jwt.RequirePermission(role.ReadProfiles), interestsHandler.Autocomplete)
+
+	viewer := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+	token, err := jwt.MakeToken(viewer.UserId, viewer.Roles, signingKey)
+	require.NoError(t, err)
+
+	get := func(url string) *http.Response {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	t.Run("test Autocomplete", func(t *testing.T) {
+		found := []models.InterestCount{{Name: "чтение", Profiles: 3}, {Name: "чтение вслух", Profiles: 1}}
+		service.On("Autocomplete", mock.Anything, viewer, "Чт", 5).Return(found, nil).Once()
+
+		resp := get("/interests/autocomplete?prefix=%D0%A7%D1%82&limit=5")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "чтение", "profiles": 3}, {"name": "чтение вслух", "profiles": 1}]`, string(body))
+	})
+
+	t.Run("test Autocomplete default limit", func(t *testing.T) {
+		service.On("Autocomplete", mock.Anything, viewer, "", defaultAutocompleteLimit).Return([]models.InterestCount{}, nil).Once()
+
+		resp := get("/interests/autocomplete")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[]`, string(body))
+	})
+
+	t.Run("test Autocomplete bad limit", func(t *testing.T) {
+		for _, limit := range []string{"0", "51", "many"} {
+			resp := get("/interests/autocomplete?limit=" + limit)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, limit)
+		}
+	})
+
+	t.Run("test Autocomplete failed", func(t *testing.T) {
+		service.On("Autocomplete", mock.Anything, viewer, "a", defaultAutocompleteLimit).Return(nil, fmt.Errorf("service error")).Once()
+
+		resp := get("/interests/autocomplete?prefix=a")
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
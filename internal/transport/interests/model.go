@@ -0,0 +1,16 @@
+package interests
+
+import "github.com/Lucky112/social/internal/models"
+
+// Подсказка интереса с числом анкет, в которых он указан
+type interest struct {
+	Name     string `json:"name"`
+	Profiles int    `json:"profiles"`
+}
+
+func fromModel(i models.InterestCount) interest {
+	return interest{
+		Name:     i.Name,
+		Profiles: i.Profiles,
+	}
+}
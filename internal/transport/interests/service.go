@@ -0,0 +1,12 @@
+package interests
+
+import (
+	"context"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис интересов анкет
+type InterestsService interface {
+	Autocomplete(ctx context.Context, viewer models.Actor, prefix string, limit int) ([]models.InterestCount, error)
+}
@@ -1,26 +1,72 @@
 package jwt
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/problem"
 )
 
 const jwtContextKey = "user"
 const userIdClaim = "sub"
+const tokenTypeClaim = "typ"
+const rolesClaim = "roles"
+const mfaGenerationClaim = "gen"
+
+const (
+	accessTokenType = "access"
+	mfaTokenType    = "mfa"
+)
+
+const (
+	accessTokenTTL = time.Hour * 72
+	mfaTokenTTL    = time.Minute * 5
+)
+
+var invalidToken = problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "invalid or expired JWT")
+
+func MakeToken(userId string, roles []role.Role, keys *Keys) (string, error) {
+	var extra jwt.MapClaims
+	if len(roles) > 0 {
+		extra = jwt.MapClaims{rolesClaim: roles}
+	}
+
+	return makeToken(userId, accessTokenType, accessTokenTTL, extra, keys)
+}
+
+// Создает короткоживущий токен, подтверждающий успешную проверку пароля.
+// Токен не дает доступа к API и может быть обменян на access-токен только через проверку второго фактора.
+// generation - поколение MFA-токенов пользователя: после отзыва поколения токен перестает приниматься
+func MakeMFAToken(userId string, generation int, keys *Keys) (string, error) {
+	return makeToken(userId, mfaTokenType, mfaTokenTTL, jwt.MapClaims{mfaGenerationClaim: generation}, keys)
+}
+
+func makeToken(userId, tokenType string, ttl time.Duration, extra jwt.MapClaims, keys *Keys) (string, error) {
+	now := time.Now()
 
-func MakeToken(userId string, key []byte) (string, error) {
 	payload := jwt.MapClaims{
-		userIdClaim: userId,
-		"exp":       time.Now().Add(time.Hour * 72).Unix(),
+		userIdClaim:    userId,
+		tokenTypeClaim: tokenType,
+		"iat":          now.Unix(),
+		"exp":          now.Add(ttl).Unix(),
+	}
+
+	for k, v := range extra {
+		payload[k] = v
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	t, err := token.SignedString(key)
+	t, err := token.SignedString(keys.signingKey())
 	if err != nil {
 		return "", fmt.Errorf("signing token: %v", err)
 	}
@@ -28,12 +74,25 @@ func MakeToken(userId string, key []byte) (string, error) {
 	return t, nil
 }
 
-func Middleware(key []byte) any {
+func Middleware(keys *Keys) any {
 	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{
-			Key: key,
-		},
+		KeyFunc:    keys.keyfunc,
 		ContextKey: jwtContextKey,
+		SuccessHandler: func(c *fiber.Ctx) error {
+			token, ok := c.Locals(jwtContextKey).(*jwt.Token)
+			if !ok || isMFAToken(token) {
+				return invalidToken
+			}
+
+			return c.Next()
+		},
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if errors.Is(err, jwtware.ErrJWTMissingOrMalformed) {
+				return problem.New(fiber.StatusBadRequest, problem.CodeMalformedToken, "missing or malformed JWT")
+			}
+
+			return invalidToken
+		},
 	})
 }
 
@@ -43,9 +102,231 @@ func ExtractUserId(c *fiber.Ctx) (string, error) {
 		return "", fmt.Errorf("no jwt token found in '%s' local", jwtContextKey)
 	}
 
-	claims, ok := user.Claims.(jwt.MapClaims)
+	return userIdFromToken(user)
+}
+
+// Проверка того, что выпущенный токен пользователя не отозван
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userId string, issuedAt time.Time) error
+}
+
+// Middleware, отклоняющий отозванные токены (после смены пароля, блокировки или удаления пользователя).
+// Должен использоваться после Middleware
+func RejectRevoked(validator SessionValidator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userId, err := ExtractUserId(c)
+		if err != nil {
+			return invalidToken.WithCause(err)
+		}
+
+		issuedAt, err := extractIssuedAt(c)
+		if err != nil {
+			return invalidToken.WithCause(err)
+		}
+
+		err = validator.ValidateSession(c.Context(), userId, issuedAt)
+		if err != nil {
+			return fmt.Errorf("validating session: %w", err)
+		}
+
+		return c.Next()
+	}
+}
+
+// Токены, выпущенные до появления iat, считаются выпущенными в начале эпохи
+func extractIssuedAt(c *fiber.Ctx) (time.Time, error) {
+	user, ok := c.Locals(jwtContextKey).(*jwt.Token)
 	if !ok {
-		return "", fmt.Errorf("unable to convert %v to jwt.MapClaims", user.Claims)
+		return time.Time{}, fmt.Errorf("no jwt token found in '%s' local", jwtContextKey)
+	}
+
+	return issuedAtFromToken(user)
+}
+
+func issuedAtFromToken(token *jwt.Token) (time.Time, error) {
+	issuedAt, err := token.Claims.GetIssuedAt()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("getting iat claim: %v", err)
+	}
+
+	if issuedAt == nil {
+		return time.Unix(0, 0), nil
+	}
+
+	return issuedAt.Time, nil
+}
+
+// Извлекает роли пользователя из токена.
+// Токены, выпущенные до появления ролей, считаются токенами обычного пользователя
+func ExtractRoles(c *fiber.Ctx) ([]role.Role, error) {
+	user, ok := c.Locals(jwtContextKey).(*jwt.Token)
+	if !ok {
+		return nil, fmt.Errorf("no jwt token found in '%s' local", jwtContextKey)
+	}
+
+	return rolesFromToken(user)
+}
+
+// Пользователь из токена и его роли. Ошибка - problem с кодом invalid_token, ее можно вернуть из обработчика
+func ExtractActor(c *fiber.Ctx) (models.Actor, error) {
+	userId, err := ExtractUserId(c)
+	if err != nil {
+		return models.Actor{}, problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
+	}
+
+	roles, err := ExtractRoles(c)
+	if err != nil {
+		return models.Actor{}, problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract roles from token")
+	}
+
+	return models.Actor{UserId: userId, Roles: roles}, nil
+}
+
+func rolesFromToken(token *jwt.Token) ([]role.Role, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert %v to jwt.MapClaims", token.Claims)
+	}
+
+	rawRoles, exists := claims[rolesClaim]
+	if !exists {
+		return []role.Role{role.User}, nil
+	}
+
+	list, ok := rawRoles.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unable to extract roles list from %v", rawRoles)
+	}
+
+	roles := make([]role.Role, 0, len(list))
+	for _, raw := range list {
+		str, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("unable to extract role string from %v", raw)
+		}
+
+		r, err := role.FromString(str)
+		if err != nil {
+			return nil, fmt.Errorf("parsing role: %v", err)
+		}
+
+		roles = append(roles, r)
+	}
+
+	return roles, nil
+}
+
+// Middleware, пропускающий запрос только если роли из токена дают указанное разрешение.
+// Должен использоваться после Middleware
+func RequirePermission(permission role.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		roles, err := ExtractRoles(c)
+		if err != nil {
+			return invalidToken.WithCause(err)
+		}
+
+		if !role.AnyHas(roles, permission) {
+			return problem.New(fiber.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("permission '%s' required", permission))
+		}
+
+		return c.Next()
+	}
+}
+
+// Владелец access-токена, переданного в обход fiber, например в метаданных gRPC
+type Subject struct {
+	UserId   string
+	Roles    []role.Role
+	IssuedAt time.Time
+}
+
+// Проверяет access-токен и возвращает данные его владельца
+func ParseAccessToken(tokenString string, keys *Keys) (Subject, error) {
+	token, err := parse(tokenString, keys)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	if isMFAToken(token) {
+		return Subject{}, errors.New("mfa token is not an access token")
+	}
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	roles, err := rolesFromToken(token)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	issuedAt, err := issuedAtFromToken(token)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	return Subject{
+		UserId:   userId,
+		Roles:    roles,
+		IssuedAt: issuedAt,
+	}, nil
+}
+
+// Проверяет access-токен из заголовка Authorization. В отличие от Middleware, не отклоняет запрос,
+// поэтому подходит для middleware, которые работают и с анонимными запросами
+func ParseRequestToken(c *fiber.Ctx, keys *Keys) (Subject, error) {
+	tokenString, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return Subject{}, errors.New("missing bearer token")
+	}
+
+	return ParseAccessToken(tokenString, keys)
+}
+
+// Проверяет MFA-токен и возвращает идентификатор пользователя и поколение токена
+func ParseMFAToken(tokenString string, keys *Keys) (string, int, error) {
+	token, err := parse(tokenString, keys)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if !isMFAToken(token) {
+		return "", 0, errors.New("not an mfa token")
+	}
+
+	userId, err := userIdFromToken(token)
+	if err != nil {
+		return "", 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", 0, fmt.Errorf("unable to convert %v to jwt.MapClaims", token.Claims)
+	}
+
+	// числа в JSON разбираются как float64
+	generation, ok := claims[mfaGenerationClaim].(float64)
+	if !ok {
+		return "", 0, fmt.Errorf("unable to extract mfa generation from %v", claims[mfaGenerationClaim])
+	}
+
+	return userId, int(generation), nil
+}
+
+func parse(tokenString string, keys *Keys) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, keys.keyfunc, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, fmt.Errorf("parsing token: %v", err)
+	}
+
+	return token, nil
+}
+
+func userIdFromToken(token *jwt.Token) (string, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", fmt.Errorf("unable to convert %v to jwt.MapClaims", token.Claims)
 	}
 
 	userId, ok := claims[userIdClaim].(string)
@@ -55,3 +336,13 @@ func ExtractUserId(c *fiber.Ctx) (string, error) {
 
 	return userId, nil
 }
+
+func isMFAToken(token *jwt.Token) bool {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+
+	tokenType, _ := claims[tokenTypeClaim].(string)
+	return tokenType == mfaTokenType
+}
@@ -0,0 +1,53 @@
+package jwt
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ключи HMAC для подписи и проверки токенов. Новые токены подписываются текущим ключом,
+// а принимаются токены, подписанные текущим или одним из предыдущих ключей: так ключ можно сменить,
+// не заставляя всех пользователей войти заново. Ключи можно заменить во время работы
+type Keys struct {
+	set atomic.Pointer[keySet]
+}
+
+type keySet struct {
+	signing      []byte
+	verification jwt.VerificationKeySet
+}
+
+func NewKeys(signing string, previous ...string) *Keys {
+	k := &Keys{}
+	k.Update(signing, previous...)
+
+	return k
+}
+
+// Заменяет ключи. Токены, подписанные ключами не из нового набора, перестают приниматься
+func (k *Keys) Update(signing string, previous ...string) {
+	set := &keySet{
+		signing: []byte(signing),
+	}
+
+	set.verification.Keys = append(set.verification.Keys, set.signing)
+	for _, key := range previous {
+		set.verification.Keys = append(set.verification.Keys, []byte(key))
+	}
+
+	k.set.Store(set)
+}
+
+func (k *Keys) signingKey() []byte {
+	return k.set.Load().signing
+}
+
+func (k *Keys) keyfunc(t *jwt.Token) (any, error) {
+	if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+
+	return k.set.Load().verification, nil
+}
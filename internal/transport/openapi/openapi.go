@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+
+	"github.com/Lucky112/social/api"
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+func init() {
+	// файлы фотографий проверяются как двоичные данные: декодера image/jpeg в kin-openapi нет
+	openapi3filter.RegisterBodyDecoder("image/jpeg", openapi3filter.FileBodyDecoder)
+}
+
+// Загружает и проверяет встроенное описание API
+func Load(ctx context.Context) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+
+	doc, err := loader.LoadFromData(api.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("loading spec: %v", err)
+	}
+
+	err = doc.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("validating spec: %v", err)
+	}
+
+	return doc, nil
+}
+
+// Проверка запросов и ответов на соответствие описанию API
+type Validator struct {
+	router    routers.Router
+	requests  bool
+	responses bool
+}
+
+func NewValidator(doc *openapi3.T, cfg *config.OpenAPIValidationConfig) (Validator, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return Validator{}, fmt.Errorf("creating router: %v", err)
+	}
+
+	return Validator{
+		router:    router,
+		requests:  cfg.Requests,
+		responses: cfg.Responses,
+	}, nil
+}
+
+// Middleware, отклоняющий запросы, не соответствующие описанию, и записывающий в журнал
+// несоответствующие ответы. Маршруты, отсутствующие в описании, пропускаются без проверки
+func (v Validator) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req http.Request
+		err := fasthttpadaptor.ConvertRequest(c.Context(), &req, true)
+		if err != nil {
+			return fmt.Errorf("converting request: %v", err)
+		}
+
+		route, pathParams, err := v.router.FindRoute(&req)
+		if err != nil {
+			return c.Next()
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    &req,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				// токены проверяет jwt.Middleware
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				MultiError:         true,
+			},
+		}
+
+		if v.requests {
+			err = openapi3filter.ValidateRequest(c.Context(), input)
+			if err != nil {
+				return requestProblem(err)
+			}
+		}
+
+		err = c.Next()
+		if !v.responses {
+			return err
+		}
+
+		if err != nil {
+			// ответ с ошибкой формирует обработчик ошибок приложения: вызываем его здесь, чтобы проверить ответ
+			err = c.App().Config().ErrorHandler(c, err)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = validateResponse(c, input)
+		if err != nil {
+			slog.Warn("response does not match the spec", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+		}
+
+		return nil
+	}
+}
+
+func validateResponse(c *fiber.Ctx, input *openapi3filter.RequestValidationInput) error {
+	resp := c.Response()
+
+	header := make(http.Header)
+	resp.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode(),
+		Header:                 header,
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	}
+	responseInput.SetBodyBytes(resp.Body())
+
+	return openapi3filter.ValidateResponse(c.Context(), responseInput)
+}
+
+// Описание ошибки для клиента с перечнем нарушений
+func requestProblem(err error) *problem.Problem {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "request does not match the API specification")
+
+	errs := []error{err}
+
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		errs = multi
+	}
+
+	for _, e := range errs {
+		p.Errors = append(p.Errors, fieldError(e))
+	}
+
+	return p
+}
+
+func fieldError(err error) problem.FieldError {
+	res := problem.FieldError{
+		Code:    "invalid",
+		Message: err.Error(),
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		switch {
+		case reqErr.Parameter != nil:
+			res.Field = reqErr.Parameter.Name
+		case reqErr.RequestBody != nil:
+			res.Field = "body"
+		}
+
+		if reqErr.Reason != "" {
+			res.Message = reqErr.Reason
+		}
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		pointer := schemaErr.JSONPointer()
+		if len(pointer) > 0 {
+			res.Field = strings.Join(pointer, ".")
+		}
+
+		res.Code = schemaErr.SchemaField
+		res.Message = schemaErr.Reason
+	}
+
+	return res
+}
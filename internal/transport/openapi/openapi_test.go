@@ -0,0 +1,122 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+func TestMiddleware(t *testing.T) {
+	doc, err := Load(context.Background())
+	require.NoError(t, err)
+
+	validator, err := NewValidator(doc, &config.OpenAPIValidationConfig{Requests: true, Responses: true})
+	require.NoError(t, err)
+
+	var response fiber.Map
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(validator.Middleware())
+	app.Post("/login", func(c *fiber.Ctx) error {
+		return c.JSON(response)
+	})
+	app.Post("/api/v1/login", func(c *fiber.Ctx) error {
+		return c.JSON(response)
+	})
+	app.Get("/photos/:id/:size", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "image/jpeg")
+		return c.Send([]byte{0xff, 0xd8, 0xff, 0xd9})
+	})
+	app.Get("/undocumented", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	loginAt := func(path, body string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+	login := func(body string) *http.Response {
+		return loginAt("/login", body)
+	}
+
+	t.Run("test valid request and response", func(t *testing.T) {
+		logs.Reset()
+		response = fiber.Map{"access_token": "token", "mfa_required": false}
+
+		resp := login(`{"login": "login", "password": "password"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, logs.String())
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		logs.Reset()
+
+		resp := login(`{"login": "login", "password": 42}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var payload problem.Problem
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeValidationFailed, payload.Code)
+		require.Len(t, payload.Errors, 1)
+		assert.Equal(t, "password", payload.Errors[0].Field)
+	})
+
+	t.Run("test invalid request to versioned route", func(t *testing.T) {
+		resp := loginAt("/api/v1/login", `{"login": "login"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test invalid response", func(t *testing.T) {
+		logs.Reset()
+		response = fiber.Map{"access_token": "token"}
+
+		resp := login(`{"login": "login", "password": "password"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, logs.String(), "does not match the spec")
+	})
+
+	t.Run("test binary response", func(t *testing.T) {
+		logs.Reset()
+
+		req := httptest.NewRequest("GET", "/photos/7/small", nil)
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, logs.String())
+	})
+
+	t.Run("test undocumented route", func(t *testing.T) {
+		logs.Reset()
+
+		req := httptest.NewRequest("GET", "/undocumented", nil)
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, logs.String())
+	})
+}
@@ -0,0 +1,85 @@
+package photos
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Поле multipart-формы с файлом фотографии
+const formField = "photo"
+
+// Файл по адресу фотографии не меняется: у новой фотографии новый id
+const cacheControl = "private, max-age=31536000, immutable"
+
+// Обработчик HTTP-запросов на загрузку и получение фотографий анкет
+type PhotosHandler struct {
+	service PhotosService
+}
+
+func NewPhotosHandler(service PhotosService) PhotosHandler {
+	return PhotosHandler{service}
+}
+
+// Обработчик HTTP-запросов на загрузку фотографии анкеты
+func (h *PhotosHandler) UploadPhoto(c *fiber.Ctx) error {
+	file, err := c.FormFile(formField)
+	if err != nil {
+		return problem.New(fiber.StatusBadRequest, problem.CodeInvalidBody, fmt.Sprintf("multipart form with file field '%s' expected", formField))
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("opening uploaded file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("reading uploaded file: %v", err)
+	}
+
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	photo, err := h.service.Upload(c.Context(), actor, c.Params("id"), data)
+	if err != nil {
+		return fmt.Errorf("uploading photo: %w", err)
+	}
+
+	err = c.Status(fiber.StatusCreated).JSON(FromModel(photo))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на файл фотографии указанного размера
+func (h *PhotosHandler) GetPhoto(c *fiber.Ctx) error {
+	viewer, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	v, data, err := h.service.Open(c.Context(), viewer, c.Params("id"), c.Params("size"))
+	if err != nil {
+		return fmt.Errorf("opening photo: %w", err)
+	}
+
+	c.Set(fiber.HeaderContentType, v.ContentType)
+	c.Set(fiber.HeaderCacheControl, cacheControl)
+
+	err = c.Send(data)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package photos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestPhotos(t *testing.T) {
+	service := mocks.NewPhotosService(t)
+	photosHandler := NewPhotosHandler(service)
+	signingKey := jwt.NewKeys("signing-key")
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(jwt.Middleware(signingKey))
+	app.Post("/profiles/:id/photos", jwt.RequirePermission(role.WriteProfiles), photosHandler.UploadPhoto)
+	app.Get("/photos/:id/:size", jwt.RequirePermission(role.ReadProfiles), photosHandler.GetPhoto)
+
+	userId := "1"
+	token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
+	require.NoError(t, err)
+
+	actor := models.Actor{UserId: userId, Roles: []role.Role{role.User}}
+
+	upload := func(field string, data []byte) *http.Response {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, err := w.CreateFormFile(field, "photo.jpg")
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		req := httptest.NewRequest("POST", "/profiles/2/photos", &body)
+		req.Header.Add("Content-Type", w.FormDataContentType())
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	decodeCode := func(resp *http.Response) string {
+		var p problem.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		return p.Code
+	}
+
+	t.Run("test UploadPhoto", func(t *testing.T) {
+		photo := &models.Photo{
+			Id:        "7",
+			ProfileId: "2",
+			UserId:    userId,
+			Variants: []models.PhotoVariant{
+				{Name: models.PhotoOriginal, Width: 800, Height: 600},
+				{Name: "small", Width: 64, Height: 48},
+			},
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		service.On("Upload", mock.Anything, actor, "2", []byte("image data")).Return(photo, nil).Once()
+
+		resp := upload("photo", []byte("image data"))
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		assert.Equal(t, map[string]any{
+			"id":         "7",
+			"profile_id": "2",
+			"urls": map[string]any{
+				"original": "/api/v1/photos/7/original",
+				"small":    "/api/v1/photos/7/small",
+			},
+			"sizes": []any{
+				map[string]any{"name": "original", "width": float64(800), "height": float64(600)},
+				map[string]any{"name": "small", "width": float64(64), "height": float64(48)},
+			},
+			"created_at": "2024-01-01T00:00:00Z",
+		}, payload)
+	})
+
+	t.Run("test UploadPhoto without file", func(t *testing.T) {
+		resp := upload("file", []byte("image data"))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, problem.CodeInvalidBody, decodeCode(resp))
+	})
+
+	t.Run("test UploadPhoto errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{models.UnsupportedImage, http.StatusUnsupportedMediaType, problem.CodeUnsupportedImage},
+			{models.PhotoTooLarge, http.StatusRequestEntityTooLarge, problem.CodePhotoTooLarge},
+			{models.NotProfileOwner, http.StatusForbidden, problem.CodeForbidden},
+			{models.ProfileNotFound, http.StatusNotFound, problem.CodeProfileNotFound},
+		}
+
+		for _, c := range cases {
+			service.On("Upload", mock.Anything, actor, "2", mock.Anything).Return(nil, fmt.Errorf("uploading: %w", c.err)).Once()
+
+			resp := upload("photo", []byte("image data"))
+			assert.Equal(t, c.status, resp.StatusCode, c.code)
+			assert.Equal(t, c.code, decodeCode(resp))
+		}
+	})
+
+	t.Run("test GetPhoto", func(t *testing.T) {
+		v := &models.PhotoVariant{Name: "small", ContentType: "image/jpeg"}
+		service.On("Open", mock.Anything, mock.Anything, "7", "small").Return(v, []byte("jpeg data"), nil).Once()
+
+		req := httptest.NewRequest("GET", "/photos/7/small", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "immutable")
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg data", string(body))
+	})
+
+	t.Run("test GetPhoto not found", func(t *testing.T) {
+		service.On("Open", mock.Anything, mock.Anything, "7", "huge").Return(nil, nil, fmt.Errorf("opening: %w", models.PhotoNotFound)).Once()
+
+		req := httptest.NewRequest("GET", "/photos/7/huge", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, problem.CodePhotoNotFound, decodeCode(resp))
+	})
+}
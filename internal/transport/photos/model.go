@@ -0,0 +1,43 @@
+package photos
+
+import (
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Адреса фотографий строятся с префиксом текущей версии API, даже если анкета запрошена по устаревшему маршруту
+const pathPrefix = "/api/v1/photos/"
+
+// Фотография анкеты с адресами исходного размера и миниатюр
+type Photo struct {
+	Id        string            `json:"id"`
+	ProfileId string            `json:"profile_id"`
+	URLs      map[string]string `json:"urls"`
+	Sizes     []size            `json:"sizes"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type size struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Фотография в ответах API, в том числе аватар в анкете
+func FromModel(p *models.Photo) *Photo {
+	res := &Photo{
+		Id:        p.Id,
+		ProfileId: p.ProfileId,
+		URLs:      make(map[string]string, len(p.Variants)),
+		Sizes:     make([]size, len(p.Variants)),
+		CreatedAt: p.CreatedAt,
+	}
+
+	for i, v := range p.Variants {
+		res.URLs[v.Name] = pathPrefix + p.Id + "/" + v.Name
+		res.Sizes[i] = size{v.Name, v.Width, v.Height}
+	}
+
+	return res
+}
@@ -0,0 +1,13 @@
+package photos
+
+import (
+	"context"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис фотографий анкет
+type PhotosService interface {
+	Upload(ctx context.Context, actor models.Actor, profileId string, data []byte) (*models.Photo, error)
+	Open(ctx context.Context, viewer models.Actor, photoId, variant string) (*models.PhotoVariant, []byte, error)
+}
@@ -0,0 +1,86 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Соответствие доменных ошибок ответам API.
+// Порядок важен: проверяется первая подходящая ошибка
+var domainErrors = []struct {
+	target error
+	status int
+	code   string
+	detail string
+}{
+	{models.ProfileNotFound, http.StatusNotFound, CodeProfileNotFound, "the profile not found"},
+	{models.UserNotFound, http.StatusNotFound, CodeUserNotFound, "the user not found"},
+	{models.UserAlreadyExists, http.StatusBadRequest, CodeUserAlreadyExists, "the user for given email or login already exists"},
+	{models.UserBadCredentials, http.StatusBadRequest, CodeBadCredentials, "login or password is incorrect"},
+	{models.UserDisabled, http.StatusForbidden, CodeUserDisabled, "the user is disabled"},
+	{models.SessionRevoked, http.StatusUnauthorized, CodeTokenRevoked, "token is revoked"},
+	{models.TOTPAlreadyEnabled, http.StatusConflict, CodeTOTPAlreadyEnabled, "totp is already enabled"},
+	{models.TOTPNotEnrolled, http.StatusBadRequest, CodeTOTPNotEnrolled, "totp enrollment is not started"},
+	{models.InvalidMFACode, http.StatusUnauthorized, CodeInvalidMFACode, "mfa code is incorrect"},
+	{models.WebhookNotFound, http.StatusNotFound, CodeWebhookNotFound, "the webhook not found"},
+	{models.DeliveryNotFound, http.StatusNotFound, CodeDeliveryNotFound, "the webhook delivery not found"},
+	{models.PhotoNotFound, http.StatusNotFound, CodePhotoNotFound, "the photo not found"},
+	{models.PhotoTooLarge, http.StatusRequestEntityTooLarge, CodePhotoTooLarge, "the photo exceeds the size limit"},
+	{models.UnsupportedImage, http.StatusUnsupportedMediaType, CodeUnsupportedImage, "the photo must be a JPEG, PNG or WebP image"},
+	{models.NotProfileOwner, http.StatusForbidden, CodeForbidden, "the profile belongs to another user"},
+}
+
+// Преобразует произвольную ошибку в описание для клиента.
+// Неизвестные ошибки становятся внутренними, их текст клиенту не отдается
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		return Validation(verr)
+	}
+
+	var conflict *models.UserConflictError
+	if errors.As(err, &conflict) {
+		detail := fmt.Sprintf("the user with given %s already exists", conflict.Field)
+
+		p := New(http.StatusBadRequest, CodeUserAlreadyExists, detail)
+		p.Errors = []FieldError{{conflict.Field, fieldAlreadyExists, detail}}
+
+		return p
+	}
+
+	for _, de := range domainErrors {
+		if errors.Is(err, de.target) {
+			return New(de.status, de.code, de.detail)
+		}
+	}
+
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		return New(ferr.Code, codeFromStatus(ferr.Code), ferr.Message)
+	}
+
+	return Internal(err)
+}
+
+// Единый обработчик ошибок fiber: отвечает в формате application/problem+json
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	p := *FromError(err)
+	p.Instance = c.OriginalURL()
+
+	if p.Status >= http.StatusInternalServerError {
+		slog.Error("request failed", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+	}
+
+	return c.Status(p.Status).JSON(p, ContentType)
+}
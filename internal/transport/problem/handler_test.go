@@ -0,0 +1,148 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+func TestFromError(t *testing.T) {
+	t.Run("test domain errors", func(t *testing.T) {
+		cases := []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{fmt.Errorf("finding profile: %w", models.ProfileNotFound), http.StatusNotFound, CodeProfileNotFound},
+			{fmt.Errorf("deleting account: %w", models.UserNotFound), http.StatusNotFound, CodeUserNotFound},
+			{models.UserAlreadyExists, http.StatusBadRequest, CodeUserAlreadyExists},
+			{models.UserBadCredentials, http.StatusBadRequest, CodeBadCredentials},
+			{models.UserDisabled, http.StatusForbidden, CodeUserDisabled},
+			{models.SessionRevoked, http.StatusUnauthorized, CodeTokenRevoked},
+			{models.TOTPAlreadyEnabled, http.StatusConflict, CodeTOTPAlreadyEnabled},
+			{models.TOTPNotEnrolled, http.StatusBadRequest, CodeTOTPNotEnrolled},
+			{models.InvalidMFACode, http.StatusUnauthorized, CodeInvalidMFACode},
+			{fmt.Errorf("finding webhook: %w", models.WebhookNotFound), http.StatusNotFound, CodeWebhookNotFound},
+			{models.DeliveryNotFound, http.StatusNotFound, CodeDeliveryNotFound},
+			{fmt.Errorf("processing photo: %w", models.PhotoTooLarge), http.StatusRequestEntityTooLarge, CodePhotoTooLarge},
+			{models.UnsupportedImage, http.StatusUnsupportedMediaType, CodeUnsupportedImage},
+			{models.NotProfileOwner, http.StatusForbidden, CodeForbidden},
+			{fiber.ErrNotFound, http.StatusNotFound, "not_found"},
+			{fiber.ErrMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+		}
+
+		for _, c := range cases {
+			p := FromError(c.err)
+			assert.Equal(t, c.status, p.Status, c.err.Error())
+			assert.Equal(t, c.code, p.Code, c.err.Error())
+			assert.Equal(t, typePrefix+c.code, p.Type)
+			assert.Equal(t, http.StatusText(c.status), p.Title)
+		}
+	})
+
+	t.Run("test user conflict", func(t *testing.T) {
+		p := FromError(fmt.Errorf("creating new user: %w", &models.UserConflictError{Field: "email"}))
+
+		assert.Equal(t, CodeUserAlreadyExists, p.Code)
+		assert.Equal(t, []FieldError{{"email", "already_exists", "the user with given email already exists"}}, p.Errors)
+	})
+
+	t.Run("test validation error", func(t *testing.T) {
+		verr := &models.ValidationError{}
+		verr.Add("password", "too_short", "password is too short")
+
+		p := FromError(fmt.Errorf("validating: %w", verr))
+
+		assert.Equal(t, http.StatusBadRequest, p.Status)
+		assert.Equal(t, CodeValidationFailed, p.Code)
+		assert.Equal(t, []FieldError{{"password", "too_short", "password is too short"}}, p.Errors)
+	})
+
+	t.Run("test problem is kept", func(t *testing.T) {
+		expected := New(http.StatusTeapot, "teapot", "short and stout")
+
+		assert.Equal(t, expected, FromError(fmt.Errorf("wrapped: %w", expected)))
+	})
+
+	t.Run("test internal error", func(t *testing.T) {
+		p := FromError(errors.New("executing query `select * from scl.profiles`: timeout"))
+
+		assert.Equal(t, http.StatusInternalServerError, p.Status)
+		assert.Equal(t, CodeInternal, p.Code)
+		assert.NotContains(t, p.Detail, "select")
+	})
+}
+
+func TestValidation(t *testing.T) {
+	type request struct {
+		Email string `json:"email" validate:"required"`
+		Age   int    `json:"age"   validate:"min=18"`
+	}
+
+	err := NewValidator().Struct(request{Age: 1})
+	require.Error(t, err)
+
+	p := Validation(err)
+	assert.Equal(t, CodeValidationFailed, p.Code)
+	assert.Equal(t, []FieldError{
+		{"email", "required", "failed on the 'required' rule"},
+		{"age", "min", "failed on the 'min' rule"},
+	}, p.Errors)
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/profiles/:id", func(c *fiber.Ctx) error {
+		return fmt.Errorf("finding profile: %w", models.ProfileNotFound)
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		return errors.New("executing query `select password from scl.users`")
+	})
+
+	decode := func(t *testing.T, resp *http.Response) Problem {
+		var p Problem
+		err := json.NewDecoder(resp.Body).Decode(&p)
+		require.NoError(t, err)
+
+		return p
+	}
+
+	t.Run("test domain error", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/profiles/7?full=1", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+		p := decode(t, resp)
+		assert.Equal(t, CodeProfileNotFound, p.Code)
+		assert.Equal(t, http.StatusNotFound, p.Status)
+		assert.Equal(t, "/profiles/7?full=1", p.Instance)
+	})
+
+	t.Run("test internal error does not leak", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/broken", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		p := decode(t, resp)
+		assert.Equal(t, CodeInternal, p.Code)
+		assert.False(t, strings.Contains(p.Detail, "scl.users"))
+	})
+
+	t.Run("test unknown route", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/unknown", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "not_found", decode(t, resp).Code)
+	})
+}
@@ -0,0 +1,115 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MIME-тип ответов с ошибками
+const ContentType = "application/problem+json"
+
+// Префикс URI типа ошибки, за которым следует ее код
+const typePrefix = "urn:social:problem:"
+
+// Стабильные машиночитаемые коды ошибок
+const (
+	CodeInvalidBody        = "invalid_body"
+	CodeValidationFailed   = "validation_failed"
+	CodeMalformedToken     = "malformed_token"
+	CodeInvalidToken       = "invalid_token"
+	CodeTokenRevoked       = "token_revoked"
+	CodeForbidden          = "forbidden"
+	CodeUserNotFound       = "user_not_found"
+	CodeUserAlreadyExists  = "user_already_exists"
+	CodeBadCredentials     = "bad_credentials"
+	CodeUserDisabled       = "user_disabled"
+	CodeProfileNotFound    = "profile_not_found"
+	CodeTOTPAlreadyEnabled = "totp_already_enabled"
+	CodeTOTPNotEnrolled    = "totp_not_enrolled"
+	CodeInvalidMFACode     = "invalid_mfa_code"
+	CodeInvalidMFAToken    = "invalid_mfa_token"
+	CodeWebhookNotFound    = "webhook_not_found"
+	CodeDeliveryNotFound   = "delivery_not_found"
+	CodeRateLimited        = "rate_limited"
+	CodeIdempotencyInUse   = "idempotency_key_in_use"
+	CodeIdempotencyReused  = "idempotency_key_reused"
+	CodePhotoNotFound      = "photo_not_found"
+	CodePhotoTooLarge      = "photo_too_large"
+	CodeUnsupportedImage   = "unsupported_image"
+	CodeInternal           = "internal_error"
+)
+
+// Код нарушения в поле, уже занятом другим пользователем
+const fieldAlreadyExists = "already_exists"
+
+// Описание ошибки HTTP API в формате RFC 7807
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	// исходная ошибка, попадает только в журнал
+	cause error
+}
+
+// Нарушение правила валидации в конкретном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", p.Code, p.Detail, p.cause)
+	}
+
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+// Возвращает копию с исходной ошибкой, которая будет записана в журнал, но не отдана клиенту
+func (p *Problem) WithCause(err error) *Problem {
+	res := *p
+	res.cause = err
+
+	return &res
+}
+
+// Тело запроса не удалось разобрать
+func InvalidBody(err error) *Problem {
+	return New(http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("failed to parse body: %v", err))
+}
+
+// Внутренняя ошибка сервера: подробности остаются только в журнале
+func Internal(err error) *Problem {
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error").WithCause(err)
+}
+
+// Код для ошибок самого fiber (неизвестный маршрут, неподдерживаемый метод и т.п.)
+func codeFromStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternal
+	}
+
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
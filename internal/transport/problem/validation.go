@@ -0,0 +1,61 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Валидатор тел запросов, в ошибках которого поля называются так же, как в JSON
+func NewValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	return validate
+}
+
+// Ошибка валидации тела запроса с перечнем нарушений по полям.
+// Принимает ошибки validator и *models.ValidationError
+func Validation(err error) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "request body is invalid")
+
+	var verrs validator.ValidationErrors
+	var verr *models.ValidationError
+
+	switch {
+	case errors.As(err, &verrs):
+		for _, fe := range verrs {
+			p.Errors = append(p.Errors, FieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fmt.Sprintf("failed on the '%s' rule", fe.Tag()),
+			})
+		}
+	case errors.As(err, &verr):
+		for _, fe := range verr.Fields {
+			p.Errors = append(p.Errors, FieldError{
+				Field:   fe.Field,
+				Code:    fe.Code,
+				Message: fe.Message,
+			})
+		}
+	default:
+		p.Detail = fmt.Sprintf("request body is invalid: %v", err)
+	}
+
+	return p
+}
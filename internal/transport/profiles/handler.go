@@ -1,15 +1,24 @@
 package profiles
 
 import (
-	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
 	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
+// Число похожих анкет по умолчанию и наибольшее
+const (
+	defaultSimilarLimit = 20
+	maxSimilarLimit     = 100
+)
+
 // Обработчик HTTP-запросов на создание и просмотр анкет
 type ProfilesHandler struct {
 	service  ProfilesService
@@ -19,7 +28,7 @@ type ProfilesHandler struct {
 func NewProfilesHandler(service ProfilesService) ProfilesHandler {
 	return ProfilesHandler{
 		service:  service,
-		validate: validator.New(validator.WithRequiredStructEnabled()),
+		validate: problem.NewValidator(),
 	}
 }
 
@@ -29,43 +38,28 @@ func (h *ProfilesHandler) CreateProfile(c *fiber.Ctx) error {
 
 	err := c.BodyParser(&payload)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			profileError{fmt.Sprintf("failed to parse body: %v", err)},
-		)
-		return nil
+		return problem.InvalidBody(err)
 	}
 
 	err = h.validate.Struct(payload)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			profileError{fmt.Sprintf("invalid body: %v", err)},
-		)
-		return nil
+		return problem.Validation(err)
 	}
 
 	userId, err := jwt.ExtractUserId(c)
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			profileError{fmt.Sprintf("failed to extract user id: %v", err)},
-		)
-		return nil
+		return problem.New(fiber.StatusUnauthorized, problem.CodeInvalidToken, "failed to extract user id from token")
 	}
 	payload.userId = userId
 
 	p, err := payload.toModel()
 	if err != nil {
-		c.Status(fiber.StatusBadRequest).JSON(
-			profileError{fmt.Sprintf("failed to parse profile: %v", err)},
-		)
-		return nil
+		return problem.New(fiber.StatusBadRequest, problem.CodeValidationFailed, fmt.Sprintf("failed to parse profile: %v", err))
 	}
 
 	id, err := h.service.Add(c.Context(), p)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError).JSON(
-			profileError{fmt.Sprintf("failed to save profile: %v", err)},
-		)
-		return nil
+		return fmt.Errorf("saving profile: %w", err)
 	}
 
 	err = c.Status(fiber.StatusCreated).JSON(
@@ -82,21 +76,18 @@ func (h *ProfilesHandler) CreateProfile(c *fiber.Ctx) error {
 func (h *ProfilesHandler) GetProfileById(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	p, err := h.service.Get(c.Context(), id)
+	viewer, err := jwt.ExtractActor(c)
 	if err != nil {
-		if errors.Is(err, models.ProfileNotFound) {
-			c.Status(fiber.StatusNotFound).JSON(
-				profileError{err.Error()},
-			)
-			return nil
-		}
+		return err
+	}
 
-		c.Status(fiber.StatusInternalServerError).JSON(
-			profileError{fmt.Sprintf("failed to find profile: %v", err)},
-		)
-		return nil
+	p, err := h.service.Get(c.Context(), viewer, id)
+	if err != nil {
+		return fmt.Errorf("finding profile: %w", err)
 	}
 
+	setLastModified(c, modifiedAt(p))
+
 	payload := fromModel(p)
 
 	err = c.JSON(payload)
@@ -111,25 +102,22 @@ func (h *ProfilesHandler) GetProfileById(c *fiber.Ctx) error {
 func (h *ProfilesHandler) SearchProfile(c *fiber.Ctx) error {
 	name := c.Query("name")
 	surname := c.Query("surname")
+	interest := c.Query("interest")
 
 	params := &models.SearchParams{
 		NamePrefix:    name,
 		SurnamePrefix: surname,
+		Interest:      interest,
 	}
 
-	profiles, err := h.service.Search(c.Context(), params)
+	viewer, err := jwt.ExtractActor(c)
 	if err != nil {
-		if errors.Is(err, models.ProfileNotFound) {
-			c.Status(fiber.StatusNotFound).JSON(
-				profileError{err.Error()},
-			)
-			return nil
-		}
+		return err
+	}
 
-		c.Status(fiber.StatusInternalServerError).JSON(
-			profileError{fmt.Sprintf("failed to find profile: %v", err)},
-		)
-		return nil
+	profiles, err := h.service.Search(c.Context(), viewer, params)
+	if err != nil {
+		return fmt.Errorf("searching profiles: %w", err)
 	}
 
 	payload := make([]*profile, len(profiles))
@@ -147,23 +135,130 @@ func (h *ProfilesHandler) SearchProfile(c *fiber.Ctx) error {
 
 // Обработчик HTTP-запросов на список анкет
 func (h *ProfilesHandler) GetProfiles(c *fiber.Ctx) error {
-	profiles, err := h.service.GetAll(c.Context())
+	viewer, err := jwt.ExtractActor(c)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError).JSON(
-			profileError{fmt.Sprintf("failed to get all profiles: %v", err)},
-		)
-		return nil
+		return err
+	}
+
+	profiles, err := h.service.GetAll(c.Context(), viewer)
+	if err != nil {
+		return fmt.Errorf("getting all profiles: %w", err)
 	}
 
 	payload := make([]*profile, len(profiles))
+	var lastModified time.Time
 
 	for i, p := range profiles {
 		payload[i] = fromModel(p)
+
+		if modifiedAt(p).After(lastModified) {
+			lastModified = modifiedAt(p)
+		}
 	}
 
+	setLastModified(c, lastModified)
+
 	err = c.JSON(payload)
 	if err != nil {
 		return fmt.Errorf("sending response: %v", err)
 	}
 	return nil
 }
+
+// Обработчик HTTP-запросов на анкеты других пользователей с общими интересами,
+// начиная с анкет с наибольшим числом общих интересов
+func (h *ProfilesHandler) GetSimilarProfiles(c *fiber.Ctx) error {
+	limit := defaultSimilarLimit
+	if v := c.Query("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSimilarLimit {
+			detail := fmt.Sprintf("limit must be an integer from 1 to %d", maxSimilarLimit)
+			return problem.New(fiber.StatusBadRequest, problem.CodeValidationFailed, detail)
+		}
+	}
+
+	viewer, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	matches, err := h.service.Similar(c.Context(), viewer, c.Params("id"), limit)
+	if err != nil {
+		return fmt.Errorf("finding similar profiles: %w", err)
+	}
+
+	payload := make([]similarProfile, len(matches))
+	for i, m := range matches {
+		payload[i] = fromMatchModel(m)
+	}
+
+	err = c.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на настройки видимости полей анкеты
+func (h *ProfilesHandler) GetPrivacy(c *fiber.Ctx) error {
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	p, err := h.service.Privacy(c.Context(), actor, c.Params("id"))
+	if err != nil {
+		return fmt.Errorf("getting privacy: %w", err)
+	}
+
+	err = c.JSON(fromPrivacyModel(p))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на замену настроек видимости полей анкеты
+func (h *ProfilesHandler) SetPrivacy(c *fiber.Ctx) error {
+	var payload privacy
+
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return problem.InvalidBody(err)
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		return problem.Validation(err)
+	}
+
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	p, err := h.service.SetPrivacy(c.Context(), actor, c.Params("id"), payload.toModel())
+	if err != nil {
+		return fmt.Errorf("setting privacy: %w", err)
+	}
+
+	err = c.JSON(fromPrivacyModel(p))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Заголовок Last-Modified для условных запросов. ETag добавляет middleware etag
+func setLastModified(c *fiber.Ctx, updatedAt time.Time) {
+	if updatedAt.IsZero() {
+		return
+	}
+
+	c.Set(fiber.HeaderLastModified, updatedAt.UTC().Format(http.TimeFormat))
+}
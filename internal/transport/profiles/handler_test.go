@@ -1,14 +1,19 @@
 package profiles
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
 	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
 	"github.com/Lucky112/social/mocks"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -18,14 +23,17 @@ import (
 func TestProfiles(t *testing.T) {
 	service := mocks.NewProfilesService(t)
 	profilesHandler := NewProfilesHandler(service)
-	signingKey := []byte("signing-key")
+	signingKey := jwt.NewKeys("signing-key")
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
 	app.Use(jwt.Middleware(signingKey))
 	app.Post("/profiles", profilesHandler.CreateProfile)
 	app.Get("/profiles", profilesHandler.GetProfiles)
 	app.Get("/profiles/search", profilesHandler.SearchProfile)
 	app.Get("/profiles/:id", profilesHandler.GetProfileById)
+	app.Get("/profiles/:id/privacy", profilesHandler.GetPrivacy)
+	app.Put("/profiles/:id/privacy", profilesHandler.SetPrivacy)
+	app.Get("/profiles/:id/similar", profilesHandler.GetSimilarProfiles)
 
 	t.Run("test CreateProfile", func(t *testing.T) {
 		userId := "1"
@@ -42,7 +50,7 @@ func TestProfiles(t *testing.T) {
 			"address": "Moscow"
 		}`)
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("POST", "/profiles", body)
@@ -68,7 +76,7 @@ func TestProfiles(t *testing.T) {
 			"address": "Moscow"
 		}`)
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("POST", "/profiles", body)
@@ -92,7 +100,51 @@ func TestProfiles(t *testing.T) {
 			"address": "Moscow"
 		}`)
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/profiles", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test CreateProfile with interests", func(t *testing.T) {
+		service.On("Add", mock.Anything, mock.MatchedBy(func(p *models.Profile) bool {
+			return assert.ObjectsAreEqual([]string{"Cycling", "chess"}, p.Interests)
+		})).Return("24", nil).Once()
+
+		body := strings.NewReader(`{
+			"name": "Alfred",
+			"sex": "male",
+			"birthdate": "1989-06-23",
+			"interests": ["Cycling", "chess"]
+		}`)
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/profiles", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("test CreateProfile with empty interest", func(t *testing.T) {
+		body := strings.NewReader(`{
+			"name": "Alfred",
+			"sex": "male",
+			"birthdate": "1989-06-23",
+			"interests": ["chess", ""]
+		}`)
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("POST", "/profiles", body)
@@ -106,7 +158,7 @@ func TestProfiles(t *testing.T) {
 
 	t.Run("test CreateProfile bad json", func(t *testing.T) {
 		userId := "1"
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		body := strings.NewReader(`{`)
@@ -122,7 +174,7 @@ func TestProfiles(t *testing.T) {
 
 	t.Run("test CreateProfile empty json", func(t *testing.T) {
 		userId := "1"
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		body := strings.NewReader(`{}`)
@@ -141,12 +193,13 @@ func TestProfiles(t *testing.T) {
 		profileId := "23"
 
 		profile := &models.Profile{
-			Name: "username",
+			Name:      "username",
+			UpdatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
 		}
 
-		service.On("Get", mock.Anything, profileId).Return(profile, nil).Once()
+		service.On("Get", mock.Anything, mock.Anything, profileId).Return(profile, nil).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s", profileId), nil)
@@ -155,15 +208,61 @@ func TestProfiles(t *testing.T) {
 		resp, err := app.Test(req, -1)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Wed, 01 May 2024 09:30:00 GMT", resp.Header.Get("Last-Modified"))
+	})
+
+	t.Run("test GetProfile with avatar", func(t *testing.T) {
+		userId := "1"
+		profileId := "23"
+
+		profile := &models.Profile{
+			Id:        profileId,
+			Name:      "username",
+			UpdatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+			Avatar: &models.Photo{
+				Id:        "7",
+				ProfileId: profileId,
+				Variants: []models.PhotoVariant{
+					{Name: models.PhotoOriginal, Width: 800, Height: 600},
+					{Name: "small", Width: 64, Height: 48},
+				},
+				CreatedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+		}
+
+		service.On("Get", mock.Anything, mock.Anything, profileId).Return(profile, nil).Once()
+
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s", profileId), nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		// загрузка аватара меняет анкету для условных запросов
+		assert.Equal(t, "Sat, 01 Jun 2024 00:00:00 GMT", resp.Header.Get("Last-Modified"))
+
+		var payload struct {
+			Avatar struct {
+				URLs map[string]string `json:"urls"`
+			} `json:"avatar"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		assert.Equal(t, map[string]string{
+			"original": "/api/v1/photos/7/original",
+			"small":    "/api/v1/photos/7/small",
+		}, payload.Avatar.URLs)
 	})
 
 	t.Run("test GetProfile not found", func(t *testing.T) {
 		userId := "1"
 		profileId := "23"
 
-		service.On("Get", mock.Anything, profileId).Return(nil, fmt.Errorf("%w", models.ProfileNotFound)).Once()
+		service.On("Get", mock.Anything, mock.Anything, profileId).Return(nil, fmt.Errorf("%w", models.ProfileNotFound)).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s", profileId), nil)
@@ -178,9 +277,9 @@ func TestProfiles(t *testing.T) {
 		userId := "1"
 		profileId := "23"
 
-		service.On("Get", mock.Anything, profileId).Return(nil, fmt.Errorf("service error")).Once()
+		service.On("Get", mock.Anything, mock.Anything, profileId).Return(nil, fmt.Errorf("executing query `select * from scl.profiles`: timeout")).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s", profileId), nil)
@@ -189,6 +288,11 @@ func TestProfiles(t *testing.T) {
 		resp, err := app.Test(req, -1)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), problem.CodeInternal)
+		assert.NotContains(t, string(body), "scl.profiles")
 	})
 
 	t.Run("test GetProfiles", func(t *testing.T) {
@@ -196,13 +300,18 @@ func TestProfiles(t *testing.T) {
 
 		profiles := []*models.Profile{
 			{
-				Name: "username",
+				Name:      "username",
+				UpdatedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				Name:      "other",
+				UpdatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
 			},
 		}
 
-		service.On("GetAll", mock.Anything).Return(profiles, nil).Once()
+		service.On("GetAll", mock.Anything, mock.Anything).Return(profiles, nil).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", "/profiles", nil)
@@ -211,14 +320,15 @@ func TestProfiles(t *testing.T) {
 		resp, err := app.Test(req, -1)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Thu, 02 May 2024 00:00:00 GMT", resp.Header.Get("Last-Modified"))
 	})
 
 	t.Run("test GetProfiles failed", func(t *testing.T) {
 		userId := "1"
 
-		service.On("GetAll", mock.Anything).Return(nil, fmt.Errorf("service error")).Once()
+		service.On("GetAll", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("service error")).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", "/profiles", nil)
@@ -244,9 +354,9 @@ func TestProfiles(t *testing.T) {
 			SurnamePrefix: "surname",
 		}
 
-		service.On("Search", mock.Anything, params).Return(profiles, nil).Once()
+		service.On("Search", mock.Anything, mock.Anything, params).Return(profiles, nil).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", "/profiles/search?name=username&surname=surname", nil)
@@ -257,12 +367,28 @@ func TestProfiles(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 
+	t.Run("test SearchProfiles by interest", func(t *testing.T) {
+		params := &models.SearchParams{Interest: "чтение"}
+
+		service.On("Search", mock.Anything, mock.Anything, params).Return([]*models.Profile{}, nil).Once()
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/search?interest=%D1%87%D1%82%D0%B5%D0%BD%D0%B8%D0%B5", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
 	t.Run("test SearchProfiles failed", func(t *testing.T) {
 		userId := "1"
 
-		service.On("Search", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("service error")).Once()
+		service.On("Search", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("service error")).Once()
 
-		token, err := jwt.MakeToken(userId, signingKey)
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
 		assert.NoError(t, err)
 
 		req := httptest.NewRequest("GET", "/profiles/search?name=name&surname=surname", nil)
@@ -273,4 +399,180 @@ func TestProfiles(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	})
 
+	t.Run("test GetProfile omits hidden fields", func(t *testing.T) {
+		profileId := "23"
+		viewer := models.Actor{UserId: "2", Roles: []role.Role{role.User}}
+
+		profile := &models.Profile{
+			Name:    "username",
+			Address: "Moscow",
+			Hidden:  []models.ProfileField{models.FieldSurname, models.FieldSex, models.FieldBirthdate},
+		}
+
+		service.On("Get", mock.Anything, viewer, profileId).Return(profile, nil).Once()
+
+		token, err := jwt.MakeToken(viewer.UserId, viewer.Roles, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s", profileId), nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		assert.Equal(t, map[string]any{"name": "username", "city": "Moscow"}, payload)
+	})
+
+	t.Run("test GetPrivacy", func(t *testing.T) {
+		profileId := "23"
+		owner := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+
+		privacy := &models.Privacy{
+			Fields: map[models.ProfileField]models.Visibility{models.FieldBirthdate: models.VisibilityHidden},
+		}
+
+		service.On("Privacy", mock.Anything, owner, profileId).Return(privacy, nil).Once()
+
+		token, err := jwt.MakeToken(owner.UserId, owner.Roles, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/profiles/%s/privacy", profileId), nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+		assert.Equal(t, map[string]string{
+			"surname":   "registered",
+			"sex":       "registered",
+			"birthdate": "hidden",
+			"city":      "registered",
+			"hobbies":   "registered",
+			"photos":    "registered",
+		}, payload)
+	})
+
+	t.Run("test GetPrivacy of another user", func(t *testing.T) {
+		service.On("Privacy", mock.Anything, mock.Anything, "23").Return(nil, fmt.Errorf("managing: %w", models.NotProfileOwner)).Once()
+
+		token, err := jwt.MakeToken("2", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/23/privacy", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("test SetPrivacy", func(t *testing.T) {
+		profileId := "23"
+		fields := map[models.ProfileField]models.Visibility{
+			models.FieldSurname: models.VisibilityFriends,
+			models.FieldPhotos:  models.VisibilityPublic,
+		}
+
+		service.On("SetPrivacy", mock.Anything, mock.Anything, profileId, fields).Return(&models.Privacy{Fields: fields}, nil).Once()
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		body := strings.NewReader(`{"surname": "friends", "photos": "public"}`)
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/profiles/%s/privacy", profileId), body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test SetPrivacy with unknown visibility", func(t *testing.T) {
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		body := strings.NewReader(`{"surname": "everyone"}`)
+		req := httptest.NewRequest("PUT", "/profiles/23/privacy", body)
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test GetSimilarProfiles", func(t *testing.T) {
+		viewer := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+
+		matches := []*models.ProfileMatch{
+			{Profile: &models.Profile{Id: "24", Name: "Petr", Interests: []string{"сериалы", "чтение"}, Hidden: []models.ProfileField{models.FieldSex, models.FieldBirthdate}}, Shared: []string{"чтение"}},
+		}
+
+		service.On("Similar", mock.Anything, viewer, "23", 5).Return(matches, nil).Once()
+
+		token, err := jwt.MakeToken(viewer.UserId, viewer.Roles, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/23/similar?limit=5", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{
+			"profile_id": "24",
+			"shared_interests": ["чтение"],
+			"profile": {"name": "Petr", "interests": ["сериалы", "чтение"]}
+		}]`, string(body))
+	})
+
+	t.Run("test GetSimilarProfiles default limit", func(t *testing.T) {
+		service.On("Similar", mock.Anything, mock.Anything, "23", defaultSimilarLimit).Return([]*models.ProfileMatch{}, nil).Once()
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/23/similar", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test GetSimilarProfiles bad limit", func(t *testing.T) {
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/23/similar?limit=1000", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test GetSimilarProfiles not found", func(t *testing.T) {
+		service.On("Similar", mock.Anything, mock.Anything, "100", defaultSimilarLimit).Return(nil, models.ProfileNotFound).Once()
+
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/profiles/100/similar", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
 }
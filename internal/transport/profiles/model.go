@@ -6,25 +6,46 @@ import (
 
 	"github.com/Lucky112/social/internal/models"
 	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/internal/transport/photos"
 )
 
 const birthdateFormat = "2006-01-02"
 
+// Анкета в запросах и ответах. Поля, скрытые настройками видимости, в ответах отсутствуют
 type profile struct {
 	userId    string
-	Name      string `json:"name"      validate:"required"`
-	Surname   string `json:"surname"`
-	Sex       string `json:"sex"`
-	Birthdate string `json:"birthdate" validate:"datetime=2006-01-02"`
-	City      string `json:"city"`
-	Hobbies   string `json:"hobbies"`
+	Name      string `json:"name"                validate:"required"`
+	Surname   string `json:"surname,omitempty"`
+	Sex       string `json:"sex,omitempty"`
+	Birthdate string `json:"birthdate,omitempty" validate:"datetime=2006-01-02"`
+	City      string `json:"city,omitempty"`
+	Hobbies   string `json:"hobbies,omitempty"`
+	// Если не заданы, при создании берутся из hobbies через запятую. В ответах - нормализованные
+	Interests []string `json:"interests,omitempty" validate:"max=20,dive,required,max=50"`
+	// Только в ответах: последняя загруженная фотография
+	Avatar *photos.Photo `json:"avatar,omitempty"`
+}
+
+// Видимость полей анкеты. В запросе поля без значения получают видимость по умолчанию,
+// в ответе заполнены все поля
+type privacy struct {
+	Surname   string `json:"surname,omitempty"   validate:"omitempty,oneof=public registered friends hidden"`
+	Sex       string `json:"sex,omitempty"       validate:"omitempty,oneof=public registered friends hidden"`
+	Birthdate string `json:"birthdate,omitempty" validate:"omitempty,oneof=public registered friends hidden"`
+	City      string `json:"city,omitempty"      validate:"omitempty,oneof=public registered friends hidden"`
+	Hobbies   string `json:"hobbies,omitempty"   validate:"omitempty,oneof=public registered friends hidden"`
+	Photos    string `json:"photos,omitempty"    validate:"omitempty,oneof=public registered friends hidden"`
 }
 
 type profileResponse struct {
 	Id string `json:"id"`
 }
-type profileError struct {
-	Message string `json:"msg"`
+
+// Анкета с общими интересами
+type similarProfile struct {
+	ProfileId       string   `json:"profile_id"`
+	SharedInterests []string `json:"shared_interests"`
+	Profile         *profile `json:"profile"`
 }
 
 func (p *profile) toModel() (*models.Profile, error) {
@@ -46,16 +67,90 @@ func (p *profile) toModel() (*models.Profile, error) {
 		Birthdate: birthdate,
 		Address:   p.City,
 		Hobbies:   p.Hobbies,
+		Interests: p.Interests,
 	}, nil
 }
 
+// Анкета для ответа. Поля, скрытые от запросившего пользователя, сервис уже очистил,
+// а здесь они пропускаются, чтобы в ответе не было значений по умолчанию вроде пола unknown
 func fromModel(mp *models.Profile) *profile {
-	return &profile{
+	p := &profile{
 		Name:      mp.Name,
 		Surname:   mp.Surname,
-		Sex:       mp.Sex.String(),
-		Birthdate: mp.Birthdate.Format(birthdateFormat),
 		City:      mp.Address,
 		Hobbies:   mp.Hobbies,
+		Interests: mp.Interests,
+	}
+
+	if !mp.IsHidden(models.FieldSex) {
+		p.Sex = mp.Sex.String()
+	}
+
+	if !mp.IsHidden(models.FieldBirthdate) {
+		p.Birthdate = mp.Birthdate.Format(birthdateFormat)
+	}
+
+	if mp.Avatar != nil {
+		p.Avatar = photos.FromModel(mp.Avatar)
+	}
+
+	return p
+}
+
+func fromMatchModel(m *models.ProfileMatch) similarProfile {
+	return similarProfile{
+		ProfileId:       m.Profile.Id,
+		SharedInterests: m.Shared,
+		Profile:         fromModel(m.Profile),
+	}
+}
+
+// Время последнего изменения анкеты с учетом загрузки аватара и изменения настроек видимости
+func modifiedAt(mp *models.Profile) time.Time {
+	res := mp.UpdatedAt
+
+	if mp.Avatar != nil && mp.Avatar.CreatedAt.After(res) {
+		res = mp.Avatar.CreatedAt
 	}
+
+	if mp.Privacy != nil && mp.Privacy.UpdatedAt.After(res) {
+		res = mp.Privacy.UpdatedAt
+	}
+
+	return res
+}
+
+// Поля запроса и ответа по полям анкеты
+func (p *privacy) fields() map[models.ProfileField]*string {
+	return map[models.ProfileField]*string{
+		models.FieldSurname:   &p.Surname,
+		models.FieldSex:       &p.Sex,
+		models.FieldBirthdate: &p.Birthdate,
+		models.FieldCity:      &p.City,
+		models.FieldHobbies:   &p.Hobbies,
+		models.FieldPhotos:    &p.Photos,
+	}
+}
+
+// Видимость заданных в запросе полей. Значения уже проверены валидатором
+func (p *privacy) toModel() map[models.ProfileField]models.Visibility {
+	res := make(map[models.ProfileField]models.Visibility)
+
+	for f, v := range p.fields() {
+		if *v != "" {
+			res[f] = models.Visibility(*v)
+		}
+	}
+
+	return res
+}
+
+func fromPrivacyModel(mp *models.Privacy) *privacy {
+	p := &privacy{}
+
+	for f, v := range p.fields() {
+		*v = string(mp.Visibility(f))
+	}
+
+	return p
 }
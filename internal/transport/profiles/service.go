@@ -8,8 +8,11 @@ import (
 
 // Сервис профилей пользователей
 type ProfilesService interface {
-	GetAll(ctx context.Context) ([]*models.Profile, error)
-	Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error)
-	Get(ctx context.Context, id string) (*models.Profile, error)
+	GetAll(ctx context.Context, viewer models.Actor) ([]*models.Profile, error)
+	Search(ctx context.Context, viewer models.Actor, params *models.SearchParams) ([]*models.Profile, error)
+	Get(ctx context.Context, viewer models.Actor, id string) (*models.Profile, error)
 	Add(ctx context.Context, profile *models.Profile) (string, error)
+	Privacy(ctx context.Context, actor models.Actor, profileId string) (*models.Privacy, error)
+	SetPrivacy(ctx context.Context, actor models.Actor, profileId string, fields map[models.ProfileField]models.Visibility) (*models.Privacy, error)
+	Similar(ctx context.Context, viewer models.Actor, profileId string, limit int) ([]*models.ProfileMatch, error)
 }
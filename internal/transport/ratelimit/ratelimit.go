@@ -0,0 +1,187 @@
+// Пакет ratelimit ограничивает частоту запросов к маршрутам HTTP API
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Заголовки по draft-ietf-httpapi-ratelimit-headers
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+)
+
+type Config struct {
+	Policies []*config.RateLimitConfig
+	Store    Store
+	// Ключ клиента, например идентификатор пользователя или IP-адрес
+	Key func(c *fiber.Ctx) string
+	// Префикс версии API, который отбрасывается перед поиском политики:
+	// запросы к маршруту с версией и без нее расходуют общий лимит
+	Prefix string
+}
+
+type policy struct {
+	route    string
+	method   string
+	segments []string
+	limit    Limit
+	// Значение заголовка RateLimit-Policy: емкость и окно, за которое ведро заполняется с нуля
+	header string
+}
+
+// Ограничитель запросов. Политики можно заменить во время работы
+type Limiter struct {
+	policies atomic.Pointer[[]policy]
+	store    Store
+	key      func(c *fiber.Ctx) string
+	prefix   string
+	now      func() time.Time
+}
+
+func New(cfg Config) (*Limiter, error) {
+	l := &Limiter{
+		store:  cfg.Store,
+		key:    cfg.Key,
+		prefix: cfg.Prefix,
+		now:    time.Now,
+	}
+
+	err := l.Update(cfg.Policies)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Заменяет политики. Если хотя бы одна политика невалидна, действующие политики не меняются.
+// Состояние ведер сохраняется, поэтому новые лимиты применяются к уже израсходованным запросам
+func (l *Limiter) Update(cfg []*config.RateLimitConfig) error {
+	policies := make([]policy, 0, len(cfg))
+	for _, p := range cfg {
+		parsed, err := parsePolicy(p)
+		if err != nil {
+			return err
+		}
+
+		policies = append(policies, parsed)
+	}
+
+	l.policies.Store(&policies)
+
+	return nil
+}
+
+// Middleware, ограничивающий запросы к маршрутам из политик.
+// Запросы к остальным маршрутам пропускаются без проверки
+func (l *Limiter) Handler() fiber.Handler {
+	return l.handle
+}
+
+func parsePolicy(cfg *config.RateLimitConfig) (policy, error) {
+	method, path, ok := strings.Cut(cfg.Route, " ")
+	if !ok || !strings.HasPrefix(path, "/") {
+		return policy{}, fmt.Errorf("invalid rate limit route '%s': 'METHOD /path' expected", cfg.Route)
+	}
+
+	window := int(math.Ceil(float64(cfg.Burst) * 60 / float64(cfg.PerMinute)))
+
+	return policy{
+		route:    cfg.Route,
+		method:   strings.ToUpper(method),
+		segments: splitPath(path),
+		limit: Limit{
+			Burst: cfg.Burst,
+			Rate:  float64(cfg.PerMinute) / 60,
+		},
+		header: fmt.Sprintf("%d;w=%d", cfg.Burst, window),
+	}, nil
+}
+
+func (l *Limiter) handle(c *fiber.Ctx) error {
+	p, ok := l.match(c.Method(), c.Path())
+	if !ok {
+		return c.Next()
+	}
+
+	result, err := l.store.Take(c.Context(), p.route+" "+l.key(c), p.limit, l.now())
+	if err != nil {
+		// недоступность хранилища не должна останавливать API
+		slog.Warn("rate limiting", "method", c.Method(), "url", c.OriginalURL(), "error", err)
+		return c.Next()
+	}
+
+	c.Set(HeaderLimit, strconv.Itoa(p.limit.Burst))
+	c.Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+	c.Set(HeaderReset, strconv.Itoa(ceilSeconds(result.Reset)))
+	c.Set(HeaderPolicy, p.header)
+
+	if !result.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return problem.New(fiber.StatusTooManyRequests, problem.CodeRateLimited, "too many requests, retry later")
+	}
+
+	return c.Next()
+}
+
+func (l *Limiter) match(method, path string) (policy, bool) {
+	if l.prefix != "" {
+		if trimmed, ok := strings.CutPrefix(path, l.prefix); ok && strings.HasPrefix(trimmed, "/") {
+			path = trimmed
+		}
+	}
+
+	segments := splitPath(path)
+
+	for _, p := range *l.policies.Load() {
+		if p.method == method && matchSegments(p.segments, segments) {
+			return p, true
+		}
+	}
+
+	return policy{}, false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// Сегмент шаблона вида :id совпадает с любым непустым сегментом пути
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+
+	for i := range pattern {
+		if strings.HasPrefix(pattern[i], ":") {
+			if path[i] == "" {
+				return false
+			}
+			continue
+		}
+
+		if pattern[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
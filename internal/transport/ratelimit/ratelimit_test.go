@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+var testPolicies = []*config.RateLimitConfig{
+	{Route: "GET /profiles/search", Burst: 2, PerMinute: 60},
+	{Route: "GET /profiles/:id", Burst: 1, PerMinute: 6},
+}
+
+// Приложение с ограничением запросов, в котором ключ клиента берется из заголовка X-Client,
+// а время можно сдвигать
+func newTestApp(t *testing.T, store Store) (*fiber.App, *time.Time) {
+	t.Helper()
+
+	l, err := New(Config{
+		Policies: testPolicies,
+		Store:    store,
+		Key: func(c *fiber.Ctx) string {
+			return c.Get("X-Client")
+		},
+		Prefix: "/api/v1",
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(l.Handler())
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	return app, &now
+}
+
+func get(t *testing.T, app *fiber.App, path, client string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Client", client)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("test limited route", func(t *testing.T) {
+		app, now := newTestApp(t, NewMemoryStore())
+
+		resp := get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get(HeaderLimit))
+		assert.Equal(t, "1", resp.Header.Get(HeaderRemaining))
+		assert.Equal(t, "1", resp.Header.Get(HeaderReset))
+		assert.Equal(t, "2;w=2", resp.Header.Get(HeaderPolicy))
+
+		resp = get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "0", resp.Header.Get(HeaderRemaining))
+
+		resp = get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+		assert.Equal(t, "0", resp.Header.Get(HeaderRemaining))
+		assert.Equal(t, "2", resp.Header.Get(HeaderReset))
+
+		var p problem.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		assert.Equal(t, problem.CodeRateLimited, p.Code)
+
+		*now = now.Add(time.Second)
+
+		resp = get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test clients have separate limits", func(t *testing.T) {
+		app, _ := newTestApp(t, NewMemoryStore())
+
+		resp := get(t, app, "/api/v1/profiles/42", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = get(t, app, "/api/v1/profiles/42", "user:1")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "10", resp.Header.Get(fiber.HeaderRetryAfter))
+
+		resp = get(t, app, "/api/v1/profiles/42", "ip:10.0.0.1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test routes have separate limits", func(t *testing.T) {
+		app, _ := newTestApp(t, NewMemoryStore())
+
+		resp := get(t, app, "/api/v1/profiles/1", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test legacy route shares the versioned limit", func(t *testing.T) {
+		app, _ := newTestApp(t, NewMemoryStore())
+
+		resp := get(t, app, "/api/v1/profiles/1", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = get(t, app, "/profiles/2", "user:1")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("test unlimited route", func(t *testing.T) {
+		app, _ := newTestApp(t, NewMemoryStore())
+
+		for i := 0; i < 5; i++ {
+			resp := get(t, app, "/api/v1/profiles", "user:1")
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get(HeaderLimit))
+		}
+	})
+
+	t.Run("test store failure lets requests through", func(t *testing.T) {
+		app, _ := newTestApp(t, failingStore{})
+
+		resp := get(t, app, "/api/v1/profiles/search", "user:1")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(HeaderLimit))
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	l, err := New(Config{
+		Policies: testPolicies,
+		Store:    NewMemoryStore(),
+		Key: func(c *fiber.Ctx) string {
+			return "user:1"
+		},
+	})
+	require.NoError(t, err)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(l.Handler())
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	resp := get(t, app, "/profiles/1", "")
+	assert.Equal(t, "1", resp.Header.Get(HeaderLimit))
+
+	err = l.Update([]*config.RateLimitConfig{{Route: "invalid", Burst: 1, PerMinute: 1}})
+	require.Error(t, err)
+
+	resp = get(t, app, "/profiles/1", "")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	require.NoError(t, l.Update(nil))
+
+	resp = get(t, app, "/profiles/1", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(HeaderLimit))
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	_, err := New(Config{
+		Policies: []*config.RateLimitConfig{{Route: "/profiles/search", Burst: 1, PerMinute: 1}},
+		Store:    NewMemoryStore(),
+	})
+	assert.Error(t, err)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store is unavailable")
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Параметры ведра: емкость и скорость пополнения
+type Limit struct {
+	// Емкость ведра - число запросов, которое можно сделать подряд
+	Burst int
+	// Число токенов, добавляемых в ведро в секунду
+	Rate float64
+}
+
+// Состояние ведра после попытки взять токен
+type Result struct {
+	Allowed bool
+	// Число токенов, оставшихся в ведре
+	Remaining int
+	// Время до полного заполнения ведра
+	Reset time.Duration
+	// Время до появления следующего токена, если запрос не разрешен
+	RetryAfter time.Duration
+}
+
+// Хранилище ведер. Общее хранилище (например, в Redis) позволяет нескольким экземплярам
+// приложения ограничивать запросы вместе
+type Store interface {
+	// Берет токен из ведра key, создавая полное ведро при первом запросе
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Как часто удалять из памяти ведра, которые успели заполниться
+const sweepInterval = time.Minute
+
+// Хранилище ведер в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// Момент, когда ведро заполнится: полное ведро не отличается от нового и может быть удалено
+	fullAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	b.fullAt = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// Число ведер в памяти
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Burst: 3, Rate: 1}
+
+	t.Run("test bucket allows burst and refills", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		for i := 2; i >= 0; i-- {
+			result, err := store.Take(ctx, "key", limit, now)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, i, result.Remaining)
+		}
+
+		result, err := store.Take(ctx, "key", limit, now)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, time.Second, result.RetryAfter)
+		assert.Equal(t, 3*time.Second, result.Reset)
+
+		result, err = store.Take(ctx, "key", limit, now.Add(1500*time.Millisecond))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 2500*time.Millisecond, result.Reset)
+	})
+
+	t.Run("test bucket does not exceed burst", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		_, err := store.Take(ctx, "key", limit, now)
+		require.NoError(t, err)
+
+		result, err := store.Take(ctx, "key", limit, now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Remaining)
+	})
+
+	t.Run("test keys have separate buckets", func(t *testing.T) {
+		store := NewMemoryStore()
+		single := Limit{Burst: 1, Rate: 1}
+
+		result, err := store.Take(ctx, "a", single, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, err = store.Take(ctx, "b", single, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("test full buckets are swept", func(t *testing.T) {
+		store := NewMemoryStore()
+
+		_, err := store.Take(ctx, "a", limit, now)
+		require.NoError(t, err)
+		_, err = store.Take(ctx, "b", limit, now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, store.Len())
+
+		_, err = store.Take(ctx, "c", limit, now.Add(2*sweepInterval))
+		require.NoError(t, err)
+		assert.Equal(t, 1, store.Len())
+	})
+}
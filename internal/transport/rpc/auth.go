@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	socialv1 "github.com/Lucky112/social/api/proto/social/v1"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/transport/auth"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+var invalidMFAToken = problem.New(http.StatusUnauthorized, problem.CodeInvalidMFAToken, "invalid or expired mfa token")
+
+// Регистрация и вход пользователей через gRPC
+type authServer struct {
+	socialv1.UnimplementedAuthServiceServer
+
+	service auth.AuthService
+	jwtKeys *jwt.Keys
+}
+
+func newAuthServer(service auth.AuthService, jwtKeys *jwt.Keys) authServer {
+	return authServer{
+		service: service,
+		jwtKeys: jwtKeys,
+	}
+}
+
+func (s authServer) Register(ctx context.Context, req *socialv1.RegisterRequest) (*socialv1.RegisterResponse, error) {
+	user := &models.User{
+		Email:    req.GetEmail(),
+		Login:    req.GetLogin(),
+		Password: req.GetPassword(),
+	}
+
+	id, err := s.service.NewUser(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("creating user: %w", err)
+	}
+
+	return &socialv1.RegisterResponse{Id: id}, nil
+}
+
+func (s authServer) Login(ctx context.Context, req *socialv1.LoginRequest) (*socialv1.LoginResponse, error) {
+	verr := &models.ValidationError{}
+	if req.GetLogin() == "" {
+		verr.Add("login", "required", "login is required")
+	}
+	if req.GetPassword() == "" {
+		verr.Add("password", "required", "password is required")
+	}
+
+	err := verr.OrNil()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Login(ctx, req.GetLogin(), req.GetPassword())
+	if err != nil {
+		if errors.Is(err, models.UserNotFound) {
+			return nil, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "the user for given login or email not found")
+		}
+
+		return nil, fmt.Errorf("logging in: %w", err)
+	}
+
+	if user.TOTPEnabled {
+		token, err := jwt.MakeMFAToken(user.Id, user.MFAGeneration, s.jwtKeys)
+		if err != nil {
+			return nil, fmt.Errorf("creating JWT-token: %v", err)
+		}
+
+		return &socialv1.LoginResponse{MfaToken: token, MfaRequired: true}, nil
+	}
+
+	token, err := jwt.MakeToken(user.Id, user.Roles, s.jwtKeys)
+	if err != nil {
+		return nil, fmt.Errorf("creating JWT-token: %v", err)
+	}
+
+	return &socialv1.LoginResponse{AccessToken: token}, nil
+}
+
+func (s authServer) LoginMFA(ctx context.Context, req *socialv1.LoginMFARequest) (*socialv1.LoginResponse, error) {
+	userId, generation, err := jwt.ParseMFAToken(req.GetMfaToken(), s.jwtKeys)
+	if err != nil {
+		return nil, invalidMFAToken
+	}
+
+	user, err := s.service.VerifyMFA(ctx, userId, generation, req.GetCode())
+	if err != nil {
+		switch {
+		case errors.Is(err, models.UserNotFound), errors.Is(err, models.TOTPNotEnrolled), errors.Is(err, models.MFATokenRevoked):
+			return nil, invalidMFAToken
+		default:
+			return nil, fmt.Errorf("verifying mfa code: %w", err)
+		}
+	}
+
+	token, err := jwt.MakeToken(user.Id, user.Roles, s.jwtKeys)
+	if err != nil {
+		return nil, fmt.Errorf("creating JWT-token: %v", err)
+	}
+
+	return &socialv1.LoginResponse{AccessToken: token}, nil
+}
@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Домен в google.rpc.ErrorInfo
+const errorDomain = "social"
+
+// Коды, для которых статус gRPC не выводится из HTTP-статуса.
+// В REST они остаются 400 ради совместимости с клиентами
+var codeStatuses = map[string]codes.Code{
+	problem.CodeUserAlreadyExists: codes.AlreadyExists,
+	problem.CodeMalformedToken:    codes.Unauthenticated,
+}
+
+var httpStatuses = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+// Переводит ошибку в статус gRPC с теми же кодом и нарушениями по полям, что и в REST API.
+// Статусы gRPC, например из отмененного контекста, возвращаются как есть
+func toStatus(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	p := problem.FromError(err)
+
+	if p.Status >= http.StatusInternalServerError {
+		slog.Error("grpc request failed", "method", method, "error", err)
+	}
+
+	code, ok := codeStatuses[p.Code]
+	if !ok {
+		code, ok = httpStatuses[p.Status]
+	}
+	if !ok {
+		code = codes.Unknown
+	}
+
+	detail := p.Detail
+	if detail == "" {
+		detail = p.Title
+	}
+
+	st := status.New(code, detail)
+
+	withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: p.Code, Domain: errorDomain})
+	if err == nil {
+		st = withInfo
+	}
+
+	if len(p.Errors) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(p.Errors))
+		for i, fe := range p.Errors {
+			violations[i] = &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			}
+		}
+
+		withViolations, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+		if err == nil {
+			st = withViolations
+		}
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	socialv1 "github.com/Lucky112/social/api/proto/social/v1"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Ключ метаданных с токеном
+const authorizationKey = "authorization"
+
+// Сервисы, методы которых не требуют токена
+var publicServices = []string{
+	socialv1.AuthService_ServiceDesc.ServiceName,
+	healthpb.Health_ServiceDesc.ServiceName,
+	reflectionv1.ServerReflection_ServiceDesc.ServiceName,
+	reflectionv1alpha.ServerReflection_ServiceDesc.ServiceName,
+}
+
+// Разрешения, необходимые для вызова методов.
+// Методы непубличных сервисов, которых нет в списке, недоступны никому
+var methodPermissions = map[string]role.Permission{
+	socialv1.ProfilesService_GetProfile_FullMethodName:     role.ReadProfiles,
+	socialv1.ProfilesService_ListProfiles_FullMethodName:   role.ReadProfiles,
+	socialv1.ProfilesService_SearchProfiles_FullMethodName: role.ReadProfiles,
+}
+
+var invalidToken = problem.New(http.StatusUnauthorized, problem.CodeInvalidToken, "invalid or expired JWT")
+
+// Проверка access-токена из метаданных вызова: подпись, срок действия, отзыв и разрешения
+type authenticator struct {
+	jwtKeys   *jwt.Keys
+	validator jwt.SessionValidator
+}
+
+func newAuthenticator(jwtKeys *jwt.Keys, validator jwt.SessionValidator) authenticator {
+	return authenticator{
+		jwtKeys:   jwtKeys,
+		validator: validator,
+	}
+}
+
+func (a authenticator) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a authenticator) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, authenticatedStream{ss, ctx})
+}
+
+// Проверяет токен и возвращает контекст с его владельцем
+func (a authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if isPublic(method) {
+		return ctx, nil
+	}
+
+	permission, ok := methodPermissions[method]
+	if !ok {
+		return nil, problem.New(http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("method '%s' is not available", method))
+	}
+
+	token, err := tokenFromMetadata(ctx)
+	if err != nil {
+		return nil, problem.New(http.StatusUnauthorized, problem.CodeMalformedToken, err.Error())
+	}
+
+	subject, err := jwt.ParseAccessToken(token, a.jwtKeys)
+	if err != nil {
+		return nil, invalidToken.WithCause(err)
+	}
+
+	err = a.validator.ValidateSession(ctx, subject.UserId, subject.IssuedAt)
+	if err != nil {
+		return nil, fmt.Errorf("validating session: %w", err)
+	}
+
+	if !role.AnyHas(subject.Roles, permission) {
+		return nil, problem.New(http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("permission '%s' required", permission))
+	}
+
+	return context.WithValue(ctx, subjectKey{}, subject), nil
+}
+
+// Ключ владельца токена в контексте вызова
+type subjectKey struct{}
+
+// Пользователь, выполняющий вызов. В вызовах публичных методов - пустой
+func actorFrom(ctx context.Context) models.Actor {
+	subject, _ := ctx.Value(subjectKey{}).(jwt.Subject)
+
+	return models.Actor{UserId: subject.UserId, Roles: subject.Roles}
+}
+
+// Поток с контекстом, в который добавлен владелец токена
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func isPublic(method string) bool {
+	for _, service := range publicServices {
+		if strings.HasPrefix(method, "/"+service+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tokenFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("missing metadata")
+	}
+
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return "", fmt.Errorf("missing '%s' metadata", authorizationKey)
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", fmt.Errorf("'%s' metadata must be 'Bearer <token>'", authorizationKey)
+	}
+
+	return token, nil
+}
+
+// Переводит ошибки обработчиков и других перехватчиков в статусы gRPC
+func unaryErrorsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err)
+	}
+
+	return resp, nil
+}
+
+func streamErrorsInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	if err != nil {
+		return toStatus(info.FullMethod, err)
+	}
+
+	return nil
+}
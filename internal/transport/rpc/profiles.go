@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	socialv1 "github.com/Lucky112/social/api/proto/social/v1"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/internal/transport/profiles"
+)
+
+const birthdateFormat = "2006-01-02"
+
+// Просмотр анкет через gRPC
+type profilesServer struct {
+	socialv1.UnimplementedProfilesServiceServer
+
+	service profiles.ProfilesService
+}
+
+func newProfilesServer(service profiles.ProfilesService) profilesServer {
+	return profilesServer{
+		service: service,
+	}
+}
+
+func (s profilesServer) GetProfile(ctx context.Context, req *socialv1.GetProfileRequest) (*socialv1.Profile, error) {
+	p, err := s.service.Get(ctx, actorFrom(ctx), req.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("finding profile: %w", err)
+	}
+
+	return fromModel(p), nil
+}
+
+func (s profilesServer) ListProfiles(req *socialv1.ListProfilesRequest, stream socialv1.ProfilesService_ListProfilesServer) error {
+	profiles, err := s.service.GetAll(stream.Context(), actorFrom(stream.Context()))
+	if err != nil {
+		return fmt.Errorf("getting all profiles: %w", err)
+	}
+
+	for _, p := range profiles {
+		err = stream.Send(fromModel(p))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s profilesServer) SearchProfiles(ctx context.Context, req *socialv1.SearchProfilesRequest) (*socialv1.SearchProfilesResponse, error) {
+	params := &models.SearchParams{
+		NamePrefix:    req.GetName(),
+		SurnamePrefix: req.GetSurname(),
+		Interest:      req.GetInterest(),
+	}
+
+	profiles, err := s.service.Search(ctx, actorFrom(ctx), params)
+	if err != nil {
+		return nil, fmt.Errorf("searching profiles: %w", err)
+	}
+
+	resp := &socialv1.SearchProfilesResponse{
+		Profiles: make([]*socialv1.Profile, len(profiles)),
+	}
+
+	for i, p := range profiles {
+		resp.Profiles[i] = fromModel(p)
+	}
+
+	return resp, nil
+}
+
+// Поля, скрытые от запросившего пользователя, остаются пустыми
+func fromModel(mp *models.Profile) *socialv1.Profile {
+	p := &socialv1.Profile{
+		Name:    mp.Name,
+		Surname: mp.Surname,
+		Sex:     fromSex(mp.Sex),
+		City:    mp.Address,
+		Hobbies: mp.Hobbies,
+	}
+
+	if !mp.IsHidden(models.FieldBirthdate) {
+		p.Birthdate = mp.Birthdate.Format(birthdateFormat)
+	}
+
+	return p
+}
+
+func fromSex(s sex.Sex) socialv1.Sex {
+	switch s {
+	case sex.Male:
+		return socialv1.Sex_SEX_MALE
+	case sex.Female:
+		return socialv1.Sex_SEX_FEMALE
+	default:
+		return socialv1.Sex_SEX_UNSPECIFIED
+	}
+}
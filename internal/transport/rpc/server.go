@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	socialv1 "github.com/Lucky112/social/api/proto/social/v1"
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/auth"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/profiles"
+)
+
+// gRPC-сервер, работающий поверх того же сервисного слоя, что и REST API
+type Server struct {
+	server *grpc.Server
+	health *health.Server
+	port   uint16
+}
+
+func NewServer(
+	cfg *config.GRPCConfig,
+	jwtKeys *jwt.Keys,
+	authService auth.AuthService,
+	profilesService profiles.ProfilesService,
+) Server {
+	authenticator := newAuthenticator(jwtKeys, authService)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryErrorsInterceptor, authenticator.unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamErrorsInterceptor, authenticator.streamInterceptor),
+	)
+
+	socialv1.RegisterAuthServiceServer(server, newAuthServer(authService, jwtKeys))
+	socialv1.RegisterProfilesServiceServer(server, newProfilesServer(profilesService))
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	for name := range server.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	if cfg.Reflection {
+		reflection.Register(server)
+	}
+
+	return Server{
+		server: server,
+		health: healthServer,
+		port:   cfg.Port,
+	}
+}
+
+func (s Server) Start() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("listening grpc port: %v", err)
+	}
+
+	return s.Serve(ln)
+}
+
+// Обслуживает запросы, принимаемые через ln, например в тестах
+func (s Server) Serve(ln net.Listener) error {
+	return s.server.Serve(ln)
+}
+
+// Переводит сервисы в состояние NOT_SERVING и дожидается завершения текущих вызовов
+func (s Server) Stop() {
+	s.health.Shutdown()
+	s.server.GracefulStop()
+}
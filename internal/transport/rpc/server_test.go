@@ -0,0 +1,271 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	socialv1 "github.com/Lucky112/social/api/proto/social/v1"
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestServer(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+	signingKey := jwt.NewKeys("signing-key")
+
+	server := NewServer(&config.GRPCConfig{Reflection: true}, signingKey, authService, profilesService)
+
+	ln := bufconn.Listen(1 << 20)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	authClient := socialv1.NewAuthServiceClient(conn)
+	profilesClient := socialv1.NewProfilesServiceClient(conn)
+
+	ctx := context.Background()
+
+	userToken, err := jwt.MakeToken("1", []role.Role{role.User}, signingKey)
+	require.NoError(t, err)
+
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("Bearer %s", userToken))
+
+	profile := &models.Profile{
+		Name:      "Иван",
+		Surname:   "Иванов",
+		Sex:       sex.Male,
+		Birthdate: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+		Address:   "Москва",
+		Hobbies:   "Чтение",
+	}
+	expected := &socialv1.Profile{
+		Name:      "Иван",
+		Surname:   "Иванов",
+		Sex:       socialv1.Sex_SEX_MALE,
+		Birthdate: "1990-01-02",
+		City:      "Москва",
+		Hobbies:   "Чтение",
+	}
+
+	t.Run("test Register", func(t *testing.T) {
+		authService.On("NewUser", mock.Anything, &models.User{Email: "user@example.com", Login: "login", Password: "password"}).Return("1", nil).Once()
+
+		resp, err := authClient.Register(ctx, &socialv1.RegisterRequest{Email: "user@example.com", Login: "login", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, "1", resp.GetId())
+	})
+
+	t.Run("test Register conflict", func(t *testing.T) {
+		authService.On("NewUser", mock.Anything, mock.Anything).Return("", fmt.Errorf("creating: %w", &models.UserConflictError{Field: "email"})).Once()
+
+		_, err := authClient.Register(ctx, &socialv1.RegisterRequest{Email: "user@example.com", Login: "other", Password: "password"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.AlreadyExists, st.Code())
+		assert.Equal(t, problem.CodeUserAlreadyExists, errorReason(st))
+	})
+
+	t.Run("test Login", func(t *testing.T) {
+		authService.On("Login", mock.Anything, "login", "password").Return(&models.User{Id: "1", Roles: []role.Role{role.User}}, nil).Once()
+
+		resp, err := authClient.Login(ctx, &socialv1.LoginRequest{Login: "login", Password: "password"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.GetAccessToken())
+		assert.False(t, resp.GetMfaRequired())
+	})
+
+	t.Run("test Login without password", func(t *testing.T) {
+		_, err := authClient.Login(ctx, &socialv1.LoginRequest{Login: "login"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, problem.CodeValidationFailed, errorReason(st))
+	})
+
+	t.Run("test Login with TOTP", func(t *testing.T) {
+		user := &models.User{Id: "2", TOTPEnabled: true, MFAGeneration: 3}
+		authService.On("Login", mock.Anything, "mfa", "password").Return(user, nil).Once()
+		authService.On("VerifyMFA", mock.Anything, "2", 3, "123456").Return(user, nil).Once()
+
+		resp, err := authClient.Login(ctx, &socialv1.LoginRequest{Login: "mfa", Password: "password"})
+		require.NoError(t, err)
+		require.True(t, resp.GetMfaRequired())
+		assert.Empty(t, resp.GetAccessToken())
+
+		resp, err = authClient.LoginMFA(ctx, &socialv1.LoginMFARequest{MfaToken: resp.GetMfaToken(), Code: "123456"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.GetAccessToken())
+	})
+
+	t.Run("test GetProfile", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		viewer := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+		profilesService.On("Get", mock.Anything, viewer, "10").Return(profile, nil).Once()
+
+		resp, err := profilesClient.GetProfile(authorized, &socialv1.GetProfileRequest{Id: "10"})
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), resp.String())
+	})
+
+	t.Run("test GetProfile with hidden fields", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		hidden := &models.Profile{Name: "Иван", Hidden: []models.ProfileField{models.FieldBirthdate}}
+		profilesService.On("Get", mock.Anything, mock.Anything, "13").Return(hidden, nil).Once()
+
+		resp, err := profilesClient.GetProfile(authorized, &socialv1.GetProfileRequest{Id: "13"})
+		require.NoError(t, err)
+		assert.Equal(t, "Иван", resp.GetName())
+		assert.Empty(t, resp.GetBirthdate())
+	})
+
+	t.Run("test GetProfile not found", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Get", mock.Anything, mock.Anything, "11").Return(nil, fmt.Errorf("looking up: %w", models.ProfileNotFound)).Once()
+
+		_, err := profilesClient.GetProfile(authorized, &socialv1.GetProfileRequest{Id: "11"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.NotFound, st.Code())
+		assert.Equal(t, problem.CodeProfileNotFound, errorReason(st))
+	})
+
+	t.Run("test internal errors are not leaked", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Get", mock.Anything, mock.Anything, "12").Return(nil, fmt.Errorf("select * from scl.profiles: connection refused")).Once()
+
+		_, err := profilesClient.GetProfile(authorized, &socialv1.GetProfileRequest{Id: "12"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.Internal, st.Code())
+		assert.NotContains(t, st.Message(), "scl.profiles")
+	})
+
+	t.Run("test ListProfiles", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		viewer := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+		profilesService.On("GetAll", mock.Anything, viewer).Return([]*models.Profile{profile, profile}, nil).Once()
+
+		stream, err := profilesClient.ListProfiles(authorized, &socialv1.ListProfilesRequest{})
+		require.NoError(t, err)
+
+		var received []*socialv1.Profile
+		for {
+			p, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			received = append(received, p)
+		}
+
+		require.Len(t, received, 2)
+		assert.Equal(t, expected.String(), received[1].String())
+	})
+
+	t.Run("test ListProfiles without token", func(t *testing.T) {
+		stream, err := profilesClient.ListProfiles(ctx, &socialv1.ListProfilesRequest{})
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("test SearchProfiles", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Search", mock.Anything, mock.Anything, &models.SearchParams{NamePrefix: "Ив"}).Return([]*models.Profile{profile}, nil).Once()
+
+		resp, err := profilesClient.SearchProfiles(authorized, &socialv1.SearchProfilesRequest{Name: "Ив"})
+		require.NoError(t, err)
+		require.Len(t, resp.GetProfiles(), 1)
+		assert.Equal(t, expected.String(), resp.GetProfiles()[0].String())
+	})
+
+	t.Run("test SearchProfiles by interest", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Search", mock.Anything, mock.Anything, &models.SearchParams{Interest: "шахматы"}).Return([]*models.Profile{profile}, nil).Once()
+
+		resp, err := profilesClient.SearchProfiles(authorized, &socialv1.SearchProfilesRequest{Interest: "шахматы"})
+		require.NoError(t, err)
+		require.Len(t, resp.GetProfiles(), 1)
+	})
+
+	t.Run("test revoked token", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(fmt.Errorf("%w", models.SessionRevoked)).Once()
+
+		_, err := profilesClient.GetProfile(authorized, &socialv1.GetProfileRequest{Id: "10"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+		assert.Equal(t, problem.CodeTokenRevoked, errorReason(st))
+	})
+
+	t.Run("test invalid token", func(t *testing.T) {
+		otherToken, err := jwt.MakeToken("1", []role.Role{role.User}, jwt.NewKeys("other-key"))
+		require.NoError(t, err)
+
+		ctx := metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("Bearer %s", otherToken))
+
+		_, err = profilesClient.GetProfile(ctx, &socialv1.GetProfileRequest{Id: "10"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+		assert.Equal(t, problem.CodeInvalidToken, errorReason(st))
+	})
+
+	t.Run("test MFA token is not an access token", func(t *testing.T) {
+		mfaToken, err := jwt.MakeMFAToken("1", 0, signingKey)
+		require.NoError(t, err)
+
+		ctx := metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("Bearer %s", mfaToken))
+
+		_, err = profilesClient.GetProfile(ctx, &socialv1.GetProfileRequest{Id: "10"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("test health", func(t *testing.T) {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: socialv1.ProfilesService_ServiceDesc.ServiceName})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	})
+}
+
+func errorReason(st *status.Status) string {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+
+	return ""
+}
@@ -1,47 +1,168 @@
 package transport
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
 	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/transport/account"
+	"github.com/Lucky112/social/internal/transport/admin"
 	"github.com/Lucky112/social/internal/transport/auth"
+	"github.com/Lucky112/social/internal/transport/idempotency"
+	"github.com/Lucky112/social/internal/transport/interests"
 	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/openapi"
+	"github.com/Lucky112/social/internal/transport/photos"
+	"github.com/Lucky112/social/internal/transport/problem"
 	"github.com/Lucky112/social/internal/transport/profiles"
+	"github.com/Lucky112/social/internal/transport/ratelimit"
+	"github.com/Lucky112/social/internal/transport/webhooks"
 )
 
 type Server struct {
-	server *fiber.App
-	port   uint16
+	server  *fiber.App
+	port    uint16
+	limiter *ratelimit.Limiter
+	cors    *corsOrigins
 }
 
-func NewServer(cfg *config.ServerConfig, authService auth.AuthService, profilesService profiles.ProfilesService) Server {
-	jwtKey := []byte(cfg.JWTKey)
-
-	authHandler := auth.NewAuthHandler(authService, jwtKey)
+func NewServer(
+	cfg *config.ServerConfig,
+	jwtKeys *jwt.Keys,
+	authService auth.AuthService,
+	profilesService profiles.ProfilesService,
+	adminService admin.AdminService,
+	accountService account.AccountService,
+	webhooksService webhooks.WebhooksService,
+	photosService photos.PhotosService,
+	interestsService interests.InterestsService,
+	idempotencyStore idempotency.Store,
+) (Server, error) {
+	authHandler := auth.NewAuthHandler(authService, jwtKeys)
 	profilesHandler := profiles.NewProfilesHandler(profilesService)
+	adminHandler := admin.NewAdminHandler(adminService)
+	accountHandler := account.NewAccountHandler(accountService, jwtKeys)
+	webhooksHandler := webhooks.NewWebhooksHandler(webhooksService)
+	photosHandler := photos.NewPhotosHandler(photosService)
+	interestsHandler := interests.NewInterestsHandler(interestsService)
 
-	server := fiber.New()
+	server := fiber.New(fiber.Config{
+		ErrorHandler: problem.ErrorHandler,
+		// незаданный (нулевой) размер - ограничение fiber по умолчанию
+		BodyLimit: cfg.BodyLimitBytes,
+	})
 
 	server.Use(recover.New())
 
-	publicGroup := server.Group("")
-	publicGroup.Post("/register", authHandler.Register)
-	publicGroup.Post("/login", authHandler.Login)
+	cors, err := newCORSOrigins(cfg.CORS)
+	if err != nil {
+		return Server{}, err
+	}
+	server.Use(cors.middleware())
+
+	if cfg.ExposeMetrics {
+		server.Use(expvar.New())
+	}
+
+	if cfg.OpenAPIValidation != nil {
+		doc, err := openapi.Load(context.Background())
+		if err != nil {
+			return Server{}, fmt.Errorf("loading openapi spec: %v", err)
+		}
+
+		validator, err := openapi.NewValidator(doc, cfg.OpenAPIValidation)
+		if err != nil {
+			return Server{}, fmt.Errorf("creating openapi validator: %v", err)
+		}
 
-	authorizedGroup := server.Group("")
-	authorizedGroup.Use(jwt.Middleware(jwtKey))
+		server.Use(validator.Middleware())
+	}
 
-	authorizedGroup.Post("/profiles", profilesHandler.CreateProfile)
-	authorizedGroup.Get("/profiles", profilesHandler.GetProfiles)
-	authorizedGroup.Get("/profiles/search", profilesHandler.SearchProfile)
-	authorizedGroup.Get("/profiles/:id", profilesHandler.GetProfileById)
+	// ограничитель подключается всегда, чтобы политики можно было задать при перезагрузке конфигурации
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Policies: cfg.RateLimits,
+		Store:    ratelimit.NewMemoryStore(),
+		Key:      clientKey(jwtKeys),
+		Prefix:   v1Prefix,
+	})
+	if err != nil {
+		return Server{}, fmt.Errorf("creating rate limiter: %v", err)
+	}
+	server.Use(limiter.Handler())
+
+	if cfg.Idempotency != nil {
+		server.Use(idempotency.New(idempotency.Config{
+			Store:       idempotencyStore,
+			TTL:         time.Duration(cfg.Idempotency.TTLSeconds) * time.Second,
+			LockTimeout: time.Duration(cfg.Idempotency.LockTimeoutSeconds) * time.Second,
+			Scope:       clientKey(jwtKeys),
+			Prefix:      v1Prefix,
+		}).Handler())
+	}
+
+	v1 := v1Routes{
+		jwtKeys:   jwtKeys,
+		sessions:  authService,
+		auth:      authHandler,
+		profiles:  profilesHandler,
+		admin:     adminHandler,
+		account:   accountHandler,
+		webhooks:  webhooksHandler,
+		photos:    photosHandler,
+		interests: interestsHandler,
+	}
+
+	// маршруты с версией регистрируются раньше устаревших, чтобы запросы к ним не проходили через middleware устаревших
+	v1.register(server.Group(v1Prefix))
+
+	sunset, err := legacySunset(cfg.LegacySunset)
+	if err != nil {
+		return Server{}, err
+	}
+	v1.register(server.Group("", deprecated(v1Prefix, sunset)))
 
 	return Server{
-		server: server,
-		port:   cfg.Port,
+		server:  server,
+		port:    cfg.Port,
+		limiter: limiter,
+		cors:    cors,
+	}, nil
+}
+
+// Применяет изменения настроек, которые можно менять без перезапуска: ограничения частоты запросов
+// и разрешенные источники CORS. Если новые настройки невалидны, не меняется ничего
+func (s Server) Reload(cfg *config.ServerConfig) error {
+	origins, err := parseOrigins(cfg.CORS)
+	if err != nil {
+		return err
+	}
+
+	err = s.limiter.Update(cfg.RateLimits)
+	if err != nil {
+		return fmt.Errorf("updating rate limits: %v", err)
+	}
+
+	s.cors.set.Store(origins)
+
+	return nil
+}
+
+// Ключ клиента для ограничения частоты запросов и ключей идемпотентности: пользователь
+// из валидного access-токена, а для анонимных запросов и запросов с невалидным токеном - IP-адрес
+func clientKey(jwtKeys *jwt.Keys) func(c *fiber.Ctx) string {
+	return func(c *fiber.Ctx) string {
+		subject, err := jwt.ParseRequestToken(c, jwtKeys)
+		if err != nil {
+			return "ip:" + c.IP()
+		}
+
+		return "user:" + subject.UserId
 	}
 }
 
@@ -51,3 +172,12 @@ func (s Server) Start() error {
 	err := s.server.Listen(address)
 	return err
 }
+
+// Обслуживает запросы, принимаемые через ln, например на случайном порту в тестах
+func (s Server) Serve(ln net.Listener) error {
+	return s.server.Listener(ln)
+}
+
+func (s Server) Shutdown() error {
+	return s.server.Shutdown()
+}
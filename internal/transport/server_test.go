@@ -0,0 +1,402 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/idempotency"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/openapi"
+	"github.com/Lucky112/social/mocks"
+)
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+func TestRoutesMatchSpec(t *testing.T) {
+	doc, err := openapi.Load(context.Background())
+	require.NoError(t, err)
+
+	cfg := &config.ServerConfig{
+		JWTKey:            "signing-key",
+		OpenAPIValidation: &config.OpenAPIValidationConfig{Requests: true, Responses: true},
+	}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), mocks.NewAuthService(t), mocks.NewProfilesService(t), mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	routes := make(map[string]struct{})
+	legacyRoutes := make(map[string]struct{})
+
+	for _, route := range s.server.GetRoutes(true) {
+		if route.Method == "HEAD" {
+			continue
+		}
+
+		path, versioned := strings.CutPrefix(route.Path, v1Prefix)
+		path = pathParam.ReplaceAllString(path, "{$1}")
+		key := fmt.Sprintf("%s %s", route.Method, path)
+
+		if !versioned {
+			legacyRoutes[key] = struct{}{}
+			continue
+		}
+		routes[key] = struct{}{}
+
+		item := doc.Paths.Value(path)
+		if !assert.NotNil(t, item, "route %s is missing from the spec", key) {
+			continue
+		}
+
+		assert.NotNil(t, item.GetOperation(route.Method), "route %s is missing from the spec", key)
+	}
+
+	assert.Equal(t, routes, legacyRoutes, "legacy routes must mirror %s routes", v1Prefix)
+
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			key := fmt.Sprintf("%s %s", method, path)
+
+			_, ok := routes[key]
+			assert.True(t, ok, "spec documents %s, which the server does not route", key)
+
+			checkOperation(t, key, op)
+		}
+	}
+}
+
+func checkOperation(t *testing.T, key string, op *openapi3.Operation) {
+	if op.RequestBody != nil {
+		for contentType, media := range op.RequestBody.Value.Content {
+			assert.NotNil(t, media.Schema, "request body %s of %s has no schema", contentType, key)
+		}
+	}
+
+	hasSuccess := false
+	for status, resp := range op.Responses.Map() {
+		if strings.HasPrefix(status, "2") {
+			hasSuccess = true
+		}
+
+		for contentType, media := range resp.Value.Content {
+			assert.NotNil(t, media.Schema, "response %s %s of %s has no schema", status, contentType, key)
+		}
+	}
+
+	assert.True(t, hasSuccess, "%s has no successful response", key)
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	cfg := &config.ServerConfig{
+		JWTKey:       "signing-key",
+		LegacySunset: "2027-01-31",
+	}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), mocks.NewAuthService(t), mocks.NewProfilesService(t), mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	login := func(path string) *http.Response {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{}`))
+		req.Header.Add("Content-type", "application/json")
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	t.Run("test legacy route", func(t *testing.T) {
+		resp := login("/login")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf("@%d", legacyDeprecatedAt.Unix()), resp.Header.Get("Deprecation"))
+		assert.Equal(t, "Sun, 31 Jan 2027 00:00:00 GMT", resp.Header.Get("Sunset"))
+		assert.Equal(t, `</api/v1/login>; rel="successor-version"`, resp.Header.Get("Link"))
+	})
+
+	t.Run("test versioned route", func(t *testing.T) {
+		resp := login("/api/v1/login")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Deprecation"))
+		assert.Empty(t, resp.Header.Get("Sunset"))
+	})
+
+	t.Run("test unknown versioned route", func(t *testing.T) {
+		resp := login("/api/v1/unknown")
+		assert.Empty(t, resp.Header.Get("Deprecation"))
+	})
+}
+
+func TestConditionalGet(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+
+	cfg := &config.ServerConfig{JWTKey: "signing-key"}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	token, err := jwt.MakeToken("1", []role.Role{role.User}, jwt.NewKeys(cfg.JWTKey))
+	require.NoError(t, err)
+
+	profile := &models.Profile{Name: "name", UpdatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+
+	authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil)
+	profilesService.On("Get", mock.Anything, mock.Anything, "1").Return(profile, nil)
+
+	get := func(etag string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/profiles/1", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		if etag != "" {
+			req.Header.Add("If-None-Match", etag)
+		}
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	resp := get("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Wed, 01 May 2024 00:00:00 GMT", resp.Header.Get("Last-Modified"))
+
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	resp = get(etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp = get(`"outdated"`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRateLimits(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+
+	cfg := &config.ServerConfig{
+		JWTKey: "signing-key",
+		RateLimits: []*config.RateLimitConfig{
+			{Route: "GET /profiles/search", Burst: 1, PerMinute: 1},
+		},
+	}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	authService.On("ValidateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	profilesService.On("Search", mock.Anything, mock.Anything, mock.Anything).Return([]*models.Profile{}, nil)
+
+	search := func(userId string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/profiles/search?name=a", nil)
+		if userId != "" {
+			token, err := jwt.MakeToken(userId, []role.Role{role.User}, jwt.NewKeys(cfg.JWTKey))
+			require.NoError(t, err)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	resp := search("1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+
+	resp = search("1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// у другого пользователя свой лимит
+	resp = search("2")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// анонимный запрос ограничивается по IP и доходит до проверки токена
+	resp = search("")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = search("")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+
+	cfg := &config.ServerConfig{
+		JWTKey:      "signing-key",
+		Idempotency: &config.IdempotencyConfig{TTLSeconds: 60, LockTimeoutSeconds: 60},
+	}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	authService.On("ValidateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	profilesService.On("Add", mock.Anything, mock.Anything).Return("42", nil).Once()
+
+	create := func(userId, key, body string) *http.Response {
+		token, err := jwt.MakeToken(userId, []role.Role{role.User}, jwt.NewKeys(cfg.JWTKey))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/profiles", strings.NewReader(body))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(idempotency.HeaderKey, key)
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	resp := create("1", "key-1", `{"name":"Иван","birthdate":"1990-01-01"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// повтор отдает сохраненный ответ, анкета второй раз не создается
+	resp = create("1", "key-1", `{"name":"Иван","birthdate":"1990-01-01"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get(idempotency.HeaderReplayed))
+
+	resp = create("1", "key-1", `{"name":"Петр","birthdate":"1990-01-01"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// ключ другого пользователя не пересекается с ключом первого
+	profilesService.On("Add", mock.Anything, mock.Anything).Return("43", nil).Once()
+
+	resp = create("2", "key-1", `{"name":"Иван","birthdate":"1990-01-01"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(idempotency.HeaderReplayed))
+}
+
+func TestIdempotencyKeyAfterRevokedToken(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+
+	cfg := &config.ServerConfig{
+		JWTKey:      "signing-key",
+		Idempotency: &config.IdempotencyConfig{TTLSeconds: 60, LockTimeoutSeconds: 60},
+	}
+
+	s, err := NewServer(cfg, jwt.NewKeys(cfg.JWTKey), authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	create := func() *http.Response {
+		token, err := jwt.MakeToken("1", []role.Role{role.User}, jwt.NewKeys(cfg.JWTKey))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/profiles", strings.NewReader(`{"name":"Иван","birthdate":"1990-01-01"}`))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(idempotency.HeaderKey, "key-1")
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	// отозванный токен по-прежнему разбирается, поэтому запрос попадает в область ключей пользователя
+	authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(fmt.Errorf("%w", models.SessionRevoked)).Once()
+
+	resp := create()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// после повторного входа клиент повторяет запрос с тем же ключом
+	authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+	profilesService.On("Add", mock.Anything, mock.Anything).Return("42", nil).Once()
+
+	resp = create()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(idempotency.HeaderReplayed))
+}
+
+func TestReload(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+
+	cfg := &config.ServerConfig{JWTKey: "old-key"}
+	keys := jwt.NewKeys(cfg.JWTKey)
+
+	s, err := NewServer(cfg, keys, authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), mocks.NewPhotosService(t), mocks.NewInterestsService(t), idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	authService.On("ValidateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	profilesService.On("GetAll", mock.Anything, mock.Anything).Return([]*models.Profile{}, nil)
+
+	oldToken, err := jwt.MakeToken("1", []role.Role{role.User}, keys)
+	require.NoError(t, err)
+
+	list := func(token string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/profiles", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Add("Origin", "https://social.example.com")
+
+		resp, err := s.server.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	resp := list(oldToken)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+
+	t.Run("test hot settings are applied", func(t *testing.T) {
+		updated := &config.ServerConfig{
+			JWTKey:          "new-key",
+			JWTPreviousKeys: []string{"old-key"},
+			RateLimits:      []*config.RateLimitConfig{{Route: "GET /profiles", Burst: 10, PerMinute: 60}},
+			CORS:            &config.CORSConfig{AllowOrigins: []string{"https://social.example.com"}},
+		}
+
+		require.NoError(t, s.Reload(updated))
+		keys.Update(updated.JWTKey, updated.JWTPreviousKeys...)
+
+		resp := list(oldToken)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "https://social.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "10", resp.Header.Get("RateLimit-Limit"))
+
+		newToken, err := jwt.MakeToken("1", []role.Role{role.User}, jwt.NewKeys("new-key"))
+		require.NoError(t, err)
+
+		resp = list(newToken)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test invalid settings are rejected", func(t *testing.T) {
+		invalid := &config.ServerConfig{
+			JWTKey:     "new-key",
+			RateLimits: []*config.RateLimitConfig{{Route: "/profiles", Burst: 1, PerMinute: 1}},
+			CORS:       &config.CORSConfig{AllowOrigins: []string{"https://other.example.com"}},
+		}
+
+		assert.Error(t, s.Reload(invalid))
+
+		resp := list(oldToken)
+		assert.Equal(t, "https://social.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "10", resp.Header.Get("RateLimit-Limit"))
+	})
+
+	t.Run("test removed key is no longer accepted", func(t *testing.T) {
+		keys.Update("new-key")
+
+		resp := list(oldToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
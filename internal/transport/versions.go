@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/account"
+	"github.com/Lucky112/social/internal/transport/admin"
+	"github.com/Lucky112/social/internal/transport/auth"
+	"github.com/Lucky112/social/internal/transport/interests"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/photos"
+	"github.com/Lucky112/social/internal/transport/profiles"
+	"github.com/Lucky112/social/internal/transport/webhooks"
+)
+
+// Префикс маршрутов первой версии API.
+// Маршруты без префикса - устаревшие синонимы маршрутов первой версии
+const v1Prefix = "/api/v1"
+
+// Дата, с которой маршруты без версии считаются устаревшими
+var legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Дата отключения маршрутов без версии, если она не задана в конфигурации
+var defaultLegacySunset = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+
+// Маршруты первой версии API.
+//
+// Версии API различаются обработчиками, но используют одни и те же сервисы.
+// Для несовместимых изменений рядом с пакетами обработчиков v1 создаются пакеты v2
+// (например, profiles/v2) с собственными моделями запросов и ответов, а в NewServer
+// регистрируется группа v2Routes с префиксом /api/v2. Маршруты, не изменившиеся во второй версии,
+// регистрируются в ней с обработчиками первой
+type v1Routes struct {
+	jwtKeys  *jwt.Keys
+	sessions jwt.SessionValidator
+
+	auth      auth.AuthHandler
+	profiles  profiles.ProfilesHandler
+	admin     admin.AdminHandler
+	account   account.AccountHandler
+	webhooks  webhooks.WebhooksHandler
+	photos    photos.PhotosHandler
+	interests interests.InterestsHandler
+}
+
+func (r v1Routes) register(router fiber.Router) {
+	publicGroup := router.Group("")
+	publicGroup.Post("/register", r.auth.Register)
+	publicGroup.Post("/login", r.auth.Login)
+	publicGroup.Post("/login/mfa", r.auth.LoginMFA)
+
+	authorizedGroup := router.Group("")
+	authorizedGroup.Use(jwt.Middleware(r.jwtKeys), jwt.RejectRevoked(r.sessions))
+
+	authorizedGroup.Post("/mfa/totp", r.auth.EnrollTOTP)
+	authorizedGroup.Post("/mfa/totp/confirm", r.auth.ConfirmTOTP)
+
+	authorizedGroup.Post("/me/password", r.account.ChangePassword)
+	authorizedGroup.Delete("/me", r.account.DeleteAccount)
+	authorizedGroup.Get("/me/export", r.account.Export)
+
+	authorizedGroup.Post("/profiles", jwt.RequirePermission(role.WriteProfiles), r.profiles.CreateProfile)
+	authorizedGroup.Get("/profiles", jwt.RequirePermission(role.ReadProfiles), etag.New(), r.profiles.GetProfiles)
+	authorizedGroup.Get("/profiles/search", jwt.RequirePermission(role.ReadProfiles), r.profiles.SearchProfile)
+	authorizedGroup.Get("/profiles/:id", jwt.RequirePermission(role.ReadProfiles), etag.New(), r.profiles.GetProfileById)
+	authorizedGroup.Get("/profiles/:id/privacy", jwt.RequirePermission(role.ReadProfiles), r.profiles.GetPrivacy)
+	authorizedGroup.Put("/profiles/:id/privacy", jwt.RequirePermission(role.WriteProfiles), r.profiles.SetPrivacy)
+	authorizedGroup.Get("/profiles/:id/similar", jwt.RequirePermission(role.ReadProfiles), r.profiles.GetSimilarProfiles)
+	authorizedGroup.Post("/profiles/:id/photos", jwt.RequirePermission(role.WriteProfiles), r.photos.UploadPhoto)
+
+	authorizedGroup.Get("/photos/:id/:size", jwt.RequirePermission(role.ReadProfiles), r.photos.GetPhoto)
+
+	authorizedGroup.Get("/interests/autocomplete", jwt.RequirePermission(role.ReadProfiles), r.interests.Autocomplete)
+
+	webhooksGroup := authorizedGroup.Group("/webhooks", jwt.RequirePermission(role.ManageWebhooks))
+	webhooksGroup.Post("", r.webhooks.CreateWebhook)
+	webhooksGroup.Get("", r.webhooks.ListWebhooks)
+	webhooksGroup.Get("/:id", r.webhooks.GetWebhook)
+	webhooksGroup.Put("/:id", r.webhooks.UpdateWebhook)
+	webhooksGroup.Delete("/:id", r.webhooks.DeleteWebhook)
+	webhooksGroup.Get("/:id/deliveries", r.webhooks.ListDeliveries)
+	webhooksGroup.Post("/:id/deliveries/:deliveryId/redeliver", r.webhooks.Redeliver)
+
+	adminGroup := authorizedGroup.Group("/admin")
+	adminGroup.Get("/users", jwt.RequirePermission(role.ManageUsers), r.admin.ListUsers)
+	adminGroup.Post("/users/:id/disable", jwt.RequirePermission(role.ManageUsers), r.admin.DisableUser)
+	adminGroup.Post("/users/:id/enable", jwt.RequirePermission(role.ManageUsers), r.admin.EnableUser)
+	adminGroup.Post("/users/:id/password/reset", jwt.RequirePermission(role.ManageUsers), r.admin.ResetPassword)
+	adminGroup.Delete("/profiles/:id", jwt.RequirePermission(role.ManageProfiles), r.admin.DeleteProfile)
+}
+
+// Middleware устаревших маршрутов: добавляет заголовки Deprecation (RFC 9745), Sunset (RFC 8594)
+// и ссылку на тот же маршрут в актуальной версии
+func deprecated(successorPrefix string, sunset time.Time) fiber.Handler {
+	deprecation := fmt.Sprintf("@%d", legacyDeprecatedAt.Unix())
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *fiber.Ctx) error {
+		// сюда доходят и запросы к несуществующим маршрутам с версией: они не устаревшие
+		if strings.HasPrefix(c.Path(), successorPrefix+"/") {
+			return c.Next()
+		}
+
+		c.Set("Deprecation", deprecation)
+		c.Set("Sunset", sunsetHeader)
+		c.Set(fiber.HeaderLink, fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Path()))
+
+		return c.Next()
+	}
+}
+
+func legacySunset(date string) (time.Time, error) {
+	if date == "" {
+		return defaultLegacySunset, nil
+	}
+
+	sunset, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing legacy routes sunset date: %v", err)
+	}
+
+	return sunset, nil
+}
@@ -0,0 +1,217 @@
+package webhooks
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+)
+
+// Число доставок в журнале по умолчанию и наибольшее
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 100
+)
+
+// Обработчик HTTP-запросов на управление подписками на события
+type WebhooksHandler struct {
+	service  WebhooksService
+	validate *validator.Validate
+}
+
+func NewWebhooksHandler(service WebhooksService) WebhooksHandler {
+	return WebhooksHandler{
+		service:  service,
+		validate: problem.NewValidator(),
+	}
+}
+
+// Обработчик HTTP-запросов на создание подписки
+func (h *WebhooksHandler) CreateWebhook(c *fiber.Ctx) error {
+	req, err := h.parseRequest(c)
+	if err != nil {
+		return err
+	}
+
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	w, err := h.service.Create(c.Context(), actor, req.toModel(""))
+	if err != nil {
+		return fmt.Errorf("creating webhook: %w", err)
+	}
+
+	err = c.Status(fiber.StatusCreated).JSON(createdWebhook{
+		webhook: fromModel(w),
+		Secret:  w.Secret,
+	})
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на список подписок
+func (h *WebhooksHandler) ListWebhooks(c *fiber.Ctx) error {
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := h.service.List(c.Context(), actor)
+	if err != nil {
+		return fmt.Errorf("listing webhooks: %w", err)
+	}
+
+	payload := make([]webhook, len(webhooks))
+	for i, w := range webhooks {
+		payload[i] = fromModel(w)
+	}
+
+	err = c.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на конкретную подписку
+func (h *WebhooksHandler) GetWebhook(c *fiber.Ctx) error {
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	w, err := h.service.Get(c.Context(), actor, c.Params("id"))
+	if err != nil {
+		return fmt.Errorf("finding webhook: %w", err)
+	}
+
+	err = c.JSON(fromModel(w))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на изменение подписки
+func (h *WebhooksHandler) UpdateWebhook(c *fiber.Ctx) error {
+	req, err := h.parseRequest(c)
+	if err != nil {
+		return err
+	}
+
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	w, err := h.service.Update(c.Context(), actor, req.toModel(c.Params("id")))
+	if err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+
+	err = c.JSON(fromModel(w))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на удаление подписки
+func (h *WebhooksHandler) DeleteWebhook(c *fiber.Ctx) error {
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.Delete(c.Context(), actor, c.Params("id"))
+	if err != nil {
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+
+	c.Status(fiber.StatusNoContent)
+	return nil
+}
+
+// Обработчик HTTP-запросов на журнал доставок по подписке, начиная с последних
+func (h *WebhooksHandler) ListDeliveries(c *fiber.Ctx) error {
+	limit := defaultDeliveriesLimit
+	if v := c.Query("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxDeliveriesLimit {
+			detail := fmt.Sprintf("limit must be an integer from 1 to %d", maxDeliveriesLimit)
+			return problem.New(fiber.StatusBadRequest, problem.CodeValidationFailed, detail)
+		}
+	}
+
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.service.Deliveries(c.Context(), actor, c.Params("id"), limit)
+	if err != nil {
+		return fmt.Errorf("listing deliveries: %w", err)
+	}
+
+	payload := make([]delivery, len(deliveries))
+	for i, d := range deliveries {
+		payload[i] = fromDeliveryModel(d)
+	}
+
+	err = c.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+// Обработчик HTTP-запросов на повторную отправку доставки
+func (h *WebhooksHandler) Redeliver(c *fiber.Ctx) error {
+	actor, err := jwt.ExtractActor(c)
+	if err != nil {
+		return err
+	}
+
+	d, err := h.service.Redeliver(c.Context(), actor, c.Params("id"), c.Params("deliveryId"))
+	if err != nil {
+		return fmt.Errorf("redelivering: %w", err)
+	}
+
+	err = c.Status(fiber.StatusAccepted).JSON(fromDeliveryModel(d))
+	if err != nil {
+		return fmt.Errorf("sending response: %v", err)
+	}
+
+	return nil
+}
+
+func (h *WebhooksHandler) parseRequest(c *fiber.Ctx) (*webhookRequest, error) {
+	var req webhookRequest
+
+	err := c.BodyParser(&req)
+	if err != nil {
+		return nil, problem.InvalidBody(err)
+	}
+
+	err = h.validate.Struct(req)
+	if err != nil {
+		return nil, problem.Validation(err)
+	}
+
+	return &req, nil
+}
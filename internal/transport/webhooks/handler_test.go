@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+)
+
+func TestWebhooks(t *testing.T) {
+	service := mocks.NewWebhooksService(t)
+	webhooksHandler := NewWebhooksHandler(service)
+	signingKey := jwt.NewKeys("signing-key")
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(jwt.Middleware(signingKey))
+	app.Post("/webhooks", jwt.RequirePermission(role.ManageWebhooks), webhooksHandler.CreateWebhook)
+	app.Get("/webhooks/:id", jwt.RequirePermission(role.ManageWebhooks), webhooksHandler.GetWebhook)
+	app.Put("/webhooks/:id", jwt.RequirePermission(role.ManageWebhooks), webhooksHandler.UpdateWebhook)
+	app.Get("/webhooks/:id/deliveries", jwt.RequirePermission(role.ManageWebhooks), webhooksHandler.ListDeliveries)
+	app.Post("/webhooks/:id/deliveries/:deliveryId/redeliver", jwt.RequirePermission(role.ManageWebhooks), webhooksHandler.Redeliver)
+
+	userId := "1"
+	token, err := jwt.MakeToken(userId, []role.Role{role.User}, signingKey)
+	require.NoError(t, err)
+
+	actor := models.Actor{UserId: userId, Roles: []role.Role{role.User}}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	do := func(method, url, body string) *http.Response {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Add("Content-type", "application/json")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	t.Run("test CreateWebhook returns secret", func(t *testing.T) {
+		expected := &models.Webhook{URL: "https://example.com/hook", Events: []models.EventType{models.EventProfileCreated}, Active: true}
+		created := &models.Webhook{
+			Id:        "3",
+			UserId:    userId,
+			URL:       "https://example.com/hook",
+			Events:    []models.EventType{models.EventProfileCreated},
+			Secret:    "secret",
+			Active:    true,
+			CreatedAt: createdAt,
+		}
+		service.On("Create", mock.Anything, actor, expected).Return(created, nil).Once()
+
+		resp := do("POST", "/webhooks", `{"url": "https://example.com/hook", "events": ["profile.created"]}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var payload map[string]any
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"id":         "3",
+			"user_id":    userId,
+			"url":        "https://example.com/hook",
+			"events":     []any{"profile.created"},
+			"active":     true,
+			"created_at": "2024-01-01T00:00:00Z",
+			"secret":     "secret",
+		}, payload)
+	})
+
+	t.Run("test CreateWebhook with invalid url", func(t *testing.T) {
+		resp := do("POST", "/webhooks", `{"url": "ftp://example.com/hook"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var payload problem.Problem
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, []problem.FieldError{{Field: "url", Code: "http_url", Message: "failed on the 'http_url' rule"}}, payload.Errors)
+	})
+
+	t.Run("test GetWebhook hides secret", func(t *testing.T) {
+		service.On("Get", mock.Anything, actor, "3").Return(&models.Webhook{Id: "3", Secret: "secret"}, nil).Once()
+
+		resp := do("GET", "/webhooks/3", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload map[string]any
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.NotContains(t, payload, "secret")
+	})
+
+	t.Run("test GetWebhook not found", func(t *testing.T) {
+		service.On("Get", mock.Anything, actor, "4").Return(nil, fmt.Errorf("%w", models.WebhookNotFound)).Once()
+
+		resp := do("GET", "/webhooks/4", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var payload problem.Problem
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, problem.CodeWebhookNotFound, payload.Code)
+	})
+
+	t.Run("test UpdateWebhook deactivates", func(t *testing.T) {
+		expected := &models.Webhook{Id: "3", URL: "https://example.com/new", Events: []models.EventType{}, Active: false}
+		service.On("Update", mock.Anything, actor, expected).Return(expected, nil).Once()
+
+		resp := do("PUT", "/webhooks/3", `{"url": "https://example.com/new", "active": false}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("test ListDeliveries", func(t *testing.T) {
+		deliveries := []*models.WebhookDelivery{{
+			Id:             "7",
+			WebhookId:      "3",
+			EventId:        "5",
+			EventType:      models.EventProfileCreated,
+			Payload:        json.RawMessage(`{"id":"5"}`),
+			Status:         models.DeliverySucceeded,
+			Attempts:       1,
+			ResponseStatus: 200,
+			CreatedAt:      createdAt,
+			DeliveredAt:    createdAt,
+		}}
+		service.On("Deliveries", mock.Anything, actor, "3", 10).Return(deliveries, nil).Once()
+
+		resp := do("GET", "/webhooks/3/deliveries?limit=10", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payload []map[string]any
+		err := json.NewDecoder(resp.Body).Decode(&payload)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]any{{
+			"id":              "7",
+			"webhook_id":      "3",
+			"event_id":        "5",
+			"event_type":      "profile.created",
+			"status":          "succeeded",
+			"attempts":        float64(1),
+			"response_status": float64(200),
+			"created_at":      "2024-01-01T00:00:00Z",
+			"delivered_at":    "2024-01-01T00:00:00Z",
+			"payload":         map[string]any{"id": "5"},
+		}}, payload)
+	})
+
+	t.Run("test ListDeliveries with invalid limit", func(t *testing.T) {
+		resp := do("GET", "/webhooks/3/deliveries?limit=1000", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("test Redeliver", func(t *testing.T) {
+		redelivery := &models.WebhookDelivery{Id: "8", WebhookId: "3", RedeliveryOf: "7", Status: models.DeliveryPending}
+		service.On("Redeliver", mock.Anything, actor, "3", "7").Return(redelivery, nil).Once()
+
+		resp := do("POST", "/webhooks/3/deliveries/7/redeliver", "")
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	})
+
+	t.Run("test Redeliver unknown delivery", func(t *testing.T) {
+		service.On("Redeliver", mock.Anything, actor, "3", "9").Return(nil, fmt.Errorf("%w", models.DeliveryNotFound)).Once()
+
+		resp := do("POST", "/webhooks/3/deliveries/9/redeliver", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
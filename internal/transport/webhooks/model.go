@@ -0,0 +1,110 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Структура HTTP-запроса на создание или изменение подписки
+type webhookRequest struct {
+	URL string `json:"url" validate:"required,http_url,max=2000"`
+	// Пустой список означает все события, доступные пользователю
+	Events []string `json:"events" validate:"max=20,dive,required"`
+	// По умолчанию подписка активна
+	Active *bool `json:"active"`
+}
+
+// Подписка. Секрет подписи отдается только при создании
+type webhook struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Структура HTTP-ответа на создание подписки
+type createdWebhook struct {
+	webhook
+	Secret string `json:"secret"`
+}
+
+// Доставка события по подписке
+type delivery struct {
+	Id             string          `json:"id"`
+	WebhookId      string          `json:"webhook_id"`
+	EventId        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	RedeliveryOf   string          `json:"redelivery_of,omitempty"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func (r *webhookRequest) toModel(id string) *models.Webhook {
+	events := make([]models.EventType, len(r.Events))
+	for i, e := range r.Events {
+		events[i] = models.EventType(e)
+	}
+
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return &models.Webhook{
+		Id:     id,
+		URL:    r.URL,
+		Events: events,
+		Active: active,
+	}
+}
+
+func fromModel(w *models.Webhook) webhook {
+	events := make([]string, len(w.Events))
+	for i, e := range w.Events {
+		events[i] = string(e)
+	}
+
+	return webhook{
+		Id:        w.Id,
+		UserId:    w.UserId,
+		URL:       w.URL,
+		Events:    events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func fromDeliveryModel(d *models.WebhookDelivery) delivery {
+	res := delivery{
+		Id:             d.Id,
+		WebhookId:      d.WebhookId,
+		EventId:        d.EventId,
+		EventType:      string(d.EventType),
+		RedeliveryOf:   d.RedeliveryOf,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		Payload:        d.Payload,
+	}
+
+	if d.Status == models.DeliveryPending {
+		res.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.DeliveredAt.IsZero() {
+		res.DeliveredAt = &d.DeliveredAt
+	}
+
+	return res
+}
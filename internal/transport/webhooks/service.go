@@ -0,0 +1,18 @@
+package webhooks
+
+import (
+	"context"
+
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Сервис подписок на события
+type WebhooksService interface {
+	Create(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error)
+	List(ctx context.Context, actor models.Actor) ([]*models.Webhook, error)
+	Get(ctx context.Context, actor models.Actor, id string) (*models.Webhook, error)
+	Update(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error)
+	Delete(ctx context.Context, actor models.Actor, id string) error
+	Deliveries(ctx context.Context, actor models.Actor, id string, limit int) ([]*models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, actor models.Actor, webhookId, deliveryId string) (*models.WebhookDelivery, error)
+}
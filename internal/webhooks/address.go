@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// Адрес подписки указывает на внутреннюю сеть
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// Разрешение имен хостов. Подходит net.DefaultResolver
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Проверяет, что адрес подписки не ведет во внутреннюю сеть: все адреса хоста должны быть публичными.
+// Имя хоста при доставке может разрешиться иначе, поэтому Worker повторяет проверку при подключении
+func CheckURL(ctx context.Context, resolver Resolver, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parsing url: %v", err)
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return CheckIP(ip)
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolving '%s': %v", host, err)
+	}
+
+	for _, addr := range addrs {
+		err := CheckIP(addr.IP)
+		if err != nil {
+			return fmt.Errorf("'%s' resolves to %w", host, err)
+		}
+	}
+
+	return nil
+}
+
+// Отклоняет loopback, частные, link-local и неопределенные адреса, в том числе IPv4, записанные как IPv6
+func CheckIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s: %w", ip, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+// Проверка адреса непосредственно перед подключением, для net.Dialer.Control
+func dialControl(check func(net.IP) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("parsing address '%s': %v", address, err)
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("'%s' is not an ip address", host)
+		}
+
+		return check(ip)
+	}
+}
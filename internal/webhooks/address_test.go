@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host '%s'", host)
+	}
+
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+
+	return addrs, nil
+}
+
+func TestCheckIP(t *testing.T) {
+	blocked := map[string][]string{
+		"loopback":    {"127.0.0.1", "127.1.2.3", "::1", "::ffff:127.0.0.1"},
+		"private":     {"10.0.0.1", "172.16.0.1", "172.31.255.255", "192.168.1.1", "fc00::1", "fd12:3456::1", "::ffff:10.0.0.1"},
+		"link-local":  {"169.254.169.254", "fe80::1", "224.0.0.1", "ff02::1"},
+		"unspecified": {"0.0.0.0", "::"},
+	}
+
+	for name, ips := range blocked {
+		t.Run(name, func(t *testing.T) {
+			for _, ip := range ips {
+				assert.ErrorIs(t, CheckIP(net.ParseIP(ip)), ErrForbiddenAddress, ip)
+			}
+		})
+	}
+
+	t.Run("public", func(t *testing.T) {
+		for _, ip := range []string{"93.184.215.14", "8.8.8.8", "172.32.0.1", "2606:4700::1111"} {
+			assert.NoError(t, CheckIP(net.ParseIP(ip)), ip)
+		}
+	})
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	resolver := fakeResolver{
+		"example.com": {"93.184.215.14"},
+		"localhost":   {"127.0.0.1", "::1"},
+		"mixed.com":   {"93.184.215.14", "10.0.0.1"},
+	}
+
+	assert.NoError(t, CheckURL(ctx, resolver, "https://example.com/hook"))
+	assert.NoError(t, CheckURL(ctx, resolver, "https://93.184.215.14:8443/hook"))
+
+	assert.ErrorIs(t, CheckURL(ctx, resolver, "http://localhost:8080/hook"), ErrForbiddenAddress)
+	assert.ErrorIs(t, CheckURL(ctx, resolver, "http://[::1]/hook"), ErrForbiddenAddress)
+	assert.ErrorIs(t, CheckURL(ctx, resolver, "http://169.254.169.254/latest"), ErrForbiddenAddress)
+	// достаточно одного внутреннего адреса среди разрешенных
+	assert.ErrorIs(t, CheckURL(ctx, resolver, "https://mixed.com/hook"), ErrForbiddenAddress)
+
+	// неразрешимое имя тоже отклоняется
+	assert.Error(t, CheckURL(ctx, resolver, "https://unknown.com/hook"))
+}
+
+func TestDialControl(t *testing.T) {
+	control := dialControl(CheckIP)
+
+	assert.NoError(t, control("tcp4", "93.184.215.14:443", nil))
+	assert.ErrorIs(t, control("tcp4", "127.0.0.1:80", nil), ErrForbiddenAddress)
+	assert.ErrorIs(t, control("tcp6", "[fe80::1]:80", nil), ErrForbiddenAddress)
+	assert.Error(t, control("tcp4", "garbage", nil))
+}
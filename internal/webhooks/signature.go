@@ -0,0 +1,84 @@
+// Пакет webhooks отправляет доставки событий по подпискам пользователей
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса с доставкой. Кроме них запрос содержит заголовки events.HeaderEventId и events.HeaderEventType
+const (
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Версия схемы подписи в заголовке HeaderSignature
+const signatureScheme = "v1"
+
+var (
+	ErrMalformedSignature = errors.New("malformed signature header")
+	ErrSignatureMismatch  = errors.New("signature mismatch")
+	ErrSignatureExpired   = errors.New("signature timestamp is outside of tolerance")
+)
+
+// Значение заголовка HeaderSignature: "t=<unix-время>,v1=<hex HMAC-SHA256 от "<unix-время>.<тело>">".
+// Время входит в подпись, чтобы получатель мог отклонить повтор старого запроса
+func Sign(secret string, body []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,%s=%s", t, signatureScheme, sign(secret, t, body))
+}
+
+// Проверяет подпись запроса на стороне получателя. Подпись, созданная раньше или позже now
+// больше чем на tolerance, отклоняется. Нулевой tolerance отключает проверку времени
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, signature string
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+
+		switch key {
+		case "t":
+			t = value
+		case signatureScheme:
+			signature = value
+		}
+	}
+
+	if t == "" || signature == "" {
+		return ErrMalformedSignature
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrMalformedSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, t, body))) {
+		return ErrSignatureMismatch
+	}
+
+	diff := now.Sub(time.Unix(unix, 0))
+	if tolerance > 0 && (diff > tolerance || diff < -tolerance) {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+func sign(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,25 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	at := time.Unix(1700000000, 0)
+	header := Sign("secret", body, at)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify("secret", header, body, time.Minute, at.Add(30*time.Second)))
+	assert.NoError(t, Verify("secret", header, body, 0, at.Add(time.Hour)))
+
+	assert.ErrorIs(t, Verify("other", header, body, time.Minute, at), ErrSignatureMismatch)
+	assert.ErrorIs(t, Verify("secret", header, []byte(`{"id":"2"}`), time.Minute, at), ErrSignatureMismatch)
+	assert.ErrorIs(t, Verify("secret", header, body, time.Minute, at.Add(2*time.Minute)), ErrSignatureExpired)
+	assert.ErrorIs(t, Verify("secret", "v1=abc", body, time.Minute, at), ErrMalformedSignature)
+	assert.ErrorIs(t, Verify("secret", "garbage", body, time.Minute, at), ErrMalformedSignature)
+}
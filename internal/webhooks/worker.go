@@ -0,0 +1,223 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Lucky112/social/internal/events"
+	"github.com/Lucky112/social/internal/models"
+)
+
+// Доставки, ожидающие отправки
+type Storage interface {
+	// Занимает не больше limit доставок, время отправки которых наступило, до leaseUntil,
+	// чтобы до этого времени их не отправил другой экземпляр приложения
+	ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]*models.OutgoingDelivery, error)
+	// Сохраняет результат попытки доставки
+	RecordAttempt(ctx context.Context, deliveryId string, attempt models.DeliveryAttempt) error
+}
+
+type TxManager interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type WorkerConfig struct {
+	// Интервал опроса доставок, если в прошлый раз отправлены все готовые доставки
+	PollInterval time.Duration
+	// Наибольшее число доставок, занимаемых за раз
+	BatchSize int
+	// Число попыток, после которого доставка считается неудавшейся
+	MaxAttempts int
+	// Задержка перед первой повторной попыткой. Удваивается с каждой попыткой, но не превышает MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Время ожидания ответа получателя
+	Timeout time.Duration
+}
+
+// Отправляет доставки запросами POST, подписанными секретом подписки.
+// Доставка успешна, если получатель ответил статусом 2xx, иначе она повторяется
+// с экспоненциально растущей задержкой, пока не закончатся попытки
+type Worker struct {
+	storage Storage
+	tx      TxManager
+	client  *http.Client
+	cfg     WorkerConfig
+	now     func() time.Time
+}
+
+// Запросы во внутреннюю сеть не отправляются (см. CheckIP), даже если адрес подписки
+// разрешается в нее уже после создания подписки
+func NewWorker(storage Storage, tx TxManager, cfg WorkerConfig) Worker {
+	return newWorker(storage, tx, cfg, CheckIP)
+}
+
+// check проверяет адрес, к которому подключается Worker
+func newWorker(storage Storage, tx TxManager, cfg WorkerConfig, check func(net.IP) error) Worker {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: dialControl(check),
+	}
+
+	return Worker{
+		storage: storage,
+		tx:      tx,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// прокси не используется: через него проверка адреса при подключении теряет смысл
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: cfg.Timeout,
+				MaxIdleConnsPerHost: 2,
+			},
+			// перенаправление считается неудачной доставкой: подписан запрос на адрес подписки, а не на другой
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Отправляет доставки, пока не отменен контекст
+func (w Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := w.DeliverPending(ctx)
+		if err != nil {
+			slog.Warn("delivering webhooks", "error", err)
+		}
+
+		// полная пачка означает, что, вероятно, остались готовые доставки
+		if err == nil && n == w.cfg.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Отправляет одну пачку готовых доставок. Возвращает число отправленных доставок.
+// Доставки занимаются в короткой транзакции, запросы отправляются вне транзакций,
+// а результат каждой попытки сохраняется в своей транзакции
+func (w Worker) DeliverPending(ctx context.Context) (int, error) {
+	var deliveries []*models.OutgoingDelivery
+
+	err := w.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		deliveries, err = w.storage.ClaimDeliveries(ctx, w.cfg.BatchSize, w.now().Add(w.lease()))
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("claiming deliveries: %v", err)
+	}
+
+	n := 0
+	var errs []error
+
+	for _, d := range deliveries {
+		// неотправленные доставки снова станут готовыми, когда истечет срок их занятия
+		if ctx.Err() != nil {
+			break
+		}
+
+		n++
+
+		attempt := w.deliver(ctx, d)
+
+		err := w.tx.InTx(ctx, func(ctx context.Context) error {
+			return w.storage.RecordAttempt(ctx, d.Delivery.Id, attempt)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recording attempt of delivery '%s': %v", d.Delivery.Id, err))
+		}
+	}
+
+	return n, errors.Join(errs...)
+}
+
+// Срок, на который занимается пачка доставок: за него успевают отправиться все доставки пачки,
+// даже если каждый получатель отвечает до истечения Timeout
+func (w Worker) lease() time.Duration {
+	return time.Duration(w.cfg.BatchSize+1) * w.cfg.Timeout
+}
+
+// Отправляет доставку и возвращает результат попытки
+func (w Worker) deliver(ctx context.Context, d *models.OutgoingDelivery) models.DeliveryAttempt {
+	now := w.now()
+	attempt := models.DeliveryAttempt{At: now}
+
+	status, err := w.send(ctx, d, now)
+	attempt.ResponseStatus = status
+	if err == nil {
+		attempt.Status = models.DeliverySucceeded
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+
+	attempts := d.Delivery.Attempts + 1
+	if attempts >= w.cfg.MaxAttempts {
+		slog.Warn("webhook delivery failed", "id", d.Delivery.Id, "webhook", d.Delivery.WebhookId, "attempts", attempts, "error", err)
+		attempt.Status = models.DeliveryFailed
+		return attempt
+	}
+
+	attempt.Status = models.DeliveryPending
+	attempt.NextAttemptAt = now.Add(w.backoff(attempts))
+
+	return attempt
+}
+
+// Отправляет запрос с доставкой. Возвращает статус ответа или 0, если ответа не было
+func (w Worker) send(ctx context.Context, d *models.OutgoingDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, d.Delivery.Id)
+	req.Header.Set(HeaderSignature, Sign(d.Secret, d.Delivery.Payload, now))
+	req.Header.Set(events.HeaderEventId, d.Delivery.EventId)
+	req.Header.Set(events.HeaderEventType, string(d.Delivery.EventType))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending delivery: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// тело ответа вычитывается, чтобы соединение можно было использовать повторно
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Задержка перед повторной попыткой после attempts неудачных
+func (w Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.Backoff
+	for i := 1; i < attempts && d < w.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, w.cfg.MaxBackoff)
+}
@@ -0,0 +1,217 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/internal/events"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/storage/inmemory"
+)
+
+// Получатель доставок, проверяющий подпись и отвечающий статусом status
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	received []*http.Request
+	bodies   []string
+	errs     []error
+	// вызывается при получении запроса, до ответа
+	onRequest func()
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	if r.onRequest != nil {
+		r.onRequest()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.received = append(r.received, req)
+	r.bodies = append(r.bodies, string(body))
+	r.errs = append(r.errs, Verify(r.secret, req.Header.Get(HeaderSignature), body, time.Minute, time.Now()))
+
+	w.WriteHeader(r.status)
+}
+
+// Тестовый получатель слушает loopback, поэтому проверка адреса при подключении отключена
+func allowAll(net.IP) error { return nil }
+
+func TestWorker(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, status int, check func(net.IP) error) (*receiver, inmemory.WebhooksStorage, Worker) {
+		recv := &receiver{secret: "secret", status: status}
+		server := httptest.NewServer(recv)
+		t.Cleanup(server.Close)
+
+		db := inmemory.NewDB()
+		storage := inmemory.NewWebhooksStorage(db)
+
+		webhookId, err := storage.Add(ctx, &models.Webhook{
+			UserId: "1",
+			URL:    server.URL,
+			Events: []models.EventType{models.EventProfileCreated},
+			Secret: "secret",
+			Active: true,
+		})
+		require.NoError(t, err)
+
+		_, err = storage.AddDelivery(ctx, &models.WebhookDelivery{
+			WebhookId: webhookId,
+			EventId:   "10",
+			EventType: models.EventProfileCreated,
+			Payload:   []byte(`{"id":"10"}`),
+		})
+		require.NoError(t, err)
+
+		worker := newWorker(storage, inmemory.NewTxManager(db), WorkerConfig{
+			BatchSize:   10,
+			MaxAttempts: 2,
+			Backoff:     time.Hour,
+			MaxBackoff:  time.Hour,
+			Timeout:     time.Second,
+		}, check)
+
+		return recv, storage, worker
+	}
+
+	t.Run("signed delivery", func(t *testing.T) {
+		recv, storage, worker := setup(t, http.StatusNoContent, allowAll)
+
+		n, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		require.Len(t, recv.received, 1)
+		assert.NoError(t, recv.errs[0])
+		assert.Equal(t, `{"id":"10"}`, recv.bodies[0])
+		assert.Equal(t, "10", recv.received[0].Header.Get(events.HeaderEventId))
+		assert.Equal(t, "profile.created", recv.received[0].Header.Get(events.HeaderEventType))
+
+		deliveries, err := storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, models.DeliverySucceeded, deliveries[0].Status)
+		assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+		assert.Equal(t, recv.received[0].Header.Get(HeaderDelivery), deliveries[0].Id)
+
+		// доставленное не отправляется повторно
+		n, err = worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("retried with backoff and failed after max attempts", func(t *testing.T) {
+		recv, storage, worker := setup(t, http.StatusInternalServerError, allowAll)
+
+		now := time.Now()
+		worker.now = func() time.Time { return now }
+
+		_, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+
+		deliveries, err := storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseStatus)
+		assert.Equal(t, "receiver responded with status 500", deliveries[0].LastError)
+		assert.Equal(t, now.Add(time.Hour), deliveries[0].NextAttemptAt)
+
+		// до следующей попытки доставка не отправляется
+		n, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		// время повторной попытки наступило
+		err = storage.RecordAttempt(ctx, deliveries[0].Id, models.DeliveryAttempt{
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+		require.NoError(t, err)
+
+		_, err = worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Len(t, recv.received, 2)
+
+		deliveries, err = storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliveryFailed, deliveries[0].Status)
+	})
+
+	t.Run("redirect is not followed", func(t *testing.T) {
+		_, storage, worker := setup(t, http.StatusFound, allowAll)
+
+		_, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+
+		deliveries, err := storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, deliveries[0].ResponseStatus)
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+	})
+
+	t.Run("claimed delivery is sent outside of transaction", func(t *testing.T) {
+		recv, storage, worker := setup(t, http.StatusNoContent, allowAll)
+
+		now := time.Now()
+		worker.now = func() time.Time { return now }
+
+		// база в памяти недоступна вне транзакции, пока та выполняется
+		recv.onRequest = func() {
+			deliveries, err := storage.Deliveries(ctx, "1", 10)
+			assert.NoError(t, err)
+			assert.Equal(t, now.Add(11*time.Second), deliveries[0].NextAttemptAt)
+
+			// занятая доставка не занимается повторно
+			n, err := worker.DeliverPending(ctx)
+			assert.NoError(t, err)
+			assert.Zero(t, n)
+		}
+
+		n, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Len(t, recv.received, 1)
+
+		deliveries, err := storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliverySucceeded, deliveries[0].Status)
+	})
+
+	t.Run("internal address is rejected on connect", func(t *testing.T) {
+		recv, storage, worker := setup(t, http.StatusNoContent, CheckIP)
+
+		_, err := worker.DeliverPending(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, recv.received)
+
+		deliveries, err := storage.Deliveries(ctx, "1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+		assert.Contains(t, deliveries[0].LastError, ErrForbiddenAddress.Error())
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	w := Worker{cfg: WorkerConfig{Backoff: time.Second, MaxBackoff: 5 * time.Second}}
+
+	assert.Equal(t, time.Second, w.backoff(1))
+	assert.Equal(t, 2*time.Second, w.backoff(2))
+	assert.Equal(t, 4*time.Second, w.backoff(3))
+	assert.Equal(t, 5*time.Second, w.backoff(4))
+}
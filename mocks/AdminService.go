@@ -0,0 +1,123 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AdminService is an autogenerated mock type for the AdminService type
+type AdminService struct {
+	mock.Mock
+}
+
+// DeleteProfile provides a mock function with given fields: ctx, actorId, profileId
+func (_m *AdminService) DeleteProfile(ctx context.Context, actorId string, profileId string) error {
+	ret := _m.Called(ctx, actorId, profileId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteProfile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, actorId, profileId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListUsers provides a mock function with given fields: ctx, actorId
+func (_m *AdminService) ListUsers(ctx context.Context, actorId string) ([]*models.User, error) {
+	ret := _m.Called(ctx, actorId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []*models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.User, error)); ok {
+		return rf(ctx, actorId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.User); ok {
+		r0 = rf(ctx, actorId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, actorId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetPassword provides a mock function with given fields: ctx, actorId, userId
+func (_m *AdminService) ResetPassword(ctx context.Context, actorId string, userId string) (string, error) {
+	ret := _m.Called(ctx, actorId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, actorId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, actorId, userId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, actorId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetUserDisabled provides a mock function with given fields: ctx, actorId, userId, disabled
+func (_m *AdminService) SetUserDisabled(ctx context.Context, actorId string, userId string, disabled bool) error {
+	ret := _m.Called(ctx, actorId, userId, disabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUserDisabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, actorId, userId, disabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAdminService creates a new instance of AdminService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAdminService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AdminService {
+	mock := &AdminService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,47 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AuditStorage is an autogenerated mock type for the AuditStorage type
+type AuditStorage struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: ctx, record
+func (_m *AuditStorage) Add(ctx context.Context, record *models.AuditRecord) error {
+	ret := _m.Called(ctx, record)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.AuditRecord) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAuditStorage creates a new instance of AuditStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAuditStorage(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuditStorage {
+	mock := &AuditStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -7,6 +7,8 @@ import (
 
 	models "github.com/Lucky112/social/internal/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // AuthService is an autogenerated mock type for the AuthService type
@@ -14,27 +16,77 @@ type AuthService struct {
 	mock.Mock
 }
 
-// Login provides a mock function with given fields: ctx, login, password
-func (_m *AuthService) Login(ctx context.Context, login string, password string) (string, error) {
-	ret := _m.Called(ctx, login, password)
+// ConfirmTOTP provides a mock function with given fields: ctx, userId, code
+func (_m *AuthService) ConfirmTOTP(ctx context.Context, userId string, code string) error {
+	ret := _m.Called(ctx, userId, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userId, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnrollTOTP provides a mock function with given fields: ctx, userId
+func (_m *AuthService) EnrollTOTP(ctx context.Context, userId string) (*models.TOTPEnrollment, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollTOTP")
+	}
+
+	var r0 *models.TOTPEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.TOTPEnrollment, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.TOTPEnrollment); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TOTPEnrollment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, identifier, password
+func (_m *AuthService) Login(ctx context.Context, identifier string, password string) (*models.User, error) {
+	ret := _m.Called(ctx, identifier, password)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Login")
 	}
 
-	var r0 string
+	var r0 *models.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, login, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, error)); ok {
+		return rf(ctx, identifier, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, login, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.User); ok {
+		r0 = rf(ctx, identifier, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, login, password)
+		r1 = rf(ctx, identifier, password)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -70,6 +122,54 @@ func (_m *AuthService) NewUser(ctx context.Context, user *models.User) (string,
 	return r0, r1
 }
 
+// ValidateSession provides a mock function with given fields: ctx, userId, issuedAt
+func (_m *AuthService) ValidateSession(ctx context.Context, userId string, issuedAt time.Time) error {
+	ret := _m.Called(ctx, userId, issuedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, userId, issuedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyMFA provides a mock function with given fields: ctx, userId, generation, code
+func (_m *AuthService) VerifyMFA(ctx context.Context, userId string, generation int, code string) (*models.User, error) {
+	ret := _m.Called(ctx, userId, generation, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyMFA")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) (*models.User, error)); ok {
+		return rf(ctx, userId, generation, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) *models.User); ok {
+		r0 = rf(ctx, userId, generation, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) error); ok {
+		r1 = rf(ctx, userId, generation, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewAuthService creates a new instance of AuthService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAuthService(t interface {
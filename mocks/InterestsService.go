@@ -0,0 +1,60 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/Lucky112/social/internal/models"
+)
+
+// InterestsService is an autogenerated mock type for the InterestsService type
+type InterestsService struct {
+	mock.Mock
+}
+
+// Autocomplete provides a mock function with given fields: ctx, viewer, prefix, limit
+func (_m *InterestsService) Autocomplete(ctx context.Context, viewer models.Actor, prefix string, limit int) ([]models.InterestCount, error) {
+	ret := _m.Called(ctx, viewer, prefix, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Autocomplete")
+	}
+
+	var r0 []models.InterestCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) ([]models.InterestCount, error)); ok {
+		return rf(ctx, viewer, prefix, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) []models.InterestCount); ok {
+		r0 = rf(ctx, viewer, prefix, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.InterestCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, int) error); ok {
+		r1 = rf(ctx, viewer, prefix, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewInterestsService creates a new instance of InterestsService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewInterestsService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *InterestsService {
+	mock := &InterestsService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
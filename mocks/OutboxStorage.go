@@ -0,0 +1,47 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OutboxStorage is an autogenerated mock type for the OutboxStorage type
+type OutboxStorage struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: ctx, event
+func (_m *OutboxStorage) Add(ctx context.Context, event *models.Event) error {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Event) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewOutboxStorage creates a new instance of OutboxStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOutboxStorage(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OutboxStorage {
+	mock := &OutboxStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,98 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PhotosService is an autogenerated mock type for the PhotosService type
+type PhotosService struct {
+	mock.Mock
+}
+
+// Open provides a mock function with given fields: ctx, viewer, photoId, variant
+func (_m *PhotosService) Open(ctx context.Context, viewer models.Actor, photoId string, variant string) (*models.PhotoVariant, []byte, error) {
+	ret := _m.Called(ctx, viewer, photoId, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Open")
+	}
+
+	var r0 *models.PhotoVariant
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, string) (*models.PhotoVariant, []byte, error)); ok {
+		return rf(ctx, viewer, photoId, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, string) *models.PhotoVariant); ok {
+		r0 = rf(ctx, viewer, photoId, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PhotoVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, string) []byte); ok {
+		r1 = rf(ctx, viewer, photoId, variant)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, models.Actor, string, string) error); ok {
+		r2 = rf(ctx, viewer, photoId, variant)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Upload provides a mock function with given fields: ctx, actor, profileId, data
+func (_m *PhotosService) Upload(ctx context.Context, actor models.Actor, profileId string, data []byte) (*models.Photo, error) {
+	ret := _m.Called(ctx, actor, profileId, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upload")
+	}
+
+	var r0 *models.Photo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, []byte) (*models.Photo, error)); ok {
+		return rf(ctx, actor, profileId, data)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, []byte) *models.Photo); ok {
+		r0 = rf(ctx, actor, profileId, data)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Photo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, []byte) error); ok {
+		r1 = rf(ctx, actor, profileId, data)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPhotosService creates a new instance of PhotosService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPhotosService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PhotosService {
+	mock := &PhotosService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
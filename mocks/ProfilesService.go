@@ -42,9 +42,9 @@ func (_m *ProfilesService) Add(ctx context.Context, profile *models.Profile) (st
 	return r0, r1
 }
 
-// Get provides a mock function with given fields: ctx, id
-func (_m *ProfilesService) Get(ctx context.Context, id string) (*models.Profile, error) {
-	ret := _m.Called(ctx, id)
+// Get provides a mock function with given fields: ctx, viewer, id
+func (_m *ProfilesService) Get(ctx context.Context, viewer models.Actor, id string) (*models.Profile, error) {
+	ret := _m.Called(ctx, viewer, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
@@ -52,19 +52,19 @@ func (_m *ProfilesService) Get(ctx context.Context, id string) (*models.Profile,
 
 	var r0 *models.Profile
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Profile, error)); ok {
-		return rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) (*models.Profile, error)); ok {
+		return rf(ctx, viewer, id)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Profile); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) *models.Profile); ok {
+		r0 = rf(ctx, viewer, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Profile)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, id)
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string) error); ok {
+		r1 = rf(ctx, viewer, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -72,9 +72,9 @@ func (_m *ProfilesService) Get(ctx context.Context, id string) (*models.Profile,
 	return r0, r1
 }
 
-// GetAll provides a mock function with given fields: ctx
-func (_m *ProfilesService) GetAll(ctx context.Context) ([]*models.Profile, error) {
-	ret := _m.Called(ctx)
+// GetAll provides a mock function with given fields: ctx, viewer
+func (_m *ProfilesService) GetAll(ctx context.Context, viewer models.Actor) ([]*models.Profile, error) {
+	ret := _m.Called(ctx, viewer)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
@@ -82,19 +82,19 @@ func (_m *ProfilesService) GetAll(ctx context.Context) ([]*models.Profile, error
 
 	var r0 []*models.Profile
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]*models.Profile, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor) ([]*models.Profile, error)); ok {
+		return rf(ctx, viewer)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []*models.Profile); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor) []*models.Profile); ok {
+		r0 = rf(ctx, viewer)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Profile)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor) error); ok {
+		r1 = rf(ctx, viewer)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -102,9 +102,39 @@ func (_m *ProfilesService) GetAll(ctx context.Context) ([]*models.Profile, error
 	return r0, r1
 }
 
-// Search provides a mock function with given fields: ctx, params
-func (_m *ProfilesService) Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error) {
-	ret := _m.Called(ctx, params)
+// Privacy provides a mock function with given fields: ctx, actor, profileId
+func (_m *ProfilesService) Privacy(ctx context.Context, actor models.Actor, profileId string) (*models.Privacy, error) {
+	ret := _m.Called(ctx, actor, profileId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Privacy")
+	}
+
+	var r0 *models.Privacy
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) (*models.Privacy, error)); ok {
+		return rf(ctx, actor, profileId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) *models.Privacy); ok {
+		r0 = rf(ctx, actor, profileId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Privacy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string) error); ok {
+		r1 = rf(ctx, actor, profileId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, viewer, params
+func (_m *ProfilesService) Search(ctx context.Context, viewer models.Actor, params *models.SearchParams) ([]*models.Profile, error) {
+	ret := _m.Called(ctx, viewer, params)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Search")
@@ -112,19 +142,79 @@ func (_m *ProfilesService) Search(ctx context.Context, params *models.SearchPara
 
 	var r0 []*models.Profile
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.SearchParams) ([]*models.Profile, error)); ok {
-		return rf(ctx, params)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.SearchParams) ([]*models.Profile, error)); ok {
+		return rf(ctx, viewer, params)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *models.SearchParams) []*models.Profile); ok {
-		r0 = rf(ctx, params)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.SearchParams) []*models.Profile); ok {
+		r0 = rf(ctx, viewer, params)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Profile)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *models.SearchParams) error); ok {
-		r1 = rf(ctx, params)
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, *models.SearchParams) error); ok {
+		r1 = rf(ctx, viewer, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetPrivacy provides a mock function with given fields: ctx, actor, profileId, fields
+func (_m *ProfilesService) SetPrivacy(ctx context.Context, actor models.Actor, profileId string, fields map[models.ProfileField]models.Visibility) (*models.Privacy, error) {
+	ret := _m.Called(ctx, actor, profileId, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPrivacy")
+	}
+
+	var r0 *models.Privacy
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, map[models.ProfileField]models.Visibility) (*models.Privacy, error)); ok {
+		return rf(ctx, actor, profileId, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, map[models.ProfileField]models.Visibility) *models.Privacy); ok {
+		r0 = rf(ctx, actor, profileId, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Privacy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, map[models.ProfileField]models.Visibility) error); ok {
+		r1 = rf(ctx, actor, profileId, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Similar provides a mock function with given fields: ctx, viewer, profileId, limit
+func (_m *ProfilesService) Similar(ctx context.Context, viewer models.Actor, profileId string, limit int) ([]*models.ProfileMatch, error) {
+	ret := _m.Called(ctx, viewer, profileId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Similar")
+	}
+
+	var r0 []*models.ProfileMatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) ([]*models.ProfileMatch, error)); ok {
+		return rf(ctx, viewer, profileId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) []*models.ProfileMatch); ok {
+		r0 = rf(ctx, viewer, profileId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.ProfileMatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, int) error); ok {
+		r1 = rf(ctx, viewer, profileId, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -42,6 +42,42 @@ func (_m *ProfilesStorage) Add(ctx context.Context, profile *models.Profile) (st
 	return r0, r1
 }
 
+// Delete provides a mock function with given fields: ctx, id
+func (_m *ProfilesStorage) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUser provides a mock function with given fields: ctx, userId
+func (_m *ProfilesStorage) DeleteByUser(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: ctx, id
 func (_m *ProfilesStorage) Get(ctx context.Context, id string) (*models.Profile, error) {
 	ret := _m.Called(ctx, id)
@@ -102,6 +138,36 @@ func (_m *ProfilesStorage) GetAll(ctx context.Context) ([]*models.Profile, error
 	return r0, r1
 }
 
+// GetByUser provides a mock function with given fields: ctx, userId
+func (_m *ProfilesStorage) GetByUser(ctx context.Context, userId string) ([]*models.Profile, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByUser")
+	}
+
+	var r0 []*models.Profile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.Profile, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Profile); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Profile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Search provides a mock function with given fields: ctx, params
 func (_m *ProfilesStorage) Search(ctx context.Context, params *models.SearchParams) ([]*models.Profile, error) {
 	ret := _m.Called(ctx, params)
@@ -7,6 +7,8 @@ import (
 
 	models "github.com/Lucky112/social/internal/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // UsersStorage is an autogenerated mock type for the UsersStorage type
@@ -42,27 +44,27 @@ func (_m *UsersStorage) Add(ctx context.Context, user *models.User) (string, err
 	return r0, r1
 }
 
-// Exists provides a mock function with given fields: ctx, user
-func (_m *UsersStorage) Exists(ctx context.Context, user *models.User) (bool, error) {
-	ret := _m.Called(ctx, user)
+// AddMFAAttempt provides a mock function with given fields: ctx, userId, generation, limit, window
+func (_m *UsersStorage) AddMFAAttempt(ctx context.Context, userId string, generation int, limit int, window time.Duration) (int, error) {
+	ret := _m.Called(ctx, userId, generation, limit, window)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Exists")
+		panic("no return value specified for AddMFAAttempt")
 	}
 
-	var r0 bool
+	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.User) (bool, error)); ok {
-		return rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int, time.Duration) (int, error)); ok {
+		return rf(ctx, userId, generation, limit, window)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, *models.User) bool); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int, time.Duration) int); ok {
+		r0 = rf(ctx, userId, generation, limit, window)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
-		r1 = rf(ctx, user)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int, time.Duration) error); ok {
+		r1 = rf(ctx, userId, generation, limit, window)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -70,14 +72,110 @@ func (_m *UsersStorage) Exists(ctx context.Context, user *models.User) (bool, er
 	return r0, r1
 }
 
-// Get provides a mock function with given fields: ctx, userId
-func (_m *UsersStorage) Get(ctx context.Context, userId string) (*models.User, error) {
+// Delete provides a mock function with given fields: ctx, userId
+func (_m *UsersStorage) Delete(ctx context.Context, userId string) error {
 	ret := _m.Called(ctx, userId)
 
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableTOTP provides a mock function with given fields: ctx, userId
+func (_m *UsersStorage) EnableTOTP(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, identifier
+func (_m *UsersStorage) Get(ctx context.Context, identifier string) (*models.User, error) {
+	ret := _m.Called(ctx, identifier)
+
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
 	}
 
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.User, error)); ok {
+		return rf(ctx, identifier)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.User); ok {
+		r0 = rf(ctx, identifier)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, identifier)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAll provides a mock function with given fields: ctx
+func (_m *UsersStorage) GetAll(ctx context.Context) ([]*models.User, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []*models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*models.User, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*models.User); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetById provides a mock function with given fields: ctx, userId
+func (_m *UsersStorage) GetById(ctx context.Context, userId string) (*models.User, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
 	var r0 *models.User
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.User, error)); ok {
@@ -100,6 +198,170 @@ func (_m *UsersStorage) Get(ctx context.Context, userId string) (*models.User, e
 	return r0, r1
 }
 
+// ResetMFAAttempts provides a mock function with given fields: ctx, userId
+func (_m *UsersStorage) ResetMFAAttempts(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetMFAAttempts")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeMFATokens provides a mock function with given fields: ctx, userId
+func (_m *UsersStorage) RevokeMFATokens(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeMFATokens")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDisabled provides a mock function with given fields: ctx, userId, disabled
+func (_m *UsersStorage) SetDisabled(ctx context.Context, userId string, disabled bool) error {
+	ret := _m.Called(ctx, userId, disabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDisabled")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = rf(ctx, userId, disabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPassword provides a mock function with given fields: ctx, userId, hashedPassword
+func (_m *UsersStorage) SetPassword(ctx context.Context, userId string, hashedPassword []byte) error {
+	ret := _m.Called(ctx, userId, hashedPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(ctx, userId, hashedPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetTOTP provides a mock function with given fields: ctx, userId, secret, recoveryCodes
+func (_m *UsersStorage) SetTOTP(ctx context.Context, userId string, secret []byte, recoveryCodes [][]byte) error {
+	ret := _m.Called(ctx, userId, secret, recoveryCodes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, [][]byte) error); ok {
+		r0 = rf(ctx, userId, secret, recoveryCodes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePasswordHash provides a mock function with given fields: ctx, userId, hashedPassword
+func (_m *UsersStorage) UpdatePasswordHash(ctx context.Context, userId string, hashedPassword []byte) error {
+	ret := _m.Called(ctx, userId, hashedPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(ctx, userId, hashedPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, userId, code
+func (_m *UsersStorage) UseRecoveryCode(ctx context.Context, userId string, code []byte) (bool, error) {
+	ret := _m.Called(ctx, userId, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) (bool, error)); ok {
+		return rf(ctx, userId, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) bool); ok {
+		r0 = rf(ctx, userId, code)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []byte) error); ok {
+		r1 = rf(ctx, userId, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseTOTPStep provides a mock function with given fields: ctx, userId, step
+func (_m *UsersStorage) UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error) {
+	ret := _m.Called(ctx, userId, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPStep")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) (bool, error)); ok {
+		return rf(ctx, userId, step)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) bool); ok {
+		r0 = rf(ctx, userId, step)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, userId, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUsersStorage creates a new instance of UsersStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUsersStorage(t interface {
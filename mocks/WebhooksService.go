@@ -0,0 +1,227 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhooksService is an autogenerated mock type for the WebhooksService type
+type WebhooksService struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, actor, webhook
+func (_m *WebhooksService) Create(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error) {
+	ret := _m.Called(ctx, actor, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.Webhook) (*models.Webhook, error)); ok {
+		return rf(ctx, actor, webhook)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.Webhook) *models.Webhook); ok {
+		r0 = rf(ctx, actor, webhook)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, *models.Webhook) error); ok {
+		r1 = rf(ctx, actor, webhook)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, actor, id
+func (_m *WebhooksService) Delete(ctx context.Context, actor models.Actor, id string) error {
+	ret := _m.Called(ctx, actor, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) error); ok {
+		r0 = rf(ctx, actor, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Deliveries provides a mock function with given fields: ctx, actor, id, limit
+func (_m *WebhooksService) Deliveries(ctx context.Context, actor models.Actor, id string, limit int) ([]*models.WebhookDelivery, error) {
+	ret := _m.Called(ctx, actor, id, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Deliveries")
+	}
+
+	var r0 []*models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) ([]*models.WebhookDelivery, error)); ok {
+		return rf(ctx, actor, id, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, int) []*models.WebhookDelivery); ok {
+		r0 = rf(ctx, actor, id, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, int) error); ok {
+		r1 = rf(ctx, actor, id, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, actor, id
+func (_m *WebhooksService) Get(ctx context.Context, actor models.Actor, id string) (*models.Webhook, error) {
+	ret := _m.Called(ctx, actor, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) (*models.Webhook, error)); ok {
+		return rf(ctx, actor, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string) *models.Webhook); ok {
+		r0 = rf(ctx, actor, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string) error); ok {
+		r1 = rf(ctx, actor, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, actor
+func (_m *WebhooksService) List(ctx context.Context, actor models.Actor) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx, actor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor) ([]*models.Webhook, error)); ok {
+		return rf(ctx, actor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor) []*models.Webhook); ok {
+		r0 = rf(ctx, actor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor) error); ok {
+		r1 = rf(ctx, actor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Redeliver provides a mock function with given fields: ctx, actor, webhookId, deliveryId
+func (_m *WebhooksService) Redeliver(ctx context.Context, actor models.Actor, webhookId string, deliveryId string) (*models.WebhookDelivery, error) {
+	ret := _m.Called(ctx, actor, webhookId, deliveryId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 *models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, string) (*models.WebhookDelivery, error)); ok {
+		return rf(ctx, actor, webhookId, deliveryId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, string, string) *models.WebhookDelivery); ok {
+		r0 = rf(ctx, actor, webhookId, deliveryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, string, string) error); ok {
+		r1 = rf(ctx, actor, webhookId, deliveryId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, actor, webhook
+func (_m *WebhooksService) Update(ctx context.Context, actor models.Actor, webhook *models.Webhook) (*models.Webhook, error) {
+	ret := _m.Called(ctx, actor, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.Webhook) (*models.Webhook, error)); ok {
+		return rf(ctx, actor, webhook)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Actor, *models.Webhook) *models.Webhook); ok {
+		r0 = rf(ctx, actor, webhook)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Actor, *models.Webhook) error); ok {
+		r1 = rf(ctx, actor, webhook)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWebhooksService creates a new instance of WebhooksService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhooksService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhooksService {
+	mock := &WebhooksService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
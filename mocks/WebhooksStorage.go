@@ -0,0 +1,319 @@
+// Code generated by mockery v2.43.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Lucky112/social/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhooksStorage is an autogenerated mock type for the WebhooksStorage type
+type WebhooksStorage struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: ctx, webhook
+func (_m *WebhooksStorage) Add(ctx context.Context, webhook *models.Webhook) (string, error) {
+	ret := _m.Called(ctx, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Webhook) (string, error)); ok {
+		return rf(ctx, webhook)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Webhook) string); ok {
+		r0 = rf(ctx, webhook)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Webhook) error); ok {
+		r1 = rf(ctx, webhook)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddDelivery provides a mock function with given fields: ctx, delivery
+func (_m *WebhooksStorage) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) (string, error) {
+	ret := _m.Called(ctx, delivery)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDelivery")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebhookDelivery) (string, error)); ok {
+		return rf(ctx, delivery)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebhookDelivery) string); ok {
+		r0 = rf(ctx, delivery)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebhookDelivery) error); ok {
+		r1 = rf(ctx, delivery)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *WebhooksStorage) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUser provides a mock function with given fields: ctx, userId
+func (_m *WebhooksStorage) DeleteByUser(ctx context.Context, userId string) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Deliveries provides a mock function with given fields: ctx, webhookId, limit
+func (_m *WebhooksStorage) Deliveries(ctx context.Context, webhookId string, limit int) ([]*models.WebhookDelivery, error) {
+	ret := _m.Called(ctx, webhookId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Deliveries")
+	}
+
+	var r0 []*models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*models.WebhookDelivery, error)); ok {
+		return rf(ctx, webhookId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*models.WebhookDelivery); ok {
+		r0 = rf(ctx, webhookId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, webhookId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, id
+func (_m *WebhooksStorage) Get(ctx context.Context, id string) (*models.Webhook, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Webhook, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Webhook); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAll provides a mock function with given fields: ctx
+func (_m *WebhooksStorage) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAll")
+	}
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*models.Webhook, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*models.Webhook); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByUser provides a mock function with given fields: ctx, userId
+func (_m *WebhooksStorage) GetByUser(ctx context.Context, userId string) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByUser")
+	}
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.Webhook, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Webhook); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelivery provides a mock function with given fields: ctx, webhookId, deliveryId
+func (_m *WebhooksStorage) GetDelivery(ctx context.Context, webhookId string, deliveryId string) (*models.WebhookDelivery, error) {
+	ret := _m.Called(ctx, webhookId, deliveryId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelivery")
+	}
+
+	var r0 *models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.WebhookDelivery, error)); ok {
+		return rf(ctx, webhookId, deliveryId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.WebhookDelivery); ok {
+		r0 = rf(ctx, webhookId, deliveryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, webhookId, deliveryId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Subscribed provides a mock function with given fields: ctx, t
+func (_m *WebhooksStorage) Subscribed(ctx context.Context, t models.EventType) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx, t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribed")
+	}
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.EventType) ([]*models.Webhook, error)); ok {
+		return rf(ctx, t)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.EventType) []*models.Webhook); ok {
+		r0 = rf(ctx, t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.EventType) error); ok {
+		r1 = rf(ctx, t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, webhook
+func (_m *WebhooksStorage) Update(ctx context.Context, webhook *models.Webhook) error {
+	ret := _m.Called(ctx, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Webhook) error); ok {
+		r0 = rf(ctx, webhook)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewWebhooksStorage creates a new instance of WebhooksStorage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhooksStorage(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhooksStorage {
+	mock := &WebhooksStorage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
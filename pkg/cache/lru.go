@@ -0,0 +1,148 @@
+// Пакет cache - кэши в памяти процесса
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Кэш ограниченного размера, вытесняющий давно не использованные записи.
+// Записи старше TTL считаются отсутствующими. Безопасен для одновременного использования
+type LRU[K comparable, V any] struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[K]*list.Element
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Счетчики обращений к кэшу
+type Stats struct {
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Size    int     `json:"size"`
+}
+
+// Создает кэш не более чем на size записей, живущих ttl
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+
+		var zero V
+		return zero, false
+	}
+
+	e := elem.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt) {
+		c.remove(elem)
+		c.misses.Add(1)
+
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+
+	return e.value, true
+}
+
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+
+	elem, ok := c.entries[key]
+	if ok {
+		e := elem.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		c.remove(elem)
+	}
+}
+
+// Удаляет все записи
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+
+	var hitRate float64
+	if total := hits + misses; total > 0 {
+		hitRate = float64(hits) / float64(total)
+	}
+
+	return Stats{
+		Hits:    hits,
+		Misses:  misses,
+		HitRate: hitRate,
+		Size:    size,
+	}
+}
+
+func (c *LRU[K, V]) remove(elem *list.Element) {
+	e := c.order.Remove(elem).(*entry[K, V])
+	delete(c.entries, e.key)
+}
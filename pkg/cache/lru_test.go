@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("test get and set", func(t *testing.T) {
+		c := NewLRU[string, int](2, time.Minute)
+
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+
+		c.Set("a", 1)
+		value, ok := c.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+
+		c.Set("a", 2)
+		value, _ = c.Get("a")
+		assert.Equal(t, 2, value)
+
+		assert.Equal(t, Stats{Hits: 2, Misses: 1, HitRate: 2.0 / 3, Size: 1}, c.Stats())
+	})
+
+	t.Run("test eviction of least recently used", func(t *testing.T) {
+		c := NewLRU[string, int](2, time.Minute)
+
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Get("a")
+		c.Set("c", 3)
+
+		_, ok := c.Get("b")
+		assert.False(t, ok)
+
+		_, ok = c.Get("a")
+		assert.True(t, ok)
+		_, ok = c.Get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("test expiration", func(t *testing.T) {
+		now := time.Now()
+
+		c := NewLRU[string, int](2, time.Minute)
+		c.now = func() time.Time { return now }
+
+		c.Set("a", 1)
+
+		now = now.Add(59 * time.Second)
+		_, ok := c.Get("a")
+		assert.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok = c.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Stats().Size)
+	})
+
+	t.Run("test delete and purge", func(t *testing.T) {
+		c := NewLRU[string, int](3, time.Minute)
+
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Set("c", 3)
+
+		c.Delete("a")
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+
+		c.Purge()
+		assert.Equal(t, 0, c.Stats().Size)
+		_, ok = c.Get("b")
+		assert.False(t, ok)
+	})
+
+	t.Run("test concurrent access", func(t *testing.T) {
+		c := NewLRU[int, int](10, time.Minute)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 1000; j++ {
+					c.Set(j%20, j)
+					c.Get(j % 20)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, c.Stats().Size, 10)
+	})
+}
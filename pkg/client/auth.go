@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// За сколько до истечения токен перевыпускается заранее
+const refreshLeeway = 30 * time.Second
+
+// Ошибка вызова метода, требующего входа, до вызова Login
+var ErrNotLoggedIn = errors.New("social api: not logged in")
+
+// Регистрирует пользователя и возвращает его id.
+// Если email или логин заняты, возвращает ошибку с кодом CodeUserAlreadyExists
+func (c *Client) Register(ctx context.Context, registration Registration) (string, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return "", err
+	}
+
+	var resp idResponse
+
+	err = c.do(ctx, request{
+		method:         http.MethodPost,
+		path:           "/register",
+		body:           registration,
+		idempotencyKey: key,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Id, nil
+}
+
+// Входит по логину или email и паролю. Клиент запоминает их, чтобы перевыпускать истекший или отозванный токен.
+// Если у пользователя включен TOTP, возвращает *MFARequiredError: вход завершается через LoginMFA
+func (c *Client) Login(ctx context.Context, identifier, password string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	creds := &credentials{identifier: identifier, password: password}
+
+	token, err := c.login(ctx, creds)
+	if err != nil {
+		return err
+	}
+
+	c.token = token
+	c.credentials = creds
+
+	return nil
+}
+
+// Завершает вход проверкой TOTP-кода или кода восстановления.
+// Токен, полученный таким образом, не перевыпускается автоматически
+func (c *Client) LoginMFA(ctx context.Context, mfaToken, code string) error {
+	var resp loginResponse
+
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/login/mfa",
+		body:   loginMFARequest{MFAToken: mfaToken, Code: code},
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = resp.AccessToken
+	c.credentials = nil
+
+	return nil
+}
+
+func (c *Client) login(ctx context.Context, creds *credentials) (string, error) {
+	var resp loginResponse
+
+	err := c.send(ctx, request{
+		method: http.MethodPost,
+		path:   "/login",
+		body:   loginRequest{Login: creds.identifier, Password: creds.password},
+	}, "", &resp)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.MFARequired {
+		return "", &MFARequiredError{MFAToken: resp.MFAToken}
+	}
+
+	return resp.AccessToken, nil
+}
+
+// Токен для запроса. Токен, который скоро истечет, перевыпускается заранее
+func (c *Client) validToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	if c.credentials == nil || !expiresSoon(c.token) {
+		return c.token, nil
+	}
+
+	token, err := c.login(ctx, c.credentials)
+	if err != nil {
+		return "", fmt.Errorf("refreshing token: %w", err)
+	}
+	c.token = token
+
+	return token, nil
+}
+
+func (c *Client) canRelogin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.credentials != nil
+}
+
+// Перевыпускает токен, отвергнутый сервером. Если другая горутина уже перевыпустила его, возвращает новый токен
+func (c *Client) relogin(ctx context.Context, rejected string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != rejected {
+		return c.token, nil
+	}
+
+	token, err := c.login(ctx, c.credentials)
+	if err != nil {
+		return "", fmt.Errorf("refreshing token: %w", err)
+	}
+	c.token = token
+
+	return token, nil
+}
+
+// Проверяет срок действия токена по его содержимому, не проверяя подпись:
+// подпись проверяет сервер
+func expiresSoon(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	if err != nil || claims.Exp == 0 {
+		return false
+	}
+
+	return time.Until(time.Unix(claims.Exp, 0)) < refreshLeeway
+}
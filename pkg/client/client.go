@@ -0,0 +1,290 @@
+// Пакет client - типизированный клиент HTTP API социальной сети
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Префикс маршрутов версии API, с которой работает клиент
+const apiPrefix = "/api/v1"
+
+// Параметры по умолчанию
+const (
+	defaultTimeout = 30 * time.Second
+	defaultRetries = 3
+	defaultBackoff = 200 * time.Millisecond
+)
+
+// Клиент API. Безопасен для одновременного использования из нескольких горутин
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+
+	// токен и данные для его перевыпуска
+	mu          sync.Mutex
+	token       string
+	credentials *credentials
+}
+
+type credentials struct {
+	identifier string
+	password   string
+}
+
+// Настройка клиента
+type Option func(*Client)
+
+// Задает HTTP-клиент, через который выполняются запросы
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// Задает число повторов идемпотентных запросов и начальную паузу между ними.
+// Пауза удваивается после каждой попытки
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// Задает уже полученный access-токен. Такой токен не перевыпускается автоматически
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// Создает клиент API, доступного по адресу baseURL, например http://localhost:15000
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base url: %v", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("base url '%s' must be absolute", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Текущий access-токен
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// Описание запроса к API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// готовое тело запроса, например multipart-форма, и его тип. Используется вместо body
+	rawBody     []byte
+	contentType string
+	// запрос требует access-токена
+	authorized bool
+	// значение заголовка Idempotency-Key: сервер не выполнит повтор запроса с тем же ключом дважды
+	idempotencyKey string
+}
+
+// Запросы, которые можно безопасно повторить
+func (r request) idempotent() bool {
+	return r.method == http.MethodGet || r.method == http.MethodHead || r.method == http.MethodPut || r.idempotencyKey != ""
+}
+
+// Случайный ключ идемпотентности, общий для всех попыток одного вызова метода клиента
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", fmt.Errorf("generating idempotency key: %v", err)
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// Выполняет запрос и декодирует тело ответа в out, если он не nil.
+// Если токен истек или отозван, а клиент знает логин и пароль, токен перевыпускается и запрос повторяется один раз
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	if !req.authorized {
+		return c.send(ctx, req, "", out)
+	}
+
+	token, err := c.validToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, req, token, out)
+	if !isTokenError(err) || !c.canRelogin() {
+		return err
+	}
+
+	token, err = c.relogin(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return c.send(ctx, req, token, out)
+}
+
+// Выполняет запрос, повторяя идемпотентные запросы при сетевых ошибках и временной недоступности сервера
+func (c *Client) send(ctx context.Context, req request, token string, out any) error {
+	body := req.rawBody
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return fmt.Errorf("encoding request body: %v", err)
+		}
+	}
+
+	attempts := 1
+	if req.idempotent() {
+		attempts += c.retries
+	}
+
+	backoff := c.backoff
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = c.roundTrip(ctx, req, body, token, out)
+		if !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Выполняет одну попытку запроса. Возвращает признак того, что попытку имеет смысл повторить
+func (c *Client) roundTrip(ctx context.Context, req request, body []byte, token string, out any) (bool, error) {
+	u := c.baseURL.JoinPath(apiPrefix, req.path)
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return false, fmt.Errorf("creating request: %v", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	} else if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	if req.idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", req.idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		// отмену контекста повторять бессмысленно
+		return ctx.Err() == nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		err := decodeError(resp)
+		// предыдущая попытка с тем же ключом еще выполняется: повтор получит ее ответ
+		inProgress := req.idempotencyKey != "" && errors.Is(err, ErrIdempotencyInUse)
+
+		return retryableStatus(resp.StatusCode) || inProgress, err
+	}
+
+	if out == nil {
+		return false, nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return false, fmt.Errorf("decoding response of %s %s: %v", req.method, req.path, err)
+	}
+
+	return false, nil
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Ошибки, после которых стоит перевыпустить токен
+func isTokenError(err error) bool {
+	return errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked)
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, problemContentType) || strings.HasPrefix(contentType, "application/json") {
+		// тело может оказаться не описанием ошибки, например при ответе прокси: достаточно статуса
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		apiErr.StatusCode = resp.StatusCode
+	}
+
+	if apiErr.Code == "" {
+		apiErr.Code = codeFromStatus(resp.StatusCode)
+	}
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
@@ -0,0 +1,396 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Lucky112/social/config"
+	"github.com/Lucky112/social/internal/models"
+	"github.com/Lucky112/social/internal/models/role"
+	"github.com/Lucky112/social/internal/models/sex"
+	"github.com/Lucky112/social/internal/transport"
+	"github.com/Lucky112/social/internal/transport/idempotency"
+	"github.com/Lucky112/social/internal/transport/jwt"
+	"github.com/Lucky112/social/internal/transport/problem"
+	"github.com/Lucky112/social/mocks"
+	"github.com/Lucky112/social/pkg/client"
+)
+
+// Запускает сервер API на случайном порту и возвращает его адрес
+func startServer(t *testing.T, authService *mocks.AuthService, profilesService *mocks.ProfilesService, photosService *mocks.PhotosService, interestsService *mocks.InterestsService) string {
+	cfg := &config.ServerConfig{
+		JWTKey:            "signing-key",
+		OpenAPIValidation: &config.OpenAPIValidationConfig{Requests: true},
+		Idempotency:       &config.IdempotencyConfig{TTLSeconds: 60, LockTimeoutSeconds: 60},
+	}
+
+	server, err := transport.NewServer(cfg, jwt.NewKeys(cfg.JWTKey), authService, profilesService, mocks.NewAdminService(t), mocks.NewAccountService(t), mocks.NewWebhooksService(t), photosService, interestsService, idempotency.NewMemoryStore())
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go server.Serve(ln)
+	t.Cleanup(func() { server.Shutdown() })
+
+	return fmt.Sprintf("http://%s", ln.Addr())
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	authService := mocks.NewAuthService(t)
+	profilesService := mocks.NewProfilesService(t)
+	photosService := mocks.NewPhotosService(t)
+	interestsService := mocks.NewInterestsService(t)
+	baseURL := startServer(t, authService, profilesService, photosService, interestsService)
+
+	c, err := client.New(baseURL)
+	require.NoError(t, err)
+
+	user := &models.User{Id: "1", Roles: []role.Role{role.User}}
+	profile := &models.Profile{
+		Name:      "Иван",
+		Surname:   "Иванов",
+		Sex:       sex.Male,
+		Birthdate: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+		Address:   "Москва",
+		Hobbies:   "Чтение",
+	}
+
+	t.Run("test Register", func(t *testing.T) {
+		authService.On("NewUser", mock.Anything, &models.User{Email: "user@example.com", Login: "login", Password: "password"}).Return("1", nil).Once()
+
+		id, err := c.Register(ctx, client.Registration{Email: "user@example.com", Login: "login", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, "1", id)
+	})
+
+	t.Run("test Register conflict", func(t *testing.T) {
+		authService.On("NewUser", mock.Anything, mock.Anything).Return("", &models.UserConflictError{Field: "email"}).Once()
+
+		_, err := c.Register(ctx, client.Registration{Email: "user@example.com", Login: "other", Password: "password"})
+		require.ErrorIs(t, err, client.ErrUserAlreadyExists)
+
+		var apiErr *client.Error
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+		field, ok := apiErr.Field("email")
+		require.True(t, ok)
+		assert.Equal(t, client.FieldAlreadyExists, field.Code)
+	})
+
+	t.Run("test calls before Login", func(t *testing.T) {
+		_, err := c.ListProfiles(ctx)
+		assert.ErrorIs(t, err, client.ErrNotLoggedIn)
+	})
+
+	t.Run("test Login with bad credentials", func(t *testing.T) {
+		authService.On("Login", mock.Anything, "login", "wrong").Return(nil, models.UserBadCredentials).Once()
+
+		err := c.Login(ctx, "login", "wrong")
+		assert.ErrorIs(t, err, client.ErrBadCredentials)
+	})
+
+	t.Run("test Login", func(t *testing.T) {
+		authService.On("Login", mock.Anything, "login", "password").Return(user, nil).Once()
+
+		err := c.Login(ctx, "login", "password")
+		require.NoError(t, err)
+		assert.NotEmpty(t, c.Token())
+	})
+
+	t.Run("test CreateProfile", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		expected := *profile
+		expected.UserId = "1"
+		profilesService.On("Add", mock.Anything, &expected).Return("10", nil).Once()
+
+		id, err := c.CreateProfile(ctx, &client.Profile{
+			Name:      "Иван",
+			Surname:   "Иванов",
+			Sex:       client.SexMale,
+			Birthdate: client.NewDate(1990, time.January, 2),
+			City:      "Москва",
+			Hobbies:   "Чтение",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "10", id)
+	})
+
+	expected := &client.Profile{
+		Name:      "Иван",
+		Surname:   "Иванов",
+		Sex:       client.SexMale,
+		Birthdate: client.NewDate(1990, time.January, 2),
+		City:      "Москва",
+		Hobbies:   "Чтение",
+	}
+
+	t.Run("test GetProfile", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Get", mock.Anything, mock.Anything, "10").Return(profile, nil).Once()
+
+		p, err := c.GetProfile(ctx, "10")
+		require.NoError(t, err)
+		assert.Equal(t, expected, p)
+	})
+
+	t.Run("test GetProfile not found", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Get", mock.Anything, mock.Anything, "11").Return(nil, models.ProfileNotFound).Once()
+
+		_, err := c.GetProfile(ctx, "11")
+		assert.ErrorIs(t, err, client.ErrProfileNotFound)
+	})
+
+	t.Run("test ListProfiles", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("GetAll", mock.Anything, mock.Anything).Return([]*models.Profile{profile}, nil).Once()
+
+		profiles, err := c.ListProfiles(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*client.Profile{expected}, profiles)
+	})
+
+	t.Run("test SearchProfiles", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Search", mock.Anything, mock.Anything, &models.SearchParams{NamePrefix: "Ив", SurnamePrefix: "Ив"}).Return([]*models.Profile{profile}, nil).Once()
+
+		profiles, err := c.SearchProfiles(ctx, client.SearchParams{Name: "Ив", Surname: "Ив"})
+		require.NoError(t, err)
+		assert.Equal(t, []*client.Profile{expected}, profiles)
+	})
+
+	t.Run("test SearchProfiles by interest", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Search", mock.Anything, mock.Anything, &models.SearchParams{Interest: "чтение"}).Return([]*models.Profile{profile}, nil).Once()
+
+		profiles, err := c.SearchProfiles(ctx, client.SearchParams{Interest: "чтение"})
+		require.NoError(t, err)
+		assert.Equal(t, []*client.Profile{expected}, profiles)
+	})
+
+	t.Run("test SimilarProfiles", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		other := &models.Profile{Id: "12", Name: "Петр", Interests: []string{"сериалы", "чтение"}, Hidden: []models.ProfileField{models.FieldSex, models.FieldBirthdate}}
+		matches := []*models.ProfileMatch{{Profile: other, Shared: []string{"чтение"}}}
+		profilesService.On("Similar", mock.Anything, mock.Anything, "10", 5).Return(matches, nil).Once()
+
+		similar, err := c.SimilarProfiles(ctx, "10", 5)
+		require.NoError(t, err)
+		assert.Equal(t, []*client.SimilarProfile{{
+			ProfileId:       "12",
+			SharedInterests: []string{"чтение"},
+			Profile:         &client.Profile{Name: "Петр", Interests: []string{"сериалы", "чтение"}},
+		}}, similar)
+	})
+
+	t.Run("test AutocompleteInterests", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		interestsService.On("Autocomplete", mock.Anything, mock.Anything, "чт", 10).Return([]models.InterestCount{{Name: "чтение", Profiles: 2}}, nil).Once()
+
+		suggestions, err := c.AutocompleteInterests(ctx, "чт", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []*client.InterestSuggestion{{Name: "чтение", Profiles: 2}}, suggestions)
+	})
+
+	t.Run("test UploadPhoto", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		actor := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+		photo := &models.Photo{
+			Id:        "7",
+			ProfileId: "10",
+			Variants:  []models.PhotoVariant{{Name: models.PhotoOriginal, Width: 800, Height: 600}},
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		photosService.On("Upload", mock.Anything, actor, "10", []byte("image data")).Return(photo, nil).Once()
+
+		p, err := c.UploadPhoto(ctx, "10", "photo.jpg", []byte("image data"))
+		require.NoError(t, err)
+		assert.Equal(t, &client.Photo{
+			Id:        "7",
+			ProfileId: "10",
+			URLs:      map[string]string{"original": "/api/v1/photos/7/original"},
+			Sizes:     []client.PhotoSize{{Name: "original", Width: 800, Height: 600}},
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, p)
+	})
+
+	t.Run("test UploadPhoto unsupported", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		photosService.On("Upload", mock.Anything, mock.Anything, "10", mock.Anything).Return(nil, models.UnsupportedImage).Once()
+
+		_, err := c.UploadPhoto(ctx, "10", "photo.gif", []byte("GIF89a"))
+		assert.ErrorIs(t, err, client.ErrUnsupportedImage)
+	})
+
+	t.Run("test SetPrivacy", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		actor := models.Actor{UserId: "1", Roles: []role.Role{role.User}}
+		fields := map[models.ProfileField]models.Visibility{models.FieldBirthdate: models.VisibilityHidden}
+		profilesService.On("SetPrivacy", mock.Anything, actor, "10", fields).Return(&models.Privacy{Fields: fields}, nil).Once()
+
+		privacy, err := c.SetPrivacy(ctx, "10", client.Privacy{Birthdate: client.VisibilityHidden})
+		require.NoError(t, err)
+		assert.Equal(t, client.VisibilityHidden, privacy.Birthdate)
+		assert.Equal(t, client.VisibilityRegistered, privacy.Surname)
+	})
+
+	t.Run("test GetPrivacy of another user", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("Privacy", mock.Anything, mock.Anything, "11").Return(nil, models.NotProfileOwner).Once()
+
+		_, err := c.GetPrivacy(ctx, "11")
+		assert.ErrorIs(t, err, client.ErrForbidden)
+	})
+
+	t.Run("test token refresh after revocation", func(t *testing.T) {
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(fmt.Errorf("%w", models.SessionRevoked)).Once()
+		authService.On("Login", mock.Anything, "login", "password").Return(user, nil).Once()
+		authService.On("ValidateSession", mock.Anything, "1", mock.Anything).Return(nil).Once()
+		profilesService.On("GetAll", mock.Anything, mock.Anything).Return([]*models.Profile{}, nil).Once()
+
+		profiles, err := c.ListProfiles(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, profiles)
+	})
+
+	t.Run("test Login with TOTP", func(t *testing.T) {
+		mfaUser := &models.User{Id: "2", TOTPEnabled: true}
+		authService.On("Login", mock.Anything, "mfa", "password").Return(mfaUser, nil).Once()
+		authService.On("VerifyMFA", mock.Anything, "2", 0, "123456").Return(mfaUser, nil).Once()
+
+		c, err := client.New(baseURL)
+		require.NoError(t, err)
+
+		err = c.Login(ctx, "mfa", "password")
+
+		var mfaErr *client.MFARequiredError
+		require.True(t, errors.As(err, &mfaErr))
+
+		err = c.LoginMFA(ctx, mfaErr.MFAToken, "123456")
+		require.NoError(t, err)
+		assert.NotEmpty(t, c.Token())
+	})
+}
+
+func TestClientErrorCodes(t *testing.T) {
+	codes := map[string]string{
+		client.CodeInvalidBody:        problem.CodeInvalidBody,
+		client.CodeValidationFailed:   problem.CodeValidationFailed,
+		client.CodeMalformedToken:     problem.CodeMalformedToken,
+		client.CodeInvalidToken:       problem.CodeInvalidToken,
+		client.CodeTokenRevoked:       problem.CodeTokenRevoked,
+		client.CodeForbidden:          problem.CodeForbidden,
+		client.CodeUserNotFound:       problem.CodeUserNotFound,
+		client.CodeUserAlreadyExists:  problem.CodeUserAlreadyExists,
+		client.CodeBadCredentials:     problem.CodeBadCredentials,
+		client.CodeUserDisabled:       problem.CodeUserDisabled,
+		client.CodeProfileNotFound:    problem.CodeProfileNotFound,
+		client.CodeTOTPAlreadyEnabled: problem.CodeTOTPAlreadyEnabled,
+		client.CodeTOTPNotEnrolled:    problem.CodeTOTPNotEnrolled,
+		client.CodeInvalidMFACode:     problem.CodeInvalidMFACode,
+		client.CodeInvalidMFAToken:    problem.CodeInvalidMFAToken,
+		client.CodeWebhookNotFound:    problem.CodeWebhookNotFound,
+		client.CodeDeliveryNotFound:   problem.CodeDeliveryNotFound,
+		client.CodeRateLimited:        problem.CodeRateLimited,
+		client.CodeIdempotencyInUse:   problem.CodeIdempotencyInUse,
+		client.CodeIdempotencyReused:  problem.CodeIdempotencyReused,
+		client.CodePhotoNotFound:      problem.CodePhotoNotFound,
+		client.CodePhotoTooLarge:      problem.CodePhotoTooLarge,
+		client.CodeUnsupportedImage:   problem.CodeUnsupportedImage,
+		client.CodeInternal:           problem.CodeInternal,
+	}
+
+	for clientCode, serverCode := range codes {
+		assert.Equal(t, serverCode, clientCode)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			fmt.Fprint(w, `{"id":"1"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL, client.WithToken("token"), client.WithRetries(3, time.Millisecond))
+	require.NoError(t, err)
+
+	t.Run("test idempotent call is retried", func(t *testing.T) {
+		calls.Store(0)
+
+		profiles, err := c.ListProfiles(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, profiles)
+		assert.EqualValues(t, 3, calls.Load())
+	})
+
+	t.Run("test call with idempotency key is retried with the same key", func(t *testing.T) {
+		calls.Store(0)
+		keys = nil
+
+		id, err := c.CreateProfile(ctx, &client.Profile{Name: "name"})
+		require.NoError(t, err)
+		assert.Equal(t, "1", id)
+		assert.EqualValues(t, 3, calls.Load())
+
+		require.Len(t, keys, 3)
+		assert.NotEmpty(t, keys[0])
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], keys[2])
+	})
+
+	t.Run("test non-idempotent call is not retried", func(t *testing.T) {
+		calls.Store(0)
+
+		err := c.LoginMFA(ctx, "mfa-token", "123456")
+
+		var apiErr *client.Error
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, "service_unavailable", apiErr.Code)
+		assert.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("test retries stop on context cancellation", func(t *testing.T) {
+		calls.Store(-100)
+
+		c, err := client.New(server.URL, client.WithToken("token"), client.WithRetries(10, time.Hour))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err = c.ListProfiles(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
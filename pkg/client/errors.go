@@ -0,0 +1,144 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MIME-тип ответов сервера с ошибками
+const problemContentType = "application/problem+json"
+
+// Машиночитаемые коды ошибок сервера
+const (
+	CodeInvalidBody        = "invalid_body"
+	CodeValidationFailed   = "validation_failed"
+	CodeMalformedToken     = "malformed_token"
+	CodeInvalidToken       = "invalid_token"
+	CodeTokenRevoked       = "token_revoked"
+	CodeForbidden          = "forbidden"
+	CodeUserNotFound       = "user_not_found"
+	CodeUserAlreadyExists  = "user_already_exists"
+	CodeBadCredentials     = "bad_credentials"
+	CodeUserDisabled       = "user_disabled"
+	CodeProfileNotFound    = "profile_not_found"
+	CodeTOTPAlreadyEnabled = "totp_already_enabled"
+	CodeTOTPNotEnrolled    = "totp_not_enrolled"
+	CodeInvalidMFACode     = "invalid_mfa_code"
+	CodeInvalidMFAToken    = "invalid_mfa_token"
+	CodeWebhookNotFound    = "webhook_not_found"
+	CodeDeliveryNotFound   = "delivery_not_found"
+	CodeRateLimited        = "rate_limited"
+	CodeIdempotencyInUse   = "idempotency_key_in_use"
+	CodeIdempotencyReused  = "idempotency_key_reused"
+	CodePhotoNotFound      = "photo_not_found"
+	CodePhotoTooLarge      = "photo_too_large"
+	CodeUnsupportedImage   = "unsupported_image"
+	CodeInternal           = "internal_error"
+)
+
+// Код нарушения в поле, уже занятом другим пользователем
+const FieldAlreadyExists = "already_exists"
+
+// Ошибки для сравнения через errors.Is: совпадение определяется кодом ошибки
+var (
+	ErrInvalidBody        = &Error{Code: CodeInvalidBody}
+	ErrValidationFailed   = &Error{Code: CodeValidationFailed}
+	ErrMalformedToken     = &Error{Code: CodeMalformedToken}
+	ErrInvalidToken       = &Error{Code: CodeInvalidToken}
+	ErrTokenRevoked       = &Error{Code: CodeTokenRevoked}
+	ErrForbidden          = &Error{Code: CodeForbidden}
+	ErrUserNotFound       = &Error{Code: CodeUserNotFound}
+	ErrUserAlreadyExists  = &Error{Code: CodeUserAlreadyExists}
+	ErrBadCredentials     = &Error{Code: CodeBadCredentials}
+	ErrUserDisabled       = &Error{Code: CodeUserDisabled}
+	ErrProfileNotFound    = &Error{Code: CodeProfileNotFound}
+	ErrTOTPAlreadyEnabled = &Error{Code: CodeTOTPAlreadyEnabled}
+	ErrTOTPNotEnrolled    = &Error{Code: CodeTOTPNotEnrolled}
+	ErrInvalidMFACode     = &Error{Code: CodeInvalidMFACode}
+	ErrInvalidMFAToken    = &Error{Code: CodeInvalidMFAToken}
+	ErrWebhookNotFound    = &Error{Code: CodeWebhookNotFound}
+	ErrDeliveryNotFound   = &Error{Code: CodeDeliveryNotFound}
+	ErrRateLimited        = &Error{Code: CodeRateLimited}
+	ErrIdempotencyInUse   = &Error{Code: CodeIdempotencyInUse}
+	ErrIdempotencyReused  = &Error{Code: CodeIdempotencyReused}
+	ErrPhotoNotFound      = &Error{Code: CodePhotoNotFound}
+	ErrPhotoTooLarge      = &Error{Code: CodePhotoTooLarge}
+	ErrUnsupportedImage   = &Error{Code: CodeUnsupportedImage}
+	ErrInternal           = &Error{Code: CodeInternal}
+)
+
+// Ошибка, возвращенная сервером в формате RFC 7807
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Instance   string       `json:"instance"`
+	Code       string       `json:"code"`
+	Fields     []FieldError `json:"errors"`
+}
+
+// Нарушение правила валидации в конкретном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("social api: %d %s", e.StatusCode, e.Code)
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Detail)
+	}
+
+	if len(e.Fields) > 0 {
+		fields := make([]string, len(e.Fields))
+		for i, f := range e.Fields {
+			fields[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+		}
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(fields, "; "))
+	}
+
+	return msg
+}
+
+// Ошибки совпадают, если совпадают их коды
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return e.Code == t.Code
+}
+
+// Нарушение в поле field, если оно есть
+func (e *Error) Field(field string) (FieldError, bool) {
+	for _, f := range e.Fields {
+		if f.Field == field {
+			return f, true
+		}
+	}
+
+	return FieldError{}, false
+}
+
+// Ошибка входа пользователя с включенным TOTP: вход нужно завершить через Client.LoginMFA
+type MFARequiredError struct {
+	MFAToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "social api: second factor required"
+}
+
+// Код ошибки, которую сервер вернул без описания, например прокси
+func codeFromStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternal
+	}
+
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Формат даты рождения в API
+const dateFormat = "2006-01-02"
+
+// Данные для регистрации пользователя
+type Registration struct {
+	Email    string `json:"email"`
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Пол владельца анкеты
+type Sex string
+
+const (
+	SexUnknown Sex = "unknown"
+	SexMale    Sex = "male"
+	SexFemale  Sex = "female"
+)
+
+// Дата без времени в формате YYYY-MM-DD
+type Date struct {
+	time.Time
+}
+
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateFormat)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", d.String())), nil
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return fmt.Errorf("parsing date '%s': %v", s, err)
+	}
+
+	d.Time = t
+	return nil
+}
+
+// Анкета пользователя
+type Profile struct {
+	Name      string `json:"name"`
+	Surname   string `json:"surname,omitempty"`
+	Sex       Sex    `json:"sex,omitempty"`
+	Birthdate Date   `json:"birthdate"`
+	City      string `json:"city,omitempty"`
+	Hobbies   string `json:"hobbies,omitempty"`
+	// Если не заданы при создании, сервер берет их из Hobbies через запятую.
+	// В ответах - в нижнем регистре, в алфавитном порядке
+	Interests []string `json:"interests,omitempty"`
+	// Последняя загруженная фотография. Заполняется сервером, при создании анкеты не передается
+	Avatar *Photo `json:"avatar,omitempty"`
+}
+
+// Кому видно поле анкеты
+type Visibility string
+
+const (
+	VisibilityPublic     Visibility = "public"
+	VisibilityRegistered Visibility = "registered"
+	VisibilityFriends    Visibility = "friends"
+	VisibilityHidden     Visibility = "hidden"
+)
+
+// Видимость полей анкеты. Поля без значения при изменении получают видимость по умолчанию (registered).
+// Владелец анкеты и администраторы видят все поля, у остальных скрытые поля в анкете пустые
+type Privacy struct {
+	Surname   Visibility `json:"surname,omitempty"`
+	Sex       Visibility `json:"sex,omitempty"`
+	Birthdate Visibility `json:"birthdate,omitempty"`
+	City      Visibility `json:"city,omitempty"`
+	Hobbies   Visibility `json:"hobbies,omitempty"`
+	// Аватар и файлы фотографий
+	Photos Visibility `json:"photos,omitempty"`
+}
+
+// Фотография анкеты
+type Photo struct {
+	Id        string `json:"id"`
+	ProfileId string `json:"profile_id"`
+	// Пути к файлам фотографии по имени размера, в том числе original, например /api/v1/photos/7/small
+	URLs      map[string]string `json:"urls"`
+	Sizes     []PhotoSize       `json:"sizes"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Размер фотографии в пикселях
+type PhotoSize struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Параметры поиска анкет: префиксы имени и фамилии и интерес целиком
+type SearchParams struct {
+	Name     string
+	Surname  string
+	Interest string
+}
+
+// Анкета с общими интересами
+type SimilarProfile struct {
+	ProfileId       string   `json:"profile_id"`
+	SharedInterests []string `json:"shared_interests"`
+	Profile         *Profile `json:"profile"`
+}
+
+// Подсказка интереса с числом анкет, в которых он указан
+type InterestSuggestion struct {
+	Name     string `json:"name"`
+	Profiles int    `json:"profiles"`
+}
+
+type idResponse struct {
+	Id string `json:"id"`
+}
+
+type loginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type loginResponse struct {
+	AccessToken string `json:"access_token"`
+	MFAToken    string `json:"mfa_token"`
+	MFARequired bool   `json:"mfa_required"`
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Создает анкету текущего пользователя и возвращает ее id
+func (c *Client) CreateProfile(ctx context.Context, profile *Profile) (string, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return "", err
+	}
+
+	var resp idResponse
+
+	err = c.do(ctx, request{
+		method:         http.MethodPost,
+		path:           "/profiles",
+		body:           profile,
+		authorized:     true,
+		idempotencyKey: key,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Id, nil
+}
+
+// Загружает фотографию анкеты (JPEG, PNG или WebP), которая становится ее аватаром.
+// Слишком большой файл - ошибка с кодом CodePhotoTooLarge, файл другого формата - CodeUnsupportedImage
+func (c *Client) UploadPhoto(ctx context.Context, profileId, filename string, data []byte) (*Photo, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("photo", filename)
+	if err != nil {
+		return nil, fmt.Errorf("creating form: %v", err)
+	}
+
+	_, err = part.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("writing form: %v", err)
+	}
+
+	err = form.Close()
+	if err != nil {
+		return nil, fmt.Errorf("closing form: %v", err)
+	}
+
+	var resp Photo
+
+	err = c.do(ctx, request{
+		method:         http.MethodPost,
+		path:           "/profiles/" + url.PathEscape(profileId) + "/photos",
+		rawBody:        body.Bytes(),
+		contentType:    form.FormDataContentType(),
+		authorized:     true,
+		idempotencyKey: key,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Возвращает настройки видимости полей анкеты. Чужие настройки доступны только администратору,
+// для остальных - ошибка с кодом CodeForbidden
+func (c *Client) GetPrivacy(ctx context.Context, profileId string) (*Privacy, error) {
+	var resp Privacy
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/profiles/" + url.PathEscape(profileId) + "/privacy",
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Заменяет настройки видимости полей анкеты и возвращает новые настройки всех полей
+func (c *Client) SetPrivacy(ctx context.Context, profileId string, privacy Privacy) (*Privacy, error) {
+	var resp Privacy
+
+	err := c.do(ctx, request{
+		method:     http.MethodPut,
+		path:       "/profiles/" + url.PathEscape(profileId) + "/privacy",
+		body:       privacy,
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Возвращает анкету по id. Если анкеты нет, возвращает ошибку с кодом CodeProfileNotFound
+func (c *Client) GetProfile(ctx context.Context, id string) (*Profile, error) {
+	var resp Profile
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/profiles/" + url.PathEscape(id),
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Возвращает все анкеты
+func (c *Client) ListProfiles(ctx context.Context) ([]*Profile, error) {
+	var resp []*Profile
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/profiles",
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Ищет анкеты по префиксам имени и фамилии и по интересу
+func (c *Client) SearchProfiles(ctx context.Context, params SearchParams) ([]*Profile, error) {
+	query := url.Values{}
+	if params.Name != "" {
+		query.Set("name", params.Name)
+	}
+	if params.Surname != "" {
+		query.Set("surname", params.Surname)
+	}
+	if params.Interest != "" {
+		query.Set("interest", params.Interest)
+	}
+
+	var resp []*Profile
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/profiles/search",
+		query:      query,
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Возвращает не более limit анкет других пользователей с общими с анкетой интересами,
+// начиная с анкет с наибольшим числом общих интересов. При limit 0 - число по умолчанию сервера
+func (c *Client) SimilarProfiles(ctx context.Context, profileId string, limit int) ([]*SimilarProfile, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp []*SimilarProfile
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/profiles/" + url.PathEscape(profileId) + "/similar",
+		query:      query,
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Подсказывает не более limit интересов, начинающихся с prefix, начиная с самых популярных.
+// При limit 0 - число по умолчанию сервера
+func (c *Client) AutocompleteInterests(ctx context.Context, prefix string, limit int) ([]*InterestSuggestion, error) {
+	query := url.Values{}
+	query.Set("prefix", prefix)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp []*InterestSuggestion
+
+	err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/interests/autocomplete",
+		query:      query,
+		authorized: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,111 @@
+// Пакет coalesce объединяет одновременные одинаковые запросы в один
+package coalesce
+
+import (
+	"context"
+	"sync"
+)
+
+// Группа запросов: пока выполняется запрос с некоторым ключом, новые запросы с тем же ключом
+// не выполняются, а дожидаются его результата. Нулевое значение готово к использованию
+type Group[V any] struct {
+	// Переносит в контекст запроса значения из контекста вызывающего, который его начал,
+	// например идентификатор запроса или логгер. Может быть не задана
+	Values func(ctx, from context.Context) context.Context
+
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+type call[V any] struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	// число присоединившихся вызовов: только растет и не меняется после завершения запроса
+	joined int
+
+	val V
+	err error
+}
+
+// Выполняет fn или присоединяется к уже выполняющемуся запросу с тем же ключом.
+// Возвращает признак того, что результат получен вместе с другими вызывающими.
+//
+// fn получает контекст, не зависящий от отмены контекста отдельного вызывающего:
+// если ctx отменен, Do сразу возвращает ошибку контекста, а запрос продолжается для остальных.
+// Контекст fn отменяется, только когда результата не ждет никто.
+// Результат общий, поэтому значения ctx, например транзакция вызывающего, в контекст fn
+// не попадают: переносятся только значения, скопированные через Values
+func (g *Group[V]) Do(ctx context.Context, key string, fn func(ctx context.Context) (V, error)) (V, bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[V])
+	}
+
+	c, shared := g.calls[key]
+	if shared {
+		c.waiters++
+		c.joined++
+	} else {
+		c = g.start(ctx, key, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, shared || c.joined > 0, c.err
+	case <-ctx.Done():
+		g.leave(key, c)
+
+		var zero V
+		return zero, shared, ctx.Err()
+	}
+}
+
+// Запускает запрос. Вызывается под g.mu
+func (g *Group[V]) start(ctx context.Context, key string, fn func(ctx context.Context) (V, error)) *call[V] {
+	callCtx := context.Background()
+	if g.Values != nil {
+		callCtx = g.Values(callCtx, ctx)
+	}
+	callCtx, cancel := context.WithCancel(callCtx)
+
+	c := &call[V]{
+		done:    make(chan struct{}),
+		cancel:  cancel,
+		waiters: 1,
+	}
+	g.calls[key] = c
+
+	go func() {
+		c.val, c.err = fn(callCtx)
+		cancel()
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	return c
+}
+
+// Отказ от ожидания результата. Запрос, результата которого никто не ждет, отменяется,
+// а следующие вызовы с тем же ключом начинают новый запрос
+func (g *Group[V]) leave(key string, c *call[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+
+	c.cancel()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
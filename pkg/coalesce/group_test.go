@@ -0,0 +1,176 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test concurrent calls share one execution", func(t *testing.T) {
+		var g Group[int]
+		var calls atomic.Int32
+		release := make(chan struct{})
+
+		fn := func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			<-release
+			return 42, nil
+		}
+
+		const callers = 10
+
+		var wg sync.WaitGroup
+		results := make([]int, callers)
+		shared := make([]bool, callers)
+
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				var err error
+				results[i], shared[i], err = g.Do(ctx, "key", fn)
+				assert.NoError(t, err)
+			}(i)
+		}
+
+		waitForWaiters(t, &g, "key", callers)
+		close(release)
+		wg.Wait()
+
+		assert.EqualValues(t, 1, calls.Load())
+		for i := 0; i < callers; i++ {
+			assert.Equal(t, 42, results[i])
+			assert.True(t, shared[i])
+		}
+	})
+
+	t.Run("test different keys run separately", func(t *testing.T) {
+		var g Group[string]
+
+		a, shared, err := g.Do(ctx, "a", func(ctx context.Context) (string, error) { return "a", nil })
+		require.NoError(t, err)
+		assert.False(t, shared)
+		assert.Equal(t, "a", a)
+
+		b, _, err := g.Do(ctx, "b", func(ctx context.Context) (string, error) { return "b", nil })
+		require.NoError(t, err)
+		assert.Equal(t, "b", b)
+	})
+
+	t.Run("test errors are shared", func(t *testing.T) {
+		var g Group[int]
+		expected := errors.New("db error")
+
+		_, _, err := g.Do(ctx, "key", func(ctx context.Context) (int, error) { return 0, expected })
+		assert.ErrorIs(t, err, expected)
+	})
+
+	t.Run("test cancelled caller does not cancel others", func(t *testing.T) {
+		var g Group[int]
+		release := make(chan struct{})
+
+		fn := func(ctx context.Context) (int, error) {
+			select {
+			case <-release:
+				return 42, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+
+		errs := make(chan error, 1)
+		go func() {
+			_, _, err := g.Do(cancelled, "key", fn)
+			errs <- err
+		}()
+
+		results := make(chan int, 1)
+		go func() {
+			val, _, err := g.Do(ctx, "key", fn)
+			assert.NoError(t, err)
+			results <- val
+		}()
+
+		waitForWaiters(t, &g, "key", 2)
+		cancel()
+		assert.ErrorIs(t, <-errs, context.Canceled)
+
+		close(release)
+		assert.Equal(t, 42, <-results)
+	})
+
+	t.Run("test call is cancelled when nobody waits", func(t *testing.T) {
+		var g Group[int]
+		stopped := make(chan error, 1)
+
+		fn := func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return 0, ctx.Err()
+		}
+
+		cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, _, err := g.Do(cancelled, "key", fn)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, <-stopped, context.Canceled)
+
+		// следующий вызов не должен получить результат отмененного запроса
+		val, shared, err := g.Do(ctx, "key", func(ctx context.Context) (int, error) { return 1, nil })
+		require.NoError(t, err)
+		assert.False(t, shared)
+		assert.Equal(t, 1, val)
+	})
+
+	t.Run("test caller values are not propagated", func(t *testing.T) {
+		type txKey struct{}
+		type requestIdKey struct{}
+
+		g := Group[string]{
+			Values: func(ctx, from context.Context) context.Context {
+				return context.WithValue(ctx, requestIdKey{}, from.Value(requestIdKey{}))
+			},
+		}
+
+		callerCtx := context.WithValue(ctx, txKey{}, "tx")
+		callerCtx = context.WithValue(callerCtx, requestIdKey{}, "request")
+
+		val, _, err := g.Do(callerCtx, "key", func(ctx context.Context) (string, error) {
+			assert.Nil(t, ctx.Value(txKey{}))
+			requestId, _ := ctx.Value(requestIdKey{}).(string)
+			return requestId, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "request", val)
+
+		var plain Group[any]
+		val2, _, err := plain.Do(callerCtx, "key", func(ctx context.Context) (any, error) {
+			return ctx.Value(txKey{}), nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, val2)
+	})
+}
+
+func waitForWaiters[V any](t *testing.T, g *Group[V], key string, waiters int) {
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		c, ok := g.calls[key]
+		return ok && c.waiters == waiters
+	}, time.Second, time.Millisecond)
+}
@@ -1,6 +1,12 @@
 package postgres
 
-import "fmt"
+import (
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	User     string
@@ -8,8 +14,59 @@ type Config struct {
 	Database string
 	Host     string
 	Port     uint16
+
+	// Режим TLS: disable, allow, prefer, require, verify-ca или verify-full. Пустой - по умолчанию pgx (prefer)
+	SSLMode string
+	// Время ожидания установки соединения
+	ConnectTimeout time.Duration
+	// Наибольшая длительность запроса, после которой сервер его прерывает. Не действует на соединение ViaSTD
+	StatementTimeout time.Duration
+	// Имя приложения в pg_stat_activity
+	ApplicationName string
+	// Схемы, в которых ищутся таблицы без указания схемы
+	SearchPath string
+
+	// Параметры пула соединений. Нулевые значения - по умолчанию pgxpool
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+
+	// Число повторных попыток подключения при запуске и задержка перед первой из них.
+	// Задержка удваивается с каждой попыткой, но не превышает maxConnectBackoff
+	ConnectRetries int
+	ConnectBackoff time.Duration
 }
 
+// Параметры соединения, общие для пула pgx и database/sql. Параметры пула задаются отдельно,
+// поскольку database/sql передал бы их серверу как параметры сеанса
 func (cfg Config) connectionURL() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	query := url.Values{}
+
+	if cfg.SSLMode != "" {
+		query.Set("sslmode", cfg.SSLMode)
+	}
+	if cfg.ConnectTimeout > 0 {
+		// pgx принимает таймаут в целых секундах, а 0 означает его отсутствие
+		query.Set("connect_timeout", strconv.Itoa(int(math.Ceil(cfg.ConnectTimeout.Seconds()))))
+	}
+	if cfg.StatementTimeout > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	if cfg.ApplicationName != "" {
+		query.Set("application_name", cfg.ApplicationName)
+	}
+	if cfg.SearchPath != "" {
+		query.Set("search_path", cfg.SearchPath)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))),
+		Path:     "/" + cfg.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
 }
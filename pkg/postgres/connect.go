@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Наибольшая задержка между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
+// Ждет, пока база начнет принимать соединения, например при одновременном запуске с приложением в docker-compose.
+// Делает до cfg.ConnectRetries повторных попыток с экспоненциально растущей задержкой
+func WaitReady(ctx context.Context, ping func(ctx context.Context) error, cfg *Config) error {
+	backoff := cfg.ConnectBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("connecting to %s:%d after %d attempts: %v", cfg.Host, cfg.Port, attempt+1, err)
+		}
+
+		slog.Warn("database is not ready, retrying", "attempt", attempt+1, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for database: %w", ctx.Err())
+		}
+
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
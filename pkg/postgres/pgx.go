@@ -12,8 +12,32 @@ type Pool struct {
 	pool *pgxpool.Pool
 }
 
+// Создает пул соединений. Соединения устанавливаются по мере надобности,
+// поэтому доступность базы нужно проверять отдельно, например через WaitReady
 func ViaPGX(ctx context.Context, cfg *Config) (Pool, error) {
-	pool, err := pgxpool.New(ctx, cfg.connectionURL())
+	poolConfig, err := pgxpool.ParseConfig(cfg.connectionURL())
+	if err != nil {
+		return Pool{}, fmt.Errorf("parsing pgx pool config: %v", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		return Pool{}, fmt.Errorf("min conns %d exceeds max conns %d", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return Pool{}, fmt.Errorf("creating new pgx pool: %v", err)
 	}
@@ -25,6 +49,10 @@ func (p Pool) Close() {
 	p.pool.Close()
 }
 
+func (p Pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.pool.Begin(ctx)
+}
+
 func (p Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
 	return p.pool.Query(ctx, sql, args...)
 }
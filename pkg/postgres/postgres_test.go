@@ -2,9 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig(t *testing.T) {
@@ -20,6 +25,55 @@ func TestConfig(t *testing.T) {
 	actual := cfg.connectionURL()
 
 	assert.Equal(t, expectedConnectionURL, actual)
+
+	t.Run("Credentials are escaped", func(t *testing.T) {
+		cfg := Config{
+			User:     "user@corp",
+			Password: "p@ss:w/rd?#",
+			Database: "db",
+			Host:     "host",
+			Port:     5432,
+		}
+
+		parsed, err := pgx.ParseConfig(cfg.connectionURL())
+		require.NoError(t, err)
+
+		assert.Equal(t, "user@corp", parsed.User)
+		assert.Equal(t, "p@ss:w/rd?#", parsed.Password)
+		assert.Equal(t, "host", parsed.Host)
+	})
+
+	t.Run("Connection options", func(t *testing.T) {
+		cfg := Config{
+			User:             "A",
+			Password:         "123",
+			Database:         "db",
+			Host:             "host",
+			Port:             10000,
+			SSLMode:          "disable",
+			ConnectTimeout:   1500 * time.Millisecond,
+			StatementTimeout: 2 * time.Second,
+			ApplicationName:  "social",
+			SearchPath:       "scl,public",
+		}
+
+		parsed, err := pgx.ParseConfig(cfg.connectionURL())
+		require.NoError(t, err)
+
+		assert.Nil(t, parsed.TLSConfig)
+		assert.Equal(t, 2*time.Second, parsed.ConnectTimeout)
+		assert.Equal(t, "2000", parsed.RuntimeParams["statement_timeout"])
+		assert.Equal(t, "social", parsed.RuntimeParams["application_name"])
+		assert.Equal(t, "scl,public", parsed.RuntimeParams["search_path"])
+
+		// соединение для миграций не ограничено statement_timeout
+		parsed, err = pgx.ParseConfig(cfg.stdURL())
+		require.NoError(t, err)
+
+		assert.NotContains(t, parsed.RuntimeParams, "statement_timeout")
+		assert.Equal(t, "social", parsed.RuntimeParams["application_name"])
+		assert.Equal(t, "scl,public", parsed.RuntimeParams["search_path"])
+	})
 }
 
 func TestNewPool(t *testing.T) {
@@ -44,6 +98,79 @@ func TestNewPool(t *testing.T) {
 
 		assert.Error(t, err)
 	})
+
+	t.Run("Pool options", func(t *testing.T) {
+		cfg := Config{
+			User:            "A",
+			Password:        "123",
+			Database:        "db",
+			Host:            "host",
+			Port:            10000,
+			MaxConns:        20,
+			MinConns:        2,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: time.Minute,
+		}
+
+		pool, err := ViaPGX(context.Background(), &cfg)
+		require.NoError(t, err)
+		defer pool.Close()
+
+		poolConfig := pool.pool.Config()
+		assert.EqualValues(t, 20, poolConfig.MaxConns)
+		assert.EqualValues(t, 2, poolConfig.MinConns)
+		assert.Equal(t, time.Hour, poolConfig.MaxConnLifetime)
+		assert.Equal(t, time.Minute, poolConfig.MaxConnIdleTime)
+	})
+
+	t.Run("Min conns exceed max conns", func(t *testing.T) {
+		cfg := Config{Database: "db", Host: "host", Port: 10000, MaxConns: 2, MinConns: 5}
+
+		_, err := ViaPGX(context.Background(), &cfg)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWaitReady(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{Host: "host", Port: 5432, ConnectRetries: 3, ConnectBackoff: time.Millisecond}
+
+	t.Run("Ready after retries", func(t *testing.T) {
+		calls := 0
+		ping := func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+
+		err := WaitReady(ctx, ping, cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Retries exhausted", func(t *testing.T) {
+		calls := 0
+		ping := func(context.Context) error {
+			calls++
+			return errors.New("connection refused")
+		}
+
+		err := WaitReady(ctx, ping, cfg)
+		assert.ErrorContains(t, err, "after 4 attempts")
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		slow := &Config{ConnectRetries: 3, ConnectBackoff: time.Hour}
+		err := WaitReady(cancelled, func(context.Context) error { return errors.New("connection refused") }, slow)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
 }
 
 func TestStd(t *testing.T) {
@@ -61,3 +188,81 @@ func TestStd(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestTxManager(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	m := NewTxManager(mock)
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("select").WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(1)))
+		mock.ExpectCommit()
+
+		err := m.InTx(ctx, func(ctx context.Context) error {
+			tx, ok := TxFrom(ctx)
+			require.True(t, ok)
+			require.Equal(t, tx, QuerierFrom(ctx, nil))
+
+			rows, err := QuerierFrom(ctx, nil).Query(ctx, "select 1")
+			require.NoError(t, err)
+			rows.Close()
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		fnErr := errors.New("failed")
+		err := m.InTx(ctx, func(ctx context.Context) error {
+			return fnErr
+		})
+		require.ErrorIs(t, err, fnErr)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		// вложенная транзакция начинается из внешней (точка сохранения), а не из пула
+		mock.ExpectBegin()
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectCommit()
+
+		err := m.InTx(ctx, func(ctx context.Context) error {
+			err := m.InTx(ctx, func(ctx context.Context) error {
+				return errors.New("failed")
+			})
+			require.Error(t, err)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		called := false
+		err := m.InTx(ctx, func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+		require.ErrorContains(t, err, "connection refused")
+		require.False(t, called)
+	})
+
+	t.Run("no transaction", func(t *testing.T) {
+		_, ok := TxFrom(ctx)
+		require.False(t, ok)
+		require.Equal(t, Querier(mock), QuerierFrom(ctx, mock))
+	})
+}
@@ -7,11 +7,19 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
 )
 
+// Соединение database/sql, через которое применяются миграции
 func ViaSTD(config *Config) (*sql.DB, error) {
-	db, err := sql.Open("pgx", config.connectionURL())
+	db, err := sql.Open("pgx", config.stdURL())
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %v", err)
 	}
 
 	return db, nil
 }
+
+// Параметры соединения для миграций. statement_timeout не задается: миграция может
+// выполняться дольше любого запроса приложения, и прерывать ее на середине нельзя
+func (cfg Config) stdURL() string {
+	cfg.StatementTimeout = 0
+	return cfg.connectionURL()
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Выполняет запросы: пул соединений или транзакция
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Источник транзакций, например Pool
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// Менеджер транзакций. Транзакция передается через контекст,
+// поэтому хранилища, получающие соединение через QuerierFrom, подхватывают ее сами
+type TxManager struct {
+	db Beginner
+}
+
+func NewTxManager(db Beginner) TxManager {
+	return TxManager{db}
+}
+
+// Выполняет функцию в транзакции и фиксирует ее, если функция не вернула ошибку.
+// Если в контексте уже есть транзакция, функция выполняется в точке сохранения внутри нее:
+// ошибка откатывает только изменения этой функции, а фиксация происходит вместе с внешней транзакцией
+func (m TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	var tx pgx.Tx
+	var err error
+
+	if outer, ok := TxFrom(ctx); ok {
+		// Begin у транзакции pgx создает точку сохранения
+		tx, err = outer.Begin(ctx)
+	} else {
+		tx, err = m.db.Begin(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// Транзакция, начатая TxManager.InTx, если контекст получен внутри нее
+func TxFrom(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Транзакция из контекста, а если ее нет - querier, например пул соединений
+func QuerierFrom(ctx context.Context, querier Querier) Querier {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx
+	}
+
+	return querier
+}